@@ -0,0 +1,134 @@
+package bootstrap
+
+import (
+	"app/domain"
+	"app/repository"
+	"app/usecase"
+	"database/sql"
+	"os"
+	"path/filepath"
+)
+
+// Config holds the settings and long-lived dependencies of the use cases.
+// The dependencies left nil are replaced by private ones.
+type Config struct {
+	JWTPrivateKey string
+	JWTPublicKey  string
+	SearchIndex   domain.SearchIndex
+	// BlobStore keeps uploaded files, in a temporary directory by default.
+	BlobStore domain.BlobStore
+	// ImageVariants are the smaller versions made of uploaded images,
+	// domain.DefaultImageVariants by default.
+	ImageVariants []domain.ImageVariant
+	// SitemapSize caps the URLs per sitemap, the protocol limit by default.
+	SitemapSize int
+	// Outbox records the domain events of the use cases; their handlers
+	// are registered on it.
+	Outbox *usecase.OutboxRelay
+	// Jobs runs the deferred work of the use cases; their handlers are
+	// registered and the recurring jobs scheduled on it.
+	Jobs *usecase.JobUseCaseImpl
+}
+
+// UseCases are the use cases of the application, wired to their
+// repositories, the outbox and the jobs. The API serves them and the
+// workers run the background ones.
+type UseCases struct {
+	Auth         domain.AuthUseCase
+	Post         domain.PostUseCase
+	Tag          domain.TagUseCase
+	Category     domain.CategoryUseCase
+	Comment      domain.CommentUsecase
+	Search       domain.SearchUseCase
+	Reaction     domain.ReactionUseCase
+	Bookmark     domain.BookmarkUseCase
+	Follow       domain.FollowUseCase
+	Notification domain.NotificationUseCase
+	Mention      domain.MentionUseCase
+	Sitemap      domain.SitemapUseCase
+	Attachment   domain.AttachmentUseCase
+	Webhook      *usecase.WebhookUseCaseImpl
+	Outbox       *usecase.OutboxRelay
+	Jobs         *usecase.JobUseCaseImpl
+}
+
+// NewUseCases builds the use cases, registers the outbox handlers and the
+// job handlers, and schedules the recurring jobs.
+func NewUseCases(db *sql.DB, config Config) (*UseCases, error) {
+	transactor := repository.NewSQLTransactor(db)
+	tokenRepository := repository.NewTokenRepositoryJWT(config.JWTPrivateKey, config.JWTPublicKey)
+	userRepository := repository.NewUserRepositoryMySQL(db)
+	postRepository := repository.NewPostRepositoryMySQL(db)
+	commentRepository := repository.NewCommentRepositoryMySQL(db)
+	tagRepository := repository.NewTagRepositoryMySQL(db)
+	categoryRepository := repository.NewCategoryRepositoryMySQL(db)
+	reactionRepository := repository.NewReactionRepositoryMySQL(db)
+	bookmarkRepository := repository.NewBookmarkRepositoryMySQL(db)
+	followRepository := repository.NewFollowRepositoryMySQL(db)
+	notificationRepository := repository.NewNotificationRepositoryMySQL(db)
+	mentionRepository := repository.NewMentionRepositoryMySQL(db)
+	webhookRepository := repository.NewWebhookRepositoryMySQL(db)
+	attachmentRepository := repository.NewAttachmentRepositoryMySQL(db)
+
+	outbox := config.Outbox
+	if outbox == nil {
+		outbox = usecase.NewOutboxRelay(repository.NewOutboxRepositoryMySQL(db), transactor)
+	}
+	blobStore := config.BlobStore
+	if blobStore == nil {
+		var err error
+		blobStore, err = repository.NewBlobStoreLocal(filepath.Join(os.TempDir(), "backend-takehome-uploads"))
+		if err != nil {
+			return nil, err
+		}
+	}
+	jobs := config.Jobs
+	if jobs == nil {
+		jobs = usecase.NewJobUseCaseImpl(repository.NewJobRepositoryMySQL(db), transactor)
+	}
+	imageVariants := config.ImageVariants
+	if imageVariants == nil {
+		imageVariants = domain.DefaultImageVariants
+	}
+	broker := repository.NewEventBrokerMemory()
+	notificationUseCase := usecase.NewNotificationUseCaseImpl(notificationRepository, userRepository, broker)
+	webhookUseCase := usecase.NewWebhookUseCaseImpl(webhookRepository, postRepository, transactor)
+	postUseCase := usecase.NewPostUsecaseImpl(postRepository, userRepository, tagRepository, categoryRepository, reactionRepository, bookmarkRepository, mentionRepository, attachmentRepository, config.SearchIndex, outbox, transactor)
+	searchUseCase := usecase.NewSearchUseCaseImpl(config.SearchIndex, postRepository, commentRepository, userRepository, tagRepository)
+	attachmentUseCase := usecase.NewAttachmentUseCaseImpl(attachmentRepository, postRepository, blobStore, jobs, transactor, imageVariants)
+	outbox.Register("notifications", notificationUseCase)
+	outbox.Register("search", searchUseCase)
+	outbox.Register("webhooks", webhookUseCase)
+	jobs.Register(domain.JobCleanupJobs, usecase.JobFunc(jobs.CleanupJobs))
+	jobs.Register(domain.JobCleanupOutbox, usecase.JobFunc(outbox.Cleanup))
+	jobs.Register(domain.JobCleanupAttachments, usecase.JobFunc(attachmentUseCase.CleanupAttachments))
+	jobs.Register(domain.JobProcessAttachment, usecase.JobFunc(attachmentUseCase.Process))
+	if err := jobs.Schedule(domain.JobCleanupJobs, "30 3 * * *", domain.JobCleanupJobs, domain.CleanupJobPayload{RetentionDays: 7}); err != nil {
+		return nil, err
+	}
+	if err := jobs.Schedule(domain.JobCleanupOutbox, "45 3 * * *", domain.JobCleanupOutbox, domain.CleanupJobPayload{RetentionDays: 7}); err != nil {
+		return nil, err
+	}
+	if err := jobs.Schedule(domain.JobCleanupAttachments, "@hourly", domain.JobCleanupAttachments, domain.CleanupJobPayload{RetentionDays: 1}); err != nil {
+		return nil, err
+	}
+
+	return &UseCases{
+		Auth:         usecase.NewAuthUseCaseImpl(userRepository, tokenRepository, transactor),
+		Post:         postUseCase,
+		Tag:          usecase.NewTagUseCaseImpl(tagRepository, postUseCase),
+		Category:     usecase.NewCategoryUseCaseImpl(categoryRepository, transactor),
+		Comment:      usecase.NewCommentUseCaseImpl(commentRepository, userRepository, postRepository, mentionRepository, outbox, broker, transactor),
+		Search:       searchUseCase,
+		Reaction:     usecase.NewReactionUseCaseImpl(reactionRepository, postRepository, transactor),
+		Bookmark:     usecase.NewBookmarkUseCaseImpl(bookmarkRepository, postRepository, transactor),
+		Follow:       usecase.NewFollowUseCaseImpl(followRepository, userRepository, usecase.NewFanOutOnReadFeed(postRepository), outbox, transactor),
+		Notification: notificationUseCase,
+		Mention:      usecase.NewMentionUseCaseImpl(mentionRepository),
+		Sitemap:      usecase.NewSitemapUseCaseImpl(postRepository, config.SitemapSize),
+		Attachment:   attachmentUseCase,
+		Webhook:      webhookUseCase,
+		Outbox:       outbox,
+		Jobs:         jobs,
+	}, nil
+}
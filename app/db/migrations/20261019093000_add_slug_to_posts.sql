@@ -0,0 +1,20 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE posts ADD COLUMN slug VARCHAR(255) NULL;
+UPDATE posts SET slug = CONCAT('post-', id);
+ALTER TABLE posts MODIFY slug VARCHAR(255) NOT NULL;
+CREATE UNIQUE INDEX index_slug_table_posts ON posts (slug);
+CREATE TABLE post_slugs (
+  slug VARCHAR(255) PRIMARY KEY,
+  post_id INT NOT NULL,
+  created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (post_id) REFERENCES posts(id)
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE post_slugs;
+DROP INDEX index_slug_table_posts ON posts;
+ALTER TABLE posts DROP COLUMN slug;
+-- +goose StatementEnd
@@ -0,0 +1,9 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE posts ADD COLUMN content_markdown MEDIUMTEXT NULL, ADD COLUMN toc JSON NULL;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE posts DROP COLUMN content_markdown, DROP COLUMN toc;
+-- +goose StatementEnd
@@ -0,0 +1,9 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE FULLTEXT INDEX fulltext_title_content_table_posts ON posts (title, content);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX fulltext_title_content_table_posts ON posts;
+-- +goose StatementEnd
@@ -0,0 +1,14 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE INDEX index_deleted_at_created_at_table_posts ON posts (deleted_at, created_at);
+CREATE INDEX index_post_id_created_at_table_comments ON comments (post_id, created_at);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+-- MySQL drops the implicit foreign key index on comments.post_id once the
+-- composite index covers it, so it has to be restored first.
+CREATE INDEX post_id ON comments (post_id);
+DROP INDEX index_post_id_created_at_table_comments ON comments;
+DROP INDEX index_deleted_at_created_at_table_posts ON posts;
+-- +goose StatementEnd
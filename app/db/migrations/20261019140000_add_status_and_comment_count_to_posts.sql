@@ -0,0 +1,14 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE posts
+  ADD COLUMN status ENUM('draft', 'published') NOT NULL DEFAULT 'published' AFTER category_id,
+  ADD COLUMN comment_count INT NOT NULL DEFAULT 0 AFTER status;
+UPDATE posts SET comment_count = (SELECT count(id) FROM comments WHERE comments.post_id = posts.id);
+CREATE INDEX index_status_author_id_table_posts ON posts (status, author_id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX index_status_author_id_table_posts ON posts;
+ALTER TABLE posts DROP COLUMN comment_count, DROP COLUMN status;
+-- +goose StatementEnd
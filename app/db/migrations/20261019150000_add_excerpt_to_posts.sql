@@ -0,0 +1,10 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE posts ADD COLUMN excerpt VARCHAR(300) NOT NULL DEFAULT '' AFTER slug;
+UPDATE posts SET excerpt = LEFT(TRIM(REGEXP_REPLACE(REGEXP_REPLACE(content, '<[^>]*>', ' '), '[[:space:]]+', ' ')), 280);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE posts DROP COLUMN excerpt;
+-- +goose StatementEnd
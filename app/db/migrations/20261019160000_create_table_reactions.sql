@@ -0,0 +1,26 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE reactions (
+  post_id INT NOT NULL,
+  user_id INT NOT NULL,
+  type VARCHAR(16) NOT NULL,
+  created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (post_id, user_id, type),
+    FOREIGN KEY (post_id) REFERENCES posts(id),
+    FOREIGN KEY (user_id) REFERENCES users(id)
+);
+CREATE INDEX index_post_id_created_at_table_reactions ON reactions (post_id, created_at);
+CREATE TABLE reaction_counts (
+  post_id INT NOT NULL,
+  type VARCHAR(16) NOT NULL,
+  count INT NOT NULL DEFAULT 0,
+  PRIMARY KEY (post_id, type),
+    FOREIGN KEY (post_id) REFERENCES posts(id)
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE reaction_counts;
+DROP TABLE reactions;
+-- +goose StatementEnd
@@ -0,0 +1,28 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE bookmark_collections (
+  id INT AUTO_INCREMENT PRIMARY KEY,
+  user_id INT NOT NULL,
+  name VARCHAR(64) NOT NULL,
+  created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  UNIQUE KEY index_user_id_name_table_bookmark_collections (user_id, name),
+    FOREIGN KEY (user_id) REFERENCES users(id)
+);
+CREATE TABLE bookmarks (
+  user_id INT NOT NULL,
+  post_id INT NOT NULL,
+  collection_id INT NULL,
+  created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (user_id, post_id),
+    FOREIGN KEY (user_id) REFERENCES users(id),
+    FOREIGN KEY (post_id) REFERENCES posts(id),
+    FOREIGN KEY (collection_id) REFERENCES bookmark_collections(id)
+);
+CREATE INDEX index_user_id_created_at_post_id_table_bookmarks ON bookmarks (user_id, created_at, post_id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE bookmarks;
+DROP TABLE bookmark_collections;
+-- +goose StatementEnd
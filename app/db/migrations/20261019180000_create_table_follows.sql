@@ -0,0 +1,22 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE follows (
+  follower_id INT NOT NULL,
+  followee_id INT NOT NULL,
+  created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (follower_id, followee_id),
+    FOREIGN KEY (follower_id) REFERENCES users(id),
+    FOREIGN KEY (followee_id) REFERENCES users(id)
+);
+CREATE INDEX index_followee_id_created_at_table_follows ON follows (followee_id, created_at);
+CREATE INDEX index_author_id_created_at_id_table_posts ON posts (author_id, created_at, id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+-- The composite index replaced the implicit foreign key index on
+-- posts.author_id, which has to be restored before it can go.
+CREATE INDEX author_id ON posts (author_id);
+DROP INDEX index_author_id_created_at_id_table_posts ON posts;
+DROP TABLE follows;
+-- +goose StatementEnd
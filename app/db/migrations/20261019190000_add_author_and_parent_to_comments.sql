@@ -0,0 +1,16 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE comments
+  ADD COLUMN author_id INT NULL AFTER post_id,
+  ADD COLUMN parent_id INT NULL AFTER author_id,
+  ADD CONSTRAINT fk_author_id_table_comments FOREIGN KEY (author_id) REFERENCES users(id),
+  ADD CONSTRAINT fk_parent_id_table_comments FOREIGN KEY (parent_id) REFERENCES comments(id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE comments
+  DROP FOREIGN KEY fk_parent_id_table_comments,
+  DROP FOREIGN KEY fk_author_id_table_comments;
+ALTER TABLE comments DROP COLUMN parent_id, DROP COLUMN author_id;
+-- +goose StatementEnd
@@ -0,0 +1,32 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE notifications (
+  id INT AUTO_INCREMENT PRIMARY KEY,
+  user_id INT NOT NULL,
+  actor_id INT NOT NULL,
+  type VARCHAR(32) NOT NULL,
+  post_id INT NULL,
+  comment_id INT NULL,
+  read_at TIMESTAMP NULL,
+  created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (user_id) REFERENCES users(id),
+    FOREIGN KEY (actor_id) REFERENCES users(id),
+    FOREIGN KEY (post_id) REFERENCES posts(id),
+    FOREIGN KEY (comment_id) REFERENCES comments(id)
+);
+CREATE INDEX index_user_id_created_at_table_notifications ON notifications (user_id, created_at);
+CREATE INDEX index_user_id_read_at_table_notifications ON notifications (user_id, read_at);
+CREATE TABLE notification_preferences (
+  user_id INT NOT NULL,
+  type VARCHAR(32) NOT NULL,
+  enabled BOOLEAN NOT NULL,
+  PRIMARY KEY (user_id, type),
+    FOREIGN KEY (user_id) REFERENCES users(id)
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE notification_preferences;
+DROP TABLE notifications;
+-- +goose StatementEnd
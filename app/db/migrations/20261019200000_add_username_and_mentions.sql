@@ -0,0 +1,27 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE users ADD COLUMN username VARCHAR(30) NULL AFTER name;
+UPDATE users SET username = CONCAT('user_', id);
+ALTER TABLE users MODIFY username VARCHAR(30) NOT NULL;
+CREATE UNIQUE INDEX index_username_table_users ON users (username);
+CREATE TABLE mentions (
+  id INT AUTO_INCREMENT PRIMARY KEY,
+  user_id INT NOT NULL,
+  author_id INT NOT NULL,
+  post_id INT NOT NULL,
+  comment_id INT NULL,
+  created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (user_id) REFERENCES users(id),
+    FOREIGN KEY (author_id) REFERENCES users(id),
+    FOREIGN KEY (post_id) REFERENCES posts(id),
+    FOREIGN KEY (comment_id) REFERENCES comments(id)
+);
+CREATE INDEX index_user_id_created_at_table_mentions ON mentions (user_id, created_at);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE mentions;
+DROP INDEX index_username_table_users ON users;
+ALTER TABLE users DROP COLUMN username;
+-- +goose StatementEnd
@@ -0,0 +1,11 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE comments
+  ADD COLUMN updated_at TIMESTAMP NULL AFTER created_at,
+  ADD COLUMN deleted_at TIMESTAMP NULL AFTER updated_at;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE comments DROP COLUMN deleted_at, DROP COLUMN updated_at;
+-- +goose StatementEnd
@@ -0,0 +1,29 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE outbox (
+  id BIGINT AUTO_INCREMENT PRIMARY KEY,
+  idempotency_key CHAR(32) NOT NULL,
+  type VARCHAR(32) NOT NULL,
+  payload MEDIUMTEXT NOT NULL,
+  attempts INT NOT NULL DEFAULT 0,
+  last_error VARCHAR(255) NULL,
+  available_at TIMESTAMP(6) NOT NULL,
+  processed_at TIMESTAMP NULL,
+  created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+CREATE UNIQUE INDEX index_idempotency_key_table_outbox ON outbox (idempotency_key);
+CREATE INDEX index_processed_at_available_at_table_outbox ON outbox (processed_at, available_at);
+CREATE TABLE outbox_handled (
+  event_id BIGINT NOT NULL,
+  handler VARCHAR(32) NOT NULL,
+  handled_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (event_id, handler),
+    FOREIGN KEY (event_id) REFERENCES outbox(id) ON DELETE CASCADE
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE outbox_handled;
+DROP TABLE outbox;
+-- +goose StatementEnd
@@ -0,0 +1,28 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE jobs (
+  id BIGINT AUTO_INCREMENT PRIMARY KEY,
+  type VARCHAR(64) NOT NULL,
+  payload MEDIUMTEXT NOT NULL,
+  status VARCHAR(16) NOT NULL DEFAULT 'pending',
+  attempts INT NOT NULL DEFAULT 0,
+  max_attempts INT NOT NULL,
+  last_error VARCHAR(255) NULL,
+  run_at TIMESTAMP(6) NOT NULL,
+  finished_at TIMESTAMP NULL,
+  created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  updated_at TIMESTAMP NULL
+);
+CREATE INDEX index_status_run_at_table_jobs ON jobs (status, run_at);
+CREATE INDEX index_type_table_jobs ON jobs (type);
+CREATE TABLE job_schedules (
+  name VARCHAR(64) PRIMARY KEY,
+  next_run_at TIMESTAMP(6) NOT NULL
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE job_schedules;
+DROP TABLE jobs;
+-- +goose StatementEnd
@@ -0,0 +1,23 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE attachments (
+  id INT AUTO_INCREMENT PRIMARY KEY,
+  owner_id INT NOT NULL,
+  post_id INT NULL,
+  blob_key VARCHAR(128) NOT NULL,
+  filename VARCHAR(255) NOT NULL,
+  content_type VARCHAR(64) NOT NULL,
+  size BIGINT NOT NULL,
+  created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (owner_id) REFERENCES users(id),
+    FOREIGN KEY (post_id) REFERENCES posts(id) ON DELETE SET NULL
+);
+CREATE UNIQUE INDEX index_blob_key_table_attachments ON attachments (blob_key);
+CREATE INDEX index_post_id_table_attachments ON attachments (post_id);
+CREATE INDEX index_created_at_table_attachments ON attachments (created_at);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE attachments;
+-- +goose StatementEnd
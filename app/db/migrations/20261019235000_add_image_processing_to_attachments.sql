@@ -0,0 +1,25 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE attachments
+  ADD COLUMN width INT NULL AFTER size,
+  ADD COLUMN height INT NULL AFTER width,
+  ADD COLUMN blurhash VARCHAR(64) NULL AFTER height,
+  ADD COLUMN processed_at TIMESTAMP NULL AFTER blurhash;
+CREATE TABLE attachment_variants (
+  attachment_id INT NOT NULL,
+  name VARCHAR(32) NOT NULL,
+  blob_key VARCHAR(160) NOT NULL,
+  content_type VARCHAR(64) NOT NULL,
+  width INT NOT NULL,
+  height INT NOT NULL,
+  size BIGINT NOT NULL,
+  PRIMARY KEY (attachment_id, name),
+    FOREIGN KEY (attachment_id) REFERENCES attachments(id) ON DELETE CASCADE
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE attachment_variants;
+ALTER TABLE attachments DROP COLUMN processed_at, DROP COLUMN blurhash, DROP COLUMN height, DROP COLUMN width;
+-- +goose StatementEnd
@@ -0,0 +1,15 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE posts ADD COLUMN content_text TEXT NULL AFTER content;
+UPDATE posts SET content_text = TRIM(REGEXP_REPLACE(REGEXP_REPLACE(content, '<[^>]*>', ' '), '[[:space:]]+', ' '));
+ALTER TABLE posts MODIFY content_text TEXT NOT NULL;
+DROP INDEX fulltext_title_content_table_posts ON posts;
+CREATE FULLTEXT INDEX fulltext_title_content_text_table_posts ON posts (title, content_text);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX fulltext_title_content_text_table_posts ON posts;
+CREATE FULLTEXT INDEX fulltext_title_content_table_posts ON posts (title, content);
+ALTER TABLE posts DROP COLUMN content_text;
+-- +goose StatementEnd
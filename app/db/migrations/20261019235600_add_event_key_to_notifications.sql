@@ -0,0 +1,11 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE notifications ADD COLUMN event_key CHAR(32) NULL AFTER comment_id;
+CREATE UNIQUE INDEX index_event_key_user_id_type_table_notifications ON notifications (event_key, user_id, type);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX index_event_key_user_id_type_table_notifications ON notifications;
+ALTER TABLE notifications DROP COLUMN event_key;
+-- +goose StatementEnd
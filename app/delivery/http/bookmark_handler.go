@@ -0,0 +1,99 @@
+package http
+
+import (
+	"app/domain"
+	"app/pkg/common"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type BookmarkHandler struct {
+	bookmarkUseCase domain.BookmarkUseCase
+	cursors         *CursorCodec
+}
+
+func NewBookmarkHandler(r *gin.RouterGroup, middleware *MiddlewareHandler, bookmarkUseCase domain.BookmarkUseCase, cursors *CursorCodec) {
+	handler := &BookmarkHandler{
+		bookmarkUseCase: bookmarkUseCase,
+		cursors:         cursors,
+	}
+	r.Use(middleware.AuthMiddleware)
+	r.GET("/bookmarks", handler.FindBookmarks)
+	r.PUT("/bookmarks/:postID", handler.Save)
+	r.DELETE("/bookmarks/:postID", handler.Delete)
+	r.GET("/collections", handler.GetCollections)
+}
+
+func (h *BookmarkHandler) Save(ctx *gin.Context) {
+	var request domain.SaveBookmarkRequestDTO
+	if ctx.Request.ContentLength != 0 {
+		if err := ctx.ShouldBindJSON(&request); err != nil {
+			err = common.NewCustomError(http.StatusBadRequest, err.Error())
+			handleError(ctx, err)
+			return
+		}
+	}
+	var path struct {
+		PostID int64 `uri:"postID" binding:"required"`
+	}
+	if err := ctx.ShouldBindUri(&path); err != nil {
+		err = common.NewCustomError(http.StatusBadRequest, err.Error())
+		handleError(ctx, err)
+		return
+	}
+	request.UserID = ctx.GetInt64("userID")
+	request.PostID = path.PostID
+	if err := h.bookmarkUseCase.Save(ctx, request); err != nil {
+		handleError(ctx, err)
+		return
+	}
+	handleOK(ctx, nil)
+}
+
+func (h *BookmarkHandler) Delete(ctx *gin.Context) {
+	var path struct {
+		PostID int64 `uri:"postID" binding:"required"`
+	}
+	if err := ctx.ShouldBindUri(&path); err != nil {
+		err = common.NewCustomError(http.StatusBadRequest, err.Error())
+		handleError(ctx, err)
+		return
+	}
+	if err := h.bookmarkUseCase.Delete(ctx, ctx.GetInt64("userID"), path.PostID); err != nil {
+		handleError(ctx, err)
+		return
+	}
+	handleOK(ctx, nil)
+}
+
+func (h *BookmarkHandler) FindBookmarks(ctx *gin.Context) {
+	var request domain.BookmarkParam
+	if err := ctx.ShouldBindQuery(&request); err != nil {
+		err = common.NewCustomError(http.StatusBadRequest, err.Error())
+		handleError(ctx, err)
+		return
+	}
+	if request.Limit == 0 {
+		request.Limit = 10
+	}
+	if err := h.cursors.bind(&request.SearchParam); err != nil {
+		handleError(ctx, err)
+		return
+	}
+	bookmarks, page, err := h.bookmarkUseCase.FindByUser(ctx, ctx.GetInt64("userID"), request)
+	if err != nil {
+		handleError(ctx, err)
+		return
+	}
+	handleCursorPagination(ctx, bookmarks, request.Limit, page, h.cursors)
+}
+
+func (h *BookmarkHandler) GetCollections(ctx *gin.Context) {
+	collections, err := h.bookmarkUseCase.GetCollections(ctx, ctx.GetInt64("userID"))
+	if err != nil {
+		handleError(ctx, err)
+		return
+	}
+	handleOK(ctx, collections)
+}
@@ -0,0 +1,49 @@
+package http
+
+import (
+	"app/domain"
+	"app/pkg/common"
+	"app/pkg/logger"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type CategoryHandler struct {
+	categoryUseCase domain.CategoryUseCase
+}
+
+func NewCategoryHandler(r *gin.RouterGroup, middleware *MiddlewareHandler, categoryUseCase domain.CategoryUseCase) {
+	handler := &CategoryHandler{
+		categoryUseCase: categoryUseCase,
+	}
+	r.GET("", handler.GetTree)
+
+	r.Use(middleware.AdminMiddleware)
+	r.POST("", handler.Create)
+}
+
+func (h *CategoryHandler) Create(ctx *gin.Context) {
+	var request *domain.CreateCategoryRequestDTO
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		logger.Log.Error(err.Error())
+		err = common.NewCustomError(http.StatusBadRequest, err.Error())
+		handleError(ctx, err)
+		return
+	}
+	response, err := h.categoryUseCase.Create(ctx, request)
+	if err != nil {
+		handleError(ctx, err)
+		return
+	}
+	handleOKCreated(ctx, response)
+}
+
+func (h *CategoryHandler) GetTree(ctx *gin.Context) {
+	response, err := h.categoryUseCase.GetTree(ctx)
+	if err != nil {
+		handleError(ctx, err)
+		return
+	}
+	handleOK(ctx, response)
+}
@@ -3,23 +3,43 @@ package http
 import (
 	"app/domain"
 	"app/pkg/common"
+	"io"
 	"net/http"
+	"strconv"
+	"time"
 
+	"github.com/gin-contrib/sse"
 	"github.com/gin-gonic/gin"
 )
 
+// streamHeartbeat is how often an idle comment stream sends a comment line,
+// so that proxies do not close it and readers notice dropped connections.
+const streamHeartbeat = 15 * time.Second
+
 type CommentHandler struct {
 	commentUseCase domain.CommentUsecase
+	cursors        *CursorCodec
+	hub            *Hub
 }
 
-func NewCommentHandler(r *gin.RouterGroup, middleware *MiddlewareHandler, commentUseCase domain.CommentUsecase) {
+func NewCommentHandler(r *gin.RouterGroup, middleware *MiddlewareHandler, commentUseCase domain.CommentUsecase, cursors *CursorCodec, hub *Hub) {
 	handler := &CommentHandler{
 		commentUseCase: commentUseCase,
+		cursors:        cursors,
+		hub:            hub,
 	}
-	r.GET("", handler.FindCommentsByPostID)
+	r.GET("", middleware.OptionalAuth, handler.FindCommentsByPostID)
+	r.GET("/stream", middleware.OptionalAuth, handler.StreamComments)
 
 	r.Use(middleware.AuthMiddleware)
 	r.POST("", handler.CreateComment)
+	r.PUT("/:commentID", handler.UpdateComment)
+	r.DELETE("/:commentID", handler.DeleteComment)
+}
+
+type commentPath struct {
+	PostID    int64 `uri:"postID" binding:"required"`
+	CommentID int64 `uri:"commentID" binding:"required"`
 }
 
 func (h *CommentHandler) CreateComment(ctx *gin.Context) {
@@ -62,6 +82,26 @@ func (h *CommentHandler) FindCommentsByPostID(ctx *gin.Context) {
 		handleError(ctx, err)
 		return
 	}
+	if request.Limit == 0 {
+		request.Limit = 10
+	}
+	if request.Page == 0 {
+		request.Page = 1
+	}
+	request.ViewerID = ctx.GetInt64("userID")
+	if request.UseCursor() {
+		if err := h.cursors.bind(&request); err != nil {
+			handleError(ctx, err)
+			return
+		}
+		comments, page, err := h.commentUseCase.FindCommentsByPostIDCursor(ctx, path.PostID, request)
+		if err != nil {
+			handleError(ctx, err)
+			return
+		}
+		handleCursorPagination(ctx, comments, request.Limit, page, h.cursors)
+		return
+	}
 	comments, total, err := h.commentUseCase.FindCommentsByPostID(ctx, path.PostID, request)
 	if err != nil {
 		handleError(ctx, err)
@@ -69,3 +109,90 @@ func (h *CommentHandler) FindCommentsByPostID(ctx *gin.Context) {
 	}
 	handlePagination(ctx, comments, request.Page, request.Limit, total)
 }
+
+func (h *CommentHandler) UpdateComment(ctx *gin.Context) {
+	var request domain.UpdateCommentRequestDTO
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		err = common.NewCustomError(http.StatusBadRequest, err.Error())
+		handleError(ctx, err)
+		return
+	}
+	var path commentPath
+	if err := ctx.ShouldBindUri(&path); err != nil {
+		err = common.NewCustomError(http.StatusBadRequest, err.Error())
+		handleError(ctx, err)
+		return
+	}
+	request.AuthorID = ctx.GetInt64("userID")
+	comment, err := h.commentUseCase.UpdateComment(ctx, path.PostID, path.CommentID, request)
+	if err != nil {
+		handleError(ctx, err)
+		return
+	}
+	handleOK(ctx, comment)
+}
+
+func (h *CommentHandler) DeleteComment(ctx *gin.Context) {
+	var path commentPath
+	if err := ctx.ShouldBindUri(&path); err != nil {
+		err = common.NewCustomError(http.StatusBadRequest, err.Error())
+		handleError(ctx, err)
+		return
+	}
+	if err := h.commentUseCase.DeleteComment(ctx, path.PostID, path.CommentID, ctx.GetInt64("userID")); err != nil {
+		handleError(ctx, err)
+		return
+	}
+	handleOK(ctx, nil)
+}
+
+// StreamComments sends the comments created, edited and deleted on a post as
+// Server-Sent Events until the reader disconnects or the server shuts down.
+// Readers reconnecting with Last-Event-ID first get the events they missed,
+// as far as they are retained.
+func (h *CommentHandler) StreamComments(ctx *gin.Context) {
+	var path struct {
+		PostID int64 `uri:"postID" binding:"required"`
+	}
+	if err := ctx.ShouldBindUri(&path); err != nil {
+		err = common.NewCustomError(http.StatusBadRequest, err.Error())
+		handleError(ctx, err)
+		return
+	}
+	var lastEventID int64
+	if header := ctx.GetHeader("Last-Event-ID"); header != "" {
+		id, err := strconv.ParseInt(header, 10, 64)
+		if err != nil {
+			handleError(ctx, common.ErrInvalidParam)
+			return
+		}
+		lastEventID = id
+	}
+	events, err := h.commentUseCase.StreamComments(ctx.Request.Context(), path.PostID, ctx.GetInt64("userID"), lastEventID)
+	if err != nil {
+		handleError(ctx, err)
+		return
+	}
+	ctx.Header("Content-Type", "text/event-stream")
+	ctx.Header("Cache-Control", "no-cache")
+	ctx.Header("X-Accel-Buffering", "no")
+	ctx.Status(http.StatusOK)
+	ctx.Writer.Flush()
+	heartbeat := time.NewTicker(streamHeartbeat)
+	defer heartbeat.Stop()
+	ctx.Stream(func(w io.Writer) bool {
+		select {
+		case event, ok := <-events:
+			if !ok {
+				return false
+			}
+			ctx.Render(-1, sse.Event{Id: strconv.FormatInt(event.ID, 10), Event: event.Type, Data: event.Data})
+			return true
+		case <-heartbeat.C:
+			_, err := io.WriteString(w, ":\n\n")
+			return err == nil
+		case <-h.hub.Done():
+			return false
+		}
+	})
+}
@@ -0,0 +1,75 @@
+package http
+
+import (
+	"app/domain"
+	"app/pkg/common"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"time"
+)
+
+const cursorMACSize = 16
+
+// CursorCodec turns keyset positions into opaque tokens. Tokens are signed,
+// so clients cannot forge positions or probe rows through them.
+type CursorCodec struct {
+	key []byte
+}
+
+// NewCursorCodec derives the signing key from secret, keeping it distinct
+// from any other use of the same secret.
+func NewCursorCodec(secret string) *CursorCodec {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte("pagination cursor"))
+	return &CursorCodec{key: mac.Sum(nil)}
+}
+
+func (c *CursorCodec) sign(payload []byte) []byte {
+	mac := hmac.New(sha256.New, c.key)
+	mac.Write(payload)
+	return mac.Sum(nil)[:cursorMACSize]
+}
+
+func (c *CursorCodec) Encode(cursor *domain.Cursor) *string {
+	if cursor == nil {
+		return nil
+	}
+	payload := make([]byte, 16)
+	binary.BigEndian.PutUint64(payload, uint64(cursor.CreatedAt.UnixNano()))
+	binary.BigEndian.PutUint64(payload[8:], uint64(cursor.ID))
+	token := base64.RawURLEncoding.EncodeToString(append(payload, c.sign(payload)...))
+	return &token
+}
+
+func (c *CursorCodec) Decode(token string) (*domain.Cursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil || len(data) != 16+cursorMACSize {
+		return nil, common.ErrInvalidCursor
+	}
+	payload := data[:16]
+	if !hmac.Equal(data[16:], c.sign(payload)) {
+		return nil, common.ErrInvalidCursor
+	}
+	return &domain.Cursor{
+		CreatedAt: time.Unix(0, int64(binary.BigEndian.Uint64(payload))).UTC(),
+		ID:        int64(binary.BigEndian.Uint64(payload[8:])),
+	}, nil
+}
+
+// bind decodes the after and before tokens of the query into positions.
+func (c *CursorCodec) bind(param *domain.SearchParam) error {
+	var err error
+	if param.After != "" {
+		if param.AfterKey, err = c.Decode(param.After); err != nil {
+			return err
+		}
+	}
+	if param.Before != "" {
+		if param.BeforeKey, err = c.Decode(param.Before); err != nil {
+			return err
+		}
+	}
+	return nil
+}
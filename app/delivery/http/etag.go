@@ -0,0 +1,77 @@
+package http
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+func versionETag(version int64) string {
+	return strconv.Quote(strconv.FormatInt(version, 10))
+}
+
+func setVersionETag(ctx *gin.Context, version int64) {
+	ctx.Header("ETag", versionETag(version))
+}
+
+// contentETag tags a post response with its version followed by a hash of
+// the response. The counters and the viewer's own state in the response do
+// not bump the version, so If-None-Match needs the hash, while If-Match only
+// looks at the version.
+func contentETag(version int64, response interface{}) (string, error) {
+	body, err := json.Marshal(response)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(body)
+	return strconv.Quote(strconv.FormatInt(version, 10) + "-" + hex.EncodeToString(sum[:8])), nil
+}
+
+// parseIfMatch returns the post versions listed in the If-Match header,
+// which may carry either tag set on posts. A missing header or "*" yields an
+// empty list, meaning the request is unconditional. Weak or foreign entity
+// tags never match a version, so they are mapped to -1 to make the
+// precondition fail instead of being ignored.
+func parseIfMatch(ctx *gin.Context) []int64 {
+	header := strings.TrimSpace(ctx.GetHeader("If-Match"))
+	if header == "" || header == "*" {
+		return nil
+	}
+	var versions []int64
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimSpace(tag)
+		unquoted, err := strconv.Unquote(tag)
+		if err != nil {
+			versions = append(versions, -1)
+			continue
+		}
+		unquoted, _, _ = strings.Cut(unquoted, "-")
+		version, err := strconv.ParseInt(unquoted, 10, 64)
+		if err != nil {
+			versions = append(versions, -1)
+			continue
+		}
+		versions = append(versions, version)
+	}
+	return versions
+}
+
+// notModified reports whether the If-None-Match header already lists the
+// given entity tag.
+func notModified(ctx *gin.Context, etag string) bool {
+	header := ctx.GetHeader("If-None-Match")
+	if header == "" {
+		return false
+	}
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimPrefix(strings.TrimSpace(tag), "W/")
+		if tag == "*" || tag == etag {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,279 @@
+package http
+
+import (
+	"app/domain"
+	"app/pkg/common"
+	"app/pkg/logger"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+const (
+	feedSize      = 20
+	rssMediaType  = "application/rss+xml; charset=utf-8"
+	atomMediaType = "application/atom+xml; charset=utf-8"
+)
+
+type atomFeed struct {
+	XMLName xml.Name    `xml:"http://www.w3.org/2005/Atom feed"`
+	ID      string      `xml:"id"`
+	Title   string      `xml:"title"`
+	Updated string      `xml:"updated"`
+	Links   []atomLink  `xml:"link"`
+	Entries []atomEntry `xml:"entry"`
+}
+
+type atomLink struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr,omitempty"`
+	Type string `xml:"type,attr,omitempty"`
+}
+
+type atomEntry struct {
+	ID         string         `xml:"id"`
+	Title      string         `xml:"title"`
+	Published  string         `xml:"published"`
+	Updated    string         `xml:"updated"`
+	Links      []atomLink     `xml:"link"`
+	Author     *atomPerson    `xml:"author,omitempty"`
+	Categories []atomCategory `xml:"category"`
+	Summary    atomText       `xml:"summary"`
+	Content    *atomText      `xml:"content,omitempty"`
+}
+
+type atomPerson struct {
+	Name string `xml:"name"`
+}
+
+type atomCategory struct {
+	Term  string `xml:"term,attr"`
+	Label string `xml:"label,attr,omitempty"`
+}
+
+type atomText struct {
+	Type string `xml:"type,attr"`
+	Body string `xml:",chardata"`
+}
+
+type rssFeed struct {
+	XMLName xml.Name   `xml:"rss"`
+	Version string     `xml:"version,attr"`
+	Channel rssChannel `xml:"channel"`
+}
+
+type rssChannel struct {
+	Title         string    `xml:"title"`
+	Link          string    `xml:"link"`
+	Description   string    `xml:"description"`
+	LastBuildDate string    `xml:"lastBuildDate,omitempty"`
+	Items         []rssItem `xml:"item"`
+}
+
+type rssItem struct {
+	Title       string   `xml:"title"`
+	Link        string   `xml:"link"`
+	GUID        rssGUID  `xml:"guid"`
+	PubDate     string   `xml:"pubDate"`
+	Categories  []string `xml:"category"`
+	Description string   `xml:"description"`
+}
+
+type rssGUID struct {
+	IsPermaLink bool   `xml:"isPermaLink,attr"`
+	Value       string `xml:",chardata"`
+}
+
+type FeedHandler struct {
+	postUseCase domain.PostUseCase
+	baseURL     string
+}
+
+func NewFeedHandler(r *gin.RouterGroup, postUseCase domain.PostUseCase, baseURL string) {
+	handler := &FeedHandler{
+		postUseCase: postUseCase,
+		baseURL:     strings.TrimSuffix(baseURL, "/"),
+	}
+	r.GET("/feed.rss", handler.SiteRSS)
+	r.GET("/feed.atom", handler.SiteAtom)
+	r.GET("/users/:userID/feed.atom", handler.UserAtom)
+	r.GET("/tags/:tag/feed.atom", handler.TagAtom)
+}
+
+func (h *FeedHandler) SiteRSS(ctx *gin.Context) {
+	h.serve(ctx, rssMediaType, "Latest posts", domain.SearchParam{})
+}
+
+func (h *FeedHandler) SiteAtom(ctx *gin.Context) {
+	h.serve(ctx, atomMediaType, "Latest posts", domain.SearchParam{})
+}
+
+func (h *FeedHandler) UserAtom(ctx *gin.Context) {
+	var path struct {
+		UserID int64 `uri:"userID" binding:"required"`
+	}
+	if err := ctx.ShouldBindUri(&path); err != nil {
+		logger.Log.Error(err.Error())
+		handleError(ctx, common.ErrInvalidParam)
+		return
+	}
+	h.serve(ctx, atomMediaType, fmt.Sprintf("Posts by user %d", path.UserID), domain.SearchParam{AuthorID: path.UserID})
+}
+
+func (h *FeedHandler) TagAtom(ctx *gin.Context) {
+	tag := ctx.Param("tag")
+	h.serve(ctx, atomMediaType, "Posts tagged "+tag, domain.SearchParam{Tag: tag})
+}
+
+// serve renders the latest posts matching search as a feed. Feeds carry the
+// excerpt of each post unless content=full is requested, and honour
+// If-None-Match and If-Modified-Since so readers can poll cheaply.
+func (h *FeedHandler) serve(ctx *gin.Context, mediaType, title string, search domain.SearchParam) {
+	var query struct {
+		Content string `form:"content" binding:"omitempty,oneof=excerpt full"`
+	}
+	if err := ctx.ShouldBindQuery(&query); err != nil {
+		logger.Log.Error(err.Error())
+		handleError(ctx, common.NewCustomError(http.StatusBadRequest, err.Error()))
+		return
+	}
+	search.Page = 1
+	search.Limit = feedSize
+	search.Sort = domain.SortNewest
+	search.Include = "author,tags"
+	posts, _, err := h.postUseCase.GetAll(ctx, search)
+	if err != nil {
+		handleError(ctx, err)
+		return
+	}
+	if search.AuthorID != 0 && len(posts) > 0 && posts[0].Author != nil {
+		title = "Posts by " + posts[0].Author.Name
+	}
+
+	full := query.Content == "full"
+	self := h.baseURL + ctx.Request.URL.RequestURI()
+	var body []byte
+	if mediaType == rssMediaType {
+		body, err = xml.Marshal(h.rss(title, posts, full))
+	} else {
+		body, err = xml.Marshal(h.atom(title, self, posts, full))
+	}
+	if err != nil {
+		logger.Log.Error("failed to render feed", zap.Error(err))
+		handleError(ctx, common.ErrInternalServerError)
+		return
+	}
+
+	sum := sha256.Sum256(body)
+	etag := strconv.Quote(hex.EncodeToString(sum[:16]))
+	modified := lastModified(posts)
+	ctx.Header("ETag", etag)
+	ctx.Header("Last-Modified", modified.Format(http.TimeFormat))
+	if ctx.GetHeader("If-None-Match") != "" {
+		if notModified(ctx, etag) {
+			ctx.Status(http.StatusNotModified)
+			return
+		}
+	} else if since, err := http.ParseTime(ctx.GetHeader("If-Modified-Since")); err == nil && !modified.After(since) {
+		ctx.Status(http.StatusNotModified)
+		return
+	}
+	ctx.Data(http.StatusOK, mediaType, append([]byte(xml.Header), body...))
+}
+
+func (h *FeedHandler) postURL(post *domain.Post) string {
+	return h.baseURL + "/posts/by-slug/" + url.PathEscape(post.Slug)
+}
+
+func (h *FeedHandler) atom(title, self string, posts []domain.Post, full bool) *atomFeed {
+	feed := &atomFeed{
+		ID:      self,
+		Title:   title,
+		Updated: lastModified(posts).Format(time.RFC3339),
+		Links:   []atomLink{{Href: self, Rel: "self", Type: atomMediaType}},
+		Entries: []atomEntry{},
+	}
+	for i := range posts {
+		post := &posts[i]
+		entry := atomEntry{
+			ID:        fmt.Sprintf("%s/posts/%d", h.baseURL, post.ID),
+			Title:     post.Title,
+			Published: post.CreatedAt.UTC().Format(time.RFC3339),
+			Updated:   postModified(post).Format(time.RFC3339),
+			Links:     []atomLink{{Href: h.postURL(post), Rel: "alternate"}},
+			Summary:   atomText{Type: "text", Body: post.Excerpt},
+		}
+		if post.Author != nil {
+			entry.Author = &atomPerson{Name: post.Author.Name}
+		}
+		for _, tag := range post.Tags {
+			entry.Categories = append(entry.Categories, atomCategory{Term: tag.Slug, Label: tag.Name})
+		}
+		if full {
+			entry.Content = &atomText{Type: "html", Body: post.Content}
+		}
+		feed.Entries = append(feed.Entries, entry)
+	}
+	return feed
+}
+
+func (h *FeedHandler) rss(title string, posts []domain.Post, full bool) *rssFeed {
+	feed := &rssFeed{
+		Version: "2.0",
+		Channel: rssChannel{
+			Title:       title,
+			Link:        h.baseURL + "/posts",
+			Description: title,
+			Items:       []rssItem{},
+		},
+	}
+	if len(posts) > 0 {
+		feed.Channel.LastBuildDate = lastModified(posts).Format(time.RFC1123Z)
+	}
+	for i := range posts {
+		post := &posts[i]
+		item := rssItem{
+			Title:       post.Title,
+			Link:        h.postURL(post),
+			GUID:        rssGUID{Value: fmt.Sprintf("%s/posts/%d", h.baseURL, post.ID)},
+			PubDate:     post.CreatedAt.UTC().Format(time.RFC1123Z),
+			Description: post.Excerpt,
+		}
+		for _, tag := range post.Tags {
+			item.Categories = append(item.Categories, tag.Name)
+		}
+		if full {
+			item.Description = post.Content
+		}
+		feed.Channel.Items = append(feed.Channel.Items, item)
+	}
+	return feed
+}
+
+func postModified(post *domain.Post) time.Time {
+	if post.UpdatedAt != nil {
+		return post.UpdatedAt.UTC()
+	}
+	return post.CreatedAt.UTC()
+}
+
+// lastModified is the latest change among the posts, truncated to the
+// second precision of HTTP dates. An empty feed never changed.
+func lastModified(posts []domain.Post) time.Time {
+	modified := time.Unix(0, 0).UTC()
+	for i := range posts {
+		if t := postModified(&posts[i]); t.After(modified) {
+			modified = t
+		}
+	}
+	return modified.Truncate(time.Second)
+}
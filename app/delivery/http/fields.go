@@ -0,0 +1,54 @@
+package http
+
+import (
+	"app/domain"
+	"app/pkg/common"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+)
+
+// checkPostList rejects the fields and include names a post listing does
+// not know.
+func checkPostList(search domain.SearchParam) error {
+	for _, field := range search.FieldList() {
+		if !slices.Contains(domain.PostFields, field) {
+			return common.NewCustomError(http.StatusBadRequest, fmt.Sprintf("unknown field %s", field))
+		}
+	}
+	for _, relation := range search.IncludeList() {
+		if !slices.Contains(domain.PostIncludes, relation) {
+			return common.NewCustomError(http.StatusBadRequest, fmt.Sprintf("unknown include %s", relation))
+		}
+	}
+	return nil
+}
+
+// sparsePosts narrows every post to the requested fieldset. Embedded
+// relations and search annotations are kept whenever they are present.
+func sparsePosts(posts []domain.Post, search domain.SearchParam) (interface{}, error) {
+	fields := search.FieldList()
+	if len(fields) == 0 {
+		return posts, nil
+	}
+	keep := append(append(fields, search.IncludeList()...), "score", "snippet")
+	sparse := make([]map[string]json.RawMessage, 0, len(posts))
+	for _, post := range posts {
+		data, err := json.Marshal(post)
+		if err != nil {
+			return nil, err
+		}
+		var members map[string]json.RawMessage
+		if err := json.Unmarshal(data, &members); err != nil {
+			return nil, err
+		}
+		for name := range members {
+			if !slices.Contains(keep, name) {
+				delete(members, name)
+			}
+		}
+		sparse = append(sparse, members)
+	}
+	return sparse, nil
+}
@@ -0,0 +1,136 @@
+package http
+
+import (
+	"app/domain"
+	"app/pkg/common"
+	"context"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type FollowHandler struct {
+	followUseCase domain.FollowUseCase
+	cursors       *CursorCodec
+}
+
+func NewFollowHandler(users *gin.RouterGroup, feed *gin.RouterGroup, middleware *MiddlewareHandler, followUseCase domain.FollowUseCase, cursors *CursorCodec) {
+	handler := &FollowHandler{
+		followUseCase: followUseCase,
+		cursors:       cursors,
+	}
+	users.GET("/:userID", handler.GetProfile)
+	users.GET("/:userID/followers", handler.FindFollowers)
+	users.GET("/:userID/following", handler.FindFollowing)
+
+	users.Use(middleware.AuthMiddleware)
+	users.PUT("/:userID/follow", handler.Follow)
+	users.DELETE("/:userID/follow", handler.Unfollow)
+
+	feed.Use(middleware.AuthMiddleware)
+	feed.GET("", handler.Feed)
+}
+
+type userPath struct {
+	UserID int64 `uri:"userID" binding:"required"`
+}
+
+func (h *FollowHandler) GetProfile(ctx *gin.Context) {
+	var path userPath
+	if err := ctx.ShouldBindUri(&path); err != nil {
+		err = common.NewCustomError(http.StatusBadRequest, err.Error())
+		handleError(ctx, err)
+		return
+	}
+	profile, err := h.followUseCase.GetProfile(ctx, path.UserID)
+	if err != nil {
+		handleError(ctx, err)
+		return
+	}
+	handleOK(ctx, profile)
+}
+
+func (h *FollowHandler) Follow(ctx *gin.Context) {
+	var path userPath
+	if err := ctx.ShouldBindUri(&path); err != nil {
+		err = common.NewCustomError(http.StatusBadRequest, err.Error())
+		handleError(ctx, err)
+		return
+	}
+	if err := h.followUseCase.Follow(ctx, ctx.GetInt64("userID"), path.UserID); err != nil {
+		handleError(ctx, err)
+		return
+	}
+	handleOK(ctx, nil)
+}
+
+func (h *FollowHandler) Unfollow(ctx *gin.Context) {
+	var path userPath
+	if err := ctx.ShouldBindUri(&path); err != nil {
+		err = common.NewCustomError(http.StatusBadRequest, err.Error())
+		handleError(ctx, err)
+		return
+	}
+	if err := h.followUseCase.Unfollow(ctx, ctx.GetInt64("userID"), path.UserID); err != nil {
+		handleError(ctx, err)
+		return
+	}
+	handleOK(ctx, nil)
+}
+
+func (h *FollowHandler) FindFollowers(ctx *gin.Context) {
+	h.findFollows(ctx, h.followUseCase.FindFollowers)
+}
+
+func (h *FollowHandler) FindFollowing(ctx *gin.Context) {
+	h.findFollows(ctx, h.followUseCase.FindFollowing)
+}
+
+func (h *FollowHandler) findFollows(ctx *gin.Context, find func(ctx context.Context, userID int64, param domain.SearchParam) ([]domain.FollowUser, int64, error)) {
+	var request domain.SearchParam
+	if err := ctx.ShouldBindQuery(&request); err != nil {
+		err = common.NewCustomError(http.StatusBadRequest, err.Error())
+		handleError(ctx, err)
+		return
+	}
+	var path userPath
+	if err := ctx.ShouldBindUri(&path); err != nil {
+		err = common.NewCustomError(http.StatusBadRequest, err.Error())
+		handleError(ctx, err)
+		return
+	}
+	if request.Limit == 0 {
+		request.Limit = 10
+	}
+	if request.Page == 0 {
+		request.Page = 1
+	}
+	users, total, err := find(ctx, path.UserID, request)
+	if err != nil {
+		handleError(ctx, err)
+		return
+	}
+	handlePagination(ctx, users, request.Page, request.Limit, total)
+}
+
+func (h *FollowHandler) Feed(ctx *gin.Context) {
+	var request domain.SearchParam
+	if err := ctx.ShouldBindQuery(&request); err != nil {
+		err = common.NewCustomError(http.StatusBadRequest, err.Error())
+		handleError(ctx, err)
+		return
+	}
+	if request.Limit == 0 {
+		request.Limit = 10
+	}
+	if err := h.cursors.bind(&request); err != nil {
+		handleError(ctx, err)
+		return
+	}
+	posts, page, err := h.followUseCase.Feed(ctx, ctx.GetInt64("userID"), request)
+	if err != nil {
+		handleError(ctx, err)
+		return
+	}
+	handleCursorPagination(ctx, posts, request.Limit, page, h.cursors)
+}
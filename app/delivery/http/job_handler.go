@@ -0,0 +1,78 @@
+package http
+
+import (
+	"app/domain"
+	"app/pkg/common"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type JobHandler struct {
+	jobUseCase domain.JobUseCase
+}
+
+type jobPath struct {
+	ID int64 `uri:"id" binding:"required"`
+}
+
+func NewJobHandler(r *gin.RouterGroup, middleware *MiddlewareHandler, jobUseCase domain.JobUseCase) {
+	handler := &JobHandler{
+		jobUseCase: jobUseCase,
+	}
+	r.Use(middleware.AdminMiddleware)
+	r.GET("", handler.FindJobs)
+	r.GET("/:id", handler.FindJob)
+	r.POST("/:id/retry", handler.RetryJob)
+}
+
+func (h *JobHandler) FindJobs(ctx *gin.Context) {
+	var request domain.JobFilter
+	if err := ctx.ShouldBindQuery(&request); err != nil {
+		err = common.NewCustomError(http.StatusBadRequest, err.Error())
+		handleError(ctx, err)
+		return
+	}
+	if request.Limit == 0 {
+		request.Limit = 10
+	}
+	if request.Page == 0 {
+		request.Page = 1
+	}
+	jobs, total, err := h.jobUseCase.FindAll(ctx, request)
+	if err != nil {
+		handleError(ctx, err)
+		return
+	}
+	handlePagination(ctx, jobs, request.Page, request.Limit, total)
+}
+
+func (h *JobHandler) FindJob(ctx *gin.Context) {
+	var path jobPath
+	if err := ctx.ShouldBindUri(&path); err != nil {
+		err = common.NewCustomError(http.StatusBadRequest, err.Error())
+		handleError(ctx, err)
+		return
+	}
+	job, err := h.jobUseCase.FindByID(ctx, path.ID)
+	if err != nil {
+		handleError(ctx, err)
+		return
+	}
+	handleOK(ctx, job)
+}
+
+func (h *JobHandler) RetryJob(ctx *gin.Context) {
+	var path jobPath
+	if err := ctx.ShouldBindUri(&path); err != nil {
+		err = common.NewCustomError(http.StatusBadRequest, err.Error())
+		handleError(ctx, err)
+		return
+	}
+	job, err := h.jobUseCase.Retry(ctx, path.ID)
+	if err != nil {
+		handleError(ctx, err)
+		return
+	}
+	handleOK(ctx, job)
+}
@@ -0,0 +1,42 @@
+package http
+
+import (
+	"app/domain"
+	"app/pkg/common"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type MentionHandler struct {
+	mentionUseCase domain.MentionUseCase
+}
+
+func NewMentionHandler(r *gin.RouterGroup, middleware *MiddlewareHandler, mentionUseCase domain.MentionUseCase) {
+	handler := &MentionHandler{
+		mentionUseCase: mentionUseCase,
+	}
+	r.Use(middleware.AuthMiddleware)
+	r.GET("", handler.FindMentions)
+}
+
+func (h *MentionHandler) FindMentions(ctx *gin.Context) {
+	var request domain.SearchParam
+	if err := ctx.ShouldBindQuery(&request); err != nil {
+		err = common.NewCustomError(http.StatusBadRequest, err.Error())
+		handleError(ctx, err)
+		return
+	}
+	if request.Limit == 0 {
+		request.Limit = 10
+	}
+	if request.Page == 0 {
+		request.Page = 1
+	}
+	mentions, total, err := h.mentionUseCase.FindByUser(ctx, ctx.GetInt64("userID"), request)
+	if err != nil {
+		handleError(ctx, err)
+		return
+	}
+	handlePagination(ctx, mentions, request.Page, request.Limit, total)
+}
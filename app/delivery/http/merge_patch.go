@@ -0,0 +1,49 @@
+package http
+
+import (
+	"app/pkg/common"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+)
+
+const mergePatchContentType = "application/merge-patch+json"
+
+// bindMergePatch decodes an RFC 7396 JSON Merge Patch into target. Only the
+// members listed in fields may be patched, and a null member (which removes
+// the field) is only accepted when fields marks it as nullable. The decoded
+// target is validated with its binding tags just like a regular JSON body.
+func bindMergePatch(ctx *gin.Context, target interface{}, fields map[string]bool) error {
+	contentType := ctx.ContentType()
+	if contentType != mergePatchContentType && contentType != binding.MIMEJSON {
+		return common.ErrUnsupportedMedia
+	}
+	body, err := ctx.GetRawData()
+	if err != nil {
+		return common.NewCustomError(http.StatusBadRequest, err.Error())
+	}
+	var members map[string]json.RawMessage
+	if err := json.Unmarshal(body, &members); err != nil || members == nil {
+		return common.NewCustomError(http.StatusBadRequest, "merge patch must be a JSON object")
+	}
+	for name, value := range members {
+		nullable, ok := fields[name]
+		if !ok {
+			return common.NewCustomError(http.StatusBadRequest, fmt.Sprintf("field %s cannot be patched", name))
+		}
+		if !nullable && bytes.Equal(bytes.TrimSpace(value), []byte("null")) {
+			return common.NewCustomError(http.StatusBadRequest, fmt.Sprintf("field %s cannot be removed", name))
+		}
+	}
+	if err := json.Unmarshal(body, target); err != nil {
+		return common.NewCustomError(http.StatusBadRequest, err.Error())
+	}
+	if err := binding.Validator.ValidateStruct(target); err != nil {
+		return common.NewCustomError(http.StatusBadRequest, err.Error())
+	}
+	return nil
+}
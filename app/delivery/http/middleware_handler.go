@@ -18,23 +18,51 @@ func NewMiddlewareHandler(authUsecase domain.AuthUseCase) *MiddlewareHandler {
 }
 
 func (h *MiddlewareHandler) AuthMiddleware(ctx *gin.Context) {
-	tokenCookie, err := ctx.Request.Cookie("AUTHORIZATION")
+	userID, err := h.authenticate(ctx)
 	if err != nil {
-		err := common.ErrInvalidToken
 		handleError(ctx, err)
 		ctx.Abort()
+		return
 	}
-	token := tokenCookie.Value
-	if token == "" {
-		err := common.ErrInvalidToken
-		handleError(ctx, err)
-		ctx.Abort()
+	ctx.Set("userID", userID)
+	ctx.Next()
+}
+
+// OptionalAuth identifies the caller when a valid token is sent and lets
+// anonymous requests through, for public routes whose response depends on
+// who is asking.
+func (h *MiddlewareHandler) OptionalAuth(ctx *gin.Context) {
+	ctx.Header("Vary", "Cookie")
+	if userID, err := h.authenticate(ctx); err == nil {
+		ctx.Set("userID", userID)
+	}
+	ctx.Next()
+}
+
+// AdminMiddleware only lets signed in admins through.
+func (h *MiddlewareHandler) AdminMiddleware(ctx *gin.Context) {
+	userID, err := h.authenticate(ctx)
+	if err == nil {
+		err = h.authUsecase.RequireRole(ctx, userID, domain.RoleAdmin)
 	}
-	res, err := h.authUsecase.VerifyToken(ctx, token)
 	if err != nil {
 		handleError(ctx, err)
 		ctx.Abort()
+		return
 	}
-	ctx.Set("userID", res.UserID)
+	ctx.Set("userID", userID)
 	ctx.Next()
 }
+
+// authenticate returns the user the AUTHORIZATION cookie was issued to.
+func (h *MiddlewareHandler) authenticate(ctx *gin.Context) (int64, error) {
+	tokenCookie, err := ctx.Request.Cookie("AUTHORIZATION")
+	if err != nil || tokenCookie.Value == "" {
+		return 0, common.ErrInvalidToken
+	}
+	res, err := h.authUsecase.VerifyToken(ctx, tokenCookie.Value)
+	if err != nil {
+		return 0, err
+	}
+	return res.UserID, nil
+}
@@ -0,0 +1,104 @@
+package http
+
+import (
+	"app/domain"
+	"app/pkg/common"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type NotificationHandler struct {
+	notificationUseCase domain.NotificationUseCase
+}
+
+func NewNotificationHandler(r *gin.RouterGroup, middleware *MiddlewareHandler, notificationUseCase domain.NotificationUseCase) {
+	handler := &NotificationHandler{
+		notificationUseCase: notificationUseCase,
+	}
+	r.Use(middleware.AuthMiddleware)
+	r.GET("", handler.FindNotifications)
+	r.GET("/unread-count", handler.CountUnread)
+	r.POST("/read-all", handler.MarkAllRead)
+	r.POST("/:id/read", handler.MarkRead)
+	r.GET("/preferences", handler.GetPreferences)
+	r.PUT("/preferences", handler.SetPreferences)
+}
+
+func (h *NotificationHandler) FindNotifications(ctx *gin.Context) {
+	var request domain.NotificationParam
+	if err := ctx.ShouldBindQuery(&request); err != nil {
+		err = common.NewCustomError(http.StatusBadRequest, err.Error())
+		handleError(ctx, err)
+		return
+	}
+	if request.Limit == 0 {
+		request.Limit = 10
+	}
+	if request.Page == 0 {
+		request.Page = 1
+	}
+	notifications, total, err := h.notificationUseCase.FindByUser(ctx, ctx.GetInt64("userID"), request)
+	if err != nil {
+		handleError(ctx, err)
+		return
+	}
+	handlePagination(ctx, notifications, request.Page, request.Limit, total)
+}
+
+func (h *NotificationHandler) CountUnread(ctx *gin.Context) {
+	response, err := h.notificationUseCase.CountUnread(ctx, ctx.GetInt64("userID"))
+	if err != nil {
+		handleError(ctx, err)
+		return
+	}
+	handleOK(ctx, response)
+}
+
+func (h *NotificationHandler) MarkRead(ctx *gin.Context) {
+	var path struct {
+		ID int64 `uri:"id" binding:"required"`
+	}
+	if err := ctx.ShouldBindUri(&path); err != nil {
+		err = common.NewCustomError(http.StatusBadRequest, err.Error())
+		handleError(ctx, err)
+		return
+	}
+	if err := h.notificationUseCase.MarkRead(ctx, ctx.GetInt64("userID"), path.ID); err != nil {
+		handleError(ctx, err)
+		return
+	}
+	handleOK(ctx, nil)
+}
+
+func (h *NotificationHandler) MarkAllRead(ctx *gin.Context) {
+	if err := h.notificationUseCase.MarkAllRead(ctx, ctx.GetInt64("userID")); err != nil {
+		handleError(ctx, err)
+		return
+	}
+	handleOK(ctx, nil)
+}
+
+func (h *NotificationHandler) GetPreferences(ctx *gin.Context) {
+	preferences, err := h.notificationUseCase.GetPreferences(ctx, ctx.GetInt64("userID"))
+	if err != nil {
+		handleError(ctx, err)
+		return
+	}
+	handleOK(ctx, preferences)
+}
+
+func (h *NotificationHandler) SetPreferences(ctx *gin.Context) {
+	var request map[string]bool
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		err = common.NewCustomError(http.StatusBadRequest, err.Error())
+		handleError(ctx, err)
+		return
+	}
+	preferences, err := h.notificationUseCase.SetPreferences(ctx, ctx.GetInt64("userID"), request)
+	if err != nil {
+		handleError(ctx, err)
+		return
+	}
+	handleOK(ctx, preferences)
+}
@@ -5,26 +5,44 @@ import (
 	"app/pkg/common"
 	"app/pkg/logger"
 	"net/http"
+	"net/url"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 )
 
+// postPatchFields lists the post members a merge patch may change and
+// whether they may be set to null.
+var postPatchFields = map[string]bool{
+	"title":            false,
+	"content":          false,
+	"content_markdown": false,
+	"tags":             false,
+	"attachment_ids":   false,
+	"category_id":      true,
+	"status":           false,
+}
+
 type PostHandler struct {
 	postUseCase domain.PostUseCase
+	cursors     *CursorCodec
 }
 
-func NewPostHandler(r *gin.RouterGroup, middleware *MiddlewareHandler, postUseCase domain.PostUseCase) {
+func NewPostHandler(r *gin.RouterGroup, middleware *MiddlewareHandler, postUseCase domain.PostUseCase, cursors *CursorCodec) {
 	handler := &PostHandler{
 		postUseCase: postUseCase,
+		cursors:     cursors,
 	}
-	r.GET("/:postID", handler.GetByID)
-	r.GET("", handler.GetAll)
+	r.GET("/:postID", middleware.OptionalAuth, handler.GetByID)
+	r.GET("/by-slug/:slug", middleware.OptionalAuth, handler.GetBySlug)
+	r.GET("", middleware.OptionalAuth, handler.GetAll)
 
 	// Apply middleware
 	r.Use(middleware.AuthMiddleware)
 
 	r.POST("", handler.Create)
 	r.PUT("/:postID", handler.Update)
+	r.PATCH("/:postID", handler.Patch)
 	r.DELETE("/:postID", handler.Delete)
 }
 
@@ -43,6 +61,7 @@ func (h *PostHandler) Create(ctx *gin.Context) {
 		handleError(ctx, err)
 		return
 	}
+	setVersionETag(ctx, response.Version)
 	handleOKCreated(ctx, response)
 }
 
@@ -64,11 +83,40 @@ func (h *PostHandler) Update(ctx *gin.Context) {
 		return
 	}
 	request.AuthorID = ctx.GetInt64("userID")
+	request.IfMatch = parseIfMatch(ctx)
 	response, err := h.postUseCase.Update(ctx, path.PostID, request)
 	if err != nil {
 		handleError(ctx, err)
 		return
 	}
+	setVersionETag(ctx, response.Version)
+	handleOK(ctx, response)
+}
+
+func (h *PostHandler) Patch(ctx *gin.Context) {
+	var request domain.PatchPostRequestDTO
+	if err := bindMergePatch(ctx, &request, postPatchFields); err != nil {
+		logger.Log.Error(err.Error())
+		handleError(ctx, err)
+		return
+	}
+	var path struct {
+		PostID int64 `uri:"postID" binding:"required"`
+	}
+	if err := ctx.ShouldBindUri(&path); err != nil {
+		logger.Log.Error(err.Error())
+		err = common.NewCustomError(http.StatusBadRequest, err.Error())
+		handleError(ctx, err)
+		return
+	}
+	request.AuthorID = ctx.GetInt64("userID")
+	request.IfMatch = parseIfMatch(ctx)
+	response, err := h.postUseCase.Patch(ctx, path.PostID, &request)
+	if err != nil {
+		handleError(ctx, err)
+		return
+	}
+	setVersionETag(ctx, response.Version)
 	handleOK(ctx, response)
 }
 
@@ -76,6 +124,7 @@ func (h *PostHandler) Delete(ctx *gin.Context) {
 	userID := ctx.GetInt64("userID")
 	request := &domain.DeletePostRequestDTO{
 		AuthorID: userID,
+		IfMatch:  parseIfMatch(ctx),
 	}
 	var path struct {
 		PostID int64 `uri:"postID" binding:"required"`
@@ -104,11 +153,56 @@ func (h *PostHandler) GetByID(ctx *gin.Context) {
 		handleError(ctx, err)
 		return
 	}
-	response, err := h.postUseCase.GetByID(ctx, path.PostID)
+	response, err := h.postUseCase.GetByID(ctx, path.PostID, ctx.GetInt64("userID"))
 	if err != nil {
 		handleError(ctx, err)
 		return
 	}
+	etag, err := contentETag(response.Version, response)
+	if err != nil {
+		logger.Log.Error(err.Error())
+		handleError(ctx, common.ErrInternalServerError)
+		return
+	}
+	ctx.Header("ETag", etag)
+	if notModified(ctx, etag) {
+		ctx.Status(http.StatusNotModified)
+		return
+	}
+	handleOK(ctx, response)
+}
+
+func (h *PostHandler) GetBySlug(ctx *gin.Context) {
+	var path struct {
+		Slug string `uri:"slug" binding:"required"`
+	}
+	if err := ctx.ShouldBindUri(&path); err != nil {
+		logger.Log.Error(err.Error())
+		err = common.NewCustomError(http.StatusBadRequest, err.Error())
+		handleError(ctx, err)
+		return
+	}
+	response, err := h.postUseCase.GetBySlug(ctx, path.Slug, ctx.GetInt64("userID"))
+	if err != nil {
+		handleError(ctx, err)
+		return
+	}
+	if response.Slug != path.Slug {
+		location := strings.TrimSuffix(ctx.Request.URL.Path, path.Slug) + url.PathEscape(response.Slug)
+		ctx.Redirect(http.StatusMovedPermanently, location)
+		return
+	}
+	etag, err := contentETag(response.Version, response)
+	if err != nil {
+		logger.Log.Error(err.Error())
+		handleError(ctx, common.ErrInternalServerError)
+		return
+	}
+	ctx.Header("ETag", etag)
+	if notModified(ctx, etag) {
+		ctx.Status(http.StatusNotModified)
+		return
+	}
 	handleOK(ctx, response)
 }
 
@@ -126,7 +220,35 @@ func (h *PostHandler) GetAll(ctx *gin.Context) {
 	if search.Page == 0 {
 		search.Page = 1
 	}
-	response, total, err := h.postUseCase.GetAll(ctx, search)
+	if err := checkPostList(search); err != nil {
+		handleError(ctx, err)
+		return
+	}
+	search.ViewerID = ctx.GetInt64("userID")
+	if search.UseCursor() {
+		if err := h.cursors.bind(&search); err != nil {
+			handleError(ctx, err)
+			return
+		}
+		posts, page, err := h.postUseCase.GetAllByCursor(ctx, search)
+		if err != nil {
+			handleError(ctx, err)
+			return
+		}
+		response, err := sparsePosts(posts, search)
+		if err != nil {
+			handleError(ctx, err)
+			return
+		}
+		handleCursorPagination(ctx, response, search.Limit, page, h.cursors)
+		return
+	}
+	posts, total, err := h.postUseCase.GetAll(ctx, search)
+	if err != nil {
+		handleError(ctx, err)
+		return
+	}
+	response, err := sparsePosts(posts, search)
 	if err != nil {
 		handleError(ctx, err)
 		return
@@ -0,0 +1,86 @@
+package http
+
+import (
+	"app/domain"
+	"app/pkg/common"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type ReactionHandler struct {
+	reactionUseCase domain.ReactionUseCase
+}
+
+func NewReactionHandler(r *gin.RouterGroup, middleware *MiddlewareHandler, reactionUseCase domain.ReactionUseCase) {
+	handler := &ReactionHandler{
+		reactionUseCase: reactionUseCase,
+	}
+	r.GET("", middleware.OptionalAuth, handler.FindByPostID)
+
+	r.Use(middleware.AuthMiddleware)
+	r.PUT("/:type", handler.React)
+	r.DELETE("/:type", handler.Unreact)
+}
+
+func (h *ReactionHandler) React(ctx *gin.Context) {
+	var request domain.ReactionRequestDTO
+	if err := ctx.ShouldBindUri(&request); err != nil {
+		err = common.NewCustomError(http.StatusBadRequest, err.Error())
+		handleError(ctx, err)
+		return
+	}
+	request.UserID = ctx.GetInt64("userID")
+	response, err := h.reactionUseCase.React(ctx, request)
+	if err != nil {
+		handleError(ctx, err)
+		return
+	}
+	handleOK(ctx, response)
+}
+
+func (h *ReactionHandler) Unreact(ctx *gin.Context) {
+	var request domain.ReactionRequestDTO
+	if err := ctx.ShouldBindUri(&request); err != nil {
+		err = common.NewCustomError(http.StatusBadRequest, err.Error())
+		handleError(ctx, err)
+		return
+	}
+	request.UserID = ctx.GetInt64("userID")
+	response, err := h.reactionUseCase.Unreact(ctx, request)
+	if err != nil {
+		handleError(ctx, err)
+		return
+	}
+	handleOK(ctx, response)
+}
+
+func (h *ReactionHandler) FindByPostID(ctx *gin.Context) {
+	var request domain.SearchParam
+	if err := ctx.ShouldBindQuery(&request); err != nil {
+		err = common.NewCustomError(http.StatusBadRequest, err.Error())
+		handleError(ctx, err)
+		return
+	}
+	var path struct {
+		PostID int64 `uri:"postID" binding:"required"`
+	}
+	if err := ctx.ShouldBindUri(&path); err != nil {
+		err = common.NewCustomError(http.StatusBadRequest, err.Error())
+		handleError(ctx, err)
+		return
+	}
+	if request.Limit == 0 {
+		request.Limit = 10
+	}
+	if request.Page == 0 {
+		request.Page = 1
+	}
+	request.ViewerID = ctx.GetInt64("userID")
+	reactions, total, err := h.reactionUseCase.FindByPostID(ctx, path.PostID, request)
+	if err != nil {
+		handleError(ctx, err)
+		return
+	}
+	handlePagination(ctx, reactions, request.Page, request.Limit, total)
+}
@@ -1,6 +1,7 @@
 package http
 
 import (
+	"app/domain"
 	"app/pkg/common"
 	"net/http"
 	"time"
@@ -25,6 +26,20 @@ type PagedResponse struct {
 	Data      interface{} `json:"data"`
 }
 
+// CursorPagedResponse is the page shape of keyset pagination. The cursors
+// are null at either end of the list and the total is only present when it
+// was asked for.
+type CursorPagedResponse struct {
+	Success    bool        `json:"success"`
+	Status     int         `json:"status"`
+	Timestamp  time.Time   `json:"timestamp"`
+	Size       int         `json:"size"`
+	Total      *int64      `json:"total,omitempty"`
+	NextCursor *string     `json:"next_cursor"`
+	PrevCursor *string     `json:"prev_cursor"`
+	Data       interface{} `json:"data"`
+}
+
 func handleOKCreated(ctx *gin.Context, data interface{}) {
 	now := time.Now()
 	ctx.JSON(http.StatusCreated, BaseResponse{
@@ -58,6 +73,20 @@ func handlePagination(ctx *gin.Context, data interface{}, page, size int, total
 	})
 }
 
+func handleCursorPagination(ctx *gin.Context, data interface{}, size int, page *domain.PageInfo, cursors *CursorCodec) {
+	now := time.Now()
+	ctx.JSON(http.StatusOK, CursorPagedResponse{
+		Success:    true,
+		Status:     http.StatusOK,
+		Timestamp:  now,
+		Size:       size,
+		Total:      page.Total,
+		NextCursor: cursors.Encode(page.Next),
+		PrevCursor: cursors.Encode(page.Prev),
+		Data:       data,
+	})
+}
+
 func handleError(ctx *gin.Context, err error) {
 	now := time.Now()
 	if customErr, ok := err.(*common.CustomError); ok {
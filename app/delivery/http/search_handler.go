@@ -0,0 +1,43 @@
+package http
+
+import (
+	"app/domain"
+	"app/pkg/common"
+	"app/pkg/logger"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type SearchHandler struct {
+	searchUseCase domain.SearchUseCase
+}
+
+func NewSearchHandler(r *gin.RouterGroup, searchUseCase domain.SearchUseCase) {
+	handler := &SearchHandler{
+		searchUseCase: searchUseCase,
+	}
+	r.GET("", handler.Search)
+}
+
+func (h *SearchHandler) Search(ctx *gin.Context) {
+	var query domain.SearchQuery
+	if err := ctx.ShouldBindQuery(&query); err != nil {
+		logger.Log.Error(err.Error())
+		err = common.NewCustomError(http.StatusBadRequest, err.Error())
+		handleError(ctx, err)
+		return
+	}
+	if query.Limit == 0 {
+		query.Limit = 10
+	}
+	if query.Page == 0 {
+		query.Page = 1
+	}
+	response, err := h.searchUseCase.Search(ctx, query)
+	if err != nil {
+		handleError(ctx, err)
+		return
+	}
+	handleOK(ctx, response)
+}
@@ -1,36 +1,72 @@
 package http
 
 import (
-	"app/repository"
-	"app/usecase"
-	"database/sql"
+	"app/bootstrap"
 
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
 )
 
-func SetupRouter(db *sql.DB, jwtPrivateKey, jwtPublicKey string) (*gin.Engine, error) {
+// Config holds the settings and long-lived dependencies of the router. The
+// dependencies left nil are replaced by private ones.
+type Config struct {
+	// JWTPrivateKey signs the pagination cursors.
+	JWTPrivateKey string
+	// BaseURL is the public address of the API, used for absolute links.
+	BaseURL string
+	// Hub tracks the live connections to close on shutdown.
+	Hub *Hub
+}
+
+// SetupRouter builds the routes serving the use cases, which are wired by
+// bootstrap.NewUseCases.
+func SetupRouter(useCases *bootstrap.UseCases, config Config) *gin.Engine {
 	r := gin.Default()
 	r.Use(cors.New(cors.Config{
-		AllowOrigins: []string{"*"},
-		AllowMethods: []string{"GET", "POST", "PUT", "DELETE"},
+		AllowOrigins:  []string{"*"},
+		AllowMethods:  []string{"GET", "POST", "PUT", "PATCH", "DELETE"},
+		AllowHeaders:  []string{"Origin", "Content-Length", "Content-Type", "If-Match", "If-None-Match", "If-Modified-Since"},
+		ExposeHeaders: []string{"ETag", "Last-Modified"},
 	}))
-	transactor := repository.NewSQLTransactor(db)
-	tokenRepository := repository.NewTokenRepositoryJWT(jwtPrivateKey, jwtPublicKey)
-	userRepository := repository.NewUserRepositoryMySQL(db)
-	postRepository := repository.NewPostRepositoryMySQL(db)
-	commentRepository := repository.NewCommentRepositoryMySQL(db)
-
-	authUseCase := usecase.NewAuthUseCaseImpl(userRepository, tokenRepository, transactor)
-	postUseCase := usecase.NewPostUsecaseImpl(postRepository, userRepository, transactor)
-	commentUseCase := usecase.NewCommentUseCaseImpl(commentRepository, userRepository, postRepository, transactor)
-
-	middleware := NewMiddlewareHandler(authUseCase)
+	hub := config.Hub
+	if hub == nil {
+		hub = NewHub()
+	}
+	middleware := NewMiddlewareHandler(useCases.Auth)
+	cursors := NewCursorCodec(config.JWTPrivateKey)
+	rootGroup := r.Group("")
 	authGroup := r.Group("")
 	postGroup := r.Group("/posts")
 	commentGroup := r.Group("/posts/:postID/comments")
-	NewAuthHandler(authGroup, authUseCase)
-	NewPostHandler(postGroup, middleware, postUseCase)
-	NewCommentHandler(commentGroup, middleware, commentUseCase)
-	return r, nil
+	reactionGroup := r.Group("/posts/:postID/reactions")
+	tagGroup := r.Group("/tags")
+	categoryGroup := r.Group("/categories")
+	searchGroup := r.Group("/search")
+	meGroup := r.Group("/me")
+	userGroup := r.Group("/users")
+	homeFeedGroup := r.Group("/me/feed")
+	notificationGroup := r.Group("/me/notifications")
+	mentionGroup := r.Group("/me/mentions")
+	wsGroup := r.Group("/ws")
+	webhookGroup := r.Group("/admin/webhooks")
+	jobGroup := r.Group("/admin/jobs")
+	uploadGroup := r.Group("/uploads")
+	NewAuthHandler(authGroup, useCases.Auth)
+	NewPostHandler(postGroup, middleware, useCases.Post, cursors)
+	NewCommentHandler(commentGroup, middleware, useCases.Comment, cursors, hub)
+	NewReactionHandler(reactionGroup, middleware, useCases.Reaction)
+	NewBookmarkHandler(meGroup, middleware, useCases.Bookmark, cursors)
+	NewFollowHandler(userGroup, homeFeedGroup, middleware, useCases.Follow, cursors)
+	NewNotificationHandler(notificationGroup, middleware, useCases.Notification)
+	NewMentionHandler(mentionGroup, middleware, useCases.Mention)
+	NewWebSocketHandler(wsGroup, middleware, useCases.Notification, useCases.Comment, hub, config.BaseURL)
+	NewWebhookHandler(webhookGroup, middleware, useCases.Webhook)
+	NewJobHandler(jobGroup, middleware, useCases.Jobs)
+	NewUploadHandler(uploadGroup, middleware, useCases.Attachment)
+	NewTagHandler(tagGroup, middleware, useCases.Tag)
+	NewCategoryHandler(categoryGroup, middleware, useCases.Category)
+	NewSearchHandler(searchGroup, useCases.Search)
+	NewFeedHandler(rootGroup, useCases.Post, config.BaseURL)
+	NewSitemapHandler(rootGroup, useCases.Sitemap, config.BaseURL)
+	return r
 }
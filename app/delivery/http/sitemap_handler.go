@@ -0,0 +1,146 @@
+package http
+
+import (
+	"app/domain"
+	"app/pkg/common"
+	"app/pkg/logger"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+const (
+	sitemapNamespace = "http://www.sitemaps.org/schemas/sitemap/0.9"
+	sitemapMediaType = "application/xml; charset=utf-8"
+)
+
+type sitemapIndex struct {
+	XMLName  xml.Name       `xml:"http://www.sitemaps.org/schemas/sitemap/0.9 sitemapindex"`
+	Sitemaps []sitemapEntry `xml:"sitemap"`
+}
+
+type sitemapEntry struct {
+	Loc string `xml:"loc"`
+}
+
+type sitemapURL struct {
+	XMLName xml.Name `xml:"url"`
+	Loc     string   `xml:"loc"`
+	LastMod string   `xml:"lastmod"`
+}
+
+type SitemapHandler struct {
+	sitemapUseCase domain.SitemapUseCase
+	baseURL        string
+}
+
+func NewSitemapHandler(r *gin.RouterGroup, sitemapUseCase domain.SitemapUseCase, baseURL string) {
+	handler := &SitemapHandler{
+		sitemapUseCase: sitemapUseCase,
+		baseURL:        strings.TrimSuffix(baseURL, "/"),
+	}
+	r.GET("/sitemap.xml", handler.Index)
+	r.GET("/sitemaps/:name", handler.Page)
+}
+
+// Index serves the only sitemap of a small site, or a sitemap index pointing
+// at /sitemaps/posts-N.xml once there are more posts than fit in one.
+func (h *SitemapHandler) Index(ctx *gin.Context) {
+	pages, err := h.sitemapUseCase.Pages(ctx)
+	if err != nil {
+		handleError(ctx, err)
+		return
+	}
+	if pages == 1 {
+		h.writeURLSet(ctx, 1)
+		return
+	}
+	index := sitemapIndex{}
+	for page := 1; page <= pages; page++ {
+		index.Sitemaps = append(index.Sitemaps, sitemapEntry{Loc: h.baseURL + "/sitemaps/" + sitemapName(page)})
+	}
+	body, err := xml.Marshal(index)
+	if err != nil {
+		logger.Log.Error("failed to render sitemap index", zap.Error(err))
+		handleError(ctx, common.ErrInternalServerError)
+		return
+	}
+	ctx.Data(http.StatusOK, sitemapMediaType, append([]byte(xml.Header), body...))
+}
+
+func (h *SitemapHandler) Page(ctx *gin.Context) {
+	var page int
+	name := ctx.Param("name")
+	if _, err := fmt.Sscanf(name, "posts-%d.xml", &page); err != nil || sitemapName(page) != name {
+		handleError(ctx, common.ErrSitemapNotFound)
+		return
+	}
+	pages, err := h.sitemapUseCase.Pages(ctx)
+	if err != nil {
+		handleError(ctx, err)
+		return
+	}
+	if page < 1 || page > pages {
+		handleError(ctx, common.ErrSitemapNotFound)
+		return
+	}
+	h.writeURLSet(ctx, page)
+}
+
+// writeURLSet encodes the posts of a sitemap page while they are read from
+// the database. The response starts with the first post, so an error before
+// it is still reported normally; later errors can only cut the body short.
+func (h *SitemapHandler) writeURLSet(ctx *gin.Context, page int) {
+	encoder := xml.NewEncoder(ctx.Writer)
+	urlSet := xml.StartElement{Name: xml.Name{Space: sitemapNamespace, Local: "urlset"}}
+	started := false
+	start := func() error {
+		started = true
+		ctx.Header("Content-Type", sitemapMediaType)
+		ctx.Status(http.StatusOK)
+		if _, err := io.WriteString(ctx.Writer, xml.Header); err != nil {
+			return err
+		}
+		return encoder.EncodeToken(urlSet)
+	}
+	err := h.sitemapUseCase.Stream(ctx, page, func(entry domain.SitemapEntry) error {
+		if !started {
+			if err := start(); err != nil {
+				return err
+			}
+		}
+		return encoder.Encode(sitemapURL{
+			Loc:     h.baseURL + "/posts/by-slug/" + url.PathEscape(entry.Slug),
+			LastMod: entry.UpdatedAt.UTC().Format(time.RFC3339),
+		})
+	})
+	if err == nil && !started {
+		err = start()
+	}
+	if err != nil {
+		if !started {
+			handleError(ctx, err)
+			return
+		}
+		logger.Log.Error("failed to write sitemap", zap.Error(err))
+		return
+	}
+	if err := encoder.EncodeToken(urlSet.End()); err != nil {
+		logger.Log.Error("failed to write sitemap", zap.Error(err))
+		return
+	}
+	if err := encoder.Flush(); err != nil {
+		logger.Log.Error("failed to write sitemap", zap.Error(err))
+	}
+}
+
+func sitemapName(page int) string {
+	return fmt.Sprintf("posts-%d.xml", page)
+}
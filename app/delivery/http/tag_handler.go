@@ -0,0 +1,72 @@
+package http
+
+import (
+	"app/domain"
+	"app/pkg/common"
+	"app/pkg/logger"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type TagHandler struct {
+	tagUseCase domain.TagUseCase
+}
+
+func NewTagHandler(r *gin.RouterGroup, middleware *MiddlewareHandler, tagUseCase domain.TagUseCase) {
+	handler := &TagHandler{
+		tagUseCase: tagUseCase,
+	}
+	r.GET("", handler.GetAll)
+	r.GET("/:tag/posts", middleware.OptionalAuth, handler.GetPosts)
+}
+
+func (h *TagHandler) GetAll(ctx *gin.Context) {
+	response, err := h.tagUseCase.GetAll(ctx)
+	if err != nil {
+		handleError(ctx, err)
+		return
+	}
+	handleOK(ctx, response)
+}
+
+func (h *TagHandler) GetPosts(ctx *gin.Context) {
+	var search domain.SearchParam
+	if err := ctx.ShouldBindQuery(&search); err != nil {
+		logger.Log.Error(err.Error())
+		err = common.NewCustomError(http.StatusBadRequest, err.Error())
+		handleError(ctx, err)
+		return
+	}
+	var path struct {
+		Tag string `uri:"tag" binding:"required"`
+	}
+	if err := ctx.ShouldBindUri(&path); err != nil {
+		logger.Log.Error(err.Error())
+		err = common.NewCustomError(http.StatusBadRequest, err.Error())
+		handleError(ctx, err)
+		return
+	}
+	if search.Limit == 0 {
+		search.Limit = 10
+	}
+	if search.Page == 0 {
+		search.Page = 1
+	}
+	if err := checkPostList(search); err != nil {
+		handleError(ctx, err)
+		return
+	}
+	search.ViewerID = ctx.GetInt64("userID")
+	posts, total, err := h.tagUseCase.GetPosts(ctx, path.Tag, search)
+	if err != nil {
+		handleError(ctx, err)
+		return
+	}
+	response, err := sparsePosts(posts, search)
+	if err != nil {
+		handleError(ctx, err)
+		return
+	}
+	handlePagination(ctx, response, search.Page, search.Limit, total)
+}
@@ -0,0 +1,104 @@
+package http
+
+import (
+	"app/domain"
+	"app/pkg/common"
+	"app/usecase"
+	"errors"
+	"mime"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// uploadOverhead leaves room for the multipart framing around the file.
+const uploadOverhead = 1 << 20
+
+type UploadHandler struct {
+	attachmentUseCase domain.AttachmentUseCase
+}
+
+type uploadPath struct {
+	ID int64 `uri:"id" binding:"required"`
+}
+
+func NewUploadHandler(r *gin.RouterGroup, middleware *MiddlewareHandler, attachmentUseCase domain.AttachmentUseCase) {
+	handler := &UploadHandler{
+		attachmentUseCase: attachmentUseCase,
+	}
+	r.POST("", middleware.AuthMiddleware, handler.Upload)
+	r.GET("/:id", middleware.OptionalAuth, handler.Download)
+}
+
+// Upload stores the file sent in the "file" field of a multipart form.
+func (h *UploadHandler) Upload(ctx *gin.Context) {
+	ctx.Request.Body = http.MaxBytesReader(ctx.Writer, ctx.Request.Body, usecase.MaxUploadSize+uploadOverhead)
+	header, err := ctx.FormFile("file")
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			handleError(ctx, common.ErrFileTooLarge)
+			return
+		}
+		err = common.NewCustomError(http.StatusBadRequest, err.Error())
+		handleError(ctx, err)
+		return
+	}
+	file, err := header.Open()
+	if err != nil {
+		handleError(ctx, err)
+		return
+	}
+	defer file.Close()
+	attachment, err := h.attachmentUseCase.Upload(ctx, domain.UploadRequestDTO{
+		OwnerID:  ctx.GetInt64("userID"),
+		Filename: header.Filename,
+		Size:     header.Size,
+		File:     file,
+	})
+	if err != nil {
+		handleError(ctx, err)
+		return
+	}
+	handleOKCreated(ctx, attachment)
+}
+
+// Download streams the file, or the variant named by the variant query
+// parameter, with the type detected at upload, which the browser is told not
+// to second-guess. Processed files never change, so they are cached for
+// good; by shared caches too when anyone may see them.
+func (h *UploadHandler) Download(ctx *gin.Context) {
+	var path uploadPath
+	if err := ctx.ShouldBindUri(&path); err != nil {
+		err = common.NewCustomError(http.StatusBadRequest, err.Error())
+		handleError(ctx, err)
+		return
+	}
+	file, err := h.attachmentUseCase.Open(ctx, path.ID, ctx.GetInt64("userID"), ctx.Query("variant"))
+	if err != nil {
+		handleError(ctx, err)
+		return
+	}
+	defer file.Content.Close()
+	headers := map[string]string{
+		"Content-Disposition":    mime.FormatMediaType("inline", map[string]string{"filename": file.Filename}),
+		"X-Content-Type-Options": "nosniff",
+		"Cache-Control":          "private, no-cache",
+	}
+	if file.Processed {
+		etag := `"` + file.Key + `"`
+		headers["ETag"] = etag
+		headers["Cache-Control"] = "private, max-age=31536000, immutable"
+		if file.Public {
+			headers["Cache-Control"] = "public, max-age=31536000, immutable"
+		}
+		if notModified(ctx, etag) {
+			for name, value := range headers {
+				ctx.Header(name, value)
+			}
+			ctx.Status(http.StatusNotModified)
+			return
+		}
+	}
+	ctx.DataFromReader(http.StatusOK, file.Size, file.ContentType, file.Content, headers)
+}
@@ -0,0 +1,150 @@
+package http
+
+import (
+	"app/domain"
+	"app/pkg/common"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type WebhookHandler struct {
+	webhookUseCase domain.WebhookUseCase
+}
+
+type webhookPath struct {
+	ID int64 `uri:"id" binding:"required"`
+}
+
+func NewWebhookHandler(r *gin.RouterGroup, middleware *MiddlewareHandler, webhookUseCase domain.WebhookUseCase) {
+	handler := &WebhookHandler{
+		webhookUseCase: webhookUseCase,
+	}
+	r.Use(middleware.AdminMiddleware)
+	r.GET("", handler.FindWebhooks)
+	r.POST("", handler.CreateWebhook)
+	r.GET("/:id", handler.FindWebhook)
+	r.PUT("/:id", handler.UpdateWebhook)
+	r.DELETE("/:id", handler.DeleteWebhook)
+	r.GET("/:id/deliveries", handler.FindDeliveries)
+	r.POST("/:id/deliveries/:deliveryID/replay", handler.Replay)
+}
+
+func (h *WebhookHandler) FindWebhooks(ctx *gin.Context) {
+	webhooks, err := h.webhookUseCase.FindAll(ctx)
+	if err != nil {
+		handleError(ctx, err)
+		return
+	}
+	handleOK(ctx, webhooks)
+}
+
+func (h *WebhookHandler) CreateWebhook(ctx *gin.Context) {
+	var request domain.WebhookRequestDTO
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		err = common.NewCustomError(http.StatusBadRequest, err.Error())
+		handleError(ctx, err)
+		return
+	}
+	response, err := h.webhookUseCase.Create(ctx, request)
+	if err != nil {
+		handleError(ctx, err)
+		return
+	}
+	handleOKCreated(ctx, response)
+}
+
+func (h *WebhookHandler) FindWebhook(ctx *gin.Context) {
+	var path webhookPath
+	if err := ctx.ShouldBindUri(&path); err != nil {
+		err = common.NewCustomError(http.StatusBadRequest, err.Error())
+		handleError(ctx, err)
+		return
+	}
+	webhook, err := h.webhookUseCase.FindByID(ctx, path.ID)
+	if err != nil {
+		handleError(ctx, err)
+		return
+	}
+	handleOK(ctx, webhook)
+}
+
+func (h *WebhookHandler) UpdateWebhook(ctx *gin.Context) {
+	var path webhookPath
+	if err := ctx.ShouldBindUri(&path); err != nil {
+		err = common.NewCustomError(http.StatusBadRequest, err.Error())
+		handleError(ctx, err)
+		return
+	}
+	var request domain.WebhookRequestDTO
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		err = common.NewCustomError(http.StatusBadRequest, err.Error())
+		handleError(ctx, err)
+		return
+	}
+	webhook, err := h.webhookUseCase.Update(ctx, path.ID, request)
+	if err != nil {
+		handleError(ctx, err)
+		return
+	}
+	handleOK(ctx, webhook)
+}
+
+func (h *WebhookHandler) DeleteWebhook(ctx *gin.Context) {
+	var path webhookPath
+	if err := ctx.ShouldBindUri(&path); err != nil {
+		err = common.NewCustomError(http.StatusBadRequest, err.Error())
+		handleError(ctx, err)
+		return
+	}
+	if err := h.webhookUseCase.Delete(ctx, path.ID); err != nil {
+		handleError(ctx, err)
+		return
+	}
+	handleOK(ctx, nil)
+}
+
+func (h *WebhookHandler) FindDeliveries(ctx *gin.Context) {
+	var path webhookPath
+	if err := ctx.ShouldBindUri(&path); err != nil {
+		err = common.NewCustomError(http.StatusBadRequest, err.Error())
+		handleError(ctx, err)
+		return
+	}
+	var request domain.SearchParam
+	if err := ctx.ShouldBindQuery(&request); err != nil {
+		err = common.NewCustomError(http.StatusBadRequest, err.Error())
+		handleError(ctx, err)
+		return
+	}
+	if request.Limit == 0 {
+		request.Limit = 10
+	}
+	if request.Page == 0 {
+		request.Page = 1
+	}
+	deliveries, total, err := h.webhookUseCase.FindDeliveries(ctx, path.ID, request)
+	if err != nil {
+		handleError(ctx, err)
+		return
+	}
+	handlePagination(ctx, deliveries, request.Page, request.Limit, total)
+}
+
+func (h *WebhookHandler) Replay(ctx *gin.Context) {
+	var path struct {
+		ID         int64 `uri:"id" binding:"required"`
+		DeliveryID int64 `uri:"deliveryID" binding:"required"`
+	}
+	if err := ctx.ShouldBindUri(&path); err != nil {
+		err = common.NewCustomError(http.StatusBadRequest, err.Error())
+		handleError(ctx, err)
+		return
+	}
+	delivery, err := h.webhookUseCase.Replay(ctx, path.ID, path.DeliveryID)
+	if err != nil {
+		handleError(ctx, err)
+		return
+	}
+	handleOKCreated(ctx, delivery)
+}
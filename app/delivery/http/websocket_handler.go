@@ -0,0 +1,241 @@
+package http
+
+import (
+	"app/domain"
+	"app/pkg/common"
+	"app/pkg/logger"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+	"golang.org/x/net/websocket"
+)
+
+const (
+	wsSendBuffer       = 64
+	wsMaxMessage       = 4096
+	wsMaxSubscriptions = 20
+	wsPingInterval     = 30 * time.Second
+	wsWriteTimeout     = 10 * time.Second
+
+	wsSubscribe    = "subscribe"
+	wsUnsubscribe  = "unsubscribe"
+	wsSubscribed   = "subscribed"
+	wsUnsubscribed = "unsubscribed"
+	wsPresence     = "presence"
+	wsError        = "error"
+)
+
+// wsMessage is a message exchanged over /ws. Clients send subscribe and
+// unsubscribe with the id of a post. The server sends notifications, the
+// comment events and presence counts of the subscribed posts, and errors.
+type wsMessage struct {
+	Type    string      `json:"type"`
+	PostID  int64       `json:"post_id,omitempty"`
+	Readers int         `json:"readers,omitempty"`
+	Data    interface{} `json:"data,omitempty"`
+	Message string      `json:"message,omitempty"`
+}
+
+// wsPing sends ping frames, which clients answer on their own.
+var wsPing = websocket.Codec{Marshal: func(interface{}) ([]byte, byte, error) {
+	return nil, websocket.PingFrame, nil
+}}
+
+type WebSocketHandler struct {
+	notificationUseCase domain.NotificationUseCase
+	commentUseCase      domain.CommentUsecase
+	hub                 *Hub
+	baseURL             *url.URL
+}
+
+func NewWebSocketHandler(r *gin.RouterGroup, middleware *MiddlewareHandler, notificationUseCase domain.NotificationUseCase, commentUseCase domain.CommentUsecase, hub *Hub, baseURL string) {
+	handler := &WebSocketHandler{
+		notificationUseCase: notificationUseCase,
+		commentUseCase:      commentUseCase,
+		hub:                 hub,
+	}
+	if parsed, err := url.Parse(baseURL); err == nil {
+		handler.baseURL = parsed
+	}
+	r.Use(middleware.AuthMiddleware)
+	r.GET("", handler.Serve)
+}
+
+// Serve upgrades the request of a signed in user to a WebSocket connection.
+func (h *WebSocketHandler) Serve(ctx *gin.Context) {
+	userID := ctx.GetInt64("userID")
+	server := websocket.Server{
+		Handshake: h.checkOrigin,
+		Handler: func(ws *websocket.Conn) {
+			h.serve(ws, userID)
+		},
+	}
+	server.ServeHTTP(ctx.Writer, ctx.Request)
+}
+
+// checkOrigin refuses connections opened by pages of other sites, which
+// would otherwise ride on the cookie of the user. Clients other than browsers
+// send no origin.
+func (h *WebSocketHandler) checkOrigin(config *websocket.Config, req *http.Request) error {
+	origin, err := websocket.Origin(config, req)
+	if err != nil || origin == nil {
+		return err
+	}
+	config.Origin = origin
+	if strings.EqualFold(origin.Host, req.Host) {
+		return nil
+	}
+	if h.baseURL != nil && strings.EqualFold(origin.Host, h.baseURL.Host) {
+		return nil
+	}
+	return errors.New("websocket: cross origin connection refused")
+}
+
+// serve pushes the notifications of the user and reads the subscriptions of
+// the connection until either side closes it or the hub shuts down.
+func (h *WebSocketHandler) serve(ws *websocket.Conn, userID int64) {
+	ws.MaxPayloadBytes = wsMaxMessage
+	ctx, cancel := context.WithCancel(ws.Request().Context())
+	conn := &wsConn{ws: ws, userID: userID, send: make(chan wsMessage, wsSendBuffer), ctx: ctx, cancel: cancel}
+	if !h.hub.add(conn) {
+		conn.close()
+		return
+	}
+	defer h.hub.remove(conn)
+	defer conn.close()
+	notifications, err := h.notificationUseCase.Stream(ctx, userID)
+	if err != nil {
+		logger.Log.Error("failed to stream notifications", zap.Error(err))
+		return
+	}
+	go conn.forward(ctx, notifications, 0)
+	go conn.write()
+
+	subscriptions := map[int64]context.CancelFunc{}
+	for {
+		var message wsMessage
+		if err := websocket.JSON.Receive(ws, &message); err != nil {
+			var syntaxError *json.SyntaxError
+			var typeError *json.UnmarshalTypeError
+			if errors.As(err, &syntaxError) || errors.As(err, &typeError) {
+				conn.push(wsMessage{Type: wsError, Message: "Invalid message"})
+				continue
+			}
+			return
+		}
+		switch message.Type {
+		case wsSubscribe:
+			h.subscribe(conn, subscriptions, message.PostID)
+		case wsUnsubscribe:
+			if unsubscribe, ok := subscriptions[message.PostID]; ok {
+				unsubscribe()
+				delete(subscriptions, message.PostID)
+				h.hub.leave(message.PostID, conn)
+			}
+			conn.push(wsMessage{Type: wsUnsubscribed, PostID: message.PostID})
+		default:
+			conn.push(wsMessage{Type: wsError, Message: "Unknown message type"})
+		}
+	}
+}
+
+// subscribe starts relaying the comment events of a post the user can read
+// and counts them among its readers.
+func (h *WebSocketHandler) subscribe(conn *wsConn, subscriptions map[int64]context.CancelFunc, postID int64) {
+	if _, ok := subscriptions[postID]; ok {
+		conn.push(wsMessage{Type: wsSubscribed, PostID: postID})
+		return
+	}
+	if len(subscriptions) >= wsMaxSubscriptions {
+		conn.push(wsMessage{Type: wsError, PostID: postID, Message: "Too many subscriptions"})
+		return
+	}
+	ctx, cancel := context.WithCancel(conn.ctx)
+	events, err := h.commentUseCase.StreamComments(ctx, postID, conn.userID, 0)
+	if err != nil {
+		cancel()
+		var customError *common.CustomError
+		if !errors.As(err, &customError) {
+			customError = common.ErrInternalServerError
+		}
+		conn.push(wsMessage{Type: wsError, PostID: postID, Message: customError.Message})
+		return
+	}
+	subscriptions[postID] = cancel
+	go conn.forward(ctx, events, postID)
+	conn.push(wsMessage{Type: wsSubscribed, PostID: postID})
+	h.hub.join(postID, conn)
+}
+
+// wsConn is an open WebSocket connection. Messages are queued for a single
+// writer so that producers never wait on the network.
+type wsConn struct {
+	ws        *websocket.Conn
+	userID    int64
+	send      chan wsMessage
+	ctx       context.Context
+	cancel    context.CancelFunc
+	closeOnce sync.Once
+}
+
+// push queues a message without blocking. A connection that cannot keep up
+// is closed; the client reconnects and reloads what it missed.
+func (c *wsConn) push(message wsMessage) {
+	select {
+	case c.send <- message:
+	case <-c.ctx.Done():
+	default:
+		logger.Log.Warn("closing slow websocket connection", zap.Int64("userID", c.userID))
+		c.close()
+	}
+}
+
+// forward relays the events of a subscription until it ends. A subscription
+// the broker dropped for falling behind takes the connection down with it.
+func (c *wsConn) forward(ctx context.Context, events <-chan domain.Event, postID int64) {
+	for event := range events {
+		c.push(wsMessage{Type: event.Type, PostID: postID, Data: event.Data})
+	}
+	if ctx.Err() == nil {
+		c.close()
+	}
+}
+
+func (c *wsConn) write() {
+	ping := time.NewTicker(wsPingInterval)
+	defer ping.Stop()
+	for {
+		var err error
+		select {
+		case <-c.ctx.Done():
+			return
+		case message := <-c.send:
+			c.ws.SetWriteDeadline(time.Now().Add(wsWriteTimeout))
+			err = websocket.JSON.Send(c.ws, message)
+		case <-ping.C:
+			c.ws.SetWriteDeadline(time.Now().Add(wsWriteTimeout))
+			err = wsPing.Send(c.ws, nil)
+		}
+		if err != nil {
+			c.close()
+			return
+		}
+	}
+}
+
+// close sends a close frame and releases the connection. It is safe to call
+// from any goroutine, any number of times.
+func (c *wsConn) close() {
+	c.closeOnce.Do(func() {
+		c.cancel()
+		c.ws.Close()
+	})
+}
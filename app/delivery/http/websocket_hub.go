@@ -0,0 +1,121 @@
+package http
+
+import (
+	"sync"
+)
+
+// Hub keeps track of the open WebSocket connections and of who is reading
+// each post, so that presence counts can be broadcast and every live stream
+// closed on shutdown. Presence is counted per instance.
+type Hub struct {
+	mu      sync.Mutex
+	conns   map[*wsConn]bool
+	readers map[int64]map[*wsConn]bool
+	done    chan struct{}
+	closed  bool
+	wg      sync.WaitGroup
+}
+
+func NewHub() *Hub {
+	return &Hub{
+		conns:   map[*wsConn]bool{},
+		readers: map[int64]map[*wsConn]bool{},
+		done:    make(chan struct{}),
+	}
+}
+
+// Done is closed when the hub shuts down. Long-lived responses other than
+// WebSocket connections, such as event streams, end when it is.
+func (h *Hub) Done() <-chan struct{} {
+	return h.done
+}
+
+// Close closes every WebSocket connection and waits for their handlers to
+// return, however many times it is called. Connections opened afterwards are
+// refused.
+func (h *Hub) Close() {
+	h.mu.Lock()
+	if h.closed {
+		h.mu.Unlock()
+		h.wg.Wait()
+		return
+	}
+	h.closed = true
+	close(h.done)
+	conns := make([]*wsConn, 0, len(h.conns))
+	for conn := range h.conns {
+		conns = append(conns, conn)
+	}
+	h.mu.Unlock()
+	for _, conn := range conns {
+		conn.close()
+	}
+	h.wg.Wait()
+}
+
+// add registers a new connection and reports whether the hub still accepts
+// them. Registered connections must be removed once their handler is done.
+func (h *Hub) add(conn *wsConn) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.closed {
+		return false
+	}
+	h.conns[conn] = true
+	h.wg.Add(1)
+	return true
+}
+
+func (h *Hub) remove(conn *wsConn) {
+	h.mu.Lock()
+	delete(h.conns, conn)
+	var left []int64
+	for postID, readers := range h.readers {
+		if readers[conn] {
+			left = append(left, postID)
+		}
+	}
+	h.mu.Unlock()
+	for _, postID := range left {
+		h.leave(postID, conn)
+	}
+	h.wg.Done()
+}
+
+// join counts the connection as reading the post.
+func (h *Hub) join(postID int64, conn *wsConn) {
+	h.mu.Lock()
+	if h.readers[postID] == nil {
+		h.readers[postID] = map[*wsConn]bool{}
+	}
+	h.readers[postID][conn] = true
+	h.mu.Unlock()
+	h.broadcastPresence(postID)
+}
+
+func (h *Hub) leave(postID int64, conn *wsConn) {
+	h.mu.Lock()
+	delete(h.readers[postID], conn)
+	if len(h.readers[postID]) == 0 {
+		delete(h.readers, postID)
+	}
+	h.mu.Unlock()
+	h.broadcastPresence(postID)
+}
+
+// broadcastPresence tells the readers of a post how many people are reading
+// it. People reading from several connections are counted once.
+func (h *Hub) broadcastPresence(postID int64) {
+	h.mu.Lock()
+	users := map[int64]bool{}
+	conns := make([]*wsConn, 0, len(h.readers[postID]))
+	for conn := range h.readers[postID] {
+		users[conn.userID] = true
+		conns = append(conns, conn)
+	}
+	h.mu.Unlock()
+	message := wsMessage{Type: wsPresence, PostID: postID, Readers: len(users)}
+	for _, conn := range conns {
+		conn.push(message)
+	}
+}
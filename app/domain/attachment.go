@@ -0,0 +1,125 @@
+package domain
+
+import (
+	"context"
+	"io"
+	"time"
+)
+
+// AttachmentTypes are the content types accepted for uploads, as sniffed
+// from the file rather than taken from the client.
+var AttachmentTypes = []string{"image/jpeg", "image/png", "image/gif", "image/webp"}
+
+// ImageVariant is a smaller version made of every uploaded image, named
+// after its purpose and sized by its longer side.
+type ImageVariant struct {
+	Name string
+	Size int
+}
+
+// DefaultImageVariants are the versions made of uploaded images unless
+// configured otherwise.
+var DefaultImageVariants = []ImageVariant{{Name: "thumb", Size: 160}, {Name: "small", Size: 480}, {Name: "medium", Size: 1024}}
+
+// Attachment is a file uploaded by a user. It starts out on its own and is
+// linked to a post once the post lists it; uploads left unlinked, or whose
+// post is deleted, are removed after a while. The file itself is kept in a
+// BlobStore under Key.
+//
+// Images are processed in the background after the upload: their metadata is
+// stripped, and their dimensions, placeholder and variants are recorded.
+// Until ProcessedAt is set, the file is only served to its owner.
+type Attachment struct {
+	ID          int64               `json:"id"`
+	OwnerID     int64               `json:"owner_id"`
+	PostID      *int64              `json:"post_id"`
+	Key         string              `json:"-"`
+	Filename    string              `json:"filename"`
+	ContentType string              `json:"content_type"`
+	Size        int64               `json:"size"`
+	Width       *int                `json:"width"`
+	Height      *int                `json:"height"`
+	Blurhash    *string             `json:"blurhash"`
+	Variants    []AttachmentVariant `json:"variants"`
+	URL         string              `json:"url"`
+	ProcessedAt *time.Time          `json:"processed_at"`
+	CreatedAt   time.Time           `json:"created_at"`
+}
+
+// AttachmentVariant is a scaled down version of an image attachment, kept in
+// the BlobStore under Key.
+type AttachmentVariant struct {
+	Name        string `json:"name"`
+	Key         string `json:"-"`
+	ContentType string `json:"content_type"`
+	Width       int    `json:"width"`
+	Height      int    `json:"height"`
+	Size        int64  `json:"size"`
+	URL         string `json:"url"`
+}
+
+// AttachmentFile is the content of an attachment, or of one of its variants,
+// as served. Public tells whether anyone may see it, and Processed whether it
+// is final and may be cached for good.
+type AttachmentFile struct {
+	Key         string
+	Filename    string
+	ContentType string
+	Size        int64
+	Processed   bool
+	Public      bool
+	Content     io.ReadCloser
+}
+
+// ProcessAttachmentPayload is the payload of the job processing an upload.
+type ProcessAttachmentPayload struct {
+	AttachmentID int64 `json:"attachment_id"`
+}
+
+// UploadRequestDTO is a file to store, with the size the client announced.
+type UploadRequestDTO struct {
+	OwnerID  int64
+	Filename string
+	Size     int64
+	File     io.Reader
+}
+
+// BlobStore keeps the content of uploaded files.
+type BlobStore interface {
+	// Put stores size bytes read from body under the key, replacing any
+	// previous content.
+	Put(ctx context.Context, key string, body io.Reader, size int64, contentType string) error
+	// Get returns the content stored under the key, or common.ErrBlobNotFound.
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	// Delete removes the content under the key; a missing key is not an
+	// error.
+	Delete(ctx context.Context, key string) error
+}
+
+// AttachmentRepository stores attachments along with their variants.
+type AttachmentRepository interface {
+	// Create records the attachment in tx and sets its id.
+	Create(ctx context.Context, tx Transaction, attachment *Attachment) error
+	FindByID(ctx context.Context, id int64) (*Attachment, error)
+	FindByPostIDs(ctx context.Context, postIDs []int64) (map[int64][]Attachment, error)
+	// Attach links the attachments to the post in place of the ones linked
+	// before, which are left to be cleaned up. Every attachment must belong
+	// to the owner and not to another post.
+	Attach(ctx context.Context, tx Transaction, postID, ownerID int64, ids []int64) ([]Attachment, error)
+	// FindOrphans returns up to limit attachments that were never linked and
+	// were uploaded before the time, or whose post was deleted before it.
+	FindOrphans(ctx context.Context, before time.Time, limit int) ([]Attachment, error)
+	// SaveProcessed stores the outcome of processing the attachment,
+	// replacing its variants.
+	SaveProcessed(ctx context.Context, tx Transaction, attachment *Attachment) error
+	Delete(ctx context.Context, id int64) error
+}
+
+type AttachmentUseCase interface {
+	Upload(ctx context.Context, request UploadRequestDTO) (*Attachment, error)
+	// Open returns the content of the attachment, or of the named variant
+	// when there is one, if the viewer may see it: attachments of a post are
+	// as visible as the post once processed, others only to their owner.
+	// Images too small for a variant are served as they are.
+	Open(ctx context.Context, id, viewerID int64, variant string) (*AttachmentFile, error)
+}
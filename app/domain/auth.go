@@ -5,10 +5,17 @@ import (
 	"time"
 )
 
+const (
+	RoleUser  = "user"
+	RoleAdmin = "admin"
+)
+
 type User struct {
 	ID           int64      `json:"id"`
 	Name         string     `json:"name"`
+	Username     string     `json:"username"`
 	Email        string     `json:"email"`
+	Role         string     `json:"role"`
 	PasswordHash string     `json:"-"`
 	CreatedAt    time.Time  `json:"created_at"`
 	UpdatedAt    *time.Time `json:"updated_at"`
@@ -17,6 +24,7 @@ type User struct {
 
 type RegisterRequestDTO struct {
 	Name     string `json:"name" binding:"required"`
+	Username string `json:"username" binding:"required"`
 	Email    string `json:"email" binding:"required"`
 	Password string `json:"password" binding:"required"`
 }
@@ -54,12 +62,19 @@ type AuthUseCase interface {
 	Login(ctx context.Context, request *LoginRequestDTO) (*LoginResponseDTO, error)
 	VerifyToken(ctx context.Context, token string) (*VerifyTokenResponse, error)
 	RefreshToken(ctx context.Context, token string) (*RefreshTokenResponse, error)
+	// RequireRole fails with common.ErrForbidden unless the user has the role.
+	RequireRole(ctx context.Context, userID int64, role string) error
+	// SetRole gives the user with that email the role.
+	SetRole(ctx context.Context, email string, role string) error
 }
 
 type UserRepository interface {
 	FindByEmail(ctx context.Context, email string) (*User, error)
 	FindByID(ctx context.Context, id int64) (*User, error)
+	FindByIDs(ctx context.Context, ids []int64) ([]User, error)
+	FindByUsernames(ctx context.Context, usernames []string) ([]User, error)
 	Create(ctx context.Context, tx Transaction, user *User) error
+	SetRole(ctx context.Context, id int64, role string) error
 }
 
 type TokenRepository interface {
@@ -0,0 +1,48 @@
+package domain
+
+import (
+	"context"
+	"time"
+)
+
+// Bookmark is a post saved by a user to read later, optionally filed under
+// one of the user's named collections.
+type Bookmark struct {
+	PostID     int64     `json:"post_id"`
+	Collection *string   `json:"collection"`
+	CreatedAt  time.Time `json:"created_at"`
+	Post       *Post     `json:"post,omitempty"`
+}
+
+type BookmarkCollection struct {
+	ID            int64  `json:"id"`
+	Name          string `json:"name"`
+	BookmarkCount int64  `json:"bookmark_count"`
+}
+
+type BookmarkParam struct {
+	SearchParam
+	Collection string `form:"collection"`
+}
+
+type BookmarkRepository interface {
+	UpsertCollection(ctx context.Context, tx Transaction, userID int64, name string) (int64, error)
+	Save(ctx context.Context, tx Transaction, userID, postID int64, collectionID *int64) error
+	Delete(ctx context.Context, userID, postID int64) error
+	FindBookmarked(ctx context.Context, userID int64, postIDs []int64) (map[int64]bool, error)
+	FindByUserCursor(ctx context.Context, userID int64, param BookmarkParam) ([]Bookmark, *PageInfo, error)
+	GetCollections(ctx context.Context, userID int64) ([]BookmarkCollection, error)
+}
+
+type SaveBookmarkRequestDTO struct {
+	UserID     int64  `json:"-"`
+	PostID     int64  `json:"-"`
+	Collection string `json:"collection" binding:"max=64"`
+}
+
+type BookmarkUseCase interface {
+	Save(ctx context.Context, req SaveBookmarkRequestDTO) error
+	Delete(ctx context.Context, userID, postID int64) error
+	FindByUser(ctx context.Context, userID int64, param BookmarkParam) ([]Bookmark, *PageInfo, error)
+	GetCollections(ctx context.Context, userID int64) ([]BookmarkCollection, error)
+}
@@ -0,0 +1,32 @@
+package domain
+
+import (
+	"context"
+	"time"
+)
+
+type Category struct {
+	ID        int64       `json:"id"`
+	Name      string      `json:"name"`
+	Slug      string      `json:"slug"`
+	ParentID  *int64      `json:"parent_id"`
+	CreatedAt time.Time   `json:"created_at"`
+	Children  []*Category `json:"children,omitempty"`
+}
+
+type CategoryRepository interface {
+	Create(ctx context.Context, tx Transaction, category *Category) error
+	GetByID(ctx context.Context, id int64) (*Category, error)
+	GetBySlug(ctx context.Context, slug string) (*Category, error)
+	GetAll(ctx context.Context) ([]*Category, error)
+}
+
+type CreateCategoryRequestDTO struct {
+	Name     string `json:"name" binding:"required,max=255"`
+	ParentID *int64 `json:"parent_id"`
+}
+
+type CategoryUseCase interface {
+	Create(ctx context.Context, request *CreateCategoryRequestDTO) (*Category, error)
+	GetTree(ctx context.Context) ([]*Category, error)
+}
@@ -5,28 +5,52 @@ import (
 	"time"
 )
 
+// Comment is a comment on a post, or a reply to another comment when it has
+// a parent. Comments written before authors were recorded have no AuthorID.
 type Comment struct {
-	ID         int64     `json:"id"`
-	Content    string    `json:"content"`
-	PostID     int64     `json:"post_id"`
-	AuthorName string    `json:"author_name"`
-	CreatedAt  time.Time `json:"created_at"`
+	ID         int64      `json:"id"`
+	Content    string     `json:"content"`
+	PostID     int64      `json:"post_id"`
+	AuthorID   *int64     `json:"author_id"`
+	ParentID   *int64     `json:"parent_id"`
+	AuthorName string     `json:"author_name"`
+	CreatedAt  time.Time  `json:"created_at"`
+	UpdatedAt  *time.Time `json:"updated_at"`
+}
+
+// CommentDeletedEvent is the data of a comment.deleted event.
+type CommentDeletedEvent struct {
+	ID     int64 `json:"id"`
+	PostID int64 `json:"post_id"`
 }
 
 type CommentRepository interface {
 	Create(ctx context.Context, tx Transaction, comment *Comment) error
+	GetByID(ctx context.Context, id int64) (*Comment, error)
+	Update(ctx context.Context, tx Transaction, comment *Comment) error
+	// Delete removes the comment and reports whether it was still there.
+	Delete(ctx context.Context, tx Transaction, id int64) (bool, error)
 	FindByPostID(ctx context.Context, postID int64, param SearchParam) ([]*Comment, int64, error)
+	FindByPostIDCursor(ctx context.Context, postID int64, param SearchParam) ([]*Comment, *PageInfo, error)
 }
 
 type CreateCommentRequestDTO struct {
 	AuthorID int64  `json:"-"`
 	Content  string `json:"content" binding:"required"`
+	ParentID *int64 `json:"parent_id"`
+}
+
+type UpdateCommentRequestDTO struct {
+	AuthorID int64  `json:"-"`
+	Content  string `json:"content" binding:"required"`
 }
 
 type CreateCommentResponseDTO struct {
 	ID         int64     `json:"id"`
 	Content    string    `json:"content"`
 	PostID     int64     `json:"post_id"`
+	AuthorID   *int64    `json:"author_id"`
+	ParentID   *int64    `json:"parent_id"`
 	AuthorName string    `json:"author_name"`
 	CreatedAt  time.Time `json:"created_at"`
 }
@@ -34,4 +58,8 @@ type CreateCommentResponseDTO struct {
 type CommentUsecase interface {
 	CreateComment(ctx context.Context, postId int64, req CreateCommentRequestDTO) (*CreateCommentResponseDTO, error)
 	FindCommentsByPostID(ctx context.Context, postID int64, param SearchParam) ([]*Comment, int64, error)
+	FindCommentsByPostIDCursor(ctx context.Context, postID int64, param SearchParam) ([]*Comment, *PageInfo, error)
+	UpdateComment(ctx context.Context, postID, commentID int64, req UpdateCommentRequestDTO) (*Comment, error)
+	DeleteComment(ctx context.Context, postID, commentID, authorID int64) error
+	StreamComments(ctx context.Context, postID, viewerID, lastEventID int64) (<-chan Event, error)
 }
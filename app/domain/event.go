@@ -0,0 +1,35 @@
+package domain
+
+import "context"
+
+const (
+	EventPostCreated     = "post.created"
+	EventPostUpdated     = "post.updated"
+	EventPostUnpublished = "post.unpublished"
+	EventPostDeleted     = "post.deleted"
+	EventCommentCreated  = "comment.created"
+	EventCommentUpdated  = "comment.updated"
+	EventCommentDeleted  = "comment.deleted"
+	EventNotification    = "notification"
+)
+
+// Event is a message published to the subscribers of a topic. The broker
+// numbers the events of each topic in increasing order.
+type Event struct {
+	ID   int64
+	Type string
+	Data interface{}
+}
+
+// EventBroker fans events out to the subscribers of a topic. It is kept in
+// process for now; an external broker can take its place once the API runs
+// on several instances.
+type EventBroker interface {
+	// Publish delivers the event to the subscribers of the topic.
+	Publish(ctx context.Context, topic string, event Event) error
+	// Subscribe streams the events of the topic until ctx is done, starting
+	// with the retained events numbered after lastID. The channel is closed
+	// when the subscription ends, including when the subscriber falls too
+	// far behind, in which case it can resume from the last event it read.
+	Subscribe(ctx context.Context, topic string, lastID int64) (<-chan Event, error)
+}
@@ -0,0 +1,48 @@
+package domain
+
+import (
+	"context"
+	"time"
+)
+
+type FollowUser struct {
+	ID         int64     `json:"id"`
+	Name       string    `json:"name"`
+	FollowedAt time.Time `json:"followed_at"`
+}
+
+// UserProfile is the public page of a user, which mentions link to.
+type UserProfile struct {
+	ID        int64  `json:"id"`
+	Name      string `json:"name"`
+	Username  string `json:"username"`
+	Followers int64  `json:"followers"`
+	Following int64  `json:"following"`
+}
+
+type FollowRepository interface {
+	// Follow records the follow in tx and reports whether it is new.
+	Follow(ctx context.Context, tx Transaction, followerID, followeeID int64) (bool, error)
+	Unfollow(ctx context.Context, followerID, followeeID int64) error
+	// Count returns how many users follow userID and how many it follows.
+	Count(ctx context.Context, userID int64) (followers, following int64, err error)
+	FindFollowers(ctx context.Context, userID int64, param SearchParam) ([]FollowUser, int64, error)
+	FindFollowing(ctx context.Context, userID int64, param SearchParam) ([]FollowUser, int64, error)
+}
+
+// FeedStrategy assembles the home feed of a user from the authors they
+// follow. Reading the posts of every followed author on request keeps writes
+// cheap; a strategy that copies new posts into follower timelines when they
+// are published can replace it once feeds get expensive to read.
+type FeedStrategy interface {
+	Feed(ctx context.Context, userID int64, param SearchParam) ([]Post, *PageInfo, error)
+}
+
+type FollowUseCase interface {
+	GetProfile(ctx context.Context, userID int64) (*UserProfile, error)
+	Follow(ctx context.Context, followerID, followeeID int64) error
+	Unfollow(ctx context.Context, followerID, followeeID int64) error
+	FindFollowers(ctx context.Context, userID int64, param SearchParam) ([]FollowUser, int64, error)
+	FindFollowing(ctx context.Context, userID int64, param SearchParam) ([]FollowUser, int64, error)
+	Feed(ctx context.Context, userID int64, param SearchParam) ([]Post, *PageInfo, error)
+}
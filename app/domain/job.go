@@ -0,0 +1,101 @@
+package domain
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+)
+
+const (
+	JobPending   = "pending"
+	JobRunning   = "running"
+	JobSucceeded = "succeeded"
+	JobDead      = "dead"
+)
+
+// Job types run by the workers.
+const (
+	JobCleanupJobs        = "jobs.cleanup"
+	JobCleanupOutbox      = "outbox.cleanup"
+	JobCleanupAttachments = "attachments.cleanup"
+	JobProcessAttachment  = "attachments.process"
+)
+
+// Job is deferred work run by a worker. It is retried with backoff until it
+// succeeds or runs out of attempts, after which it is left dead for an admin
+// to look into and retry. RunAt is when it is due next, or until when the
+// worker running it holds it.
+type Job struct {
+	ID          int64           `json:"id"`
+	Type        string          `json:"type"`
+	Payload     json.RawMessage `json:"payload"`
+	Status      string          `json:"status"`
+	Attempts    int             `json:"attempts"`
+	MaxAttempts int             `json:"max_attempts"`
+	Error       *string         `json:"error"`
+	RunAt       time.Time       `json:"run_at"`
+	FinishedAt  *time.Time      `json:"finished_at"`
+	CreatedAt   time.Time       `json:"created_at"`
+	UpdatedAt   *time.Time      `json:"updated_at"`
+}
+
+// JobFilter narrows down the jobs listed to admins.
+type JobFilter struct {
+	Status string `form:"status" binding:"omitempty,oneof=pending running succeeded dead"`
+	Type   string `form:"type"`
+	Page   int    `form:"page"`
+	Limit  int    `form:"limit"`
+}
+
+// CleanupJobPayload is the payload of the cleanup jobs, which delete what
+// is older than the retention.
+type CleanupJobPayload struct {
+	RetentionDays int `json:"retention_days"`
+}
+
+// JobHandler runs the jobs of one type. A job may run more than once when a
+// worker stops halfway, so handling should be idempotent.
+type JobHandler interface {
+	Handle(ctx context.Context, job Job) error
+}
+
+// JobQueue defers work to the workers.
+type JobQueue interface {
+	// Enqueue records a job of the type, due at runAt, as part of tx so that
+	// it only runs if tx is committed.
+	Enqueue(ctx context.Context, tx Transaction, jobType string, payload interface{}, runAt time.Time) error
+}
+
+type JobRepository interface {
+	// Create records the job in tx and sets its id.
+	Create(ctx context.Context, tx Transaction, job *Job) error
+	FindAll(ctx context.Context, filter JobFilter) ([]Job, int64, error)
+	FindByID(ctx context.Context, id int64) (*Job, error)
+	// Claim picks up to limit due jobs, oldest first, including running ones
+	// whose worker has let go of them, marks them running and holds them
+	// until then so that other workers leave them alone.
+	Claim(ctx context.Context, tx Transaction, limit int, until time.Time) ([]Job, error)
+	// SaveAttempt stores the outcome of the last run of the job.
+	SaveAttempt(ctx context.Context, job *Job) error
+	// Retry makes a dead job pending again with fresh attempts, and reports
+	// whether it was dead.
+	Retry(ctx context.Context, id int64) (bool, error)
+	// DeleteFinished removes the jobs that succeeded before the time.
+	DeleteFinished(ctx context.Context, before time.Time) (int64, error)
+	// EnsureSchedule records a recurring job, first due at next, unless it
+	// is recorded already.
+	EnsureSchedule(ctx context.Context, name string, next time.Time) error
+	// ClaimSchedules picks the recurring jobs among names that are due and
+	// locks them until tx ends.
+	ClaimSchedules(ctx context.Context, tx Transaction, names []string) ([]string, error)
+	SetScheduleNext(ctx context.Context, tx Transaction, name string, next time.Time) error
+}
+
+type JobUseCase interface {
+	FindAll(ctx context.Context, filter JobFilter) ([]Job, int64, error)
+	FindByID(ctx context.Context, id int64) (*Job, error)
+	// Retry runs a dead job again.
+	Retry(ctx context.Context, id int64) (*Job, error)
+	// Work runs a batch of due jobs and returns how many it picked up.
+	Work(ctx context.Context) (int, error)
+}
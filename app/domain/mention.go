@@ -0,0 +1,31 @@
+package domain
+
+import (
+	"context"
+	"time"
+)
+
+// Mention records that AuthorID mentioned UserID as @username in a post, or
+// in a comment on it when CommentID is set.
+type Mention struct {
+	ID         int64     `json:"id"`
+	UserID     int64     `json:"-"`
+	AuthorID   int64     `json:"author_id"`
+	AuthorName string    `json:"author_name"`
+	PostID     int64     `json:"post_id"`
+	PostTitle  string    `json:"post_title"`
+	PostSlug   string    `json:"post_slug"`
+	CommentID  *int64    `json:"comment_id"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+type MentionRepository interface {
+	// Replace makes userIDs the users mentioned by the post, or by one of its
+	// comments, and returns those who were not mentioned there before.
+	Replace(ctx context.Context, tx Transaction, authorID, postID int64, commentID *int64, userIDs []int64) ([]int64, error)
+	FindByUser(ctx context.Context, userID int64, param SearchParam) ([]Mention, int64, error)
+}
+
+type MentionUseCase interface {
+	FindByUser(ctx context.Context, userID int64, param SearchParam) ([]Mention, int64, error)
+}
@@ -0,0 +1,64 @@
+package domain
+
+import (
+	"context"
+	"time"
+)
+
+const (
+	NotificationComment = "comment"
+	NotificationReply   = "reply"
+	NotificationFollow  = "follow"
+	NotificationMention = "mention"
+)
+
+// NotificationTypes are the events users are notified about, each of which
+// can be switched off in the notification preferences.
+var NotificationTypes = []string{NotificationComment, NotificationReply, NotificationFollow, NotificationMention}
+
+// Notification tells UserID that ActorID did something. The post and comment
+// point at what it was about, when there is one. Notifications sent for an
+// outbox event carry its key, so the event never notifies a user twice.
+type Notification struct {
+	ID        int64      `json:"id"`
+	UserID    int64      `json:"-"`
+	ActorID   int64      `json:"actor_id"`
+	ActorName string     `json:"actor_name"`
+	Type      string     `json:"type"`
+	PostID    *int64     `json:"post_id"`
+	CommentID *int64     `json:"comment_id"`
+	EventKey  *string    `json:"-"`
+	ReadAt    *time.Time `json:"read_at"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+type NotificationParam struct {
+	SearchParam
+	Unread bool `form:"unread"`
+}
+
+type NotificationRepository interface {
+	// Create stores the notifications and sets their ids. Notifications
+	// already stored for their event are skipped and keep a zero id.
+	Create(ctx context.Context, notifications []Notification) error
+	FindByUser(ctx context.Context, userID int64, param NotificationParam) ([]Notification, int64, error)
+	CountUnread(ctx context.Context, userID int64) (int64, error)
+	MarkRead(ctx context.Context, userID, id int64) (bool, error)
+	MarkAllRead(ctx context.Context, userID int64) error
+	GetPreferences(ctx context.Context, userIDs []int64) (map[int64]map[string]bool, error)
+	SetPreferences(ctx context.Context, userID int64, preferences map[string]bool) error
+}
+
+type UnreadCountResponseDTO struct {
+	Unread int64 `json:"unread"`
+}
+
+type NotificationUseCase interface {
+	FindByUser(ctx context.Context, userID int64, param NotificationParam) ([]Notification, int64, error)
+	CountUnread(ctx context.Context, userID int64) (*UnreadCountResponseDTO, error)
+	MarkRead(ctx context.Context, userID, id int64) error
+	MarkAllRead(ctx context.Context, userID int64) error
+	GetPreferences(ctx context.Context, userID int64) (map[string]bool, error)
+	SetPreferences(ctx context.Context, userID int64, preferences map[string]bool) (map[string]bool, error)
+	Stream(ctx context.Context, userID int64) (<-chan Event, error)
+}
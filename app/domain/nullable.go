@@ -0,0 +1,22 @@
+package domain
+
+import (
+	"bytes"
+	"encoding/json"
+)
+
+// NullableInt64 tells apart a JSON member that is absent (Set is false), set
+// to null (Set is true and Value is nil) or set to a number.
+type NullableInt64 struct {
+	Set   bool
+	Value *int64
+}
+
+func (n *NullableInt64) UnmarshalJSON(data []byte) error {
+	n.Set = true
+	if bytes.Equal(bytes.TrimSpace(data), []byte("null")) {
+		n.Value = nil
+		return nil
+	}
+	return json.Unmarshal(data, &n.Value)
+}
@@ -0,0 +1,54 @@
+package domain
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+)
+
+// OutboxEvent is a domain event recorded in the outbox by the transaction of
+// the change it is about. Handlers may see an event more than once and can
+// tell repeats by its Key.
+type OutboxEvent struct {
+	ID        int64
+	Key       string
+	Type      string
+	Payload   json.RawMessage
+	Attempts  int
+	CreatedAt time.Time
+}
+
+// EventPublisher records domain events for the side effects of a change,
+// such as notifications, search indexing and webhooks, so that they happen
+// if and only if the change is committed.
+type EventPublisher interface {
+	// Publish records the event as part of tx.
+	Publish(ctx context.Context, tx Transaction, eventType string, data interface{}) error
+	// Flush tells the relay that events were committed, so that they are
+	// handled without waiting for its next poll. It does not wait for them.
+	Flush(ctx context.Context)
+}
+
+// OutboxHandler reacts to the events relayed from the outbox. Events it
+// does not care about are ignored. A failed event is retried, so handling
+// should be idempotent.
+type OutboxHandler interface {
+	Handle(ctx context.Context, event OutboxEvent) error
+}
+
+type OutboxRepository interface {
+	// Create records the event in tx and sets its id.
+	Create(ctx context.Context, tx Transaction, event *OutboxEvent) error
+	// Claim picks up to limit unprocessed events that are due, oldest first,
+	// and postpones them to until so that other relays leave them alone.
+	Claim(ctx context.Context, tx Transaction, limit int, until time.Time) ([]OutboxEvent, error)
+	// FindHandled returns the names of the handlers done with each event.
+	FindHandled(ctx context.Context, eventIDs []int64) (map[int64][]string, error)
+	MarkHandled(ctx context.Context, eventID int64, handler string) error
+	MarkProcessed(ctx context.Context, eventID int64) error
+	// RecordFailure stores a failed attempt. The event is retried at
+	// retryAt, or given up on when it is nil.
+	RecordFailure(ctx context.Context, eventID int64, attempts int, message string, retryAt *time.Time) error
+	// DeleteProcessed removes the events processed before the time.
+	DeleteProcessed(ctx context.Context, before time.Time) (int64, error)
+}
@@ -0,0 +1,23 @@
+package domain
+
+import "time"
+
+const (
+	PaginationOffset = "offset"
+	PaginationCursor = "cursor"
+)
+
+// Cursor is the position of a row in a list ordered by (created_at, id).
+type Cursor struct {
+	CreatedAt time.Time
+	ID        int64
+}
+
+// PageInfo describes a page read with keyset pagination. Next and Prev are
+// the positions to continue from in each direction, nil when there is
+// nothing more. Total is only counted on request.
+type PageInfo struct {
+	Next  *Cursor
+	Prev  *Cursor
+	Total *int64
+}
@@ -2,64 +2,193 @@ package domain
 
 import (
 	"context"
+	"encoding/json"
 	"time"
 )
 
+const (
+	PostStatusDraft     = "draft"
+	PostStatusPublished = "published"
+)
+
+// PostFields are the post members a listing can be narrowed to with the
+// fields parameter, and PostIncludes the relations it can embed.
+var (
+	PostFields   = []string{"id", "title", "slug", "excerpt", "content", "content_html", "content_markdown", "toc", "author_id", "category_id", "status", "comment_count", "reactions", "my_reactions", "bookmarked", "version", "created_at", "updated_at"}
+	PostIncludes = []string{"author", "comment_count", "tags"}
+)
+
+type Author struct {
+	ID   int64  `json:"id"`
+	Name string `json:"name"`
+}
+
 type Post struct {
-	ID        int64      `json:"id"`
-	Title     string     `json:"title"`
-	Content   string     `json:"content"`
-	AuthorID  int64      `json:"author_id"`
-	CreatedAt time.Time  `json:"created_at"`
-	UpdatedAt *time.Time `json:"updated_at"`
-	DeletedAt *time.Time `json:"deleted_at"`
+	ID              int64            `json:"id"`
+	Title           string           `json:"title"`
+	Slug            string           `json:"slug"`
+	Excerpt         string           `json:"excerpt"`
+	Content         string           `json:"content"`
+	ContentText     string           `json:"-"`
+	ContentMarkdown *string          `json:"content_markdown"`
+	TOC             TableOfContents  `json:"toc"`
+	AuthorID        int64            `json:"author_id"`
+	Author          *Author          `json:"author,omitempty"`
+	CategoryID      *int64           `json:"category_id"`
+	Tags            []Tag            `json:"tags,omitempty"`
+	Attachments     []Attachment     `json:"attachments,omitempty"`
+	Status          string           `json:"status"`
+	CommentCount    int64            `json:"comment_count"`
+	Reactions       map[string]int64 `json:"reactions"`
+	MyReactions     []string         `json:"my_reactions,omitempty"`
+	Bookmarked      *bool            `json:"bookmarked,omitempty"`
+	Version         int64            `json:"version"`
+	CreatedAt       time.Time        `json:"created_at"`
+	UpdatedAt       *time.Time       `json:"updated_at"`
+	DeletedAt       *time.Time       `json:"deleted_at"`
+	Score           *float64         `json:"score,omitempty"`
+	Snippet         string           `json:"snippet,omitempty"`
+}
+
+// MarshalJSON adds the rendered HTML as content_html too, next to the
+// content member older clients read it from.
+func (post Post) MarshalJSON() ([]byte, error) {
+	type plain Post
+	return json.Marshal(struct {
+		plain
+		ContentHTML string `json:"content_html"`
+	}{plain(post), post.Content})
+}
+
+// PostDeletedEvent is the data of a post.deleted or post.unpublished event.
+// Status is the one the post had before it was taken down.
+type PostDeletedEvent struct {
+	ID     int64  `json:"id"`
+	Status string `json:"status"`
+}
+
+// VisibleTo reports whether the user may see the post. Drafts are only shown
+// to their author, and anonymous viewers have the zero id.
+func (post *Post) VisibleTo(viewerID int64) bool {
+	return post.Status != PostStatusDraft || post.AuthorID == viewerID
 }
 
 type PostRepository interface {
 	Create(ctx context.Context, tx Transaction, post *Post) error
 	GetByID(ctx context.Context, id int64) (*Post, error)
+	GetBySlug(ctx context.Context, slug string) (*Post, error)
+	FindByIDs(ctx context.Context, ids []int64) ([]Post, error)
+	FindSlugs(ctx context.Context, tx Transaction, base string, excludePostID int64) ([]string, error)
+	ArchiveSlug(ctx context.Context, tx Transaction, postID int64, oldSlug, newSlug string) error
 	SelectForUpdate(ctx context.Context, tx Transaction, id int64) (*Post, error)
 	Update(ctx context.Context, tx Transaction, id int64, post *Post) error
 	GetAll(ctx context.Context, search SearchParam) ([]Post, int64, error)
+	GetAllByCursor(ctx context.Context, search SearchParam) ([]Post, *PageInfo, error)
+	IncrementCommentCount(ctx context.Context, tx Transaction, id int64, delta int) error
+	CountPublished(ctx context.Context) (int64, error)
+	StreamPublished(ctx context.Context, offset, limit int, fn func(SitemapEntry) error) error
 }
 
 type CreatePostRequestDTO struct {
-	AuthorID int64  `json:"-"`
-	Title    string `json:"title" binding:"required"`
-	Content  string `json:"content" binding:"required"`
+	AuthorID        int64    `json:"-"`
+	Title           string   `json:"title" binding:"required"`
+	Content         string   `json:"content" binding:"required_without=ContentMarkdown,excluded_with=ContentMarkdown"`
+	ContentMarkdown string   `json:"content_markdown" binding:"required_without=Content"`
+	Tags            []string `json:"tags" binding:"max=10,dive,min=1,max=64"`
+	AttachmentIDs   []int64  `json:"attachment_ids" binding:"max=20"`
+	CategoryID      *int64   `json:"category_id"`
+	Status          string   `json:"status" binding:"omitempty,oneof=draft published"`
 }
 
 type UpdatePostRequestDTO struct {
-	AuthorID int64  `json:"-"`
-	Title    string `json:"title" binding:"required"`
-	Content  string `json:"content" binding:"required"`
+	AuthorID        int64    `json:"-"`
+	IfMatch         []int64  `json:"-"`
+	Title           string   `json:"title" binding:"required"`
+	Content         string   `json:"content" binding:"required_without=ContentMarkdown,excluded_with=ContentMarkdown"`
+	ContentMarkdown string   `json:"content_markdown" binding:"required_without=Content"`
+	Tags            []string `json:"tags" binding:"max=10,dive,min=1,max=64"`
+	AttachmentIDs   []int64  `json:"attachment_ids" binding:"max=20"`
+	CategoryID      *int64   `json:"category_id"`
+	Status          string   `json:"status" binding:"omitempty,oneof=draft published"`
+}
+
+type PatchPostRequestDTO struct {
+	AuthorID        int64         `json:"-"`
+	IfMatch         []int64       `json:"-"`
+	Title           *string       `json:"title" binding:"omitnil,min=1"`
+	Content         *string       `json:"content" binding:"omitnil,min=1,excluded_with=ContentMarkdown"`
+	ContentMarkdown *string       `json:"content_markdown" binding:"omitnil,min=1"`
+	Tags            *[]string     `json:"tags" binding:"omitnil,max=10,dive,min=1,max=64"`
+	AttachmentIDs   *[]int64      `json:"attachment_ids" binding:"omitnil,max=20"`
+	CategoryID      NullableInt64 `json:"category_id"`
+	Status          *string       `json:"status" binding:"omitnil,oneof=draft published"`
 }
 
 type CreatePostResponseDTO struct {
-	ID        int64     `json:"id"`
-	Title     string    `json:"title"`
-	Content   string    `json:"content"`
-	AuthorID  int64     `json:"author_id"`
-	CreatedAt time.Time `json:"created_at"`
+	ID              int64           `json:"id"`
+	Title           string          `json:"title"`
+	Slug            string          `json:"slug"`
+	Excerpt         string          `json:"excerpt"`
+	Content         string          `json:"content"`
+	ContentMarkdown *string         `json:"content_markdown"`
+	TOC             TableOfContents `json:"toc"`
+	AuthorID        int64           `json:"author_id"`
+	CategoryID      *int64          `json:"category_id"`
+	Tags            []Tag           `json:"tags"`
+	Attachments     []Attachment    `json:"attachments"`
+	Status          string          `json:"status"`
+	Version         int64           `json:"version"`
+	CreatedAt       time.Time       `json:"created_at"`
+}
+
+// MarshalJSON adds the rendered HTML as content_html too, like Post does.
+func (response CreatePostResponseDTO) MarshalJSON() ([]byte, error) {
+	type plain CreatePostResponseDTO
+	return json.Marshal(struct {
+		plain
+		ContentHTML string `json:"content_html"`
+	}{plain(response), response.Content})
 }
 
 type UpdatePostResponseDTO struct {
-	ID        int64      `json:"id"`
-	Title     string     `json:"title"`
-	Content   string     `json:"content"`
-	AuthorID  int64      `json:"author_id"`
-	CreatedAt time.Time  `json:"created_at"`
-	UpdatedAt *time.Time `json:"updated_at"`
+	ID              int64           `json:"id"`
+	Title           string          `json:"title"`
+	Slug            string          `json:"slug"`
+	Excerpt         string          `json:"excerpt"`
+	Content         string          `json:"content"`
+	ContentMarkdown *string         `json:"content_markdown"`
+	TOC             TableOfContents `json:"toc"`
+	AuthorID        int64           `json:"author_id"`
+	CategoryID      *int64          `json:"category_id"`
+	Tags            []Tag           `json:"tags"`
+	Attachments     []Attachment    `json:"attachments"`
+	Status          string          `json:"status"`
+	Version         int64           `json:"version"`
+	CreatedAt       time.Time       `json:"created_at"`
+	UpdatedAt       *time.Time      `json:"updated_at"`
+}
+
+// MarshalJSON adds the rendered HTML as content_html too, like Post does.
+func (response UpdatePostResponseDTO) MarshalJSON() ([]byte, error) {
+	type plain UpdatePostResponseDTO
+	return json.Marshal(struct {
+		plain
+		ContentHTML string `json:"content_html"`
+	}{plain(response), response.Content})
 }
 
 type DeletePostRequestDTO struct {
-	AuthorID int64 `json:"-"`
+	AuthorID int64   `json:"-"`
+	IfMatch  []int64 `json:"-"`
 }
 
 type PostUseCase interface {
 	Create(ctx context.Context, post *CreatePostRequestDTO) (*CreatePostResponseDTO, error)
-	GetByID(ctx context.Context, id int64) (*Post, error)
+	GetByID(ctx context.Context, id int64, viewerID int64) (*Post, error)
+	GetBySlug(ctx context.Context, slug string, viewerID int64) (*Post, error)
 	Update(ctx context.Context, id int64, post *UpdatePostRequestDTO) (*UpdatePostResponseDTO, error)
+	Patch(ctx context.Context, id int64, post *PatchPostRequestDTO) (*UpdatePostResponseDTO, error)
 	Delete(ctx context.Context, id int64, post *DeletePostRequestDTO) error
 	GetAll(ctx context.Context, search SearchParam) ([]Post, int64, error)
+	GetAllByCursor(ctx context.Context, search SearchParam) ([]Post, *PageInfo, error)
 }
@@ -0,0 +1,43 @@
+package domain
+
+import (
+	"context"
+	"time"
+)
+
+type Reaction struct {
+	UserID    int64     `json:"user_id"`
+	UserName  string    `json:"user_name"`
+	Type      string    `json:"type"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type ReactionRepository interface {
+	// Add records the reaction and reports whether it was new.
+	Add(ctx context.Context, tx Transaction, postID, userID int64, reactionType string) (bool, error)
+	// Remove deletes the reaction and reports whether it existed.
+	Remove(ctx context.Context, tx Transaction, postID, userID int64, reactionType string) (bool, error)
+	IncrementCount(ctx context.Context, tx Transaction, postID int64, reactionType string, delta int) error
+	CountByPostIDs(ctx context.Context, postIDs []int64) (map[int64]map[string]int64, error)
+	FindByUser(ctx context.Context, userID int64, postIDs []int64) (map[int64][]string, error)
+	FindByPostID(ctx context.Context, postID int64, param SearchParam) ([]Reaction, int64, error)
+}
+
+// ReactionRequestDTO names one reaction of a user on a post. A user may leave
+// several types of reaction on the same post, but each only once.
+type ReactionRequestDTO struct {
+	PostID int64  `uri:"postID" binding:"required"`
+	Type   string `uri:"type" binding:"required,oneof=like love laugh wow sad angry"`
+	UserID int64  `uri:"-"`
+}
+
+type ReactionCountsResponseDTO struct {
+	PostID    int64            `json:"post_id"`
+	Reactions map[string]int64 `json:"reactions"`
+}
+
+type ReactionUseCase interface {
+	React(ctx context.Context, req ReactionRequestDTO) (*ReactionCountsResponseDTO, error)
+	Unreact(ctx context.Context, req ReactionRequestDTO) (*ReactionCountsResponseDTO, error)
+	FindByPostID(ctx context.Context, postID int64, param SearchParam) ([]Reaction, int64, error)
+}
@@ -1,7 +1,96 @@
 package domain
 
+import (
+	"slices"
+	"strings"
+	"time"
+)
+
+const (
+	SearchModeNatural = "natural"
+	SearchModeBoolean = "boolean"
+
+	SortRelevance    = "relevance"
+	SortNewest       = "newest"
+	SortOldest       = "oldest"
+	SortCreatedAt    = "created_at"
+	SortUpdatedAt    = "updated_at"
+	SortTitle        = "title"
+	SortCommentCount = "comment_count"
+	SortPopularity   = "popularity"
+
+	OrderAsc  = "asc"
+	OrderDesc = "desc"
+
+	StatusAll = "all"
+)
+
 type SearchParam struct {
-	Search string `form:"search"`
-	Page   int    `form:"page"`
-	Limit  int    `form:"limit"`
+	Search      string     `form:"search"`
+	Mode        string     `form:"mode" binding:"omitempty,oneof=natural boolean"`
+	Sort        string     `form:"sort" binding:"omitempty,oneof=relevance newest oldest created_at updated_at title comment_count popularity"`
+	Order       string     `form:"order" binding:"omitempty,oneof=asc desc"`
+	Page        int        `form:"page"`
+	Limit       int        `form:"limit"`
+	Tag         string     `form:"tag"`
+	Category    string     `form:"category"`
+	AuthorID    int64      `form:"author_id"`
+	Status      string     `form:"status" binding:"omitempty,oneof=draft published all"`
+	CreatedFrom *time.Time `form:"created_from" time_format:"2006-01-02"`
+	CreatedTo   *time.Time `form:"created_to" time_format:"2006-01-02"`
+	UpdatedFrom *time.Time `form:"updated_from" time_format:"2006-01-02"`
+	UpdatedTo   *time.Time `form:"updated_to" time_format:"2006-01-02"`
+	Fields      string     `form:"fields"`
+	Include     string     `form:"include"`
+	ViewerID    int64      `form:"-"`
+	FollowerID  int64      `form:"-"`
+	Pagination  string     `form:"pagination" binding:"omitempty,oneof=offset cursor"`
+	After       string     `form:"after" binding:"excluded_with=Before"`
+	Before      string     `form:"before"`
+	WithTotal   bool       `form:"with_total"`
+	AfterKey    *Cursor    `form:"-"`
+	BeforeKey   *Cursor    `form:"-"`
+}
+
+// Ascending reports whether the requested sort runs from the smallest value
+// up. Titles sort alphabetically by default, everything else newest or
+// largest first.
+func (param SearchParam) Ascending() bool {
+	switch param.Order {
+	case OrderAsc:
+		return true
+	case OrderDesc:
+		return false
+	}
+	return param.Sort == SortOldest || param.Sort == SortTitle
+}
+
+// FieldList returns the requested sparse fieldset, empty for every field.
+func (param SearchParam) FieldList() []string {
+	return splitList(param.Fields)
+}
+
+// IncludeList returns the relations asked to be embedded.
+func (param SearchParam) IncludeList() []string {
+	return splitList(param.Include)
+}
+
+// Includes reports whether the relation was asked to be embedded.
+func (param SearchParam) Includes(relation string) bool {
+	return slices.Contains(param.IncludeList(), relation)
+}
+
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// UseCursor reports whether the request asked for keyset pagination.
+func (param SearchParam) UseCursor() bool {
+	return param.Pagination == PaginationCursor || param.After != "" || param.Before != ""
 }
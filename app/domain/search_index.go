@@ -0,0 +1,73 @@
+package domain
+
+import (
+	"context"
+	"time"
+)
+
+const (
+	SearchTypePost    = "post"
+	SearchTypeComment = "comment"
+)
+
+// SearchDocument is the denormalized form of a post or a comment stored in
+// the search index. Content is plain text and comments have no title.
+type SearchDocument struct {
+	Type      string
+	ID        int64
+	PostID    int64
+	Title     string
+	Content   string
+	Author    string
+	Tags      []string
+	CreatedAt time.Time
+}
+
+type SearchQuery struct {
+	Query  string     `form:"q" binding:"required"`
+	Mode   string     `form:"mode" binding:"omitempty,oneof=natural boolean"`
+	Sort   string     `form:"sort" binding:"omitempty,oneof=relevance newest oldest"`
+	Type   string     `form:"type" binding:"omitempty,oneof=post comment"`
+	Tag    string     `form:"tag"`
+	Author string     `form:"author"`
+	From   *time.Time `form:"from" time_format:"2006-01-02"`
+	To     *time.Time `form:"to" time_format:"2006-01-02"`
+	Page   int        `form:"page"`
+	Limit  int        `form:"limit"`
+}
+
+type SearchHit struct {
+	Type       string              `json:"type"`
+	ID         int64               `json:"id"`
+	PostID     int64               `json:"post_id"`
+	Title      string              `json:"title"`
+	Score      float64             `json:"score"`
+	Highlights map[string][]string `json:"highlights,omitempty"`
+}
+
+type FacetCount struct {
+	Term  string `json:"term"`
+	Count int    `json:"count"`
+}
+
+type SearchResult struct {
+	Hits   []SearchHit             `json:"hits"`
+	Total  int64                   `json:"total"`
+	Facets map[string][]FacetCount `json:"facets"`
+}
+
+// SearchIndex is a full text index over posts and comments kept next to the
+// MySQL tables, which remain the source of truth.
+type SearchIndex interface {
+	Index(ctx context.Context, documents ...SearchDocument) error
+	DeletePost(ctx context.Context, postID int64) error
+	DeleteComment(ctx context.Context, commentID int64) error
+	Search(ctx context.Context, query SearchQuery) (*SearchResult, error)
+	Reset(ctx context.Context) error
+	Close() error
+}
+
+type SearchUseCase interface {
+	Search(ctx context.Context, query SearchQuery) (*SearchResult, error)
+	Reindex(ctx context.Context) error
+}
@@ -0,0 +1,23 @@
+package domain
+
+import (
+	"context"
+	"time"
+)
+
+// SitemapMaxURLs is the limit of URLs in a single sitemap set by the
+// sitemaps.org protocol. Larger sites are split behind a sitemap index.
+const SitemapMaxURLs = 50000
+
+type SitemapEntry struct {
+	ID        int64
+	Slug      string
+	UpdatedAt time.Time
+}
+
+type SitemapUseCase interface {
+	// Pages is the number of sitemaps needed to list every published post.
+	Pages(ctx context.Context) (int, error)
+	// Stream calls fn with each post of the 1-based sitemap page in turn.
+	Stream(ctx context.Context, page int, fn func(SitemapEntry) error) error
+}
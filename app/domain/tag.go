@@ -0,0 +1,27 @@
+package domain
+
+import "context"
+
+type Tag struct {
+	ID   int64  `json:"id"`
+	Name string `json:"name"`
+	Slug string `json:"slug"`
+}
+
+type TagCount struct {
+	Tag
+	PostCount int64 `json:"post_count"`
+}
+
+type TagRepository interface {
+	Upsert(ctx context.Context, tx Transaction, tags []Tag) ([]Tag, error)
+	SetPostTags(ctx context.Context, tx Transaction, postID int64, tags []Tag) error
+	FindByPostIDs(ctx context.Context, postIDs []int64) (map[int64][]Tag, error)
+	GetBySlug(ctx context.Context, slug string) (*Tag, error)
+	GetAllWithCount(ctx context.Context) ([]TagCount, error)
+}
+
+type TagUseCase interface {
+	GetAll(ctx context.Context) ([]TagCount, error)
+	GetPosts(ctx context.Context, slug string, search SearchParam) ([]Post, int64, error)
+}
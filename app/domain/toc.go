@@ -0,0 +1,36 @@
+package domain
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+)
+
+type TOCEntry struct {
+	Level int    `json:"level"`
+	ID    string `json:"id"`
+	Title string `json:"title"`
+}
+
+// TableOfContents is stored as a JSON column.
+type TableOfContents []TOCEntry
+
+func (toc *TableOfContents) Scan(src interface{}) error {
+	switch value := src.(type) {
+	case nil:
+		*toc = nil
+		return nil
+	case []byte:
+		return json.Unmarshal(value, toc)
+	case string:
+		return json.Unmarshal([]byte(value), toc)
+	}
+	return fmt.Errorf("cannot scan %T into TableOfContents", src)
+}
+
+func (toc TableOfContents) Value() (driver.Value, error) {
+	if toc == nil {
+		return nil, nil
+	}
+	return json.Marshal(toc)
+}
@@ -0,0 +1,97 @@
+package domain
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+)
+
+const (
+	WebhookDeliveryPending   = "pending"
+	WebhookDeliverySucceeded = "succeeded"
+	WebhookDeliveryFailed    = "failed"
+)
+
+// WebhookEvents are the events webhooks can subscribe to.
+var WebhookEvents = []string{EventPostCreated, EventPostUpdated, EventPostUnpublished, EventPostDeleted, EventCommentCreated, EventCommentUpdated, EventCommentDeleted}
+
+// Webhook posts the events it subscribes to to URL, signed with Secret. It
+// is disabled once too many attempts in a row have failed.
+type Webhook struct {
+	ID           int64      `json:"id"`
+	URL          string     `json:"url"`
+	Secret       string     `json:"-"`
+	Events       []string   `json:"events"`
+	Enabled      bool       `json:"enabled"`
+	FailureCount int        `json:"failure_count"`
+	DisabledAt   *time.Time `json:"disabled_at"`
+	CreatedAt    time.Time  `json:"created_at"`
+	UpdatedAt    *time.Time `json:"updated_at"`
+}
+
+// WebhookDelivery is an event queued for a webhook. It stays pending until
+// the webhook accepts it or it runs out of attempts, and is kept afterwards
+// as the delivery log. NextAttemptAt is nil once it is no longer pending.
+type WebhookDelivery struct {
+	ID             int64           `json:"id"`
+	WebhookID      int64           `json:"webhook_id"`
+	Event          string          `json:"event"`
+	Payload        json.RawMessage `json:"payload"`
+	Status         string          `json:"status"`
+	Attempts       int             `json:"attempts"`
+	ResponseStatus *int            `json:"response_status"`
+	Error          *string         `json:"error"`
+	NextAttemptAt  *time.Time      `json:"next_attempt_at"`
+	DeliveredAt    *time.Time      `json:"delivered_at"`
+	CreatedAt      time.Time       `json:"created_at"`
+}
+
+// WebhookRequestDTO creates or replaces a webhook. Webhooks are created
+// enabled, and updates keep the current state when Enabled is left out.
+type WebhookRequestDTO struct {
+	URL     string   `json:"url" binding:"required"`
+	Events  []string `json:"events" binding:"required"`
+	Enabled *bool    `json:"enabled"`
+}
+
+// CreateWebhookResponseDTO is the only response that carries the secret.
+type CreateWebhookResponseDTO struct {
+	*Webhook
+	Secret string `json:"secret"`
+}
+
+type WebhookRepository interface {
+	Create(ctx context.Context, webhook *Webhook) error
+	Update(ctx context.Context, webhook *Webhook) error
+	Delete(ctx context.Context, id int64) (bool, error)
+	FindByID(ctx context.Context, id int64) (*Webhook, error)
+	FindAll(ctx context.Context) ([]Webhook, error)
+	// CreateDeliveries queues the deliveries and sets their ids.
+	CreateDeliveries(ctx context.Context, deliveries []WebhookDelivery) error
+	FindDeliveries(ctx context.Context, webhookID int64, param SearchParam) ([]WebhookDelivery, int64, error)
+	FindDelivery(ctx context.Context, webhookID, id int64) (*WebhookDelivery, error)
+	// ClaimDeliveries picks up to limit pending deliveries that are due, on
+	// enabled webhooks, and postpones them to until so that other workers
+	// leave them alone while they are attempted.
+	ClaimDeliveries(ctx context.Context, tx Transaction, limit int, until time.Time) ([]WebhookDelivery, error)
+	// SaveAttempt stores the outcome of the last attempt of the delivery.
+	SaveAttempt(ctx context.Context, delivery *WebhookDelivery) error
+	ResetFailures(ctx context.Context, webhookID int64) error
+	// RecordFailure counts a failed attempt, disables the webhook when it
+	// makes disableAfter in a row, and reports whether it is disabled.
+	RecordFailure(ctx context.Context, webhookID int64, disableAfter int) (bool, error)
+}
+
+type WebhookUseCase interface {
+	Create(ctx context.Context, request WebhookRequestDTO) (*CreateWebhookResponseDTO, error)
+	Update(ctx context.Context, id int64, request WebhookRequestDTO) (*Webhook, error)
+	Delete(ctx context.Context, id int64) error
+	FindByID(ctx context.Context, id int64) (*Webhook, error)
+	FindAll(ctx context.Context) ([]Webhook, error)
+	FindDeliveries(ctx context.Context, webhookID int64, param SearchParam) ([]WebhookDelivery, int64, error)
+	// Replay queues a new delivery of the payload of an earlier one.
+	Replay(ctx context.Context, webhookID, deliveryID int64) (*WebhookDelivery, error)
+	// Deliver attempts a batch of due deliveries and returns how many it
+	// picked up.
+	Deliver(ctx context.Context) (int, error)
+}
@@ -1,11 +1,37 @@
 package main
 
 import (
+	"app/bootstrap"
 	"app/delivery/http"
+	"app/domain"
 	"app/pkg/database"
 	"app/pkg/logger"
+	"app/repository"
+	"app/usecase"
+	"context"
+	"errors"
 	"fmt"
+	nethttp "net/http"
 	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+const (
+	// shutdownTimeout bounds how long requests in flight may take to finish
+	// once the server is asked to stop.
+	shutdownTimeout = 30 * time.Second
+	// webhookPollInterval is how often due webhook deliveries are looked for.
+	webhookPollInterval = 5 * time.Second
+	// jobPollInterval is how often due jobs are looked for.
+	jobPollInterval = 5 * time.Second
+	// outboxPollInterval is how often the outbox is looked at for events that
+	// are due again or were published without waking the relay up.
+	outboxPollInterval = time.Second
 )
 
 func main() {
@@ -27,6 +53,20 @@ func main() {
 	jwtPrivateKeyPath := os.Getenv("BACKEND_TAKE_HOME_JWT_PRIVATE_KEY_PATH")
 	jwtPublicKeyPath := os.Getenv("BACKEND_TAKE_HOME_JWT_PUBLIC_KEY_PATH")
 
+	baseURL := os.Getenv("BACKEND_TAKE_HOME_BASE_URL")
+	if baseURL == "" {
+		baseURL = "http://localhost:8080"
+	}
+
+	// The API runs the jobs and webhook deliveries itself unless they are
+	// left to separate "worker" processes.
+	inProcessWorkers := os.Getenv("BACKEND_TAKE_HOME_IN_PROCESS_WORKERS") != "false"
+
+	searchIndexPath := os.Getenv("BACKEND_TAKE_HOME_SEARCH_INDEX_PATH")
+	if searchIndexPath == "" {
+		searchIndexPath = "data/search.bleve"
+	}
+
 	db, err := database.NewMysqlConnection(mysqlHost, mysqlPort, mysqlDatabase, mysqlUser, mysqlPassword)
 	if err != nil {
 		logger.Log.Error(err.Error())
@@ -36,6 +76,54 @@ func main() {
 		logger.Log.Error(err.Error())
 		return
 	}
+	// "admin <email>" gives the user with that email the admin role and exits.
+	// It runs alongside the API, so it leaves the search index alone, and no
+	// token is signed, so the keys are not read.
+	if len(os.Args) > 2 && os.Args[1] == "admin" {
+		authUseCase := usecase.NewAuthUseCaseImpl(repository.NewUserRepositoryMySQL(db), repository.NewTokenRepositoryJWT("", ""), repository.NewSQLTransactor(db))
+		if err := authUseCase.SetRole(context.Background(), os.Args[2], domain.RoleAdmin); err != nil {
+			logger.Log.Error(err.Error())
+		}
+		return
+	}
+
+	blobStore, err := newBlobStore(context.Background())
+	if err != nil {
+		logger.Log.Error(err.Error())
+		return
+	}
+
+	// "worker" runs the jobs and webhook deliveries until SIGINT or SIGTERM
+	// without serving the API. Events in the outbox are still relayed by the
+	// API, as the search index can only be open in one process.
+	if len(os.Args) > 1 && os.Args[1] == "worker" {
+		signals, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+		useCases, err := bootstrap.NewUseCases(db, bootstrap.Config{BlobStore: blobStore})
+		if err != nil {
+			logger.Log.Error(err.Error())
+			return
+		}
+		runWorkers(signals, useCases)
+		return
+	}
+
+	searchIndex, err := repository.NewSearchIndexBleve(searchIndexPath)
+	if err != nil {
+		logger.Log.Error(err.Error())
+		return
+	}
+	defer searchIndex.Close()
+
+	// "reindex" rebuilds the search index from MySQL and exits. The index is
+	// locked while open, so the server must not be running at the same time.
+	if len(os.Args) > 1 && os.Args[1] == "reindex" {
+		searchUseCase := usecase.NewSearchUseCaseImpl(searchIndex, repository.NewPostRepositoryMySQL(db), repository.NewCommentRepositoryMySQL(db), repository.NewUserRepositoryMySQL(db), repository.NewTagRepositoryMySQL(db))
+		if err := searchUseCase.Reindex(context.Background()); err != nil {
+			logger.Log.Error(err.Error())
+		}
+		return
+	}
 
 	privateKey, err := os.ReadFile(jwtPrivateKeyPath)
 	if err != nil {
@@ -47,13 +135,108 @@ func main() {
 		logger.Log.Error(err.Error())
 		return
 	}
-	router, err := http.SetupRouter(db, string(privateKey), string(publicKey))
+	useCases, err := bootstrap.NewUseCases(db, bootstrap.Config{
+		JWTPrivateKey: string(privateKey),
+		JWTPublicKey:  string(publicKey),
+		SearchIndex:   searchIndex,
+		BlobStore:     blobStore,
+	})
 	if err != nil {
 		logger.Log.Error(err.Error())
 		return
 	}
-	if err := router.Run(":8080"); err != nil {
+	hub := http.NewHub()
+	router := http.SetupRouter(useCases, http.Config{
+		JWTPrivateKey: string(privateKey),
+		BaseURL:       baseURL,
+		Hub:           hub,
+	})
+
+	// On SIGINT or SIGTERM the server stops accepting connections and lets
+	// the requests in flight finish. WebSocket connections are hijacked from
+	// the server and event streams never finish, so the hub closes them.
+	server := &nethttp.Server{Addr: ":8080", Handler: router}
+	server.RegisterOnShutdown(hub.Close)
+	signals, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		<-signals.Done()
+		ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+		if err := server.Shutdown(ctx); err != nil {
+			logger.Log.Error("failed to shut down gracefully", zap.Error(err))
+		}
+		hub.Close()
+	}()
+
+	workersStopped := make(chan struct{})
+	go func() {
+		defer close(workersStopped)
+		if inProcessWorkers {
+			runWorkers(signals, useCases)
+		}
+	}()
+
+	// Events in the outbox are relayed in the background.
+	relayStopped := make(chan struct{})
+	go func() {
+		defer close(relayStopped)
+		useCases.Outbox.Run(signals, outboxPollInterval)
+	}()
+
+	if err := server.ListenAndServe(); !errors.Is(err, nethttp.ErrServerClosed) {
 		logger.Log.Error(err.Error())
 		return
 	}
+	<-stopped
+	<-workersStopped
+	<-relayStopped
+}
+
+// runWorkers delivers webhooks and runs jobs in the background until ctx is
+// done. Work cut short is picked up again once its claim runs out.
+func runWorkers(ctx context.Context, useCases *bootstrap.UseCases) {
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		useCases.Webhook.Run(ctx, webhookPollInterval)
+	}()
+	go func() {
+		defer wg.Done()
+		useCases.Jobs.Run(ctx, jobPollInterval)
+	}()
+	wg.Wait()
+}
+
+// newBlobStore keeps uploads on the local disk, unless
+// BACKEND_TAKE_HOME_BLOB_STORE is "s3" and they go to the bucket described by
+// the BACKEND_TAKE_HOME_S3_* variables. The bucket is created on start when
+// BACKEND_TAKE_HOME_S3_CREATE_BUCKET is "true", as for a local MinIO.
+func newBlobStore(ctx context.Context) (domain.BlobStore, error) {
+	if os.Getenv("BACKEND_TAKE_HOME_BLOB_STORE") != "s3" {
+		uploadPath := os.Getenv("BACKEND_TAKE_HOME_UPLOAD_PATH")
+		if uploadPath == "" {
+			uploadPath = "data/uploads"
+		}
+		return repository.NewBlobStoreLocal(uploadPath)
+	}
+	store, err := repository.NewBlobStoreS3(repository.S3Config{
+		Endpoint:        os.Getenv("BACKEND_TAKE_HOME_S3_ENDPOINT"),
+		Region:          os.Getenv("BACKEND_TAKE_HOME_S3_REGION"),
+		Bucket:          os.Getenv("BACKEND_TAKE_HOME_S3_BUCKET"),
+		AccessKeyID:     os.Getenv("BACKEND_TAKE_HOME_S3_ACCESS_KEY_ID"),
+		SecretAccessKey: os.Getenv("BACKEND_TAKE_HOME_S3_SECRET_ACCESS_KEY"),
+	})
+	if err != nil {
+		return nil, err
+	}
+	if os.Getenv("BACKEND_TAKE_HOME_S3_CREATE_BUCKET") == "true" {
+		if err := store.EnsureBucket(ctx); err != nil {
+			return nil, err
+		}
+	}
+	return store, nil
 }
@@ -5,17 +5,47 @@ import (
 )
 
 var (
-	ErrInternalServerError = NewCustomError(http.StatusInternalServerError, "An internal server error occurred")
-	ErrEmailAlreadyExists  = NewCustomError(http.StatusBadRequest, "Email already exists")
-	ErrEmailNotFound       = NewCustomError(http.StatusNotFound, "Email not found")
-	ErrInvalidPassword     = NewCustomError(http.StatusBadRequest, "Invalid password")
-	ErrUserNotFound        = NewCustomError(http.StatusNotFound, "User not found")
-	ErrInvalidParam        = NewCustomError(http.StatusBadRequest, "Invalid parameter")
-	ErrPostNotFound        = NewCustomError(http.StatusNotFound, "Post not found")
-	ErrUnauthorized        = NewCustomError(http.StatusUnauthorized, "Unauthorized")
-	ErrInvalidTokenMethod  = NewCustomError(http.StatusUnauthorized, "Invalid token method")
-	ErrInvalidToken        = NewCustomError(http.StatusUnauthorized, "Invalid token")
-	ErrPostOwnerMismatch   = NewCustomError(http.StatusForbidden, "Post owner mismatch")
+	ErrInternalServerError     = NewCustomError(http.StatusInternalServerError, "An internal server error occurred")
+	ErrEmailAlreadyExists      = NewCustomError(http.StatusBadRequest, "Email already exists")
+	ErrUsernameAlreadyExists   = NewCustomError(http.StatusBadRequest, "Username already exists")
+	ErrInvalidUsername         = NewCustomError(http.StatusBadRequest, "Username must be 3 to 30 lowercase letters, digits or underscores")
+	ErrEmailNotFound           = NewCustomError(http.StatusNotFound, "Email not found")
+	ErrInvalidPassword         = NewCustomError(http.StatusBadRequest, "Invalid password")
+	ErrUserNotFound            = NewCustomError(http.StatusNotFound, "User not found")
+	ErrInvalidParam            = NewCustomError(http.StatusBadRequest, "Invalid parameter")
+	ErrPostNotFound            = NewCustomError(http.StatusNotFound, "Post not found")
+	ErrUnauthorized            = NewCustomError(http.StatusUnauthorized, "Unauthorized")
+	ErrInvalidTokenMethod      = NewCustomError(http.StatusUnauthorized, "Invalid token method")
+	ErrForbidden               = NewCustomError(http.StatusForbidden, "Forbidden")
+	ErrInvalidToken            = NewCustomError(http.StatusUnauthorized, "Invalid token")
+	ErrPostOwnerMismatch       = NewCustomError(http.StatusForbidden, "Post owner mismatch")
+	ErrPostVersionMismatch     = NewCustomError(http.StatusPreconditionFailed, "Post has been modified")
+	ErrSlugTaken               = NewCustomError(http.StatusConflict, "Slug already taken")
+	ErrUnsupportedMedia        = NewCustomError(http.StatusUnsupportedMediaType, "Unsupported media type")
+	ErrTagNotFound             = NewCustomError(http.StatusNotFound, "Tag not found")
+	ErrInvalidTag              = NewCustomError(http.StatusBadRequest, "Invalid tag")
+	ErrCategoryNotFound        = NewCustomError(http.StatusNotFound, "Category not found")
+	ErrCategoryExists          = NewCustomError(http.StatusConflict, "Category already exists")
+	ErrInvalidSearchQuery      = NewCustomError(http.StatusBadRequest, "Invalid search query")
+	ErrInvalidCursor           = NewCustomError(http.StatusBadRequest, "Invalid cursor")
+	ErrCursorSort              = NewCustomError(http.StatusBadRequest, "Cursor pagination only supports sorting by creation time")
+	ErrSitemapNotFound         = NewCustomError(http.StatusNotFound, "Sitemap not found")
+	ErrFollowSelf              = NewCustomError(http.StatusBadRequest, "Users cannot follow themselves")
+	ErrCommentOwnerMismatch    = NewCustomError(http.StatusForbidden, "Comment owner mismatch")
+	ErrCommentNotFound         = NewCustomError(http.StatusNotFound, "Comment not found")
+	ErrNotificationNotFound    = NewCustomError(http.StatusNotFound, "Notification not found")
+	ErrNotificationType        = NewCustomError(http.StatusBadRequest, "Unknown notification type")
+	ErrWebhookNotFound         = NewCustomError(http.StatusNotFound, "Webhook not found")
+	ErrWebhookDeliveryNotFound = NewCustomError(http.StatusNotFound, "Webhook delivery not found")
+	ErrInvalidWebhookURL       = NewCustomError(http.StatusBadRequest, "Webhook URL must be an absolute http or https URL")
+	ErrWebhookEvent            = NewCustomError(http.StatusBadRequest, "Unknown webhook event")
+	ErrJobNotFound             = NewCustomError(http.StatusNotFound, "Job not found")
+	ErrJobNotDead              = NewCustomError(http.StatusConflict, "Only dead jobs can be retried")
+	ErrAttachmentNotFound      = NewCustomError(http.StatusNotFound, "Attachment not found")
+	ErrInvalidAttachment       = NewCustomError(http.StatusBadRequest, "Attachments must be your own uploads not used by another post")
+	ErrFileTooLarge            = NewCustomError(http.StatusRequestEntityTooLarge, "File too large")
+	ErrBlobNotFound            = NewCustomError(http.StatusNotFound, "File not found")
+	ErrInvalidVariant          = NewCustomError(http.StatusBadRequest, "Unknown image variant")
 )
 
 type CustomError struct {
@@ -0,0 +1,107 @@
+package common
+
+import (
+	"html"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/microcosm-cc/bluemonday"
+)
+
+var stripper = bluemonday.StrictPolicy()
+
+// StripTags returns the plain text of an HTML fragment.
+func StripTags(input string) string {
+	return html.UnescapeString(stripper.Sanitize(input))
+}
+
+// SearchTerms splits a search query, including boolean mode operators, into
+// the words worth highlighting.
+func SearchTerms(query string) []string {
+	var terms []string
+	for _, term := range strings.FieldsFunc(query, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}) {
+		if utf8.RuneCountInString(term) > 1 {
+			terms = append(terms, term)
+		}
+	}
+	return terms
+}
+
+// Highlight cuts an excerpt of about width bytes out of text, centered on the
+// first occurrence of any of the terms, and wraps every occurrence in <mark>.
+// The excerpt is HTML escaped so it can be rendered as is.
+func Highlight(text string, terms []string, width int) string {
+	text = strings.Join(strings.Fields(text), " ")
+	var pattern *regexp.Regexp
+	if len(terms) > 0 {
+		quoted := make([]string, len(terms))
+		for i, term := range terms {
+			quoted[i] = regexp.QuoteMeta(term)
+		}
+		pattern = regexp.MustCompile(`(?i)` + strings.Join(quoted, "|"))
+	}
+	start, end := 0, len(text)
+	if len(text) > width {
+		if pattern != nil {
+			if loc := pattern.FindStringIndex(text); loc != nil {
+				start = max(0, loc[0]-width/3)
+			}
+		}
+		start = min(start, len(text)-width)
+		end = start + width
+		for start > 0 && !utf8.RuneStart(text[start]) {
+			start--
+		}
+		for end < len(text) && !utf8.RuneStart(text[end]) {
+			end--
+		}
+		if i := strings.IndexByte(text[start:end], ' '); start > 0 && i >= 0 {
+			start += i + 1
+		}
+		if i := strings.LastIndexByte(text[start:end], ' '); end < len(text) && i >= 0 {
+			end = start + i
+		}
+	}
+	excerpt := text[start:end]
+	var b strings.Builder
+	if start > 0 {
+		b.WriteString("…")
+	}
+	last := 0
+	if pattern != nil {
+		for _, loc := range pattern.FindAllStringIndex(excerpt, -1) {
+			b.WriteString(html.EscapeString(excerpt[last:loc[0]]))
+			b.WriteString("<mark>")
+			b.WriteString(html.EscapeString(excerpt[loc[0]:loc[1]]))
+			b.WriteString("</mark>")
+			last = loc[1]
+		}
+	}
+	b.WriteString(html.EscapeString(excerpt[last:]))
+	if end < len(text) {
+		b.WriteString("…")
+	}
+	return b.String()
+}
+
+// Excerpt returns the start of text, cut at a word boundary to at most
+// width bytes with an ellipsis when something was left out. Unlike
+// Highlight the result is plain text.
+func Excerpt(text string, width int) string {
+	text = strings.Join(strings.Fields(text), " ")
+	if len(text) <= width {
+		return text
+	}
+	end := width
+	for end > 0 && !utf8.RuneStart(text[end]) {
+		end--
+	}
+	if i := strings.LastIndexByte(text[:end], ' '); i > 0 {
+		end = i
+	}
+	return text[:end] + "…"
+}
@@ -0,0 +1,97 @@
+package common
+
+import (
+	"bytes"
+	"html"
+	"io"
+	"regexp"
+	"slices"
+	"strings"
+
+	htmlparser "golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+var (
+	usernamePattern = regexp.MustCompile(`^[a-z0-9_]{3,30}$`)
+	mentionPattern  = regexp.MustCompile(`(^|[^\w@/])@([A-Za-z0-9_]{3,30})\b`)
+	codeTags        = []atom.Atom{atom.Code, atom.Pre}
+	linkOrCodeTags  = []atom.Atom{atom.A, atom.Code, atom.Pre}
+)
+
+// ValidUsername reports whether username is a handle users can register:
+// 3 to 30 lowercase letters, digits or underscores.
+func ValidUsername(username string) bool {
+	return usernamePattern.MatchString(username)
+}
+
+// Mentions returns the lowercased usernames mentioned as @username in the
+// text of sanitized HTML, once each and in order of appearance. Code is not
+// searched, but links are, so mentions already linked still count.
+func Mentions(input string) []string {
+	var usernames []string
+	seen := map[string]bool{}
+	eachText(input, codeTags, func(text string) string {
+		for _, match := range mentionPattern.FindAllStringSubmatch(text, -1) {
+			username := strings.ToLower(match[2])
+			if !seen[username] {
+				seen[username] = true
+				usernames = append(usernames, username)
+			}
+		}
+		return ""
+	})
+	return usernames
+}
+
+// LinkMentions wraps the mentions of known users in links to their profile,
+// as given by link. Mentions of unknown usernames are left as text.
+func LinkMentions(input string, link func(username string) (string, bool)) string {
+	return eachText(input, linkOrCodeTags, func(text string) string {
+		escaped := html.EscapeString(text)
+		return mentionPattern.ReplaceAllStringFunc(escaped, func(match string) string {
+			groups := mentionPattern.FindStringSubmatch(match)
+			href, ok := link(strings.ToLower(groups[2]))
+			if !ok {
+				return match
+			}
+			return groups[1] + `<a href="` + html.EscapeString(href) + `">@` + groups[2] + `</a>`
+		})
+	})
+}
+
+// eachText walks the HTML and replaces every text node outside the skipped
+// elements with what visit returns for its unescaped text. Everything else is
+// copied as is.
+func eachText(input string, skipped []atom.Atom, visit func(text string) string) string {
+	var output bytes.Buffer
+	tokenizer := htmlparser.NewTokenizer(strings.NewReader(input))
+	skipping := 0
+	for {
+		kind := tokenizer.Next()
+		if kind == htmlparser.ErrorToken {
+			if tokenizer.Err() != io.EOF {
+				return input
+			}
+			return output.String()
+		}
+		raw := tokenizer.Raw()
+		token := tokenizer.Token()
+		switch kind {
+		case htmlparser.StartTagToken:
+			if slices.Contains(skipped, token.DataAtom) {
+				skipping++
+			}
+		case htmlparser.EndTagToken:
+			if slices.Contains(skipped, token.DataAtom) && skipping > 0 {
+				skipping--
+			}
+		case htmlparser.TextToken:
+			if skipping == 0 {
+				output.WriteString(visit(token.Data))
+				continue
+			}
+		}
+		output.Write(raw)
+	}
+}
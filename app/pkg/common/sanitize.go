@@ -1,12 +1,33 @@
 package common
 
-import "github.com/microcosm-cc/bluemonday"
+import (
+	"regexp"
+
+	"github.com/microcosm-cc/bluemonday"
+)
 
 var sanitizer *bluemonday.Policy
 
+var markdownSanitizer = newMarkdownPolicy()
+
 func Sanitize(input string) string {
 	if sanitizer == nil {
 		sanitizer = bluemonday.UGCPolicy()
 	}
 	return sanitizer.Sanitize(input)
 }
+
+// SanitizeMarkdown sanitizes HTML rendered from Markdown. On top of the user
+// generated content policy it keeps the disabled checkboxes of task lists and
+// the language class of fenced code blocks.
+func SanitizeMarkdown(input string) string {
+	return markdownSanitizer.Sanitize(input)
+}
+
+func newMarkdownPolicy() *bluemonday.Policy {
+	policy := bluemonday.UGCPolicy()
+	policy.AllowAttrs("type").Matching(regexp.MustCompile(`^checkbox$`)).OnElements("input")
+	policy.AllowAttrs("checked", "disabled").Matching(regexp.MustCompile(`^$`)).OnElements("input")
+	policy.AllowAttrs("class").Matching(regexp.MustCompile(`^language-[\w+#.-]+$`)).OnElements("code")
+	return policy
+}
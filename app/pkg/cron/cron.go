@@ -0,0 +1,160 @@
+package cron
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// descriptors are the shorthands accepted in place of the five fields.
+var descriptors = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+// bounds are the ranges of the minute, hour, day of month, month and day of
+// week fields, in that order.
+var bounds = [5][2]int{{0, 59}, {0, 23}, {1, 31}, {1, 12}, {0, 7}}
+
+// Schedule tells when a recurring job is due next.
+type Schedule struct {
+	minute, hour, dom, month, dow uint64
+	// anyDay is set when either day field is a wildcard, in which case a day
+	// has to match both; otherwise matching either is enough, as in cron.
+	anyDay bool
+	every  time.Duration
+}
+
+// Parse reads a standard five field cron expression (minute, hour, day of
+// month, month, day of week) made of numbers, wildcards, ranges, steps and
+// lists, one of the @hourly style descriptors, or "@every <duration>".
+// Sunday is both 0 and 7.
+func Parse(spec string) (*Schedule, error) {
+	spec = strings.TrimSpace(spec)
+	if duration, ok := strings.CutPrefix(spec, "@every "); ok {
+		every, err := time.ParseDuration(strings.TrimSpace(duration))
+		if err != nil || every < time.Second {
+			return nil, fmt.Errorf("cron: invalid interval %q", duration)
+		}
+		return &Schedule{every: every}, nil
+	}
+	if expanded, ok := descriptors[spec]; ok {
+		spec = expanded
+	}
+	fields := strings.Fields(spec)
+	if len(fields) != len(bounds) {
+		return nil, fmt.Errorf("cron: expected 5 fields in %q", spec)
+	}
+	var sets [5]uint64
+	for i, field := range fields {
+		set, err := parseField(field, bounds[i][0], bounds[i][1])
+		if err != nil {
+			return nil, err
+		}
+		sets[i] = set
+	}
+	// Sunday is stored as 0 only.
+	if sets[4]&(1<<7) != 0 {
+		sets[4] = sets[4]&^(1<<7) | 1
+	}
+	return &Schedule{
+		minute: sets[0],
+		hour:   sets[1],
+		dom:    sets[2],
+		month:  sets[3],
+		dow:    sets[4],
+		anyDay: strings.HasPrefix(fields[2], "*") || strings.HasPrefix(fields[4], "*"),
+	}, nil
+}
+
+// Next returns the first time after t the schedule is due, in the location
+// of t, or the zero time when it never is.
+func (s *Schedule) Next(t time.Time) time.Time {
+	if s.every > 0 {
+		return t.Add(s.every)
+	}
+	loc := t.Location()
+	t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), 0, 0, loc).Add(time.Minute)
+	// Days such as 30 February never come, so the search gives up after a
+	// few decades.
+	limit := t.AddDate(30, 0, 0)
+	for t.Before(limit) {
+		year, month, day := t.Date()
+		switch {
+		case s.month&(1<<uint(month)) == 0:
+			t = time.Date(year, month+1, 1, 0, 0, 0, 0, loc)
+		case !s.matchesDay(t):
+			t = time.Date(year, month, day+1, 0, 0, 0, 0, loc)
+		case s.hour&(1<<uint(t.Hour())) == 0:
+			t = time.Date(year, month, day, t.Hour()+1, 0, 0, 0, loc)
+		case s.minute&(1<<uint(t.Minute())) == 0:
+			t = t.Add(time.Minute)
+		default:
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+func (s *Schedule) matchesDay(t time.Time) bool {
+	dom := s.dom&(1<<uint(t.Day())) != 0
+	dow := s.dow&(1<<uint(t.Weekday())) != 0
+	if s.anyDay {
+		return dom && dow
+	}
+	return dom || dow
+}
+
+// parseField turns a comma separated list of "*", "n", "a-b", each with an
+// optional "/step", into a bit set of the values it covers.
+func parseField(field string, first, last int) (uint64, error) {
+	var set uint64
+	for _, part := range strings.Split(field, ",") {
+		rangePart, stepPart, hasStep := strings.Cut(part, "/")
+		step := 1
+		if hasStep {
+			var err error
+			step, err = strconv.Atoi(stepPart)
+			if err != nil || step < 1 {
+				return 0, fmt.Errorf("cron: invalid step in %q", part)
+			}
+		}
+		var low, high int
+		switch {
+		case rangePart == "*":
+			low, high = first, last
+		case strings.Contains(rangePart, "-"):
+			from, to, _ := strings.Cut(rangePart, "-")
+			var err error
+			if low, err = strconv.Atoi(from); err != nil {
+				return 0, fmt.Errorf("cron: invalid range %q", part)
+			}
+			if high, err = strconv.Atoi(to); err != nil {
+				return 0, fmt.Errorf("cron: invalid range %q", part)
+			}
+		default:
+			var err error
+			if low, err = strconv.Atoi(rangePart); err != nil {
+				return 0, fmt.Errorf("cron: invalid value %q", part)
+			}
+			high = low
+			// "n/step" runs from n to the end of the range.
+			if hasStep {
+				high = last
+			}
+		}
+		if low < first || high > last || low > high {
+			return 0, fmt.Errorf("cron: %q out of range %d-%d", part, first, last)
+		}
+		for value := low; value <= high; value += step {
+			set |= 1 << uint(value)
+		}
+	}
+	return set, nil
+}
@@ -0,0 +1,74 @@
+package cron
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseErrors(t *testing.T) {
+	for _, spec := range []string{
+		"",
+		"* * * *",
+		"* * * * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * 32 * *",
+		"* * * 0 *",
+		"* * * 13 *",
+		"* * * * 8",
+		"*/0 * * * *",
+		"*/x * * * *",
+		"5-1 * * * *",
+		"a * * * *",
+		"1-x * * * *",
+		"1,,2 * * * *",
+		"@fortnightly",
+		"@every nope",
+		"@every 500ms",
+		"@every -1m",
+	} {
+		_, err := Parse(spec)
+		assert.Error(t, err, spec)
+	}
+}
+
+func TestNext(t *testing.T) {
+	date := func(year int, month time.Month, day, hour, minute int) time.Time {
+		return time.Date(year, month, day, hour, minute, 0, 0, time.UTC)
+	}
+	for _, test := range []struct {
+		name string
+		spec string
+		from time.Time
+		want time.Time
+	}{
+		{"later today", "30 3 * * *", date(2026, 10, 19, 3, 29), date(2026, 10, 19, 3, 30)},
+		{"strictly after", "30 3 * * *", date(2026, 10, 19, 3, 30), date(2026, 10, 20, 3, 30)},
+		{"seconds are dropped", "30 3 * * *", date(2026, 10, 19, 3, 29).Add(59 * time.Second), date(2026, 10, 19, 3, 30)},
+		{"tomorrow", "30 3 * * *", date(2026, 10, 19, 12, 0), date(2026, 10, 20, 3, 30)},
+		{"next month", "0 0 1 * *", date(2026, 1, 31, 10, 0), date(2026, 2, 1, 0, 0)},
+		{"short month is skipped", "0 0 31 * *", date(2026, 4, 1, 0, 0), date(2026, 5, 31, 0, 0)},
+		{"next year", "@yearly", date(2026, 12, 31, 23, 59), date(2027, 1, 1, 0, 0)},
+		{"leap day", "0 12 29 2 *", date(2026, 3, 1, 0, 0), date(2028, 2, 29, 12, 0)},
+		{"never due", "0 0 30 2 *", date(2026, 1, 1, 0, 0), time.Time{}},
+		{"step", "*/20 * * * *", date(2026, 10, 19, 10, 41), date(2026, 10, 19, 11, 0)},
+		{"step from a value", "5/20 * * * *", date(2026, 10, 19, 10, 26), date(2026, 10, 19, 10, 45)},
+		{"range with step and list", "0 9-17/4,20 * * *", date(2026, 10, 19, 17, 30), date(2026, 10, 19, 20, 0)},
+		{"weekdays only", "0 9 * * 1-5", date(2026, 10, 23, 18, 0), date(2026, 10, 26, 9, 0)},
+		{"sunday as 0", "0 0 * * 0", date(2026, 10, 19, 0, 0), date(2026, 10, 25, 0, 0)},
+		{"sunday as 7", "0 0 * * 7", date(2026, 10, 19, 0, 0), date(2026, 10, 25, 0, 0)},
+		{"@weekly", "@weekly", date(2026, 10, 19, 0, 0), date(2026, 10, 25, 0, 0)},
+		{"day of month or week", "0 0 13 * 5", date(2026, 10, 19, 0, 0), date(2026, 10, 23, 0, 0)},
+		{"day of month and week with a wildcard", "0 0 */2 * 1", date(2026, 10, 19, 12, 0), date(2026, 11, 9, 0, 0)},
+		{"every", "@every 90m", date(2026, 10, 19, 10, 0), date(2026, 10, 19, 11, 30)},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			schedule, err := Parse(test.spec)
+			assert.Nil(t, err)
+			assert.Equal(t, test.want, schedule.Next(test.from))
+		})
+	}
+}
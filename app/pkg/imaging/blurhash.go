@@ -0,0 +1,109 @@
+package imaging
+
+import (
+	"image"
+	"math"
+	"strings"
+)
+
+const base83 = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz#$%*+,-.:;=?@[]^_{|}~"
+
+// Blurhash encodes the image as a BlurHash (https://blurha.sh), a short
+// string clients decode into a blurred placeholder while the image loads.
+// The components, from 1 to 9 on each axis, set how much detail it keeps.
+// It is best computed from a small version of the image.
+func Blurhash(img *image.RGBA, xComponents, yComponents int) string {
+	w, h := img.Bounds().Dx(), img.Bounds().Dy()
+	linear := make([][3]float64, w*h)
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			offset := img.PixOffset(x, y)
+			alpha := int(img.Pix[offset+3])
+			for c := 0; c < 3; c++ {
+				value := int(img.Pix[offset+c])
+				if alpha > 0 && alpha < 255 {
+					value = min(255, value*255/alpha)
+				}
+				linear[y*w+x][c] = srgbToLinear(value)
+			}
+		}
+	}
+
+	factors := make([][3]float64, 0, xComponents*yComponents)
+	for j := 0; j < yComponents; j++ {
+		for i := 0; i < xComponents; i++ {
+			normalization := 2.0
+			if i == 0 && j == 0 {
+				normalization = 1
+			}
+			var factor [3]float64
+			for y := 0; y < h; y++ {
+				basisY := math.Cos(math.Pi * float64(j) * float64(y) / float64(h))
+				for x := 0; x < w; x++ {
+					basis := normalization * math.Cos(math.Pi*float64(i)*float64(x)/float64(w)) * basisY
+					for c := range factor {
+						factor[c] += basis * linear[y*w+x][c]
+					}
+				}
+			}
+			for c := range factor {
+				factor[c] /= float64(w * h)
+			}
+			factors = append(factors, factor)
+		}
+	}
+
+	var hash strings.Builder
+	encode83(&hash, (xComponents-1)+(yComponents-1)*9, 1)
+	maxValue := 1.0
+	if len(factors) > 1 {
+		actualMax := 0.0
+		for _, factor := range factors[1:] {
+			for _, value := range factor {
+				actualMax = math.Max(actualMax, math.Abs(value))
+			}
+		}
+		quantisedMax := int(math.Max(0, math.Min(82, math.Floor(actualMax*166-0.5))))
+		maxValue = float64(quantisedMax+1) / 166
+		encode83(&hash, quantisedMax, 1)
+	} else {
+		encode83(&hash, 0, 1)
+	}
+	dc := factors[0]
+	encode83(&hash, linearToSRGB(dc[0])<<16+linearToSRGB(dc[1])<<8+linearToSRGB(dc[2]), 4)
+	for _, factor := range factors[1:] {
+		value := 0
+		for _, component := range factor {
+			quantised := int(math.Max(0, math.Min(18, math.Floor(signPow(component/maxValue, 0.5)*9+9.5))))
+			value = value*19 + quantised
+		}
+		encode83(&hash, value, 2)
+	}
+	return hash.String()
+}
+
+func encode83(hash *strings.Builder, value, length int) {
+	for i := length - 1; i >= 0; i-- {
+		hash.WriteByte(base83[value/int(math.Pow(83, float64(i)))%83])
+	}
+}
+
+func srgbToLinear(value int) float64 {
+	v := float64(value) / 255
+	if v <= 0.04045 {
+		return v / 12.92
+	}
+	return math.Pow((v+0.055)/1.055, 2.4)
+}
+
+func linearToSRGB(value float64) int {
+	v := math.Max(0, math.Min(1, value))
+	if v <= 0.0031308 {
+		return int(v*12.92*255 + 0.5)
+	}
+	return int((1.055*math.Pow(v, 1/2.4)-0.055)*255 + 0.5)
+}
+
+func signPow(value, exponent float64) float64 {
+	return math.Copysign(math.Pow(math.Abs(value), exponent), value)
+}
@@ -0,0 +1,33 @@
+package imaging
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBlurhash(t *testing.T) {
+	uniform := func(c color.RGBA, size int) *image.RGBA {
+		img := image.NewRGBA(image.Rect(0, 0, size, size))
+		draw.Draw(img, img.Bounds(), image.NewUniform(c), image.Point{}, draw.Src)
+		return img
+	}
+	black := color.RGBA{0, 0, 0, 255}
+	// A flat image has no detail: every AC component is the zero "fQ".
+	assert.Equal(t, "L00000fQfQfQfQfQfQfQfQfQfQfQ", Blurhash(uniform(black, 8), 4, 3))
+	assert.Equal(t, Blurhash(uniform(black, 8), 4, 3), Blurhash(uniform(black, 32), 4, 3))
+	assert.Equal(t, "000000", Blurhash(uniform(black, 8), 1, 1))
+
+	gradient := image.NewRGBA(image.Rect(0, 0, 16, 16))
+	for y := 0; y < 16; y++ {
+		for x := 0; x < 16; x++ {
+			gradient.Set(x, y, color.RGBA{uint8(x * 16), 0, uint8(y * 16), 255})
+		}
+	}
+	hash := Blurhash(gradient, 4, 3)
+	assert.Len(t, hash, 4+2*4*3)
+	assert.NotEqual(t, "fQ", hash[6:8])
+}
@@ -0,0 +1,260 @@
+package imaging
+
+import (
+	"bytes"
+	"encoding/binary"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// gpsLatitude stands for the location a camera records in the EXIF data.
+const gpsLatitude = "N 52 22 12"
+
+func testImage() *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, 4, 3))
+	for y := 0; y < 3; y++ {
+		for x := 0; x < 4; x++ {
+			img.Set(x, y, color.RGBA{uint8(x * 60), uint8(y * 80), 100, 255})
+		}
+	}
+	return img
+}
+
+// tiff returns EXIF data in the given byte order holding the orientation
+// and a GPS tag pointing at the latitude.
+func tiff(order binary.ByteOrder, orientation uint16) []byte {
+	var buf bytes.Buffer
+	if order == binary.LittleEndian {
+		buf.WriteString("II")
+	} else {
+		buf.WriteString("MM")
+	}
+	binary.Write(&buf, order, uint16(42))
+	binary.Write(&buf, order, uint32(8))
+	binary.Write(&buf, order, uint16(2))
+	// Orientation, a SHORT held in the entry itself.
+	binary.Write(&buf, order, uint16(0x0112))
+	binary.Write(&buf, order, uint16(3))
+	binary.Write(&buf, order, uint32(1))
+	binary.Write(&buf, order, orientation)
+	binary.Write(&buf, order, uint16(0))
+	// GPS IFD pointer.
+	binary.Write(&buf, order, uint16(0x8825))
+	binary.Write(&buf, order, uint16(4))
+	binary.Write(&buf, order, uint32(1))
+	binary.Write(&buf, order, uint32(38))
+	binary.Write(&buf, order, uint32(0))
+	buf.WriteString(gpsLatitude)
+	return buf.Bytes()
+}
+
+func jpegSegment(marker byte, payload []byte) []byte {
+	segment := []byte{0xFF, marker, 0, 0}
+	binary.BigEndian.PutUint16(segment[2:], uint16(len(payload)+2))
+	return append(segment, payload...)
+}
+
+// testJPEG encodes the test image and inserts the given segments after the
+// start of image marker.
+func testJPEG(t *testing.T, segments ...[]byte) []byte {
+	var buf bytes.Buffer
+	assert.Nil(t, jpeg.Encode(&buf, testImage(), nil))
+	encoded := buf.Bytes()
+	data := append([]byte{}, encoded[:2]...)
+	for _, segment := range segments {
+		data = append(data, segment...)
+	}
+	return append(data, encoded[2:]...)
+}
+
+// jpegMarkers lists the markers of the segments before the image data.
+func jpegMarkers(data []byte) []byte {
+	var markers []byte
+	for i := 2; i+4 <= len(data) && data[i] == 0xFF && data[i+1] != 0xDA; {
+		markers = append(markers, data[i+1])
+		i += 2 + int(binary.BigEndian.Uint16(data[i+2:]))
+	}
+	return markers
+}
+
+func pngChunk(kind string, payload []byte) []byte {
+	chunk := binary.BigEndian.AppendUint32(nil, uint32(len(payload)))
+	chunk = append(chunk, kind...)
+	chunk = append(chunk, payload...)
+	return binary.BigEndian.AppendUint32(chunk, crc32.ChecksumIEEE(chunk[4:]))
+}
+
+// pngChunks lists the chunk types of a PNG image.
+func pngChunks(data []byte) []string {
+	var chunks []string
+	for i := len(pngSignature); i+8 <= len(data); {
+		chunks = append(chunks, string(data[i+4:i+8]))
+		i += 12 + int(binary.BigEndian.Uint32(data[i:]))
+	}
+	return chunks
+}
+
+func webpChunk(kind string, payload []byte) []byte {
+	chunk := append([]byte(kind), binary.LittleEndian.AppendUint32(nil, uint32(len(payload)))...)
+	chunk = append(chunk, payload...)
+	if len(payload)%2 == 1 {
+		chunk = append(chunk, 0)
+	}
+	return chunk
+}
+
+// webpChunks lists the chunk types of a WebP image.
+func webpChunks(data []byte) []string {
+	var chunks []string
+	for i := 12; i+8 <= len(data); {
+		chunks = append(chunks, string(data[i:i+4]))
+		size := int(binary.LittleEndian.Uint32(data[i+4:]))
+		i += 8 + size + size%2
+	}
+	return chunks
+}
+
+func TestStripMetadataJPEG(t *testing.T) {
+	exif := append(append([]byte{}, exifHeader...), tiff(binary.BigEndian, 6)...)
+	icc := append([]byte("ICC_PROFILE\x00\x01\x01"), make([]byte, 16)...)
+	data := testJPEG(t,
+		jpegSegment(0xE1, exif),
+		jpegSegment(0xE1, []byte("http://ns.adobe.com/xap/1.0/\x00<x:xmpmeta/>")),
+		jpegSegment(0xED, []byte("Photoshop 3.0\x008BIM")),
+		jpegSegment(0xFE, []byte("taken at home")),
+		jpegSegment(0xE2, icc),
+	)
+	assert.Equal(t, 6, Orientation("image/jpeg", data))
+
+	stripped, err := StripMetadata("image/jpeg", data)
+	assert.Nil(t, err)
+	markers := jpegMarkers(stripped)
+	for _, marker := range []byte{0xE1, 0xED, 0xFE} {
+		assert.NotContains(t, markers, marker)
+	}
+	assert.Contains(t, markers, byte(0xE2))
+	for _, leak := range []string{"Exif", gpsLatitude, "xmpmeta", "Photoshop", "taken at home"} {
+		assert.False(t, bytes.Contains(stripped, []byte(leak)), leak)
+	}
+	assert.Equal(t, 1, Orientation("image/jpeg", stripped))
+	// The pixels are left alone.
+	scan := bytes.Index(data, []byte{0xFF, 0xDA})
+	assert.True(t, bytes.HasSuffix(stripped, data[scan:]))
+	decoded, err := jpeg.Decode(bytes.NewReader(stripped))
+	assert.Nil(t, err)
+	assert.Equal(t, image.Rect(0, 0, 4, 3), decoded.Bounds())
+}
+
+func TestStripMetadataPNG(t *testing.T) {
+	var buf bytes.Buffer
+	assert.Nil(t, png.Encode(&buf, testImage()))
+	encoded := buf.Bytes()
+	// The IHDR chunk comes first and is 25 bytes long.
+	header := len(pngSignature) + 25
+	data := append([]byte{}, encoded[:header]...)
+	data = append(data, pngChunk("gAMA", []byte{0, 1, 0x86, 0xA0})...)
+	data = append(data, pngChunk("eXIf", tiff(binary.LittleEndian, 1))...)
+	data = append(data, pngChunk("tEXt", []byte("Comment\x00taken at home"))...)
+	data = append(data, pngChunk("zTXt", []byte("Author\x00\x00x"))...)
+	data = append(data, pngChunk("iTXt", []byte("XML:com.adobe.xmp\x00\x00\x00\x00\x00<x:xmpmeta/>"))...)
+	data = append(data, pngChunk("tIME", []byte{0x07, 0xEA, 10, 19, 12, 0, 0})...)
+	data = append(data, encoded[header:]...)
+
+	stripped, err := StripMetadata("image/png", data)
+	assert.Nil(t, err)
+	chunks := pngChunks(stripped)
+	for _, chunk := range []string{"eXIf", "tEXt", "zTXt", "iTXt", "tIME"} {
+		assert.NotContains(t, chunks, chunk)
+	}
+	assert.Equal(t, "IHDR", chunks[0])
+	assert.Contains(t, chunks, "gAMA")
+	assert.Equal(t, "IEND", chunks[len(chunks)-1])
+	for _, leak := range []string{gpsLatitude, "taken at home", "xmpmeta"} {
+		assert.False(t, bytes.Contains(stripped, []byte(leak)), leak)
+	}
+	decoded, err := png.Decode(bytes.NewReader(stripped))
+	assert.Nil(t, err)
+	assert.Equal(t, testImage().Pix, RGBA(decoded).Pix)
+}
+
+func TestStripMetadataWebP(t *testing.T) {
+	// An extended WebP announcing a color profile, EXIF and XMP data. The
+	// image data is not decoded, so any bytes do.
+	vp8x := []byte{0x20 | 0x08 | 0x04, 0, 0, 0, 3, 0, 0, 2, 0, 0}
+	body := []byte("WEBP")
+	body = append(body, webpChunk("VP8X", vp8x)...)
+	body = append(body, webpChunk("ICCP", []byte("profile"))...)
+	body = append(body, webpChunk("VP8L", []byte("pixels"))...)
+	body = append(body, webpChunk("EXIF", tiff(binary.LittleEndian, 3))...)
+	body = append(body, webpChunk("XMP ", []byte("<x:xmpmeta/>"))...)
+	data := append([]byte("RIFF"), binary.LittleEndian.AppendUint32(nil, uint32(len(body)))...)
+	data = append(data, body...)
+
+	stripped, err := StripMetadata("image/webp", data)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"VP8X", "ICCP", "VP8L"}, webpChunks(stripped))
+	assert.Equal(t, byte(0x20), stripped[20])
+	assert.Equal(t, uint32(len(stripped)-8), binary.LittleEndian.Uint32(stripped[4:]))
+	for _, leak := range []string{gpsLatitude, "xmpmeta"} {
+		assert.False(t, bytes.Contains(stripped, []byte(leak)), leak)
+	}
+	assert.True(t, bytes.Contains(stripped, webpChunk("VP8L", []byte("pixels"))))
+}
+
+func TestStripMetadataOtherTypes(t *testing.T) {
+	data := []byte("GIF89a")
+	stripped, err := StripMetadata("image/gif", data)
+	assert.Nil(t, err)
+	assert.Equal(t, data, stripped)
+}
+
+func TestStripMetadataErrors(t *testing.T) {
+	exif := append(append([]byte{}, exifHeader...), tiff(binary.BigEndian, 6)...)
+	jpegData := testJPEG(t, jpegSegment(0xE1, exif))
+	var buf bytes.Buffer
+	assert.Nil(t, png.Encode(&buf, testImage()))
+	pngData := buf.Bytes()
+	for _, test := range []struct {
+		name        string
+		contentType string
+		data        []byte
+	}{
+		{"not a JPEG", "image/jpeg", pngData},
+		{"JPEG cut in its header", "image/jpeg", jpegData[:20]},
+		{"not a PNG", "image/png", jpegData},
+		{"PNG cut in a chunk", "image/png", pngData[:len(pngSignature)+20]},
+		{"not a WebP", "image/webp", []byte("RIFF\x04\x00\x00\x00WAVE")},
+		{"WebP cut in a chunk", "image/webp", append([]byte("RIFF\x10\x00\x00\x00WEBP"), webpChunk("VP8L", []byte("pixels"))[:10]...)},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := StripMetadata(test.contentType, test.data)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestOrientation(t *testing.T) {
+	for _, test := range []struct {
+		name string
+		data []byte
+		want int
+	}{
+		{"big endian", testJPEG(t, jpegSegment(0xE1, append(append([]byte{}, exifHeader...), tiff(binary.BigEndian, 8)...))), 8},
+		{"little endian", testJPEG(t, jpegSegment(0xE1, append(append([]byte{}, exifHeader...), tiff(binary.LittleEndian, 3)...))), 3},
+		{"out of range", testJPEG(t, jpegSegment(0xE1, append(append([]byte{}, exifHeader...), tiff(binary.BigEndian, 9)...))), 1},
+		{"XMP only", testJPEG(t, jpegSegment(0xE1, []byte("http://ns.adobe.com/xap/1.0/\x00"))), 1},
+		{"no metadata", testJPEG(t), 1},
+		{"garbage", []byte{0xFF, 0xD8, 0xFF, 0xE1, 0xFF, 0xFF}, 1},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			assert.Equal(t, test.want, Orientation("image/jpeg", test.data))
+		})
+	}
+	assert.Equal(t, 1, Orientation("image/png", testJPEG(t, jpegSegment(0xE1, append(append([]byte{}, exifHeader...), tiff(binary.BigEndian, 6)...)))))
+}
@@ -0,0 +1,87 @@
+package imaging
+
+import (
+	"image"
+	"image/color"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFit(t *testing.T) {
+	for _, test := range []struct {
+		width, height, size   int
+		wantWidth, wantHeight int
+	}{
+		{800, 600, 1000, 800, 600},
+		{1000, 1000, 1000, 1000, 1000},
+		{4000, 3000, 1000, 1000, 750},
+		{3000, 4000, 1000, 750, 1000},
+		{1001, 333, 100, 100, 33},
+		{5000, 1, 100, 100, 1},
+	} {
+		width, height := Fit(test.width, test.height, test.size)
+		assert.Equal(t, [2]int{test.wantWidth, test.wantHeight}, [2]int{width, height}, "%dx%d in %d", test.width, test.height, test.size)
+	}
+}
+
+func TestOrient(t *testing.T) {
+	// Each pixel of the 2x3 source holds its own coordinates.
+	src := image.NewRGBA(image.Rect(0, 0, 2, 3))
+	for y := 0; y < 3; y++ {
+		for x := 0; x < 2; x++ {
+			src.Set(x, y, color.RGBA{uint8(x), uint8(y), 0, 255})
+		}
+	}
+	at := func(img *image.RGBA, x, y int) [2]uint8 {
+		pixel := img.RGBAAt(x, y)
+		return [2]uint8{pixel.R, pixel.G}
+	}
+	for _, test := range []struct {
+		orientation       int
+		width, height     int
+		topLeft, topRight [2]uint8
+	}{
+		{1, 2, 3, [2]uint8{0, 0}, [2]uint8{1, 0}},
+		{2, 2, 3, [2]uint8{1, 0}, [2]uint8{0, 0}},
+		{3, 2, 3, [2]uint8{1, 2}, [2]uint8{0, 2}},
+		{4, 2, 3, [2]uint8{0, 2}, [2]uint8{1, 2}},
+		{5, 3, 2, [2]uint8{0, 0}, [2]uint8{0, 2}},
+		{6, 3, 2, [2]uint8{0, 2}, [2]uint8{0, 0}},
+		{7, 3, 2, [2]uint8{1, 2}, [2]uint8{1, 0}},
+		{8, 3, 2, [2]uint8{1, 0}, [2]uint8{1, 2}},
+		{9, 2, 3, [2]uint8{0, 0}, [2]uint8{1, 0}},
+	} {
+		dst := Orient(src, test.orientation)
+		assert.Equal(t, image.Rect(0, 0, test.width, test.height), dst.Bounds(), "orientation %d", test.orientation)
+		assert.Equal(t, test.topLeft, at(dst, 0, 0), "orientation %d", test.orientation)
+		assert.Equal(t, test.topRight, at(dst, test.width-1, 0), "orientation %d", test.orientation)
+	}
+}
+
+func TestResize(t *testing.T) {
+	// Black and white columns average out to grey.
+	src := image.NewRGBA(image.Rect(0, 0, 4, 2))
+	for y := 0; y < 2; y++ {
+		for x := 0; x < 4; x++ {
+			value := uint8(0)
+			if x%2 == 1 {
+				value = 255
+			}
+			src.Set(x, y, color.RGBA{value, value, value, 255})
+		}
+	}
+	dst := Resize(src, 2, 1)
+	assert.Equal(t, image.Rect(0, 0, 2, 1), dst.Bounds())
+	for x := 0; x < 2; x++ {
+		assert.Equal(t, color.RGBA{128, 128, 128, 255}, dst.RGBAAt(x, 0))
+	}
+}
+
+func TestRGBA(t *testing.T) {
+	src := image.NewGray(image.Rect(5, 5, 7, 6))
+	src.SetGray(6, 5, color.Gray{200})
+	rgba := RGBA(src)
+	assert.Equal(t, image.Rect(0, 0, 2, 1), rgba.Bounds())
+	assert.Equal(t, color.RGBA{200, 200, 200, 255}, rgba.RGBAAt(1, 0))
+}
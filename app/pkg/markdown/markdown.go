@@ -0,0 +1,78 @@
+package markdown
+
+import (
+	"bytes"
+	"strings"
+
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/extension"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/renderer/html"
+	"github.com/yuin/goldmark/text"
+)
+
+// Heading is an entry of the table of contents generated from the document.
+type Heading struct {
+	Level int
+	ID    string
+	Title string
+}
+
+var converter = goldmark.New(
+	goldmark.WithExtensions(extension.GFM),
+	goldmark.WithParserOptions(parser.WithAutoHeadingID()),
+	// Raw HTML is kept as CommonMark allows it; the output must be sanitized
+	// before it is stored or served.
+	goldmark.WithRendererOptions(html.WithUnsafe()),
+)
+
+// Render converts CommonMark with the GFM extensions (tables, task lists,
+// strikethrough and autolinks) into HTML. Every heading gets an id anchor,
+// and the headings are returned in document order to build a table of
+// contents.
+func Render(source string) (string, []Heading, error) {
+	src := []byte(source)
+	doc := converter.Parser().Parse(text.NewReader(src))
+	var headings []Heading
+	err := ast.Walk(doc, func(node ast.Node, entering bool) (ast.WalkStatus, error) {
+		heading, ok := node.(*ast.Heading)
+		if !ok || !entering {
+			return ast.WalkContinue, nil
+		}
+		id, _ := heading.AttributeString("id")
+		idBytes, _ := id.([]byte)
+		headings = append(headings, Heading{
+			Level: heading.Level,
+			ID:    string(idBytes),
+			Title: strings.TrimSpace(plainText(heading, src)),
+		})
+		return ast.WalkSkipChildren, nil
+	})
+	if err != nil {
+		return "", nil, err
+	}
+	var buf bytes.Buffer
+	if err := converter.Renderer().Render(&buf, src, doc); err != nil {
+		return "", nil, err
+	}
+	return buf.String(), headings, nil
+}
+
+func plainText(node ast.Node, source []byte) string {
+	var b strings.Builder
+	for child := node.FirstChild(); child != nil; child = child.NextSibling() {
+		switch n := child.(type) {
+		case *ast.Text:
+			b.Write(n.Segment.Value(source))
+			if n.SoftLineBreak() {
+				b.WriteByte(' ')
+			}
+		case *ast.String:
+			b.Write(n.Value)
+		default:
+			b.WriteString(plainText(child, source))
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,195 @@
+package repository
+
+import (
+	"app/domain"
+	"app/pkg/common"
+	"app/pkg/logger"
+	"context"
+	"database/sql"
+	"slices"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+const attachmentColumns = "a.id, a.owner_id, a.post_id, a.blob_key, a.filename, a.content_type, a.size, a.width, a.height, a.blurhash, a.processed_at, a.created_at"
+
+// attachmentQueryer runs the reads shared by queries in and out of a
+// transaction.
+type attachmentQueryer interface {
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+}
+
+type AttachmentRepositoryMySQL struct {
+	db *sql.DB
+}
+
+func NewAttachmentRepositoryMySQL(db *sql.DB) domain.AttachmentRepository {
+	return &AttachmentRepositoryMySQL{db: db}
+}
+
+// Create implements domain.AttachmentRepository.
+func (repository *AttachmentRepositoryMySQL) Create(ctx context.Context, tx domain.Transaction, attachment *domain.Attachment) error {
+	result, err := tx.GetTx().ExecContext(ctx, "INSERT INTO attachments (owner_id, blob_key, filename, content_type, size, created_at) VALUES (?, ?, ?, ?, ?, ?)", attachment.OwnerID, attachment.Key, attachment.Filename, attachment.ContentType, attachment.Size, attachment.CreatedAt)
+	if err != nil {
+		logger.Log.Error("failed to insert attachment", zap.Error(err))
+		return common.ErrInternalServerError
+	}
+	attachment.ID, err = result.LastInsertId()
+	if err != nil {
+		logger.Log.Error("failed to get last insert id", zap.Error(err))
+		return common.ErrInternalServerError
+	}
+	attachment.Variants = []domain.AttachmentVariant{}
+	return nil
+}
+
+// FindByID implements domain.AttachmentRepository.
+func (repository *AttachmentRepositoryMySQL) FindByID(ctx context.Context, id int64) (*domain.Attachment, error) {
+	attachments, err := repository.query(ctx, repository.db, "SELECT "+attachmentColumns+" FROM attachments a WHERE a.id = ?", id)
+	if err != nil {
+		return nil, err
+	}
+	if len(attachments) == 0 {
+		return nil, common.ErrAttachmentNotFound
+	}
+	return &attachments[0], nil
+}
+
+// FindByPostIDs implements domain.AttachmentRepository.
+func (repository *AttachmentRepositoryMySQL) FindByPostIDs(ctx context.Context, postIDs []int64) (map[int64][]domain.Attachment, error) {
+	attachments := make(map[int64][]domain.Attachment, len(postIDs))
+	if len(postIDs) == 0 {
+		return attachments, nil
+	}
+	placeholders, args := inClause(postIDs)
+	found, err := repository.query(ctx, repository.db, "SELECT "+attachmentColumns+" FROM attachments a WHERE a.post_id IN ("+placeholders+") ORDER BY a.id", args...)
+	if err != nil {
+		return nil, err
+	}
+	for _, attachment := range found {
+		attachments[*attachment.PostID] = append(attachments[*attachment.PostID], attachment)
+	}
+	return attachments, nil
+}
+
+// Attach implements domain.AttachmentRepository. The attachments are locked
+// so that two posts cannot claim the same upload.
+func (repository *AttachmentRepositoryMySQL) Attach(ctx context.Context, tx domain.Transaction, postID, ownerID int64, ids []int64) ([]domain.Attachment, error) {
+	var unique []int64
+	for _, id := range ids {
+		if !slices.Contains(unique, id) {
+			unique = append(unique, id)
+		}
+	}
+	if _, err := tx.GetTx().ExecContext(ctx, "UPDATE attachments SET post_id = NULL WHERE post_id = ?", postID); err != nil {
+		logger.Log.Error("failed to detach attachments", zap.Error(err))
+		return nil, common.ErrInternalServerError
+	}
+	if len(unique) == 0 {
+		return []domain.Attachment{}, nil
+	}
+	placeholders, args := inClause(unique)
+	attachments, err := repository.query(ctx, tx.GetTx(), "SELECT "+attachmentColumns+" FROM attachments a WHERE a.id IN ("+placeholders+") AND a.owner_id = ? AND a.post_id IS NULL ORDER BY a.id FOR UPDATE", append(args, ownerID)...)
+	if err != nil {
+		return nil, err
+	}
+	if len(attachments) != len(unique) {
+		return nil, common.ErrInvalidAttachment
+	}
+	if _, err := tx.GetTx().ExecContext(ctx, "UPDATE attachments SET post_id = ? WHERE id IN ("+placeholders+")", append([]interface{}{postID}, args...)...); err != nil {
+		logger.Log.Error("failed to attach attachments", zap.Error(err))
+		return nil, common.ErrInternalServerError
+	}
+	for i := range attachments {
+		attachments[i].PostID = &postID
+	}
+	return attachments, nil
+}
+
+// FindOrphans implements domain.AttachmentRepository.
+func (repository *AttachmentRepositoryMySQL) FindOrphans(ctx context.Context, before time.Time, limit int) ([]domain.Attachment, error) {
+	query := "SELECT " + attachmentColumns + " FROM attachments a LEFT JOIN posts p ON p.id = a.post_id WHERE (a.post_id IS NULL AND a.created_at < ?) OR p.deleted_at < ? ORDER BY a.id LIMIT ?"
+	return repository.query(ctx, repository.db, query, before, before, limit)
+}
+
+// SaveProcessed implements domain.AttachmentRepository.
+func (repository *AttachmentRepositoryMySQL) SaveProcessed(ctx context.Context, tx domain.Transaction, attachment *domain.Attachment) error {
+	_, err := tx.GetTx().ExecContext(ctx, "UPDATE attachments SET size = ?, width = ?, height = ?, blurhash = ?, processed_at = ? WHERE id = ?", attachment.Size, attachment.Width, attachment.Height, attachment.Blurhash, attachment.ProcessedAt, attachment.ID)
+	if err != nil {
+		logger.Log.Error("failed to update attachment", zap.Error(err))
+		return common.ErrInternalServerError
+	}
+	if _, err := tx.GetTx().ExecContext(ctx, "DELETE FROM attachment_variants WHERE attachment_id = ?", attachment.ID); err != nil {
+		logger.Log.Error("failed to delete attachment variants", zap.Error(err))
+		return common.ErrInternalServerError
+	}
+	for _, variant := range attachment.Variants {
+		_, err := tx.GetTx().ExecContext(ctx, "INSERT INTO attachment_variants (attachment_id, name, blob_key, content_type, width, height, size) VALUES (?, ?, ?, ?, ?, ?, ?)", attachment.ID, variant.Name, variant.Key, variant.ContentType, variant.Width, variant.Height, variant.Size)
+		if err != nil {
+			logger.Log.Error("failed to insert attachment variant", zap.Error(err))
+			return common.ErrInternalServerError
+		}
+	}
+	return nil
+}
+
+// Delete implements domain.AttachmentRepository. The variants go along.
+func (repository *AttachmentRepositoryMySQL) Delete(ctx context.Context, id int64) error {
+	if _, err := repository.db.ExecContext(ctx, "DELETE FROM attachments WHERE id = ?", id); err != nil {
+		logger.Log.Error("failed to delete attachment", zap.Error(err))
+		return common.ErrInternalServerError
+	}
+	return nil
+}
+
+// query reads the attachments selected by the query, then their variants
+// with a single query.
+func (repository *AttachmentRepositoryMySQL) query(ctx context.Context, queryer attachmentQueryer, query string, args ...interface{}) ([]domain.Attachment, error) {
+	rows, err := queryer.QueryContext(ctx, query, args...)
+	if err != nil {
+		logger.Log.Error("failed to select attachments", zap.Error(err))
+		return nil, common.ErrInternalServerError
+	}
+	attachments := []domain.Attachment{}
+	for rows.Next() {
+		var attachment domain.Attachment
+		err := rows.Scan(&attachment.ID, &attachment.OwnerID, &attachment.PostID, &attachment.Key, &attachment.Filename, &attachment.ContentType, &attachment.Size, &attachment.Width, &attachment.Height, &attachment.Blurhash, &attachment.ProcessedAt, &attachment.CreatedAt)
+		if err != nil {
+			rows.Close()
+			logger.Log.Error("failed to scan attachment", zap.Error(err))
+			return nil, common.ErrInternalServerError
+		}
+		attachment.Variants = []domain.AttachmentVariant{}
+		attachments = append(attachments, attachment)
+	}
+	rows.Close()
+	if len(attachments) == 0 {
+		return attachments, nil
+	}
+
+	ids := make([]int64, len(attachments))
+	positions := make(map[int64]int, len(attachments))
+	for i, attachment := range attachments {
+		ids[i] = attachment.ID
+		positions[attachment.ID] = i
+	}
+	placeholders, idArgs := inClause(ids)
+	rows, err = queryer.QueryContext(ctx, "SELECT attachment_id, name, blob_key, content_type, width, height, size FROM attachment_variants WHERE attachment_id IN ("+placeholders+") ORDER BY attachment_id, width", idArgs...)
+	if err != nil {
+		logger.Log.Error("failed to select attachment variants", zap.Error(err))
+		return nil, common.ErrInternalServerError
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var attachmentID int64
+		var variant domain.AttachmentVariant
+		if err := rows.Scan(&attachmentID, &variant.Name, &variant.Key, &variant.ContentType, &variant.Width, &variant.Height, &variant.Size); err != nil {
+			logger.Log.Error("failed to scan attachment variant", zap.Error(err))
+			return nil, common.ErrInternalServerError
+		}
+		i := positions[attachmentID]
+		attachments[i].Variants = append(attachments[i].Variants, variant)
+	}
+	return attachments, nil
+}
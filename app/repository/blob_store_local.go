@@ -0,0 +1,103 @@
+package repository
+
+import (
+	"app/domain"
+	"app/pkg/common"
+	"app/pkg/logger"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"go.uber.org/zap"
+)
+
+type BlobStoreLocal struct {
+	root string
+}
+
+// NewBlobStoreLocal stores files under the root directory, creating it when
+// it does not exist yet. Keys are relative paths below it.
+func NewBlobStoreLocal(root string) (domain.BlobStore, error) {
+	if err := os.MkdirAll(root, 0o755); err != nil {
+		return nil, err
+	}
+	return &BlobStoreLocal{root: root}, nil
+}
+
+// Put implements domain.BlobStore. The content is written to a temporary
+// file first, so a failed upload never leaves a partial file under the key.
+func (store *BlobStoreLocal) Put(ctx context.Context, key string, body io.Reader, size int64, contentType string) error {
+	path, err := store.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		logger.Log.Error("failed to create blob directory", zap.Error(err))
+		return common.ErrInternalServerError
+	}
+	file, err := os.CreateTemp(filepath.Dir(path), ".upload-*")
+	if err != nil {
+		logger.Log.Error("failed to create blob", zap.Error(err))
+		return common.ErrInternalServerError
+	}
+	defer os.Remove(file.Name())
+	written, err := io.Copy(file, body)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil && written != size {
+		err = fmt.Errorf("wrote %d bytes, expected %d", written, size)
+	}
+	if err == nil {
+		err = os.Rename(file.Name(), path)
+	}
+	if err != nil {
+		logger.Log.Error("failed to write blob", zap.String("key", key), zap.Error(err))
+		return common.ErrInternalServerError
+	}
+	return nil
+}
+
+// Get implements domain.BlobStore.
+func (store *BlobStoreLocal) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	path, err := store.path(key)
+	if err != nil {
+		return nil, err
+	}
+	file, err := os.Open(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, common.ErrBlobNotFound
+	}
+	if err != nil {
+		logger.Log.Error("failed to open blob", zap.String("key", key), zap.Error(err))
+		return nil, common.ErrInternalServerError
+	}
+	return file, nil
+}
+
+// Delete implements domain.BlobStore.
+func (store *BlobStoreLocal) Delete(ctx context.Context, key string) error {
+	path, err := store.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		logger.Log.Error("failed to delete blob", zap.String("key", key), zap.Error(err))
+		return common.ErrInternalServerError
+	}
+	return nil
+}
+
+// path resolves the key below the root, refusing keys that would escape it.
+func (store *BlobStoreLocal) path(key string) (string, error) {
+	path := filepath.FromSlash(key)
+	if !filepath.IsLocal(path) {
+		logger.Log.Error("invalid blob key", zap.String("key", key))
+		return "", common.ErrInternalServerError
+	}
+	return filepath.Join(store.root, path), nil
+}
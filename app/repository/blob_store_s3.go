@@ -0,0 +1,244 @@
+package repository
+
+import (
+	"app/pkg/common"
+	"app/pkg/logger"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+const (
+	s3Timeout = time.Minute
+	// s3EmptyPayload is the SHA-256 of an empty body.
+	s3EmptyPayload = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+	// s3UnsignedPayload lets uploads be streamed without hashing them first.
+	s3UnsignedPayload = "UNSIGNED-PAYLOAD"
+)
+
+// S3Config locates a bucket on Amazon S3 or a compatible store such as
+// MinIO. Endpoint is the base URL of the service, e.g.
+// https://s3.eu-west-1.amazonaws.com or http://localhost:9000.
+type S3Config struct {
+	Endpoint        string
+	Region          string
+	Bucket          string
+	AccessKeyID     string
+	SecretAccessKey string
+}
+
+// BlobStoreS3 keeps files as objects of a bucket, addressed path-style and
+// signed with AWS Signature Version 4.
+type BlobStoreS3 struct {
+	endpoint *url.URL
+	config   S3Config
+	client   *http.Client
+}
+
+func NewBlobStoreS3(config S3Config) (*BlobStoreS3, error) {
+	endpoint, err := url.Parse(config.Endpoint)
+	if err != nil || endpoint.Host == "" {
+		return nil, fmt.Errorf("invalid S3 endpoint %q", config.Endpoint)
+	}
+	if config.Region == "" {
+		config.Region = "us-east-1"
+	}
+	return &BlobStoreS3{
+		endpoint: endpoint,
+		config:   config,
+		client:   &http.Client{Timeout: s3Timeout},
+	}, nil
+}
+
+// EnsureBucket creates the bucket unless it exists already, for stores set
+// up by the application itself.
+func (store *BlobStoreS3) EnsureBucket(ctx context.Context) error {
+	var body string
+	if store.config.Region != "us-east-1" {
+		body = "<CreateBucketConfiguration><LocationConstraint>" + store.config.Region + "</LocationConstraint></CreateBucketConfiguration>"
+	}
+	req, err := store.newRequest(ctx, http.MethodPut, "", strings.NewReader(body), int64(len(body)))
+	if err != nil {
+		return err
+	}
+	sum := sha256.Sum256([]byte(body))
+	res, err := store.send(req, hex.EncodeToString(sum[:]))
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	message, _ := io.ReadAll(io.LimitReader(res.Body, 1024))
+	if res.StatusCode == http.StatusOK || strings.Contains(string(message), "BucketAlreadyOwnedByYou") {
+		return nil
+	}
+	return fmt.Errorf("failed to create bucket %q: %s: %s", store.config.Bucket, res.Status, message)
+}
+
+// Put implements domain.BlobStore.
+func (store *BlobStoreS3) Put(ctx context.Context, key string, body io.Reader, size int64, contentType string) error {
+	req, err := store.newRequest(ctx, http.MethodPut, key, body, size)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", contentType)
+	res, err := store.send(req, s3UnsignedPayload)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return store.failure("failed to put object", key, res)
+	}
+	return nil
+}
+
+// Get implements domain.BlobStore.
+func (store *BlobStoreS3) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	req, err := store.newRequest(ctx, http.MethodGet, key, nil, 0)
+	if err != nil {
+		return nil, err
+	}
+	res, err := store.send(req, s3EmptyPayload)
+	if err != nil {
+		return nil, err
+	}
+	switch res.StatusCode {
+	case http.StatusOK:
+		return res.Body, nil
+	case http.StatusNotFound:
+		res.Body.Close()
+		return nil, common.ErrBlobNotFound
+	}
+	defer res.Body.Close()
+	return nil, store.failure("failed to get object", key, res)
+}
+
+// Delete implements domain.BlobStore.
+func (store *BlobStoreS3) Delete(ctx context.Context, key string) error {
+	req, err := store.newRequest(ctx, http.MethodDelete, key, nil, 0)
+	if err != nil {
+		return err
+	}
+	res, err := store.send(req, s3EmptyPayload)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusNoContent && res.StatusCode != http.StatusOK && res.StatusCode != http.StatusNotFound {
+		return store.failure("failed to delete object", key, res)
+	}
+	return nil
+}
+
+// newRequest addresses the object under the key, or the bucket itself when
+// the key is empty.
+func (store *BlobStoreS3) newRequest(ctx context.Context, method, key string, body io.Reader, size int64) (*http.Request, error) {
+	path := strings.TrimSuffix(store.endpoint.EscapedPath(), "/") + "/" + s3Escape(store.config.Bucket)
+	if key != "" {
+		segments := strings.Split(key, "/")
+		for i, segment := range segments {
+			segments[i] = s3Escape(segment)
+		}
+		path += "/" + strings.Join(segments, "/")
+	}
+	req, err := http.NewRequestWithContext(ctx, method, store.endpoint.Scheme+"://"+store.endpoint.Host+path, body)
+	if err != nil {
+		logger.Log.Error("failed to build S3 request", zap.Error(err))
+		return nil, common.ErrInternalServerError
+	}
+	req.ContentLength = size
+	if size == 0 {
+		req.Body = http.NoBody
+	}
+	return req, nil
+}
+
+func (store *BlobStoreS3) send(req *http.Request, payloadHash string) (*http.Response, error) {
+	store.sign(req, payloadHash, time.Now())
+	res, err := store.client.Do(req)
+	if err != nil {
+		logger.Log.Error("failed to reach S3", zap.String("method", req.Method), zap.Error(err))
+		return nil, common.ErrInternalServerError
+	}
+	return res, nil
+}
+
+func (store *BlobStoreS3) failure(message, key string, res *http.Response) error {
+	body, _ := io.ReadAll(io.LimitReader(res.Body, 1024))
+	logger.Log.Error(message, zap.String("key", key), zap.Int("status", res.StatusCode), zap.ByteString("response", body))
+	return common.ErrInternalServerError
+}
+
+// sign adds the AWS Signature Version 4 authorization of the request, over
+// its method, path and every header set so far.
+func (store *BlobStoreS3) sign(req *http.Request, payloadHash string, now time.Time) {
+	now = now.UTC()
+	amzDate := now.Format("20060102T150405Z")
+	date := now.Format("20060102")
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+
+	host := req.Host
+	if host == "" {
+		host = req.URL.Host
+	}
+	headers := map[string]string{"host": host}
+	for name, values := range req.Header {
+		trimmed := make([]string, len(values))
+		for i, value := range values {
+			trimmed[i] = strings.Join(strings.Fields(value), " ")
+		}
+		headers[strings.ToLower(name)] = strings.Join(trimmed, ",")
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + headers[name] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+	canonicalRequest := strings.Join([]string{req.Method, req.URL.EscapedPath(), req.URL.RawQuery, canonicalHeaders.String(), signedHeaders, payloadHash}, "\n")
+
+	scope := date + "/" + store.config.Region + "/s3/aws4_request"
+	requestHash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + hex.EncodeToString(requestHash[:])
+	key := []byte("AWS4" + store.config.SecretAccessKey)
+	for _, part := range []string{date, store.config.Region, "s3", "aws4_request"} {
+		key = s3HMAC(key, part)
+	}
+	signature := hex.EncodeToString(s3HMAC(key, stringToSign))
+	req.Header.Set("Authorization", "AWS4-HMAC-SHA256 Credential="+store.config.AccessKeyID+"/"+scope+", SignedHeaders="+signedHeaders+", Signature="+signature)
+}
+
+func s3HMAC(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+// s3Escape percent-encodes everything but the unreserved characters, as the
+// signature expects.
+func s3Escape(segment string) string {
+	var b strings.Builder
+	for _, c := range []byte(segment) {
+		if 'A' <= c && c <= 'Z' || 'a' <= c && c <= 'z' || '0' <= c && c <= '9' || c == '-' || c == '.' || c == '_' || c == '~' {
+			b.WriteByte(c)
+		} else {
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,158 @@
+package repository
+
+import (
+	"app/domain"
+	"app/pkg/common"
+	"app/pkg/logger"
+	"context"
+	"database/sql"
+	"slices"
+
+	"go.uber.org/zap"
+)
+
+type BookmarkRepositoryMySQL struct {
+	db *sql.DB
+}
+
+func NewBookmarkRepositoryMySQL(db *sql.DB) domain.BookmarkRepository {
+	return &BookmarkRepositoryMySQL{db: db}
+}
+
+// UpsertCollection implements domain.BookmarkRepository.
+func (repository *BookmarkRepositoryMySQL) UpsertCollection(ctx context.Context, tx domain.Transaction, userID int64, name string) (int64, error) {
+	result, err := tx.GetTx().ExecContext(ctx, "INSERT INTO bookmark_collections (user_id, name) VALUES (?, ?) ON DUPLICATE KEY UPDATE id = LAST_INSERT_ID(id)", userID, name)
+	if err != nil {
+		logger.Log.Error("failed to upsert bookmark collection", zap.Error(err))
+		return 0, common.ErrInternalServerError
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		logger.Log.Error("failed to get last insert id", zap.Error(err))
+		return 0, common.ErrInternalServerError
+	}
+	return id, nil
+}
+
+// Save implements domain.BookmarkRepository. Saving a bookmarked post again
+// only moves it to the given collection and keeps its original date.
+func (repository *BookmarkRepositoryMySQL) Save(ctx context.Context, tx domain.Transaction, userID, postID int64, collectionID *int64) error {
+	query := "INSERT INTO bookmarks (user_id, post_id, collection_id) VALUES (?, ?, ?) ON DUPLICATE KEY UPDATE collection_id = ?"
+	if _, err := tx.GetTx().ExecContext(ctx, query, userID, postID, collectionID, collectionID); err != nil {
+		logger.Log.Error("failed to save bookmark", zap.Error(err))
+		return common.ErrInternalServerError
+	}
+	return nil
+}
+
+// Delete implements domain.BookmarkRepository.
+func (repository *BookmarkRepositoryMySQL) Delete(ctx context.Context, userID, postID int64) error {
+	if _, err := repository.db.ExecContext(ctx, "DELETE FROM bookmarks WHERE user_id = ? AND post_id = ?", userID, postID); err != nil {
+		logger.Log.Error("failed to delete bookmark", zap.Error(err))
+		return common.ErrInternalServerError
+	}
+	return nil
+}
+
+// FindBookmarked implements domain.BookmarkRepository.
+func (repository *BookmarkRepositoryMySQL) FindBookmarked(ctx context.Context, userID int64, postIDs []int64) (map[int64]bool, error) {
+	bookmarked := make(map[int64]bool, len(postIDs))
+	if len(postIDs) == 0 {
+		return bookmarked, nil
+	}
+	placeholders, args := inClause(postIDs)
+	rows, err := repository.db.QueryContext(ctx, "SELECT post_id FROM bookmarks WHERE user_id = ? AND post_id IN ("+placeholders+")", append([]interface{}{userID}, args...)...)
+	if err != nil {
+		logger.Log.Error("failed to select bookmarks", zap.Error(err))
+		return nil, common.ErrInternalServerError
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var postID int64
+		if err := rows.Scan(&postID); err != nil {
+			logger.Log.Error("failed to scan bookmark", zap.Error(err))
+			return nil, common.ErrInternalServerError
+		}
+		bookmarked[postID] = true
+	}
+	return bookmarked, nil
+}
+
+// FindByUserCursor implements domain.BookmarkRepository. Bookmarks are
+// ordered newest first by (created_at, post_id) and bookmarks of deleted
+// posts, or of drafts the user did not write, are left out.
+func (repository *BookmarkRepositoryMySQL) FindByUserCursor(ctx context.Context, userID int64, param domain.BookmarkParam) ([]domain.Bookmark, *domain.PageInfo, error) {
+	// The derived table names the key columns the way keyset expects them.
+	saved := "SELECT b.post_id AS id, c.name AS collection, b.created_at FROM bookmarks b JOIN posts p ON p.id = b.post_id AND p.deleted_at IS NULL AND (p.status = ? OR p.author_id = ?) LEFT JOIN bookmark_collections c ON c.id = b.collection_id WHERE b.user_id = ?"
+	args := []interface{}{domain.PostStatusPublished, userID, userID}
+	if param.Collection != "" {
+		saved += " AND c.name = ?"
+		args = append(args, param.Collection)
+	}
+	var total *int64
+	if param.WithTotal {
+		var count int64
+		if err := repository.db.QueryRowContext(ctx, "SELECT count(*) FROM ("+saved+") AS saved", args...).Scan(&count); err != nil {
+			logger.Log.Error("failed to count bookmarks", zap.Error(err))
+			return nil, nil, common.ErrInternalServerError
+		}
+		total = &count
+	}
+	query := "SELECT id, collection, created_at FROM (" + saved + ") AS saved"
+	condition, keyArgs, order, reversed := keyset(param.SearchParam, false)
+	if condition != "" {
+		query += " WHERE " + condition
+		args = append(args, keyArgs...)
+	}
+	query += " ORDER BY " + order + " LIMIT ?"
+	rows, err := repository.db.QueryContext(ctx, query, append(args, param.Limit+1)...)
+	if err != nil {
+		logger.Log.Error("failed to select bookmarks", zap.Error(err))
+		return nil, nil, common.ErrInternalServerError
+	}
+	defer rows.Close()
+	bookmarks := []domain.Bookmark{}
+	for rows.Next() {
+		var bookmark domain.Bookmark
+		if err := rows.Scan(&bookmark.PostID, &bookmark.Collection, &bookmark.CreatedAt); err != nil {
+			logger.Log.Error("failed to scan bookmark", zap.Error(err))
+			return nil, nil, common.ErrInternalServerError
+		}
+		bookmarks = append(bookmarks, bookmark)
+	}
+	more := len(bookmarks) > param.Limit
+	if more {
+		bookmarks = bookmarks[:param.Limit]
+	}
+	if reversed {
+		slices.Reverse(bookmarks)
+	}
+	keys := make([]domain.Cursor, len(bookmarks))
+	for i, bookmark := range bookmarks {
+		keys[i] = domain.Cursor{CreatedAt: bookmark.CreatedAt, ID: bookmark.PostID}
+	}
+	page := keysetPage(param.SearchParam, keys, more)
+	page.Total = total
+	return bookmarks, page, nil
+}
+
+// GetCollections implements domain.BookmarkRepository.
+func (repository *BookmarkRepositoryMySQL) GetCollections(ctx context.Context, userID int64) ([]domain.BookmarkCollection, error) {
+	query := "SELECT c.id, c.name, count(b.post_id) FROM bookmark_collections c LEFT JOIN bookmarks b ON b.collection_id = c.id WHERE c.user_id = ? GROUP BY c.id, c.name ORDER BY c.name"
+	rows, err := repository.db.QueryContext(ctx, query, userID)
+	if err != nil {
+		logger.Log.Error("failed to select bookmark collections", zap.Error(err))
+		return nil, common.ErrInternalServerError
+	}
+	defer rows.Close()
+	collections := []domain.BookmarkCollection{}
+	for rows.Next() {
+		var collection domain.BookmarkCollection
+		if err := rows.Scan(&collection.ID, &collection.Name, &collection.BookmarkCount); err != nil {
+			logger.Log.Error("failed to scan bookmark collection", zap.Error(err))
+			return nil, common.ErrInternalServerError
+		}
+		collections = append(collections, collection)
+	}
+	return collections, nil
+}
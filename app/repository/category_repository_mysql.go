@@ -0,0 +1,87 @@
+package repository
+
+import (
+	"app/domain"
+	"app/pkg/common"
+	"app/pkg/logger"
+	"context"
+	"database/sql"
+
+	"go.uber.org/zap"
+)
+
+type CategoryRepositoryMySQL struct {
+	db *sql.DB
+}
+
+func NewCategoryRepositoryMySQL(db *sql.DB) domain.CategoryRepository {
+	return &CategoryRepositoryMySQL{db: db}
+}
+
+// Create implements domain.CategoryRepository. A slug already used by another
+// category fails with common.ErrCategoryExists.
+func (repository *CategoryRepositoryMySQL) Create(ctx context.Context, tx domain.Transaction, category *domain.Category) error {
+	result, err := tx.GetTx().ExecContext(ctx, "INSERT INTO categories (name, slug, parent_id, created_at) VALUES (?, ?, ?, ?)", category.Name, category.Slug, category.ParentID, category.CreatedAt)
+	if duplicateKey(err) {
+		return common.ErrCategoryExists
+	}
+	if err != nil {
+		logger.Log.Error("failed to insert category", zap.Error(err))
+		return common.ErrInternalServerError
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		logger.Log.Error("failed to get last insert id", zap.Error(err))
+		return common.ErrInternalServerError
+	}
+	category.ID = id
+	return nil
+}
+
+// GetByID implements domain.CategoryRepository.
+func (repository *CategoryRepositoryMySQL) GetByID(ctx context.Context, id int64) (*domain.Category, error) {
+	var category domain.Category
+	err := repository.db.QueryRowContext(ctx, "SELECT id, name, slug, parent_id, created_at FROM categories WHERE id = ?", id).Scan(&category.ID, &category.Name, &category.Slug, &category.ParentID, &category.CreatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, common.ErrCategoryNotFound
+		}
+		logger.Log.Error("failed to select category by id", zap.Error(err))
+		return nil, common.ErrInternalServerError
+	}
+	return &category, nil
+}
+
+// GetBySlug implements domain.CategoryRepository.
+func (repository *CategoryRepositoryMySQL) GetBySlug(ctx context.Context, slug string) (*domain.Category, error) {
+	var category domain.Category
+	err := repository.db.QueryRowContext(ctx, "SELECT id, name, slug, parent_id, created_at FROM categories WHERE slug = ?", slug).Scan(&category.ID, &category.Name, &category.Slug, &category.ParentID, &category.CreatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, common.ErrCategoryNotFound
+		}
+		logger.Log.Error("failed to select category by slug", zap.Error(err))
+		return nil, common.ErrInternalServerError
+	}
+	return &category, nil
+}
+
+// GetAll implements domain.CategoryRepository.
+func (repository *CategoryRepositoryMySQL) GetAll(ctx context.Context) ([]*domain.Category, error) {
+	rows, err := repository.db.QueryContext(ctx, "SELECT id, name, slug, parent_id, created_at FROM categories ORDER BY name")
+	if err != nil {
+		logger.Log.Error("failed to select categories", zap.Error(err))
+		return nil, common.ErrInternalServerError
+	}
+	defer rows.Close()
+	var categories []*domain.Category
+	for rows.Next() {
+		var category domain.Category
+		if err := rows.Scan(&category.ID, &category.Name, &category.Slug, &category.ParentID, &category.CreatedAt); err != nil {
+			logger.Log.Error("failed to scan category", zap.Error(err))
+			return nil, common.ErrInternalServerError
+		}
+		categories = append(categories, &category)
+	}
+	return categories, nil
+}
@@ -6,10 +6,17 @@ import (
 	"app/pkg/logger"
 	"context"
 	"database/sql"
+	"slices"
 
 	"go.uber.org/zap"
 )
 
+const commentColumns = "id, content, post_id, author_id, parent_id, author_name, created_at, updated_at"
+
+func scanComment(row rowScanner, comment *domain.Comment) error {
+	return row.Scan(&comment.ID, &comment.Content, &comment.PostID, &comment.AuthorID, &comment.ParentID, &comment.AuthorName, &comment.CreatedAt, &comment.UpdatedAt)
+}
+
 type CommentRepositoryMySQL struct {
 	db *sql.DB
 }
@@ -20,7 +27,7 @@ func NewCommentRepositoryMySQL(db *sql.DB) domain.CommentRepository {
 
 // Create implements domain.CommentRepository.
 func (repository *CommentRepositoryMySQL) Create(ctx context.Context, tx domain.Transaction, comment *domain.Comment) error {
-	result, err := tx.GetTx().ExecContext(ctx, "INSERT INTO comments (content, post_id, author_name) VALUES (?, ?, ?)", comment.Content, comment.PostID, comment.AuthorName)
+	result, err := tx.GetTx().ExecContext(ctx, "INSERT INTO comments (content, post_id, author_id, parent_id, author_name, created_at) VALUES (?, ?, ?, ?, ?, ?)", comment.Content, comment.PostID, comment.AuthorID, comment.ParentID, comment.AuthorName, comment.CreatedAt)
 	if err != nil {
 		logger.Log.Error("failed to insert comment", zap.Error(err))
 		return common.ErrInternalServerError
@@ -34,17 +41,51 @@ func (repository *CommentRepositoryMySQL) Create(ctx context.Context, tx domain.
 	return nil
 }
 
+// GetByID implements domain.CommentRepository.
+func (repository *CommentRepositoryMySQL) GetByID(ctx context.Context, id int64) (*domain.Comment, error) {
+	var comment domain.Comment
+	err := scanComment(repository.db.QueryRowContext(ctx, "SELECT "+commentColumns+" FROM comments WHERE id = ? AND deleted_at IS NULL", id), &comment)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, common.ErrCommentNotFound
+		}
+		logger.Log.Error("failed to select comment by id", zap.Error(err))
+		return nil, common.ErrInternalServerError
+	}
+	return &comment, nil
+}
+
+// Update implements domain.CommentRepository.
+func (repository *CommentRepositoryMySQL) Update(ctx context.Context, tx domain.Transaction, comment *domain.Comment) error {
+	if _, err := tx.GetTx().ExecContext(ctx, "UPDATE comments SET content = ?, updated_at = ? WHERE id = ?", comment.Content, comment.UpdatedAt, comment.ID); err != nil {
+		logger.Log.Error("failed to update comment", zap.Error(err))
+		return common.ErrInternalServerError
+	}
+	return nil
+}
+
+// Delete implements domain.CommentRepository. Comments are soft deleted so
+// that their replies keep their parent.
+func (repository *CommentRepositoryMySQL) Delete(ctx context.Context, tx domain.Transaction, id int64) (bool, error) {
+	result, err := tx.GetTx().ExecContext(ctx, "UPDATE comments SET deleted_at = CURRENT_TIMESTAMP WHERE id = ? AND deleted_at IS NULL", id)
+	if err != nil {
+		logger.Log.Error("failed to delete comment", zap.Error(err))
+		return false, common.ErrInternalServerError
+	}
+	return affected(result)
+}
+
 // FindByPostID implements domain.CommentRepository.
 func (repository *CommentRepositoryMySQL) FindByPostID(ctx context.Context, postID int64, param domain.SearchParam) ([]*domain.Comment, int64, error) {
 	var comments []*domain.Comment
-	query := "SELECT count(id) FROM comments WHERE post_id = ?"
+	query := "SELECT count(id) FROM comments WHERE post_id = ? AND deleted_at IS NULL"
 	row := repository.db.QueryRowContext(ctx, query, postID)
 	var total int64
 	if err := row.Scan(&total); err != nil {
 		logger.Log.Error("failed to count comments", zap.Error(err))
 		return nil, 0, common.ErrInternalServerError
 	}
-	query = "SELECT id, content, post_id, author_name, created_at FROM comments WHERE post_id = ? ORDER BY created_at DESC LIMIT ? OFFSET ?"
+	query = "SELECT " + commentColumns + " FROM comments WHERE post_id = ? AND deleted_at IS NULL ORDER BY created_at DESC LIMIT ? OFFSET ?"
 	rows, err := repository.db.QueryContext(ctx, query, postID, param.Limit, (param.Page-1)*param.Limit)
 	if err != nil {
 		logger.Log.Error("failed to select comments", zap.Error(err))
@@ -53,7 +94,7 @@ func (repository *CommentRepositoryMySQL) FindByPostID(ctx context.Context, post
 	defer rows.Close()
 	for rows.Next() {
 		var comment domain.Comment
-		err := rows.Scan(&comment.ID, &comment.Content, &comment.PostID, &comment.AuthorName, &comment.CreatedAt)
+		err := scanComment(rows, &comment)
 		if err != nil {
 			logger.Log.Error("failed to scan comment", zap.Error(err))
 			return nil, 0, common.ErrInternalServerError
@@ -62,3 +103,56 @@ func (repository *CommentRepositoryMySQL) FindByPostID(ctx context.Context, post
 	}
 	return comments, total, nil
 }
+
+// FindByPostIDCursor implements domain.CommentRepository. Comments are
+// ordered newest first by (created_at, id).
+func (repository *CommentRepositoryMySQL) FindByPostIDCursor(ctx context.Context, postID int64, param domain.SearchParam) ([]*domain.Comment, *domain.PageInfo, error) {
+	var comments []*domain.Comment
+	var total *int64
+	if param.WithTotal {
+		var count int64
+		row := repository.db.QueryRowContext(ctx, "SELECT count(id) FROM comments WHERE post_id = ? AND deleted_at IS NULL", postID)
+		if err := row.Scan(&count); err != nil {
+			logger.Log.Error("failed to count comments", zap.Error(err))
+			return nil, nil, common.ErrInternalServerError
+		}
+		total = &count
+	}
+	query := "SELECT " + commentColumns + " FROM comments WHERE post_id = ? AND deleted_at IS NULL"
+	args := []interface{}{postID}
+	condition, keyArgs, order, reversed := keyset(param, false)
+	if condition != "" {
+		query += " AND " + condition
+		args = append(args, keyArgs...)
+	}
+	query += " ORDER BY " + order + " LIMIT ?"
+	rows, err := repository.db.QueryContext(ctx, query, append(args, param.Limit+1)...)
+	if err != nil {
+		logger.Log.Error("failed to select comments", zap.Error(err))
+		return nil, nil, common.ErrInternalServerError
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var comment domain.Comment
+		err := scanComment(rows, &comment)
+		if err != nil {
+			logger.Log.Error("failed to scan comment", zap.Error(err))
+			return nil, nil, common.ErrInternalServerError
+		}
+		comments = append(comments, &comment)
+	}
+	more := len(comments) > param.Limit
+	if more {
+		comments = comments[:param.Limit]
+	}
+	if reversed {
+		slices.Reverse(comments)
+	}
+	keys := make([]domain.Cursor, len(comments))
+	for i, comment := range comments {
+		keys[i] = domain.Cursor{CreatedAt: comment.CreatedAt, ID: comment.ID}
+	}
+	page := keysetPage(param, keys, more)
+	page.Total = total
+	return comments, page, nil
+}
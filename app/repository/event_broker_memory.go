@@ -0,0 +1,121 @@
+package repository
+
+import (
+	"app/domain"
+	"context"
+	"sync"
+	"time"
+)
+
+const (
+	eventHistorySize = 100
+	eventBufferSize  = 64
+	// eventHistoryTTL is how long a topic nobody listens to keeps its events
+	// for subscribers coming back.
+	eventHistoryTTL = 10 * time.Minute
+)
+
+type eventTopic struct {
+	lastID      int64
+	history     []domain.Event
+	subscribers map[chan domain.Event]bool
+	activeAt    time.Time
+}
+
+// EventBrokerMemory is an in-process domain.EventBroker. Each topic retains
+// its last events for subscribers resuming after a disconnection, until it
+// has had neither subscribers nor events for eventHistoryTTL.
+type EventBrokerMemory struct {
+	mu      sync.Mutex
+	topics  map[string]*eventTopic
+	sweptAt time.Time
+}
+
+func NewEventBrokerMemory() domain.EventBroker {
+	return &EventBrokerMemory{topics: map[string]*eventTopic{}}
+}
+
+// topic returns the named topic, creating it when needed. Event ids start
+// from the creation time of the topic so that they keep increasing when the
+// process restarts. The caller must hold the lock.
+func (b *EventBrokerMemory) topic(name string) *eventTopic {
+	now := time.Now()
+	b.sweep(now)
+	topic, ok := b.topics[name]
+	if !ok {
+		topic = &eventTopic{lastID: now.UnixMicro(), subscribers: map[chan domain.Event]bool{}}
+		b.topics[name] = topic
+	}
+	topic.activeAt = now
+	return topic
+}
+
+// sweep drops the topics left idle for eventHistoryTTL, looking for them at
+// most once per eventHistoryTTL. A topic created again numbers its events
+// from its new creation time, after the ones it had before. The caller must
+// hold the lock.
+func (b *EventBrokerMemory) sweep(now time.Time) {
+	if now.Sub(b.sweptAt) < eventHistoryTTL {
+		return
+	}
+	b.sweptAt = now
+	for name, topic := range b.topics {
+		if len(topic.subscribers) == 0 && now.Sub(topic.activeAt) >= eventHistoryTTL {
+			delete(b.topics, name)
+		}
+	}
+}
+
+// Publish implements domain.EventBroker. Subscribers whose buffer is full
+// are dropped rather than slowing the publisher down.
+func (b *EventBrokerMemory) Publish(ctx context.Context, topicName string, event domain.Event) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	topic := b.topic(topicName)
+	topic.lastID++
+	event.ID = topic.lastID
+	topic.history = append(topic.history, event)
+	if len(topic.history) > eventHistorySize {
+		topic.history = topic.history[len(topic.history)-eventHistorySize:]
+	}
+	for subscriber := range topic.subscribers {
+		select {
+		case subscriber <- event:
+		default:
+			delete(topic.subscribers, subscriber)
+			close(subscriber)
+		}
+	}
+	return nil
+}
+
+// Subscribe implements domain.EventBroker.
+func (b *EventBrokerMemory) Subscribe(ctx context.Context, topicName string, lastID int64) (<-chan domain.Event, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	topic := b.topic(topicName)
+	var replay []domain.Event
+	if lastID > 0 {
+		for _, event := range topic.history {
+			if event.ID > lastID {
+				replay = append(replay, event)
+			}
+		}
+	}
+	subscriber := make(chan domain.Event, len(replay)+eventBufferSize)
+	for _, event := range replay {
+		subscriber <- event
+	}
+	topic.subscribers[subscriber] = true
+	go func() {
+		<-ctx.Done()
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		if topic.subscribers[subscriber] {
+			delete(topic.subscribers, subscriber)
+			close(subscriber)
+		}
+		topic.activeAt = time.Now()
+	}()
+	return subscriber, nil
+}
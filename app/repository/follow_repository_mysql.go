@@ -0,0 +1,87 @@
+package repository
+
+import (
+	"app/domain"
+	"app/pkg/common"
+	"app/pkg/logger"
+	"context"
+	"database/sql"
+
+	"go.uber.org/zap"
+)
+
+type FollowRepositoryMySQL struct {
+	db *sql.DB
+}
+
+func NewFollowRepositoryMySQL(db *sql.DB) domain.FollowRepository {
+	return &FollowRepositoryMySQL{db: db}
+}
+
+// Follow implements domain.FollowRepository. Following twice keeps the
+// original date.
+func (repository *FollowRepositoryMySQL) Follow(ctx context.Context, tx domain.Transaction, followerID, followeeID int64) (bool, error) {
+	result, err := tx.GetTx().ExecContext(ctx, "INSERT IGNORE INTO follows (follower_id, followee_id) VALUES (?, ?)", followerID, followeeID)
+	if err != nil {
+		logger.Log.Error("failed to insert follow", zap.Error(err))
+		return false, common.ErrInternalServerError
+	}
+	return affected(result)
+}
+
+// Unfollow implements domain.FollowRepository.
+func (repository *FollowRepositoryMySQL) Unfollow(ctx context.Context, followerID, followeeID int64) error {
+	if _, err := repository.db.ExecContext(ctx, "DELETE FROM follows WHERE follower_id = ? AND followee_id = ?", followerID, followeeID); err != nil {
+		logger.Log.Error("failed to delete follow", zap.Error(err))
+		return common.ErrInternalServerError
+	}
+	return nil
+}
+
+// Count implements domain.FollowRepository.
+func (repository *FollowRepositoryMySQL) Count(ctx context.Context, userID int64) (int64, int64, error) {
+	var followers, following int64
+	err := repository.db.QueryRowContext(ctx, "SELECT (SELECT count(*) FROM follows WHERE followee_id = ?), (SELECT count(*) FROM follows WHERE follower_id = ?)", userID, userID).Scan(&followers, &following)
+	if err != nil {
+		logger.Log.Error("failed to count follows", zap.Error(err))
+		return 0, 0, common.ErrInternalServerError
+	}
+	return followers, following, nil
+}
+
+// FindFollowers implements domain.FollowRepository.
+func (repository *FollowRepositoryMySQL) FindFollowers(ctx context.Context, userID int64, param domain.SearchParam) ([]domain.FollowUser, int64, error) {
+	return repository.find(ctx, "followee_id", "follower_id", userID, param)
+}
+
+// FindFollowing implements domain.FollowRepository.
+func (repository *FollowRepositoryMySQL) FindFollowing(ctx context.Context, userID int64, param domain.SearchParam) ([]domain.FollowUser, int64, error) {
+	return repository.find(ctx, "follower_id", "followee_id", userID, param)
+}
+
+// find lists the users on the other side of the follows of userID, newest
+// first. column is the side userID is on and other the side listed.
+func (repository *FollowRepositoryMySQL) find(ctx context.Context, column, other string, userID int64, param domain.SearchParam) ([]domain.FollowUser, int64, error) {
+	var total int64
+	if err := repository.db.QueryRowContext(ctx, "SELECT count(*) FROM follows WHERE "+column+" = ?", userID).Scan(&total); err != nil {
+		logger.Log.Error("failed to count follows", zap.Error(err))
+		return nil, 0, common.ErrInternalServerError
+	}
+	query := "SELECT u.id, u.name, f.created_at FROM follows f JOIN users u ON u.id = f." + other + " WHERE f." + column + " = ? ORDER BY f.created_at DESC, u.id DESC LIMIT ? OFFSET ?"
+	rows, err := repository.db.QueryContext(ctx, query, userID, param.Limit, (param.Page-1)*param.Limit)
+	if err != nil {
+		logger.Log.Error("failed to select follows", zap.Error(err))
+		return nil, 0, common.ErrInternalServerError
+	}
+	defer rows.Close()
+	users := []domain.FollowUser{}
+	for rows.Next() {
+		var user domain.FollowUser
+		if err := rows.Scan(&user.ID, &user.Name, &user.FollowedAt); err != nil {
+			logger.Log.Error("failed to scan follow", zap.Error(err))
+			return nil, 0, common.ErrInternalServerError
+		}
+		users = append(users, user)
+	}
+	return users, total, nil
+}
@@ -0,0 +1,221 @@
+package repository
+
+import (
+	"app/domain"
+	"app/pkg/common"
+	"app/pkg/logger"
+	"context"
+	"database/sql"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+const jobColumns = "id, type, payload, status, attempts, max_attempts, last_error, run_at, finished_at, created_at, updated_at"
+
+type JobRepositoryMySQL struct {
+	db *sql.DB
+}
+
+func NewJobRepositoryMySQL(db *sql.DB) domain.JobRepository {
+	return &JobRepositoryMySQL{db: db}
+}
+
+// Create implements domain.JobRepository.
+func (repository *JobRepositoryMySQL) Create(ctx context.Context, tx domain.Transaction, job *domain.Job) error {
+	result, err := tx.GetTx().ExecContext(ctx, "INSERT INTO jobs (type, payload, status, max_attempts, run_at, created_at) VALUES (?, ?, ?, ?, ?, ?)", job.Type, string(job.Payload), job.Status, job.MaxAttempts, job.RunAt, job.CreatedAt)
+	if err != nil {
+		logger.Log.Error("failed to insert job", zap.Error(err))
+		return common.ErrInternalServerError
+	}
+	job.ID, err = result.LastInsertId()
+	if err != nil {
+		logger.Log.Error("failed to get last insert id", zap.Error(err))
+		return common.ErrInternalServerError
+	}
+	return nil
+}
+
+// FindAll implements domain.JobRepository.
+func (repository *JobRepositoryMySQL) FindAll(ctx context.Context, filter domain.JobFilter) ([]domain.Job, int64, error) {
+	var conditions []string
+	var args []interface{}
+	if filter.Status != "" {
+		conditions = append(conditions, "status = ?")
+		args = append(args, filter.Status)
+	}
+	if filter.Type != "" {
+		conditions = append(conditions, "type = ?")
+		args = append(args, filter.Type)
+	}
+	where := ""
+	if len(conditions) > 0 {
+		where = " WHERE " + strings.Join(conditions, " AND ")
+	}
+	var total int64
+	if err := repository.db.QueryRowContext(ctx, "SELECT count(*) FROM jobs"+where, args...).Scan(&total); err != nil {
+		logger.Log.Error("failed to count jobs", zap.Error(err))
+		return nil, 0, common.ErrInternalServerError
+	}
+	rows, err := repository.db.QueryContext(ctx, "SELECT "+jobColumns+" FROM jobs"+where+" ORDER BY id DESC LIMIT ? OFFSET ?", append(args, filter.Limit, (filter.Page-1)*filter.Limit)...)
+	if err != nil {
+		logger.Log.Error("failed to select jobs", zap.Error(err))
+		return nil, 0, common.ErrInternalServerError
+	}
+	defer rows.Close()
+	jobs, err := scanJobs(rows)
+	if err != nil {
+		return nil, 0, err
+	}
+	return jobs, total, nil
+}
+
+// FindByID implements domain.JobRepository.
+func (repository *JobRepositoryMySQL) FindByID(ctx context.Context, id int64) (*domain.Job, error) {
+	var job domain.Job
+	err := scanJob(repository.db.QueryRowContext(ctx, "SELECT "+jobColumns+" FROM jobs WHERE id = ?", id), &job)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, common.ErrJobNotFound
+		}
+		logger.Log.Error("failed to select job", zap.Error(err))
+		return nil, common.ErrInternalServerError
+	}
+	return &job, nil
+}
+
+// Claim implements domain.JobRepository. Rows locked by another worker are
+// skipped rather than waited for.
+func (repository *JobRepositoryMySQL) Claim(ctx context.Context, tx domain.Transaction, limit int, until time.Time) ([]domain.Job, error) {
+	query := "SELECT " + jobColumns + " FROM jobs WHERE status IN (?, ?) AND run_at <= ? ORDER BY run_at, id LIMIT ? FOR UPDATE SKIP LOCKED"
+	rows, err := tx.GetTx().QueryContext(ctx, query, domain.JobPending, domain.JobRunning, time.Now(), limit)
+	if err != nil {
+		logger.Log.Error("failed to select due jobs", zap.Error(err))
+		return nil, common.ErrInternalServerError
+	}
+	jobs, err := scanJobs(rows)
+	rows.Close()
+	if err != nil || len(jobs) == 0 {
+		return jobs, err
+	}
+	ids := make([]int64, len(jobs))
+	for i := range jobs {
+		ids[i] = jobs[i].ID
+		jobs[i].Status = domain.JobRunning
+		jobs[i].RunAt = until
+	}
+	placeholders, args := inClause(ids)
+	if _, err := tx.GetTx().ExecContext(ctx, "UPDATE jobs SET status = ?, run_at = ? WHERE id IN ("+placeholders+")", append([]interface{}{domain.JobRunning, until}, args...)...); err != nil {
+		logger.Log.Error("failed to claim jobs", zap.Error(err))
+		return nil, common.ErrInternalServerError
+	}
+	return jobs, nil
+}
+
+// SaveAttempt implements domain.JobRepository.
+func (repository *JobRepositoryMySQL) SaveAttempt(ctx context.Context, job *domain.Job) error {
+	_, err := repository.db.ExecContext(ctx, "UPDATE jobs SET status = ?, attempts = ?, last_error = ?, run_at = ?, finished_at = ?, updated_at = ? WHERE id = ?", job.Status, job.Attempts, job.Error, job.RunAt, job.FinishedAt, job.UpdatedAt, job.ID)
+	if err != nil {
+		logger.Log.Error("failed to save job attempt", zap.Error(err))
+		return common.ErrInternalServerError
+	}
+	return nil
+}
+
+// Retry implements domain.JobRepository. The last error is kept until the
+// job runs again.
+func (repository *JobRepositoryMySQL) Retry(ctx context.Context, id int64) (bool, error) {
+	now := time.Now()
+	result, err := repository.db.ExecContext(ctx, "UPDATE jobs SET status = ?, attempts = 0, run_at = ?, finished_at = NULL, updated_at = ? WHERE id = ? AND status = ?", domain.JobPending, now, now, id, domain.JobDead)
+	if err != nil {
+		logger.Log.Error("failed to retry job", zap.Error(err))
+		return false, common.ErrInternalServerError
+	}
+	return affected(result)
+}
+
+// DeleteFinished implements domain.JobRepository.
+func (repository *JobRepositoryMySQL) DeleteFinished(ctx context.Context, before time.Time) (int64, error) {
+	result, err := repository.db.ExecContext(ctx, "DELETE FROM jobs WHERE status = ? AND finished_at < ?", domain.JobSucceeded, before)
+	if err != nil {
+		logger.Log.Error("failed to delete finished jobs", zap.Error(err))
+		return 0, common.ErrInternalServerError
+	}
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		logger.Log.Error("failed to get affected rows", zap.Error(err))
+		return 0, common.ErrInternalServerError
+	}
+	return deleted, nil
+}
+
+// EnsureSchedule implements domain.JobRepository.
+func (repository *JobRepositoryMySQL) EnsureSchedule(ctx context.Context, name string, next time.Time) error {
+	if _, err := repository.db.ExecContext(ctx, "INSERT IGNORE INTO job_schedules (name, next_run_at) VALUES (?, ?)", name, next); err != nil {
+		logger.Log.Error("failed to insert job schedule", zap.Error(err))
+		return common.ErrInternalServerError
+	}
+	return nil
+}
+
+// ClaimSchedules implements domain.JobRepository. Schedules locked by
+// another worker are skipped rather than waited for.
+func (repository *JobRepositoryMySQL) ClaimSchedules(ctx context.Context, tx domain.Transaction, names []string) ([]string, error) {
+	due := []string{}
+	if len(names) == 0 {
+		return due, nil
+	}
+	args := make([]interface{}, 0, len(names)+1)
+	args = append(args, time.Now())
+	for _, name := range names {
+		args = append(args, name)
+	}
+	placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(names)), ", ")
+	rows, err := tx.GetTx().QueryContext(ctx, "SELECT name FROM job_schedules WHERE next_run_at <= ? AND name IN ("+placeholders+") ORDER BY name FOR UPDATE SKIP LOCKED", args...)
+	if err != nil {
+		logger.Log.Error("failed to select due job schedules", zap.Error(err))
+		return nil, common.ErrInternalServerError
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			logger.Log.Error("failed to scan job schedule", zap.Error(err))
+			return nil, common.ErrInternalServerError
+		}
+		due = append(due, name)
+	}
+	return due, nil
+}
+
+// SetScheduleNext implements domain.JobRepository.
+func (repository *JobRepositoryMySQL) SetScheduleNext(ctx context.Context, tx domain.Transaction, name string, next time.Time) error {
+	if _, err := tx.GetTx().ExecContext(ctx, "UPDATE job_schedules SET next_run_at = ? WHERE name = ?", next, name); err != nil {
+		logger.Log.Error("failed to update job schedule", zap.Error(err))
+		return common.ErrInternalServerError
+	}
+	return nil
+}
+
+func scanJob(row rowScanner, job *domain.Job) error {
+	var payload string
+	if err := row.Scan(&job.ID, &job.Type, &payload, &job.Status, &job.Attempts, &job.MaxAttempts, &job.Error, &job.RunAt, &job.FinishedAt, &job.CreatedAt, &job.UpdatedAt); err != nil {
+		return err
+	}
+	job.Payload = []byte(payload)
+	return nil
+}
+
+func scanJobs(rows *sql.Rows) ([]domain.Job, error) {
+	jobs := []domain.Job{}
+	for rows.Next() {
+		var job domain.Job
+		if err := scanJob(rows, &job); err != nil {
+			logger.Log.Error("failed to scan job", zap.Error(err))
+			return nil, common.ErrInternalServerError
+		}
+		jobs = append(jobs, job)
+	}
+	return jobs, nil
+}
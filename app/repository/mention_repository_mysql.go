@@ -0,0 +1,105 @@
+package repository
+
+import (
+	"app/domain"
+	"app/pkg/common"
+	"app/pkg/logger"
+	"context"
+	"database/sql"
+	"slices"
+	"strings"
+
+	"go.uber.org/zap"
+)
+
+type MentionRepositoryMySQL struct {
+	db *sql.DB
+}
+
+func NewMentionRepositoryMySQL(db *sql.DB) domain.MentionRepository {
+	return &MentionRepositoryMySQL{db: db}
+}
+
+// Replace implements domain.MentionRepository. Mentions that remain keep
+// their original date.
+func (repository *MentionRepositoryMySQL) Replace(ctx context.Context, tx domain.Transaction, authorID, postID int64, commentID *int64, userIDs []int64) ([]int64, error) {
+	source := "post_id = ? AND comment_id IS NULL"
+	sourceArgs := []interface{}{postID}
+	if commentID != nil {
+		source = "post_id = ? AND comment_id = ?"
+		sourceArgs = append(sourceArgs, *commentID)
+	}
+	rows, err := tx.GetTx().QueryContext(ctx, "SELECT user_id FROM mentions WHERE "+source, sourceArgs...)
+	if err != nil {
+		logger.Log.Error("failed to select mentions", zap.Error(err))
+		return nil, common.ErrInternalServerError
+	}
+	defer rows.Close()
+	var existing []int64
+	for rows.Next() {
+		var userID int64
+		if err := rows.Scan(&userID); err != nil {
+			logger.Log.Error("failed to scan mention", zap.Error(err))
+			return nil, common.ErrInternalServerError
+		}
+		existing = append(existing, userID)
+	}
+	var removed, added []int64
+	for _, userID := range existing {
+		if !slices.Contains(userIDs, userID) {
+			removed = append(removed, userID)
+		}
+	}
+	for _, userID := range userIDs {
+		if !slices.Contains(existing, userID) {
+			added = append(added, userID)
+		}
+	}
+	if len(removed) > 0 {
+		placeholders, args := inClause(removed)
+		if _, err := tx.GetTx().ExecContext(ctx, "DELETE FROM mentions WHERE "+source+" AND user_id IN ("+placeholders+")", append(sourceArgs, args...)...); err != nil {
+			logger.Log.Error("failed to delete mentions", zap.Error(err))
+			return nil, common.ErrInternalServerError
+		}
+	}
+	if len(added) > 0 {
+		var args []interface{}
+		for _, userID := range added {
+			args = append(args, userID, authorID, postID, commentID)
+		}
+		values := strings.TrimSuffix(strings.Repeat("(?, ?, ?, ?), ", len(added)), ", ")
+		if _, err := tx.GetTx().ExecContext(ctx, "INSERT INTO mentions (user_id, author_id, post_id, comment_id) VALUES "+values, args...); err != nil {
+			logger.Log.Error("failed to insert mentions", zap.Error(err))
+			return nil, common.ErrInternalServerError
+		}
+	}
+	return added, nil
+}
+
+// FindByUser implements domain.MentionRepository. Only mentions in published
+// posts are listed, newest first.
+func (repository *MentionRepositoryMySQL) FindByUser(ctx context.Context, userID int64, param domain.SearchParam) ([]domain.Mention, int64, error) {
+	from := " FROM mentions m JOIN posts p ON p.id = m.post_id JOIN users u ON u.id = m.author_id WHERE m.user_id = ? AND p.status = ? AND p.deleted_at IS NULL"
+	var total int64
+	if err := repository.db.QueryRowContext(ctx, "SELECT count(*)"+from, userID, domain.PostStatusPublished).Scan(&total); err != nil {
+		logger.Log.Error("failed to count mentions", zap.Error(err))
+		return nil, 0, common.ErrInternalServerError
+	}
+	query := "SELECT m.id, m.user_id, m.author_id, u.name, m.post_id, p.title, p.slug, m.comment_id, m.created_at" + from + " ORDER BY m.created_at DESC, m.id DESC LIMIT ? OFFSET ?"
+	rows, err := repository.db.QueryContext(ctx, query, userID, domain.PostStatusPublished, param.Limit, (param.Page-1)*param.Limit)
+	if err != nil {
+		logger.Log.Error("failed to select mentions", zap.Error(err))
+		return nil, 0, common.ErrInternalServerError
+	}
+	defer rows.Close()
+	mentions := []domain.Mention{}
+	for rows.Next() {
+		var mention domain.Mention
+		if err := rows.Scan(&mention.ID, &mention.UserID, &mention.AuthorID, &mention.AuthorName, &mention.PostID, &mention.PostTitle, &mention.PostSlug, &mention.CommentID, &mention.CreatedAt); err != nil {
+			logger.Log.Error("failed to scan mention", zap.Error(err))
+			return nil, 0, common.ErrInternalServerError
+		}
+		mentions = append(mentions, mention)
+	}
+	return mentions, total, nil
+}
@@ -0,0 +1,162 @@
+package repository
+
+import (
+	"app/domain"
+	"app/pkg/common"
+	"app/pkg/logger"
+	"context"
+	"database/sql"
+	"strings"
+
+	"go.uber.org/zap"
+)
+
+type NotificationRepositoryMySQL struct {
+	db *sql.DB
+}
+
+func NewNotificationRepositoryMySQL(db *sql.DB) domain.NotificationRepository {
+	return &NotificationRepositoryMySQL{db: db}
+}
+
+// Create implements domain.NotificationRepository. Rows are inserted one by
+// one since the ids of a multi-row insert are not guaranteed to be contiguous.
+// A repeated event hits the unique index on its key and inserts nothing.
+func (repository *NotificationRepositoryMySQL) Create(ctx context.Context, notifications []domain.Notification) error {
+	for i := range notifications {
+		notification := &notifications[i]
+		result, err := repository.db.ExecContext(ctx, "INSERT INTO notifications (user_id, actor_id, type, post_id, comment_id, event_key, created_at) VALUES (?, ?, ?, ?, ?, ?, ?) ON DUPLICATE KEY UPDATE id = id", notification.UserID, notification.ActorID, notification.Type, notification.PostID, notification.CommentID, notification.EventKey, notification.CreatedAt)
+		if err != nil {
+			logger.Log.Error("failed to insert notification", zap.Error(err))
+			return common.ErrInternalServerError
+		}
+		inserted, err := affected(result)
+		if err != nil {
+			return err
+		}
+		if !inserted {
+			continue
+		}
+		notification.ID, err = result.LastInsertId()
+		if err != nil {
+			logger.Log.Error("failed to get last insert id", zap.Error(err))
+			return common.ErrInternalServerError
+		}
+	}
+	return nil
+}
+
+// FindByUser implements domain.NotificationRepository. Notifications are
+// listed newest first, with the name of their actor.
+func (repository *NotificationRepositoryMySQL) FindByUser(ctx context.Context, userID int64, param domain.NotificationParam) ([]domain.Notification, int64, error) {
+	condition := "n.user_id = ?"
+	if param.Unread {
+		condition += " AND n.read_at IS NULL"
+	}
+	var total int64
+	if err := repository.db.QueryRowContext(ctx, "SELECT count(*) FROM notifications n WHERE "+condition, userID).Scan(&total); err != nil {
+		logger.Log.Error("failed to count notifications", zap.Error(err))
+		return nil, 0, common.ErrInternalServerError
+	}
+	query := "SELECT n.id, n.user_id, n.actor_id, u.name, n.type, n.post_id, n.comment_id, n.read_at, n.created_at FROM notifications n JOIN users u ON u.id = n.actor_id WHERE " + condition + " ORDER BY n.created_at DESC, n.id DESC LIMIT ? OFFSET ?"
+	rows, err := repository.db.QueryContext(ctx, query, userID, param.Limit, (param.Page-1)*param.Limit)
+	if err != nil {
+		logger.Log.Error("failed to select notifications", zap.Error(err))
+		return nil, 0, common.ErrInternalServerError
+	}
+	defer rows.Close()
+	notifications := []domain.Notification{}
+	for rows.Next() {
+		var notification domain.Notification
+		if err := rows.Scan(&notification.ID, &notification.UserID, &notification.ActorID, &notification.ActorName, &notification.Type, &notification.PostID, &notification.CommentID, &notification.ReadAt, &notification.CreatedAt); err != nil {
+			logger.Log.Error("failed to scan notification", zap.Error(err))
+			return nil, 0, common.ErrInternalServerError
+		}
+		notifications = append(notifications, notification)
+	}
+	return notifications, total, nil
+}
+
+// CountUnread implements domain.NotificationRepository.
+func (repository *NotificationRepositoryMySQL) CountUnread(ctx context.Context, userID int64) (int64, error) {
+	var unread int64
+	if err := repository.db.QueryRowContext(ctx, "SELECT count(*) FROM notifications WHERE user_id = ? AND read_at IS NULL", userID).Scan(&unread); err != nil {
+		logger.Log.Error("failed to count unread notifications", zap.Error(err))
+		return 0, common.ErrInternalServerError
+	}
+	return unread, nil
+}
+
+// MarkRead implements domain.NotificationRepository. It reports whether the
+// user has a notification with that id; marking it again keeps the first date.
+func (repository *NotificationRepositoryMySQL) MarkRead(ctx context.Context, userID, id int64) (bool, error) {
+	var found bool
+	if err := repository.db.QueryRowContext(ctx, "SELECT EXISTS (SELECT 1 FROM notifications WHERE id = ? AND user_id = ?)", id, userID).Scan(&found); err != nil {
+		logger.Log.Error("failed to select notification", zap.Error(err))
+		return false, common.ErrInternalServerError
+	}
+	if !found {
+		return false, nil
+	}
+	if _, err := repository.db.ExecContext(ctx, "UPDATE notifications SET read_at = CURRENT_TIMESTAMP WHERE id = ? AND read_at IS NULL", id); err != nil {
+		logger.Log.Error("failed to mark notification read", zap.Error(err))
+		return false, common.ErrInternalServerError
+	}
+	return true, nil
+}
+
+// MarkAllRead implements domain.NotificationRepository.
+func (repository *NotificationRepositoryMySQL) MarkAllRead(ctx context.Context, userID int64) error {
+	if _, err := repository.db.ExecContext(ctx, "UPDATE notifications SET read_at = CURRENT_TIMESTAMP WHERE user_id = ? AND read_at IS NULL", userID); err != nil {
+		logger.Log.Error("failed to mark notifications read", zap.Error(err))
+		return common.ErrInternalServerError
+	}
+	return nil
+}
+
+// GetPreferences implements domain.NotificationRepository. Only the types a
+// user has set are returned.
+func (repository *NotificationRepositoryMySQL) GetPreferences(ctx context.Context, userIDs []int64) (map[int64]map[string]bool, error) {
+	preferences := make(map[int64]map[string]bool, len(userIDs))
+	if len(userIDs) == 0 {
+		return preferences, nil
+	}
+	placeholders, args := inClause(userIDs)
+	rows, err := repository.db.QueryContext(ctx, "SELECT user_id, type, enabled FROM notification_preferences WHERE user_id IN ("+placeholders+")", args...)
+	if err != nil {
+		logger.Log.Error("failed to select notification preferences", zap.Error(err))
+		return nil, common.ErrInternalServerError
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var userID int64
+		var kind string
+		var enabled bool
+		if err := rows.Scan(&userID, &kind, &enabled); err != nil {
+			logger.Log.Error("failed to scan notification preference", zap.Error(err))
+			return nil, common.ErrInternalServerError
+		}
+		if preferences[userID] == nil {
+			preferences[userID] = map[string]bool{}
+		}
+		preferences[userID][kind] = enabled
+	}
+	return preferences, nil
+}
+
+// SetPreferences implements domain.NotificationRepository.
+func (repository *NotificationRepositoryMySQL) SetPreferences(ctx context.Context, userID int64, preferences map[string]bool) error {
+	if len(preferences) == 0 {
+		return nil
+	}
+	var args []interface{}
+	for kind, enabled := range preferences {
+		args = append(args, userID, kind, enabled)
+	}
+	values := strings.TrimSuffix(strings.Repeat("(?, ?, ?), ", len(preferences)), ", ")
+	if _, err := repository.db.ExecContext(ctx, "INSERT INTO notification_preferences (user_id, type, enabled) VALUES "+values+" ON DUPLICATE KEY UPDATE enabled = VALUES(enabled)", args...); err != nil {
+		logger.Log.Error("failed to save notification preferences", zap.Error(err))
+		return common.ErrInternalServerError
+	}
+	return nil
+}
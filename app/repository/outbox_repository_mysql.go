@@ -0,0 +1,145 @@
+package repository
+
+import (
+	"app/domain"
+	"app/pkg/common"
+	"app/pkg/logger"
+	"context"
+	"database/sql"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+type OutboxRepositoryMySQL struct {
+	db *sql.DB
+}
+
+func NewOutboxRepositoryMySQL(db *sql.DB) domain.OutboxRepository {
+	return &OutboxRepositoryMySQL{db: db}
+}
+
+// Create implements domain.OutboxRepository.
+func (repository *OutboxRepositoryMySQL) Create(ctx context.Context, tx domain.Transaction, event *domain.OutboxEvent) error {
+	result, err := tx.GetTx().ExecContext(ctx, "INSERT INTO outbox (idempotency_key, type, payload, available_at, created_at) VALUES (?, ?, ?, ?, ?)", event.Key, event.Type, string(event.Payload), event.CreatedAt, event.CreatedAt)
+	if err != nil {
+		logger.Log.Error("failed to insert outbox event", zap.Error(err))
+		return common.ErrInternalServerError
+	}
+	event.ID, err = result.LastInsertId()
+	if err != nil {
+		logger.Log.Error("failed to get last insert id", zap.Error(err))
+		return common.ErrInternalServerError
+	}
+	return nil
+}
+
+// Claim implements domain.OutboxRepository. Events locked by another relay
+// are skipped rather than waited for.
+func (repository *OutboxRepositoryMySQL) Claim(ctx context.Context, tx domain.Transaction, limit int, until time.Time) ([]domain.OutboxEvent, error) {
+	rows, err := tx.GetTx().QueryContext(ctx, "SELECT id, idempotency_key, type, payload, attempts, created_at FROM outbox WHERE processed_at IS NULL AND available_at <= ? ORDER BY id LIMIT ? FOR UPDATE SKIP LOCKED", time.Now(), limit)
+	if err != nil {
+		logger.Log.Error("failed to select outbox events", zap.Error(err))
+		return nil, common.ErrInternalServerError
+	}
+	events := []domain.OutboxEvent{}
+	for rows.Next() {
+		var event domain.OutboxEvent
+		var payload string
+		if err := rows.Scan(&event.ID, &event.Key, &event.Type, &payload, &event.Attempts, &event.CreatedAt); err != nil {
+			rows.Close()
+			logger.Log.Error("failed to scan outbox event", zap.Error(err))
+			return nil, common.ErrInternalServerError
+		}
+		event.Payload = []byte(payload)
+		events = append(events, event)
+	}
+	rows.Close()
+	if len(events) == 0 {
+		return events, nil
+	}
+	ids := make([]int64, len(events))
+	for i := range events {
+		ids[i] = events[i].ID
+	}
+	placeholders, args := inClause(ids)
+	if _, err := tx.GetTx().ExecContext(ctx, "UPDATE outbox SET available_at = ? WHERE id IN ("+placeholders+")", append([]interface{}{until}, args...)...); err != nil {
+		logger.Log.Error("failed to claim outbox events", zap.Error(err))
+		return nil, common.ErrInternalServerError
+	}
+	return events, nil
+}
+
+// FindHandled implements domain.OutboxRepository.
+func (repository *OutboxRepositoryMySQL) FindHandled(ctx context.Context, eventIDs []int64) (map[int64][]string, error) {
+	handled := make(map[int64][]string, len(eventIDs))
+	if len(eventIDs) == 0 {
+		return handled, nil
+	}
+	placeholders, args := inClause(eventIDs)
+	rows, err := repository.db.QueryContext(ctx, "SELECT event_id, handler FROM outbox_handled WHERE event_id IN ("+placeholders+")", args...)
+	if err != nil {
+		logger.Log.Error("failed to select handled outbox events", zap.Error(err))
+		return nil, common.ErrInternalServerError
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var eventID int64
+		var handler string
+		if err := rows.Scan(&eventID, &handler); err != nil {
+			logger.Log.Error("failed to scan handled outbox event", zap.Error(err))
+			return nil, common.ErrInternalServerError
+		}
+		handled[eventID] = append(handled[eventID], handler)
+	}
+	return handled, nil
+}
+
+// MarkHandled implements domain.OutboxRepository.
+func (repository *OutboxRepositoryMySQL) MarkHandled(ctx context.Context, eventID int64, handler string) error {
+	if _, err := repository.db.ExecContext(ctx, "INSERT IGNORE INTO outbox_handled (event_id, handler) VALUES (?, ?)", eventID, handler); err != nil {
+		logger.Log.Error("failed to mark outbox event handled", zap.Error(err))
+		return common.ErrInternalServerError
+	}
+	return nil
+}
+
+// MarkProcessed implements domain.OutboxRepository.
+func (repository *OutboxRepositoryMySQL) MarkProcessed(ctx context.Context, eventID int64) error {
+	if _, err := repository.db.ExecContext(ctx, "UPDATE outbox SET processed_at = ? WHERE id = ?", time.Now(), eventID); err != nil {
+		logger.Log.Error("failed to mark outbox event processed", zap.Error(err))
+		return common.ErrInternalServerError
+	}
+	return nil
+}
+
+// RecordFailure implements domain.OutboxRepository. An event given up on is
+// marked processed and keeps its last error.
+func (repository *OutboxRepositoryMySQL) RecordFailure(ctx context.Context, eventID int64, attempts int, message string, retryAt *time.Time) error {
+	var err error
+	if retryAt != nil {
+		_, err = repository.db.ExecContext(ctx, "UPDATE outbox SET attempts = ?, last_error = ?, available_at = ? WHERE id = ?", attempts, message, *retryAt, eventID)
+	} else {
+		_, err = repository.db.ExecContext(ctx, "UPDATE outbox SET attempts = ?, last_error = ?, processed_at = ? WHERE id = ?", attempts, message, time.Now(), eventID)
+	}
+	if err != nil {
+		logger.Log.Error("failed to record outbox failure", zap.Error(err))
+		return common.ErrInternalServerError
+	}
+	return nil
+}
+
+// DeleteProcessed implements domain.OutboxRepository.
+func (repository *OutboxRepositoryMySQL) DeleteProcessed(ctx context.Context, before time.Time) (int64, error) {
+	result, err := repository.db.ExecContext(ctx, "DELETE FROM outbox WHERE processed_at < ?", before)
+	if err != nil {
+		logger.Log.Error("failed to delete processed outbox events", zap.Error(err))
+		return 0, common.ErrInternalServerError
+	}
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		logger.Log.Error("failed to get affected rows", zap.Error(err))
+		return 0, common.ErrInternalServerError
+	}
+	return deleted, nil
+}
@@ -6,23 +6,130 @@ import (
 	"app/pkg/logger"
 	"context"
 	"database/sql"
+	"slices"
+	"strings"
+	"time"
 
 	"go.uber.org/zap"
 )
 
+var postColumnList = []string{"id", "title", "slug", "excerpt", "content", "content_text", "content_markdown", "toc", "author_id", "category_id", "status", "comment_count", "version", "created_at", "updated_at", "deleted_at"}
+
+var postColumns = strings.Join(postColumnList, ", ")
+
+// postKeyColumns are always read, even when the fields parameter leaves them
+// out, because cursors and embedded relations are built from them.
+var postKeyColumns = []string{"id", "author_id", "created_at"}
+
+// popularity ranks posts by comments, decaying with age so recent activity
+// outweighs old threads.
+const popularity = "(comment_count + 1) / POW(TIMESTAMPDIFF(HOUR, created_at, NOW()) + 2, 1.5)"
+
+// postSorts maps the whitelisted sort names to the expressions they order by.
+var postSorts = map[string]string{
+	domain.SortNewest:       "created_at",
+	domain.SortOldest:       "created_at",
+	domain.SortCreatedAt:    "created_at",
+	domain.SortUpdatedAt:    "COALESCE(updated_at, created_at)",
+	domain.SortTitle:        "title",
+	domain.SortCommentCount: "comment_count",
+	domain.SortPopularity:   popularity,
+}
+
 type PostRepositoryMySQL struct {
 	db *sql.DB
 }
 
+func postField(post *domain.Post, column string) interface{} {
+	switch column {
+	case "id":
+		return &post.ID
+	case "title":
+		return &post.Title
+	case "slug":
+		return &post.Slug
+	case "excerpt":
+		return &post.Excerpt
+	case "content":
+		return &post.Content
+	case "content_text":
+		return &post.ContentText
+	case "content_markdown":
+		return &post.ContentMarkdown
+	case "toc":
+		return &post.TOC
+	case "author_id":
+		return &post.AuthorID
+	case "category_id":
+		return &post.CategoryID
+	case "status":
+		return &post.Status
+	case "comment_count":
+		return &post.CommentCount
+	case "version":
+		return &post.Version
+	case "created_at":
+		return &post.CreatedAt
+	case "updated_at":
+		return &post.UpdatedAt
+	case "deleted_at":
+		return &post.DeletedAt
+	}
+	return nil
+}
+
+func postTargets(post *domain.Post, columns []string) []interface{} {
+	targets := make([]interface{}, len(columns))
+	for i, column := range columns {
+		targets[i] = postField(post, column)
+	}
+	return targets
+}
+
+func postFields(post *domain.Post) []interface{} {
+	return postTargets(post, postColumnList)
+}
+
+// selectedColumns returns the columns backing the requested sparse
+// fieldset, or every column when there is none.
+func selectedColumns(search domain.SearchParam) []string {
+	fields := search.FieldList()
+	if len(fields) == 0 {
+		return postColumnList
+	}
+	columns := slices.Clone(postKeyColumns)
+	if search.Search != "" {
+		// Search snippets are cut from the plain text.
+		columns = append(columns, "content_text")
+	}
+	for _, field := range fields {
+		if field == "content_html" {
+			field = "content"
+		}
+		if !slices.Contains(columns, field) && slices.Contains(postColumnList, field) {
+			columns = append(columns, field)
+		}
+	}
+	return columns
+}
+
+func scanPost(row rowScanner, post *domain.Post) error {
+	return row.Scan(postFields(post)...)
+}
+
 func NewPostRepositoryMySQL(db *sql.DB) domain.PostRepository {
 	return &PostRepositoryMySQL{db: db}
 }
 
-// Create implements domain.PostRepository.
+// Create implements domain.PostRepository. A slug already used by another
+// post fails with common.ErrSlugTaken.
 func (repository *PostRepositoryMySQL) Create(ctx context.Context, tx domain.Transaction, post *domain.Post) error {
-	result, err := tx.GetTx().ExecContext(ctx, "INSERT INTO posts (title, content, author_id) VALUES (?, ?, ?)", post.Title, post.Content, post.AuthorID)
+	result, err := tx.GetTx().ExecContext(ctx, "INSERT INTO posts (title, slug, excerpt, content, content_text, content_markdown, toc, author_id, category_id, status) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)", post.Title, post.Slug, post.Excerpt, post.Content, post.ContentText, post.ContentMarkdown, post.TOC, post.AuthorID, post.CategoryID, post.Status)
+	if duplicateKey(err) {
+		return common.ErrSlugTaken
+	}
 	if err != nil {
-		logger.Log.Error("failed to insert user", zap.Error(err))
+		logger.Log.Error("failed to insert post", zap.Error(err))
 		return common.ErrInternalServerError
 	}
 	id, err := result.LastInsertId()
@@ -31,23 +138,29 @@ func (repository *PostRepositoryMySQL) Create(ctx context.Context, tx domain.Tra
 		return common.ErrInternalServerError
 	}
 	post.ID = id
+	post.Version = 1
 	return nil
 }
 
 // GetAll implements domain.PostRepository.
 func (repository *PostRepositoryMySQL) GetAll(ctx context.Context, search domain.SearchParam) ([]domain.Post, int64, error) {
-	search.Search = "%" + search.Search + "%"
 	var posts []domain.Post
-	query := "SELECT count(id) FROM posts WHERE (title LIKE ? or content LIKE ?) AND deleted_at IS NULL"
-	row := repository.db.QueryRowContext(ctx, query, search.Search, search.Search)
-	var total int64
-	if err := row.Scan(&total); err != nil {
-		logger.Log.Error("failed to count posts", zap.Error(err))
-		return nil, 0, common.ErrInternalServerError
+	where, args := postFilter(search)
+	total, err := repository.count(ctx, where, args)
+	if err != nil {
+		return nil, 0, err
 	}
 
-	query = "SELECT id, title, content, author_id, created_at, updated_at, deleted_at FROM posts WHERE (title LIKE ? or content LIKE ?) AND deleted_at IS NULL ORDER BY id DESC LIMIT ? OFFSET ? "
-	rows, err := repository.db.QueryContext(ctx, query, search.Search, search.Search, search.Limit, search.Limit*(search.Page-1))
+	selected := selectedColumns(search)
+	columns := strings.Join(selected, ", ")
+	var selectArgs []interface{}
+	if search.Search != "" {
+		columns += ", " + matchAgainst(search.Mode) + " AS score"
+		selectArgs = append(selectArgs, search.Search)
+	}
+	query := "SELECT " + columns + " FROM posts" + where + " ORDER BY " + postOrder(search) + " LIMIT ? OFFSET ?"
+	args = append(append(selectArgs, args...), search.Limit, search.Limit*(search.Page-1))
+	rows, err := repository.db.QueryContext(ctx, query, args...)
 	if err != nil {
 		logger.Log.Error("failed to query posts", zap.Error(err))
 		return nil, 0, common.ErrInternalServerError
@@ -55,7 +168,13 @@ func (repository *PostRepositoryMySQL) GetAll(ctx context.Context, search domain
 	defer rows.Close()
 	for rows.Next() {
 		var post domain.Post
-		if err := rows.Scan(&post.ID, &post.Title, &post.Content, &post.AuthorID, &post.CreatedAt, &post.UpdatedAt, &post.DeletedAt); err != nil {
+		dest := postTargets(&post, selected)
+		var score float64
+		if search.Search != "" {
+			dest = append(dest, &score)
+			post.Score = &score
+		}
+		if err := rows.Scan(dest...); err != nil {
 			logger.Log.Error("failed to scan post", zap.Error(err))
 			return nil, 0, common.ErrInternalServerError
 		}
@@ -64,10 +183,206 @@ func (repository *PostRepositoryMySQL) GetAll(ctx context.Context, search domain
 	return posts, total, nil
 }
 
+// GetAllByCursor implements domain.PostRepository. Posts are ordered by
+// (created_at, id), newest first unless the oldest sort was requested.
+func (repository *PostRepositoryMySQL) GetAllByCursor(ctx context.Context, search domain.SearchParam) ([]domain.Post, *domain.PageInfo, error) {
+	var posts []domain.Post
+	where, args := postFilter(search)
+	var total *int64
+	if search.WithTotal {
+		count, err := repository.count(ctx, where, args)
+		if err != nil {
+			return nil, nil, err
+		}
+		total = &count
+	}
+	condition, keyArgs, order, reversed := keyset(search, search.Ascending())
+	if condition != "" {
+		where += " AND " + condition
+		args = append(args, keyArgs...)
+	}
+	selected := selectedColumns(search)
+	query := "SELECT " + strings.Join(selected, ", ") + " FROM posts" + where + " ORDER BY " + order + " LIMIT ?"
+	rows, err := repository.db.QueryContext(ctx, query, append(args, search.Limit+1)...)
+	if err != nil {
+		logger.Log.Error("failed to query posts", zap.Error(err))
+		return nil, nil, common.ErrInternalServerError
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var post domain.Post
+		if err := rows.Scan(postTargets(&post, selected)...); err != nil {
+			logger.Log.Error("failed to scan post", zap.Error(err))
+			return nil, nil, common.ErrInternalServerError
+		}
+		posts = append(posts, post)
+	}
+	more := len(posts) > search.Limit
+	if more {
+		posts = posts[:search.Limit]
+	}
+	if reversed {
+		slices.Reverse(posts)
+	}
+	keys := make([]domain.Cursor, len(posts))
+	for i, post := range posts {
+		keys[i] = domain.Cursor{CreatedAt: post.CreatedAt, ID: post.ID}
+	}
+	page := keysetPage(search, keys, more)
+	page.Total = total
+	return posts, page, nil
+}
+
+func (repository *PostRepositoryMySQL) count(ctx context.Context, where string, args []interface{}) (int64, error) {
+	var total int64
+	if err := repository.db.QueryRowContext(ctx, "SELECT count(id) FROM posts"+where, args...).Scan(&total); err != nil {
+		logger.Log.Error("failed to count posts", zap.Error(err))
+		return 0, common.ErrInternalServerError
+	}
+	return total, nil
+}
+
+// matchAgainst returns the FULLTEXT predicate over the post title and plain
+// text content for the given search mode; the markup of the content is left
+// out so that tag and attribute names do not match. Natural language mode
+// only matches rows with a positive relevance, so the same expression is
+// used to filter and to rank.
+func matchAgainst(mode string) string {
+	if mode == domain.SearchModeBoolean {
+		return "MATCH(title, content_text) AGAINST(? IN BOOLEAN MODE)"
+	}
+	return "MATCH(title, content_text) AGAINST(? IN NATURAL LANGUAGE MODE)"
+}
+
+func postOrder(search domain.SearchParam) string {
+	if search.Search != "" && (search.Sort == "" || search.Sort == domain.SortRelevance) {
+		return "score DESC, id DESC"
+	}
+	column, ok := postSorts[search.Sort]
+	if !ok {
+		column = "created_at"
+	}
+	if search.Ascending() {
+		return column + " ASC, id ASC"
+	}
+	return column + " DESC, id DESC"
+}
+
+// postFilter builds the WHERE clause shared by the post listing queries.
+// Tag and category filters are semi-joins so they can use the post_tags and
+// category indexes, and a category matches posts in all of its descendants.
+func postFilter(search domain.SearchParam) (string, []interface{}) {
+	conditions := []string{"deleted_at IS NULL"}
+	var args []interface{}
+	switch search.Status {
+	case "", domain.PostStatusPublished:
+		conditions = append(conditions, "status = ?")
+		args = append(args, domain.PostStatusPublished)
+	case domain.PostStatusDraft:
+		conditions = append(conditions, "status = ? AND author_id = ?")
+		args = append(args, domain.PostStatusDraft, search.ViewerID)
+	case domain.StatusAll:
+		conditions = append(conditions, "(status = ? OR author_id = ?)")
+		args = append(args, domain.PostStatusPublished, search.ViewerID)
+	}
+	if search.AuthorID != 0 {
+		conditions = append(conditions, "author_id = ?")
+		args = append(args, search.AuthorID)
+	}
+	if search.FollowerID != 0 {
+		conditions = append(conditions, "author_id IN (SELECT followee_id FROM follows WHERE follower_id = ?)")
+		args = append(args, search.FollowerID)
+	}
+	for _, bound := range []struct {
+		condition string
+		value     *time.Time
+	}{
+		{"created_at >= ?", search.CreatedFrom},
+		{"created_at < ?", dayAfter(search.CreatedTo)},
+		{"updated_at >= ?", search.UpdatedFrom},
+		{"updated_at < ?", dayAfter(search.UpdatedTo)},
+	} {
+		if bound.value != nil {
+			conditions = append(conditions, bound.condition)
+			args = append(args, *bound.value)
+		}
+	}
+	if search.Search != "" {
+		conditions = append(conditions, matchAgainst(search.Mode))
+		args = append(args, search.Search)
+	}
+	if search.Tag != "" {
+		conditions = append(conditions, "id IN (SELECT pt.post_id FROM post_tags pt JOIN tags t ON t.id = pt.tag_id WHERE t.slug = ?)")
+		args = append(args, search.Tag)
+	}
+	if search.Category != "" {
+		conditions = append(conditions, "category_id IN (WITH RECURSIVE tree AS (SELECT id FROM categories WHERE slug = ? UNION ALL SELECT c.id FROM categories c JOIN tree ON c.parent_id = tree.id) SELECT id FROM tree)")
+		args = append(args, search.Category)
+	}
+	return " WHERE " + strings.Join(conditions, " AND "), args
+}
+
+// dayAfter turns an inclusive date bound into an exclusive one.
+func dayAfter(date *time.Time) *time.Time {
+	if date == nil {
+		return nil
+	}
+	next := date.AddDate(0, 0, 1)
+	return &next
+}
+
+// IncrementCommentCount implements domain.PostRepository.
+func (repository *PostRepositoryMySQL) IncrementCommentCount(ctx context.Context, tx domain.Transaction, id int64, delta int) error {
+	_, err := tx.GetTx().ExecContext(ctx, "UPDATE posts SET comment_count = comment_count + ? WHERE id = ?", delta, id)
+	if err != nil {
+		logger.Log.Error("failed to update comment count", zap.Error(err))
+		return common.ErrInternalServerError
+	}
+	return nil
+}
+
+// CountPublished implements domain.PostRepository.
+func (repository *PostRepositoryMySQL) CountPublished(ctx context.Context) (int64, error) {
+	var total int64
+	err := repository.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM posts WHERE status = ? AND deleted_at IS NULL", domain.PostStatusPublished).Scan(&total)
+	if err != nil {
+		logger.Log.Error("failed to count published posts", zap.Error(err))
+		return 0, common.ErrInternalServerError
+	}
+	return total, nil
+}
+
+// StreamPublished implements domain.PostRepository. Rows are handed to fn as
+// they are read, so only one post is held in memory at a time. An error from
+// fn stops the iteration and is returned unchanged.
+func (repository *PostRepositoryMySQL) StreamPublished(ctx context.Context, offset, limit int, fn func(domain.SitemapEntry) error) error {
+	rows, err := repository.db.QueryContext(ctx, "SELECT id, slug, COALESCE(updated_at, created_at) FROM posts WHERE status = ? AND deleted_at IS NULL ORDER BY id LIMIT ? OFFSET ?", domain.PostStatusPublished, limit, offset)
+	if err != nil {
+		logger.Log.Error("failed to select published posts", zap.Error(err))
+		return common.ErrInternalServerError
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var entry domain.SitemapEntry
+		if err := rows.Scan(&entry.ID, &entry.Slug, &entry.UpdatedAt); err != nil {
+			logger.Log.Error("failed to scan published post", zap.Error(err))
+			return common.ErrInternalServerError
+		}
+		if err := fn(entry); err != nil {
+			return err
+		}
+	}
+	if err := rows.Err(); err != nil {
+		logger.Log.Error("failed to iterate published posts", zap.Error(err))
+		return common.ErrInternalServerError
+	}
+	return nil
+}
+
 // GetByID implements domain.PostRepository.
 func (repository *PostRepositoryMySQL) GetByID(ctx context.Context, id int64) (*domain.Post, error) {
 	var post domain.Post
-	err := repository.db.QueryRowContext(ctx, "SELECT id, title, content, author_id, created_at, updated_at, deleted_at FROM posts WHERE id = ? AND deleted_at IS NULL", id).Scan(&post.ID, &post.Title, &post.Content, &post.AuthorID, &post.CreatedAt, &post.UpdatedAt, &post.DeletedAt)
+	err := scanPost(repository.db.QueryRowContext(ctx, "SELECT "+postColumns+" FROM posts WHERE id = ? AND deleted_at IS NULL", id), &post)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, common.ErrPostNotFound
@@ -78,10 +393,88 @@ func (repository *PostRepositoryMySQL) GetByID(ctx context.Context, id int64) (*
 	return &post, nil
 }
 
+// FindByIDs implements domain.PostRepository. Deleted posts are skipped and
+// the order of the result is unspecified.
+func (repository *PostRepositoryMySQL) FindByIDs(ctx context.Context, ids []int64) ([]domain.Post, error) {
+	var posts []domain.Post
+	if len(ids) == 0 {
+		return posts, nil
+	}
+	placeholders, args := inClause(ids)
+	rows, err := repository.db.QueryContext(ctx, "SELECT "+postColumns+" FROM posts WHERE id IN ("+placeholders+") AND deleted_at IS NULL", args...)
+	if err != nil {
+		logger.Log.Error("failed to select posts by id", zap.Error(err))
+		return nil, common.ErrInternalServerError
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var post domain.Post
+		if err := scanPost(rows, &post); err != nil {
+			logger.Log.Error("failed to scan post", zap.Error(err))
+			return nil, common.ErrInternalServerError
+		}
+		posts = append(posts, post)
+	}
+	return posts, nil
+}
+
+// GetBySlug implements domain.PostRepository. Slugs a post used before being
+// renamed resolve to the post as well, so callers can detect a stale slug by
+// comparing it with post.Slug.
+func (repository *PostRepositoryMySQL) GetBySlug(ctx context.Context, slug string) (*domain.Post, error) {
+	var post domain.Post
+	err := scanPost(repository.db.QueryRowContext(ctx, "SELECT "+postColumns+" FROM posts WHERE slug = ? AND deleted_at IS NULL", slug), &post)
+	if err == sql.ErrNoRows {
+		err = scanPost(repository.db.QueryRowContext(ctx, "SELECT "+postColumns+" FROM posts WHERE id = (SELECT post_id FROM post_slugs WHERE slug = ?) AND deleted_at IS NULL", slug), &post)
+	}
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, common.ErrPostNotFound
+		}
+		logger.Log.Error("failed to select post by slug", zap.Error(err))
+		return nil, common.ErrInternalServerError
+	}
+	return &post, nil
+}
+
+// FindSlugs implements domain.PostRepository.
+func (repository *PostRepositoryMySQL) FindSlugs(ctx context.Context, tx domain.Transaction, base string, excludePostID int64) ([]string, error) {
+	query := "SELECT slug FROM posts WHERE (slug = ? OR slug LIKE ?) AND id <> ? UNION SELECT slug FROM post_slugs WHERE (slug = ? OR slug LIKE ?) AND post_id <> ?"
+	rows, err := tx.GetTx().QueryContext(ctx, query, base, base+"-%", excludePostID, base, base+"-%", excludePostID)
+	if err != nil {
+		logger.Log.Error("failed to select slugs", zap.Error(err))
+		return nil, common.ErrInternalServerError
+	}
+	defer rows.Close()
+	var slugs []string
+	for rows.Next() {
+		var slug string
+		if err := rows.Scan(&slug); err != nil {
+			logger.Log.Error("failed to scan slug", zap.Error(err))
+			return nil, common.ErrInternalServerError
+		}
+		slugs = append(slugs, slug)
+	}
+	return slugs, nil
+}
+
+// ArchiveSlug implements domain.PostRepository.
+func (repository *PostRepositoryMySQL) ArchiveSlug(ctx context.Context, tx domain.Transaction, postID int64, oldSlug, newSlug string) error {
+	if _, err := tx.GetTx().ExecContext(ctx, "DELETE FROM post_slugs WHERE slug = ? AND post_id = ?", newSlug, postID); err != nil {
+		logger.Log.Error("failed to delete slug history", zap.Error(err))
+		return common.ErrInternalServerError
+	}
+	if _, err := tx.GetTx().ExecContext(ctx, "INSERT INTO post_slugs (slug, post_id) VALUES (?, ?)", oldSlug, postID); err != nil {
+		logger.Log.Error("failed to insert slug history", zap.Error(err))
+		return common.ErrInternalServerError
+	}
+	return nil
+}
+
 // SelectForUpdate implements domain.PostRepository.
 func (repository *PostRepositoryMySQL) SelectForUpdate(ctx context.Context, tx domain.Transaction, id int64) (*domain.Post, error) {
 	var post domain.Post
-	err := tx.GetTx().QueryRowContext(ctx, "SELECT id, title, content, author_id, created_at, updated_at, deleted_at FROM posts WHERE id = ? AND deleted_at IS NULL FOR UPDATE", id).Scan(&post.ID, &post.Title, &post.Content, &post.AuthorID, &post.CreatedAt, &post.UpdatedAt, &post.DeletedAt)
+	err := scanPost(tx.GetTx().QueryRowContext(ctx, "SELECT "+postColumns+" FROM posts WHERE id = ? AND deleted_at IS NULL FOR UPDATE", id), &post)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, common.ErrPostNotFound
@@ -92,9 +485,13 @@ func (repository *PostRepositoryMySQL) SelectForUpdate(ctx context.Context, tx d
 	return &post, nil
 }
 
-// Update implements domain.PostRepository.
+// Update implements domain.PostRepository. A slug already used by another
+// post fails with common.ErrSlugTaken.
 func (repository *PostRepositoryMySQL) Update(ctx context.Context, tx domain.Transaction, id int64, post *domain.Post) error {
-	result, err := tx.GetTx().ExecContext(ctx, "UPDATE posts SET title = ?, content = ?, updated_at = ?, deleted_at = ? WHERE id = ?", post.Title, post.Content, post.UpdatedAt, post.DeletedAt, id)
+	result, err := tx.GetTx().ExecContext(ctx, "UPDATE posts SET title = ?, slug = ?, excerpt = ?, content = ?, content_text = ?, content_markdown = ?, toc = ?, category_id = ?, status = ?, updated_at = ?, deleted_at = ?, version = version + 1 WHERE id = ?", post.Title, post.Slug, post.Excerpt, post.Content, post.ContentText, post.ContentMarkdown, post.TOC, post.CategoryID, post.Status, post.UpdatedAt, post.DeletedAt, id)
+	if duplicateKey(err) {
+		return common.ErrSlugTaken
+	}
 	if err != nil {
 		logger.Log.Error("failed to update post", zap.Error(err))
 		return common.ErrInternalServerError
@@ -107,5 +504,6 @@ func (repository *PostRepositoryMySQL) Update(ctx context.Context, tx domain.Tra
 	if rowsAffected == 0 {
 		return common.ErrPostNotFound
 	}
+	post.Version++
 	return nil
 }
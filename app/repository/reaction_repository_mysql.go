@@ -0,0 +1,131 @@
+package repository
+
+import (
+	"app/domain"
+	"app/pkg/common"
+	"app/pkg/logger"
+	"context"
+	"database/sql"
+
+	"go.uber.org/zap"
+)
+
+type ReactionRepositoryMySQL struct {
+	db *sql.DB
+}
+
+func NewReactionRepositoryMySQL(db *sql.DB) domain.ReactionRepository {
+	return &ReactionRepositoryMySQL{db: db}
+}
+
+// Add implements domain.ReactionRepository.
+func (repository *ReactionRepositoryMySQL) Add(ctx context.Context, tx domain.Transaction, postID, userID int64, reactionType string) (bool, error) {
+	result, err := tx.GetTx().ExecContext(ctx, "INSERT IGNORE INTO reactions (post_id, user_id, type) VALUES (?, ?, ?)", postID, userID, reactionType)
+	if err != nil {
+		logger.Log.Error("failed to insert reaction", zap.Error(err))
+		return false, common.ErrInternalServerError
+	}
+	return affected(result)
+}
+
+// Remove implements domain.ReactionRepository.
+func (repository *ReactionRepositoryMySQL) Remove(ctx context.Context, tx domain.Transaction, postID, userID int64, reactionType string) (bool, error) {
+	result, err := tx.GetTx().ExecContext(ctx, "DELETE FROM reactions WHERE post_id = ? AND user_id = ? AND type = ?", postID, userID, reactionType)
+	if err != nil {
+		logger.Log.Error("failed to delete reaction", zap.Error(err))
+		return false, common.ErrInternalServerError
+	}
+	return affected(result)
+}
+
+// IncrementCount implements domain.ReactionRepository.
+func (repository *ReactionRepositoryMySQL) IncrementCount(ctx context.Context, tx domain.Transaction, postID int64, reactionType string, delta int) error {
+	query := "INSERT INTO reaction_counts (post_id, type, count) VALUES (?, ?, ?) ON DUPLICATE KEY UPDATE count = count + ?"
+	if _, err := tx.GetTx().ExecContext(ctx, query, postID, reactionType, delta, delta); err != nil {
+		logger.Log.Error("failed to update reaction count", zap.Error(err))
+		return common.ErrInternalServerError
+	}
+	return nil
+}
+
+// CountByPostIDs implements domain.ReactionRepository. Posts without any
+// reaction are missing from the result.
+func (repository *ReactionRepositoryMySQL) CountByPostIDs(ctx context.Context, postIDs []int64) (map[int64]map[string]int64, error) {
+	counts := make(map[int64]map[string]int64, len(postIDs))
+	if len(postIDs) == 0 {
+		return counts, nil
+	}
+	placeholders, args := inClause(postIDs)
+	rows, err := repository.db.QueryContext(ctx, "SELECT post_id, type, count FROM reaction_counts WHERE count > 0 AND post_id IN ("+placeholders+")", args...)
+	if err != nil {
+		logger.Log.Error("failed to select reaction counts", zap.Error(err))
+		return nil, common.ErrInternalServerError
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var postID, count int64
+		var reactionType string
+		if err := rows.Scan(&postID, &reactionType, &count); err != nil {
+			logger.Log.Error("failed to scan reaction count", zap.Error(err))
+			return nil, common.ErrInternalServerError
+		}
+		if counts[postID] == nil {
+			counts[postID] = make(map[string]int64)
+		}
+		counts[postID][reactionType] = count
+	}
+	return counts, nil
+}
+
+// FindByUser implements domain.ReactionRepository.
+func (repository *ReactionRepositoryMySQL) FindByUser(ctx context.Context, userID int64, postIDs []int64) (map[int64][]string, error) {
+	reactions := make(map[int64][]string, len(postIDs))
+	if len(postIDs) == 0 {
+		return reactions, nil
+	}
+	placeholders, args := inClause(postIDs)
+	query := "SELECT post_id, type FROM reactions WHERE user_id = ? AND post_id IN (" + placeholders + ") ORDER BY created_at, type"
+	rows, err := repository.db.QueryContext(ctx, query, append([]interface{}{userID}, args...)...)
+	if err != nil {
+		logger.Log.Error("failed to select user reactions", zap.Error(err))
+		return nil, common.ErrInternalServerError
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var postID int64
+		var reactionType string
+		if err := rows.Scan(&postID, &reactionType); err != nil {
+			logger.Log.Error("failed to scan user reaction", zap.Error(err))
+			return nil, common.ErrInternalServerError
+		}
+		reactions[postID] = append(reactions[postID], reactionType)
+	}
+	return reactions, nil
+}
+
+// FindByPostID implements domain.ReactionRepository. Reactions are ordered
+// newest first.
+func (repository *ReactionRepositoryMySQL) FindByPostID(ctx context.Context, postID int64, param domain.SearchParam) ([]domain.Reaction, int64, error) {
+	var total int64
+	if err := repository.db.QueryRowContext(ctx, "SELECT count(*) FROM reactions WHERE post_id = ?", postID).Scan(&total); err != nil {
+		logger.Log.Error("failed to count reactions", zap.Error(err))
+		return nil, 0, common.ErrInternalServerError
+	}
+	query := "SELECT r.user_id, u.name, r.type, r.created_at FROM reactions r JOIN users u ON u.id = r.user_id WHERE r.post_id = ? ORDER BY r.created_at DESC, r.user_id, r.type LIMIT ? OFFSET ?"
+	rows, err := repository.db.QueryContext(ctx, query, postID, param.Limit, (param.Page-1)*param.Limit)
+	if err != nil {
+		logger.Log.Error("failed to select reactions", zap.Error(err))
+		return nil, 0, common.ErrInternalServerError
+	}
+	defer rows.Close()
+	reactions := []domain.Reaction{}
+	for rows.Next() {
+		var reaction domain.Reaction
+		if err := rows.Scan(&reaction.UserID, &reaction.UserName, &reaction.Type, &reaction.CreatedAt); err != nil {
+			logger.Log.Error("failed to scan reaction", zap.Error(err))
+			return nil, 0, common.ErrInternalServerError
+		}
+		reactions = append(reactions, reaction)
+	}
+	return reactions, total, nil
+}
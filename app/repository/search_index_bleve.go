@@ -0,0 +1,344 @@
+package repository
+
+import (
+	"app/domain"
+	"app/pkg/common"
+	"app/pkg/logger"
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/blevesearch/bleve/v2"
+	"github.com/blevesearch/bleve/v2/analysis/lang/en"
+	"github.com/blevesearch/bleve/v2/mapping"
+	"github.com/blevesearch/bleve/v2/search/highlight/format/html"
+	"github.com/blevesearch/bleve/v2/search/query"
+	"go.uber.org/zap"
+)
+
+const (
+	searchFacetSize   = 10
+	searchDeleteBatch = 1000
+)
+
+// searchFields are the full text fields a query runs against, with the
+// boost applied to matches in each of them.
+var searchFields = []struct {
+	name  string
+	boost float64
+}{
+	{name: "title", boost: 3},
+	{name: "content", boost: 1},
+}
+
+type SearchIndexBleve struct {
+	path  string
+	mu    sync.RWMutex
+	index bleve.Index
+}
+
+// NewSearchIndexBleve opens the Bleve index stored at path, creating it when
+// it does not exist yet. An empty path keeps the index in memory.
+func NewSearchIndexBleve(path string) (domain.SearchIndex, error) {
+	index, err := openBleveIndex(path)
+	if err != nil {
+		return nil, err
+	}
+	return &SearchIndexBleve{path: path, index: index}, nil
+}
+
+func openBleveIndex(path string) (bleve.Index, error) {
+	if path == "" {
+		return bleve.NewMemOnly(searchMapping())
+	}
+	index, err := bleve.Open(path)
+	if err == bleve.ErrorIndexPathDoesNotExist {
+		return bleve.New(path, searchMapping())
+	}
+	return index, err
+}
+
+func searchMapping() mapping.IndexMapping {
+	text := bleve.NewTextFieldMapping()
+	text.Analyzer = en.AnalyzerName
+	text.IncludeTermVectors = true
+	keywordField := bleve.NewKeywordFieldMapping()
+	keywordField.IncludeInAll = false
+	numeric := bleve.NewNumericFieldMapping()
+	numeric.IncludeInAll = false
+	date := bleve.NewDateTimeFieldMapping()
+	date.IncludeInAll = false
+
+	document := bleve.NewDocumentStaticMapping()
+	document.AddFieldMappingsAt("type", keywordField)
+	document.AddFieldMappingsAt("id", numeric)
+	document.AddFieldMappingsAt("post_id", numeric)
+	document.AddFieldMappingsAt("title", text)
+	document.AddFieldMappingsAt("content", text)
+	document.AddFieldMappingsAt("author", keywordField)
+	document.AddFieldMappingsAt("tags", keywordField)
+	document.AddFieldMappingsAt("created_at", date)
+
+	indexMapping := bleve.NewIndexMapping()
+	indexMapping.DefaultMapping = document
+	indexMapping.DefaultAnalyzer = en.AnalyzerName
+	return indexMapping
+}
+
+func searchDocumentID(documentType string, id int64) string {
+	return fmt.Sprintf("%s:%d", documentType, id)
+}
+
+// Index implements domain.SearchIndex.
+func (s *SearchIndexBleve) Index(ctx context.Context, documents ...domain.SearchDocument) error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	batch := s.index.NewBatch()
+	for _, document := range documents {
+		err := batch.Index(searchDocumentID(document.Type, document.ID), map[string]interface{}{
+			"type":       document.Type,
+			"id":         document.ID,
+			"post_id":    document.PostID,
+			"title":      document.Title,
+			"content":    document.Content,
+			"author":     document.Author,
+			"tags":       document.Tags,
+			"created_at": document.CreatedAt,
+		})
+		if err != nil {
+			logger.Log.Error("failed to build search document", zap.Error(err))
+			return common.ErrInternalServerError
+		}
+	}
+	if err := s.index.Batch(batch); err != nil {
+		logger.Log.Error("failed to index search documents", zap.Error(err))
+		return common.ErrInternalServerError
+	}
+	return nil
+}
+
+// DeletePost implements domain.SearchIndex. The post is removed together
+// with its comments.
+func (s *SearchIndexBleve) DeletePost(ctx context.Context, postID int64) error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	id := float64(postID)
+	inclusive := true
+	byPost := bleve.NewNumericRangeInclusiveQuery(&id, &id, &inclusive, &inclusive)
+	byPost.SetField("post_id")
+	for {
+		request := bleve.NewSearchRequestOptions(byPost, searchDeleteBatch, 0, false)
+		result, err := s.index.SearchInContext(ctx, request)
+		if err != nil {
+			logger.Log.Error("failed to find search documents", zap.Error(err))
+			return common.ErrInternalServerError
+		}
+		if len(result.Hits) == 0 {
+			return nil
+		}
+		batch := s.index.NewBatch()
+		for _, hit := range result.Hits {
+			batch.Delete(hit.ID)
+		}
+		if err := s.index.Batch(batch); err != nil {
+			logger.Log.Error("failed to delete search documents", zap.Error(err))
+			return common.ErrInternalServerError
+		}
+	}
+}
+
+// DeleteComment implements domain.SearchIndex.
+func (s *SearchIndexBleve) DeleteComment(ctx context.Context, commentID int64) error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if err := s.index.Delete(searchDocumentID(domain.SearchTypeComment, commentID)); err != nil {
+		logger.Log.Error("failed to delete search document", zap.Error(err))
+		return common.ErrInternalServerError
+	}
+	return nil
+}
+
+// Search implements domain.SearchIndex.
+func (s *SearchIndexBleve) Search(ctx context.Context, search domain.SearchQuery) (*domain.SearchResult, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var text query.Query
+	if search.Mode == domain.SearchModeBoolean {
+		queryString := bleve.NewQueryStringQuery(search.Query)
+		if _, err := queryString.Parse(); err != nil {
+			return nil, common.ErrInvalidSearchQuery
+		}
+		text = queryString
+	} else {
+		text = naturalQuery(search.Query)
+	}
+	conditions := []query.Query{text}
+	for field, value := range map[string]string{"type": search.Type, "tags": search.Tag, "author": search.Author} {
+		if value == "" {
+			continue
+		}
+		term := bleve.NewTermQuery(value)
+		term.SetField(field)
+		conditions = append(conditions, term)
+	}
+	if search.From != nil || search.To != nil {
+		var from, to time.Time
+		if search.From != nil {
+			from = *search.From
+		}
+		if search.To != nil {
+			to = search.To.AddDate(0, 0, 1)
+		}
+		dates := bleve.NewDateRangeQuery(from, to)
+		dates.SetField("created_at")
+		conditions = append(conditions, dates)
+	}
+
+	request := bleve.NewSearchRequestOptions(bleve.NewConjunctionQuery(conditions...), search.Limit, search.Limit*(search.Page-1), false)
+	request.Fields = []string{"type", "id", "post_id", "title"}
+	request.Highlight = bleve.NewHighlightWithStyle(html.Name)
+	request.Highlight.AddField("title")
+	request.Highlight.AddField("content")
+	switch search.Sort {
+	case domain.SortNewest:
+		request.SortBy([]string{"-created_at", "-_score"})
+	case domain.SortOldest:
+		request.SortBy([]string{"created_at", "-_score"})
+	}
+	request.AddFacet("tags", bleve.NewFacetRequest("tags", searchFacetSize))
+	request.AddFacet("author", bleve.NewFacetRequest("author", searchFacetSize))
+	now := time.Now()
+	dates := bleve.NewFacetRequest("created_at", 4)
+	dates.AddDateTimeRange("past_week", now.AddDate(0, 0, -7), time.Time{})
+	dates.AddDateTimeRange("past_month", now.AddDate(0, -1, 0), now.AddDate(0, 0, -7))
+	dates.AddDateTimeRange("past_year", now.AddDate(-1, 0, 0), now.AddDate(0, -1, 0))
+	dates.AddDateTimeRange("older", time.Time{}, now.AddDate(-1, 0, 0))
+	request.AddFacet("created_at", dates)
+
+	result, err := s.index.SearchInContext(ctx, request)
+	if err != nil {
+		logger.Log.Error("failed to search index", zap.Error(err))
+		return nil, common.ErrInternalServerError
+	}
+
+	response := &domain.SearchResult{
+		Hits:   []domain.SearchHit{},
+		Total:  int64(result.Total),
+		Facets: map[string][]domain.FacetCount{},
+	}
+	for _, hit := range result.Hits {
+		documentType, _ := hit.Fields["type"].(string)
+		id, _ := hit.Fields["id"].(float64)
+		postID, _ := hit.Fields["post_id"].(float64)
+		title, _ := hit.Fields["title"].(string)
+		highlights := make(map[string][]string)
+		for field, fragments := range hit.Fragments {
+			for _, fragment := range fragments {
+				if strings.Contains(fragment, "<mark>") {
+					highlights[field] = append(highlights[field], fragment)
+				}
+			}
+		}
+		response.Hits = append(response.Hits, domain.SearchHit{
+			Type:       documentType,
+			ID:         int64(id),
+			PostID:     int64(postID),
+			Title:      title,
+			Score:      hit.Score,
+			Highlights: highlights,
+		})
+	}
+	for name, facet := range result.Facets {
+		counts := []domain.FacetCount{}
+		for _, term := range facet.Terms.Terms() {
+			counts = append(counts, domain.FacetCount{Term: term.Term, Count: term.Count})
+		}
+		for _, dateRange := range facet.DateRanges {
+			counts = append(counts, domain.FacetCount{Term: dateRange.Name, Count: dateRange.Count})
+		}
+		response.Facets[name] = counts
+	}
+	return response, nil
+}
+
+// naturalQuery matches quoted phrases exactly and the remaining words with a
+// small edit distance, so typos still find results. Every phrase and at least
+// one of the words must match in the title or the content.
+func naturalQuery(input string) query.Query {
+	var conditions []query.Query
+	var words []string
+	for i, part := range strings.Split(input, `"`) {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		if i%2 == 1 {
+			conditions = append(conditions, acrossFields(func() fieldQuery {
+				return bleve.NewMatchPhraseQuery(part)
+			}))
+			continue
+		}
+		words = append(words, part)
+	}
+	if len(words) > 0 {
+		conditions = append(conditions, acrossFields(func() fieldQuery {
+			match := bleve.NewMatchQuery(strings.Join(words, " "))
+			match.SetFuzziness(1)
+			return match
+		}))
+	}
+	if len(conditions) == 0 {
+		return bleve.NewMatchNoneQuery()
+	}
+	return bleve.NewConjunctionQuery(conditions...)
+}
+
+type fieldQuery interface {
+	query.FieldableQuery
+	SetBoost(b float64)
+}
+
+func acrossFields(build func() fieldQuery) query.Query {
+	var fields []query.Query
+	for _, field := range searchFields {
+		q := build()
+		q.SetField(field.name)
+		q.SetBoost(field.boost)
+		fields = append(fields, q)
+	}
+	return bleve.NewDisjunctionQuery(fields...)
+}
+
+// Reset implements domain.SearchIndex. It drops every document by replacing
+// the index with an empty one.
+func (s *SearchIndexBleve) Reset(ctx context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.index.Close(); err != nil {
+		logger.Log.Error("failed to close search index", zap.Error(err))
+		return common.ErrInternalServerError
+	}
+	if s.path != "" {
+		if err := os.RemoveAll(s.path); err != nil {
+			logger.Log.Error("failed to remove search index", zap.Error(err))
+			return common.ErrInternalServerError
+		}
+	}
+	index, err := openBleveIndex(s.path)
+	if err != nil {
+		logger.Log.Error("failed to create search index", zap.Error(err))
+		return common.ErrInternalServerError
+	}
+	s.index = index
+	return nil
+}
+
+// Close implements domain.SearchIndex.
+func (s *SearchIndexBleve) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.index.Close()
+}
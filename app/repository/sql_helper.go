@@ -0,0 +1,96 @@
+package repository
+
+import (
+	"app/domain"
+	"app/pkg/common"
+	"app/pkg/logger"
+	"database/sql"
+	"errors"
+	"strings"
+
+	"github.com/go-sql-driver/mysql"
+	"go.uber.org/zap"
+)
+
+// errDuplicateEntry is the MySQL error of a write violating a unique index.
+const errDuplicateEntry = 1062
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// inClause returns the placeholders and arguments for an IN (...) condition.
+func inClause(ids []int64) (string, []interface{}) {
+	args := make([]interface{}, len(ids))
+	for i, id := range ids {
+		args[i] = id
+	}
+	return strings.TrimSuffix(strings.Repeat("?, ", len(ids)), ", "), args
+}
+
+// keyset returns the condition and ordering that read the rows after the
+// AfterKey, or before the BeforeKey, of a list ordered by (created_at, id) in
+// the given direction. Rows before a cursor are read walking backwards, so
+// reversed tells the caller to flip them into list order.
+func keyset(param domain.SearchParam, ascending bool) (condition string, args []interface{}, order string, reversed bool) {
+	key, forward := param.AfterKey, ascending
+	if param.BeforeKey != nil {
+		key, forward, reversed = param.BeforeKey, !ascending, true
+	}
+	order = "created_at DESC, id DESC"
+	comparison := "<"
+	if forward {
+		order = "created_at ASC, id ASC"
+		comparison = ">"
+	}
+	if key == nil {
+		return "", nil, order, reversed
+	}
+	condition = "(created_at " + comparison + " ? OR (created_at = ? AND id " + comparison + " ?))"
+	return condition, []interface{}{key.CreatedAt, key.CreatedAt, key.ID}, order, reversed
+}
+
+// keysetPage builds the page info of a keyset read of up to limit+1 rows,
+// given the positions of the rows in list order after the extra row was
+// dropped. more reports whether that extra row was there.
+func keysetPage(param domain.SearchParam, keys []domain.Cursor, more bool) *domain.PageInfo {
+	page := &domain.PageInfo{}
+	if len(keys) == 0 {
+		return page
+	}
+	hasNext, hasPrev := more, param.AfterKey != nil
+	if param.BeforeKey != nil {
+		hasNext, hasPrev = true, more
+	}
+	if hasNext {
+		page.Next = &keys[len(keys)-1]
+	}
+	if hasPrev {
+		page.Prev = &keys[0]
+	}
+	return page
+}
+
+// affected reports whether a statement changed any row.
+func affected(result sql.Result) (bool, error) {
+	rows, err := result.RowsAffected()
+	if err != nil {
+		logger.Log.Error("failed to get affected rows", zap.Error(err))
+		return false, common.ErrInternalServerError
+	}
+	return rows > 0, nil
+}
+
+// duplicateKey reports whether the statement failed on a unique index.
+func duplicateKey(err error) bool {
+	var mysqlErr *mysql.MySQLError
+	return errors.As(err, &mysqlErr) && mysqlErr.Number == errDuplicateEntry
+}
+
+// duplicateKeyOn reports whether the statement failed on the named unique
+// index. MySQL only names it at the end of the message, prefixed with the
+// table since 8.0.
+func duplicateKeyOn(err error, index string) bool {
+	var mysqlErr *mysql.MySQLError
+	return errors.As(err, &mysqlErr) && mysqlErr.Number == errDuplicateEntry && strings.HasSuffix(mysqlErr.Message, index+"'")
+}
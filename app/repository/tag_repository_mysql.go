@@ -0,0 +1,129 @@
+package repository
+
+import (
+	"app/domain"
+	"app/pkg/common"
+	"app/pkg/logger"
+	"context"
+	"database/sql"
+	"strings"
+
+	"go.uber.org/zap"
+)
+
+type TagRepositoryMySQL struct {
+	db *sql.DB
+}
+
+func NewTagRepositoryMySQL(db *sql.DB) domain.TagRepository {
+	return &TagRepositoryMySQL{db: db}
+}
+
+// Upsert implements domain.TagRepository.
+func (repository *TagRepositoryMySQL) Upsert(ctx context.Context, tx domain.Transaction, tags []domain.Tag) ([]domain.Tag, error) {
+	result := make([]domain.Tag, 0, len(tags))
+	for _, tag := range tags {
+		res, err := tx.GetTx().ExecContext(ctx, "INSERT INTO tags (name, slug) VALUES (?, ?) ON DUPLICATE KEY UPDATE id = LAST_INSERT_ID(id)", tag.Name, tag.Slug)
+		if err != nil {
+			logger.Log.Error("failed to upsert tag", zap.Error(err))
+			return nil, common.ErrInternalServerError
+		}
+		id, err := res.LastInsertId()
+		if err != nil {
+			logger.Log.Error("failed to get last insert id", zap.Error(err))
+			return nil, common.ErrInternalServerError
+		}
+		err = tx.GetTx().QueryRowContext(ctx, "SELECT id, name, slug FROM tags WHERE id = ?", id).Scan(&tag.ID, &tag.Name, &tag.Slug)
+		if err != nil {
+			logger.Log.Error("failed to select tag", zap.Error(err))
+			return nil, common.ErrInternalServerError
+		}
+		result = append(result, tag)
+	}
+	return result, nil
+}
+
+// SetPostTags implements domain.TagRepository.
+func (repository *TagRepositoryMySQL) SetPostTags(ctx context.Context, tx domain.Transaction, postID int64, tags []domain.Tag) error {
+	if _, err := tx.GetTx().ExecContext(ctx, "DELETE FROM post_tags WHERE post_id = ?", postID); err != nil {
+		logger.Log.Error("failed to delete post tags", zap.Error(err))
+		return common.ErrInternalServerError
+	}
+	if len(tags) == 0 {
+		return nil
+	}
+	values := make([]string, 0, len(tags))
+	args := make([]interface{}, 0, len(tags)*2)
+	for _, tag := range tags {
+		values = append(values, "(?, ?)")
+		args = append(args, postID, tag.ID)
+	}
+	query := "INSERT INTO post_tags (post_id, tag_id) VALUES " + strings.Join(values, ", ")
+	if _, err := tx.GetTx().ExecContext(ctx, query, args...); err != nil {
+		logger.Log.Error("failed to insert post tags", zap.Error(err))
+		return common.ErrInternalServerError
+	}
+	return nil
+}
+
+// FindByPostIDs implements domain.TagRepository.
+func (repository *TagRepositoryMySQL) FindByPostIDs(ctx context.Context, postIDs []int64) (map[int64][]domain.Tag, error) {
+	tags := make(map[int64][]domain.Tag, len(postIDs))
+	if len(postIDs) == 0 {
+		return tags, nil
+	}
+	placeholders, args := inClause(postIDs)
+	query := "SELECT pt.post_id, t.id, t.name, t.slug FROM post_tags pt JOIN tags t ON t.id = pt.tag_id WHERE pt.post_id IN (" + placeholders + ") ORDER BY t.name"
+	rows, err := repository.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		logger.Log.Error("failed to select post tags", zap.Error(err))
+		return nil, common.ErrInternalServerError
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var postID int64
+		var tag domain.Tag
+		if err := rows.Scan(&postID, &tag.ID, &tag.Name, &tag.Slug); err != nil {
+			logger.Log.Error("failed to scan post tag", zap.Error(err))
+			return nil, common.ErrInternalServerError
+		}
+		tags[postID] = append(tags[postID], tag)
+	}
+	return tags, nil
+}
+
+// GetBySlug implements domain.TagRepository.
+func (repository *TagRepositoryMySQL) GetBySlug(ctx context.Context, slug string) (*domain.Tag, error) {
+	var tag domain.Tag
+	err := repository.db.QueryRowContext(ctx, "SELECT id, name, slug FROM tags WHERE slug = ?", slug).Scan(&tag.ID, &tag.Name, &tag.Slug)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, common.ErrTagNotFound
+		}
+		logger.Log.Error("failed to select tag by slug", zap.Error(err))
+		return nil, common.ErrInternalServerError
+	}
+	return &tag, nil
+}
+
+// GetAllWithCount implements domain.TagRepository. Only published posts are
+// counted, so drafts do not give away their tags.
+func (repository *TagRepositoryMySQL) GetAllWithCount(ctx context.Context) ([]domain.TagCount, error) {
+	query := "SELECT t.id, t.name, t.slug, count(p.id) FROM tags t JOIN post_tags pt ON pt.tag_id = t.id JOIN posts p ON p.id = pt.post_id AND p.deleted_at IS NULL AND p.status = ? GROUP BY t.id, t.name, t.slug ORDER BY count(p.id) DESC, t.name"
+	rows, err := repository.db.QueryContext(ctx, query, domain.PostStatusPublished)
+	if err != nil {
+		logger.Log.Error("failed to select tags", zap.Error(err))
+		return nil, common.ErrInternalServerError
+	}
+	defer rows.Close()
+	tags := []domain.TagCount{}
+	for rows.Next() {
+		var tag domain.TagCount
+		if err := rows.Scan(&tag.ID, &tag.Name, &tag.Slug, &tag.PostCount); err != nil {
+			logger.Log.Error("failed to scan tag", zap.Error(err))
+			return nil, common.ErrInternalServerError
+		}
+		tags = append(tags, tag)
+	}
+	return tags, nil
+}
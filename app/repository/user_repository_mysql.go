@@ -6,6 +6,7 @@ import (
 	"app/pkg/logger"
 	"context"
 	"database/sql"
+	"strings"
 
 	"go.uber.org/zap"
 )
@@ -20,9 +21,16 @@ func NewUserRepositoryMySQL(sql *sql.DB) domain.UserRepository {
 	}
 }
 
-// Create implements domain.UserRepository.
+// Create implements domain.UserRepository. A username or email taken by a
+// concurrent registration fails like one taken before.
 func (repository *UserRepositoryMySQL) Create(ctx context.Context, tx domain.Transaction, user *domain.User) error {
-	result, err := tx.GetTx().ExecContext(ctx, "INSERT INTO users (email, name, password_hash) VALUES (?, ?, ?)", user.Email, user.Name, user.PasswordHash)
+	result, err := tx.GetTx().ExecContext(ctx, "INSERT INTO users (email, name, username, password_hash) VALUES (?, ?, ?, ?)", user.Email, user.Name, user.Username, user.PasswordHash)
+	if duplicateKeyOn(err, "index_username_table_users") {
+		return common.ErrUsernameAlreadyExists
+	}
+	if duplicateKey(err) {
+		return common.ErrEmailAlreadyExists
+	}
 	if err != nil {
 		logger.Log.Error("failed to insert user", zap.Error(err))
 		return common.ErrInternalServerError
@@ -36,10 +44,19 @@ func (repository *UserRepositoryMySQL) Create(ctx context.Context, tx domain.Tra
 	return nil
 }
 
+// SetRole implements domain.UserRepository.
+func (repository *UserRepositoryMySQL) SetRole(ctx context.Context, id int64, role string) error {
+	if _, err := repository.sql.ExecContext(ctx, "UPDATE users SET role = ? WHERE id = ?", role, id); err != nil {
+		logger.Log.Error("failed to update user role", zap.Error(err))
+		return common.ErrInternalServerError
+	}
+	return nil
+}
+
 // FindByEmail implements domain.UserRepository.
 func (repository *UserRepositoryMySQL) FindByEmail(ctx context.Context, email string) (*domain.User, error) {
 	var user domain.User
-	err := repository.sql.QueryRowContext(ctx, "SELECT id, name, email, password_hash, created_at, updated_at, deleted_at FROM users WHERE email = ? and deleted_at is NULL", email).Scan(&user.ID, &user.Name, &user.Email, &user.PasswordHash, &user.CreatedAt, &user.UpdatedAt, &user.DeletedAt)
+	err := repository.sql.QueryRowContext(ctx, "SELECT id, name, username, email, role, password_hash, created_at, updated_at, deleted_at FROM users WHERE email = ? and deleted_at is NULL", email).Scan(&user.ID, &user.Name, &user.Username, &user.Email, &user.Role, &user.PasswordHash, &user.CreatedAt, &user.UpdatedAt, &user.DeletedAt)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, common.ErrEmailNotFound
@@ -53,7 +70,7 @@ func (repository *UserRepositoryMySQL) FindByEmail(ctx context.Context, email st
 // FindByID implements domain.UserRepository.
 func (repository *UserRepositoryMySQL) FindByID(ctx context.Context, id int64) (*domain.User, error) {
 	var user domain.User
-	err := repository.sql.QueryRowContext(ctx, "SELECT id, name, email, password_hash FROM users WHERE id = ?", id).Scan(&user.ID, &user.Name, &user.Email, &user.PasswordHash)
+	err := repository.sql.QueryRowContext(ctx, "SELECT id, name, username, email, role, password_hash FROM users WHERE id = ?", id).Scan(&user.ID, &user.Name, &user.Username, &user.Email, &user.Role, &user.PasswordHash)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, common.ErrUserNotFound
@@ -63,3 +80,55 @@ func (repository *UserRepositoryMySQL) FindByID(ctx context.Context, id int64) (
 	}
 	return &user, nil
 }
+
+// FindByIDs implements domain.UserRepository.
+func (repository *UserRepositoryMySQL) FindByIDs(ctx context.Context, ids []int64) ([]domain.User, error) {
+	var users []domain.User
+	if len(ids) == 0 {
+		return users, nil
+	}
+	placeholders, args := inClause(ids)
+	rows, err := repository.sql.QueryContext(ctx, "SELECT id, name, username FROM users WHERE id IN ("+placeholders+")", args...)
+	if err != nil {
+		logger.Log.Error("failed to select users by id", zap.Error(err))
+		return nil, common.ErrInternalServerError
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var user domain.User
+		if err := rows.Scan(&user.ID, &user.Name, &user.Username); err != nil {
+			logger.Log.Error("failed to scan user", zap.Error(err))
+			return nil, common.ErrInternalServerError
+		}
+		users = append(users, user)
+	}
+	return users, nil
+}
+
+// FindByUsernames implements domain.UserRepository.
+func (repository *UserRepositoryMySQL) FindByUsernames(ctx context.Context, usernames []string) ([]domain.User, error) {
+	var users []domain.User
+	if len(usernames) == 0 {
+		return users, nil
+	}
+	args := make([]interface{}, len(usernames))
+	for i, username := range usernames {
+		args[i] = username
+	}
+	placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(usernames)), ", ")
+	rows, err := repository.sql.QueryContext(ctx, "SELECT id, name, username FROM users WHERE username IN ("+placeholders+")", args...)
+	if err != nil {
+		logger.Log.Error("failed to select users by username", zap.Error(err))
+		return nil, common.ErrInternalServerError
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var user domain.User
+		if err := rows.Scan(&user.ID, &user.Name, &user.Username); err != nil {
+			logger.Log.Error("failed to scan user", zap.Error(err))
+			return nil, common.ErrInternalServerError
+		}
+		users = append(users, user)
+	}
+	return users, nil
+}
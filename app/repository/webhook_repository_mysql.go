@@ -0,0 +1,243 @@
+package repository
+
+import (
+	"app/domain"
+	"app/pkg/common"
+	"app/pkg/logger"
+	"context"
+	"database/sql"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+const (
+	webhookColumns  = "id, url, secret, events, enabled, failure_count, disabled_at, created_at, updated_at"
+	deliveryColumns = "id, webhook_id, event, payload, status, attempts, response_status, last_error, next_attempt_at, delivered_at, created_at"
+)
+
+type WebhookRepositoryMySQL struct {
+	db *sql.DB
+}
+
+func NewWebhookRepositoryMySQL(db *sql.DB) domain.WebhookRepository {
+	return &WebhookRepositoryMySQL{db: db}
+}
+
+// Create implements domain.WebhookRepository.
+func (repository *WebhookRepositoryMySQL) Create(ctx context.Context, webhook *domain.Webhook) error {
+	result, err := repository.db.ExecContext(ctx, "INSERT INTO webhooks (url, secret, events, enabled, created_at) VALUES (?, ?, ?, ?, ?)", webhook.URL, webhook.Secret, strings.Join(webhook.Events, ","), webhook.Enabled, webhook.CreatedAt)
+	if err != nil {
+		logger.Log.Error("failed to insert webhook", zap.Error(err))
+		return common.ErrInternalServerError
+	}
+	webhook.ID, err = result.LastInsertId()
+	if err != nil {
+		logger.Log.Error("failed to get last insert id", zap.Error(err))
+		return common.ErrInternalServerError
+	}
+	return nil
+}
+
+// Update implements domain.WebhookRepository.
+func (repository *WebhookRepositoryMySQL) Update(ctx context.Context, webhook *domain.Webhook) error {
+	_, err := repository.db.ExecContext(ctx, "UPDATE webhooks SET url = ?, events = ?, enabled = ?, failure_count = ?, disabled_at = ?, updated_at = ? WHERE id = ?", webhook.URL, strings.Join(webhook.Events, ","), webhook.Enabled, webhook.FailureCount, webhook.DisabledAt, webhook.UpdatedAt, webhook.ID)
+	if err != nil {
+		logger.Log.Error("failed to update webhook", zap.Error(err))
+		return common.ErrInternalServerError
+	}
+	return nil
+}
+
+// Delete implements domain.WebhookRepository. The deliveries of the webhook
+// go with it.
+func (repository *WebhookRepositoryMySQL) Delete(ctx context.Context, id int64) (bool, error) {
+	result, err := repository.db.ExecContext(ctx, "DELETE FROM webhooks WHERE id = ?", id)
+	if err != nil {
+		logger.Log.Error("failed to delete webhook", zap.Error(err))
+		return false, common.ErrInternalServerError
+	}
+	return affected(result)
+}
+
+// FindByID implements domain.WebhookRepository.
+func (repository *WebhookRepositoryMySQL) FindByID(ctx context.Context, id int64) (*domain.Webhook, error) {
+	var webhook domain.Webhook
+	err := scanWebhook(repository.db.QueryRowContext(ctx, "SELECT "+webhookColumns+" FROM webhooks WHERE id = ?", id), &webhook)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, common.ErrWebhookNotFound
+		}
+		logger.Log.Error("failed to select webhook", zap.Error(err))
+		return nil, common.ErrInternalServerError
+	}
+	return &webhook, nil
+}
+
+// FindAll implements domain.WebhookRepository.
+func (repository *WebhookRepositoryMySQL) FindAll(ctx context.Context) ([]domain.Webhook, error) {
+	rows, err := repository.db.QueryContext(ctx, "SELECT "+webhookColumns+" FROM webhooks ORDER BY id")
+	if err != nil {
+		logger.Log.Error("failed to select webhooks", zap.Error(err))
+		return nil, common.ErrInternalServerError
+	}
+	defer rows.Close()
+	webhooks := []domain.Webhook{}
+	for rows.Next() {
+		var webhook domain.Webhook
+		if err := scanWebhook(rows, &webhook); err != nil {
+			logger.Log.Error("failed to scan webhook", zap.Error(err))
+			return nil, common.ErrInternalServerError
+		}
+		webhooks = append(webhooks, webhook)
+	}
+	return webhooks, nil
+}
+
+// CreateDeliveries implements domain.WebhookRepository.
+func (repository *WebhookRepositoryMySQL) CreateDeliveries(ctx context.Context, deliveries []domain.WebhookDelivery) error {
+	for i := range deliveries {
+		delivery := &deliveries[i]
+		result, err := repository.db.ExecContext(ctx, "INSERT INTO webhook_deliveries (webhook_id, event, payload, status, next_attempt_at, created_at) VALUES (?, ?, ?, ?, ?, ?)", delivery.WebhookID, delivery.Event, string(delivery.Payload), delivery.Status, delivery.NextAttemptAt, delivery.CreatedAt)
+		if err != nil {
+			logger.Log.Error("failed to insert webhook delivery", zap.Error(err))
+			return common.ErrInternalServerError
+		}
+		delivery.ID, err = result.LastInsertId()
+		if err != nil {
+			logger.Log.Error("failed to get last insert id", zap.Error(err))
+			return common.ErrInternalServerError
+		}
+	}
+	return nil
+}
+
+// FindDeliveries implements domain.WebhookRepository. Deliveries are listed
+// newest first.
+func (repository *WebhookRepositoryMySQL) FindDeliveries(ctx context.Context, webhookID int64, param domain.SearchParam) ([]domain.WebhookDelivery, int64, error) {
+	var total int64
+	if err := repository.db.QueryRowContext(ctx, "SELECT count(*) FROM webhook_deliveries WHERE webhook_id = ?", webhookID).Scan(&total); err != nil {
+		logger.Log.Error("failed to count webhook deliveries", zap.Error(err))
+		return nil, 0, common.ErrInternalServerError
+	}
+	rows, err := repository.db.QueryContext(ctx, "SELECT "+deliveryColumns+" FROM webhook_deliveries WHERE webhook_id = ? ORDER BY created_at DESC, id DESC LIMIT ? OFFSET ?", webhookID, param.Limit, (param.Page-1)*param.Limit)
+	if err != nil {
+		logger.Log.Error("failed to select webhook deliveries", zap.Error(err))
+		return nil, 0, common.ErrInternalServerError
+	}
+	defer rows.Close()
+	deliveries, err := scanDeliveries(rows)
+	if err != nil {
+		return nil, 0, err
+	}
+	return deliveries, total, nil
+}
+
+// FindDelivery implements domain.WebhookRepository.
+func (repository *WebhookRepositoryMySQL) FindDelivery(ctx context.Context, webhookID, id int64) (*domain.WebhookDelivery, error) {
+	var delivery domain.WebhookDelivery
+	err := scanDelivery(repository.db.QueryRowContext(ctx, "SELECT "+deliveryColumns+" FROM webhook_deliveries WHERE id = ? AND webhook_id = ?", id, webhookID), &delivery)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, common.ErrWebhookDeliveryNotFound
+		}
+		logger.Log.Error("failed to select webhook delivery", zap.Error(err))
+		return nil, common.ErrInternalServerError
+	}
+	return &delivery, nil
+}
+
+// ClaimDeliveries implements domain.WebhookRepository. Rows locked by
+// another worker are skipped rather than waited for.
+func (repository *WebhookRepositoryMySQL) ClaimDeliveries(ctx context.Context, tx domain.Transaction, limit int, until time.Time) ([]domain.WebhookDelivery, error) {
+	query := "SELECT " + deliveryColumns + " FROM webhook_deliveries WHERE status = ? AND next_attempt_at <= ? AND webhook_id IN (SELECT id FROM webhooks WHERE enabled) ORDER BY next_attempt_at, id LIMIT ? FOR UPDATE SKIP LOCKED"
+	rows, err := tx.GetTx().QueryContext(ctx, query, domain.WebhookDeliveryPending, time.Now(), limit)
+	if err != nil {
+		logger.Log.Error("failed to select due webhook deliveries", zap.Error(err))
+		return nil, common.ErrInternalServerError
+	}
+	deliveries, err := scanDeliveries(rows)
+	rows.Close()
+	if err != nil || len(deliveries) == 0 {
+		return deliveries, err
+	}
+	ids := make([]int64, len(deliveries))
+	for i := range deliveries {
+		ids[i] = deliveries[i].ID
+		deliveries[i].NextAttemptAt = &until
+	}
+	placeholders, args := inClause(ids)
+	if _, err := tx.GetTx().ExecContext(ctx, "UPDATE webhook_deliveries SET next_attempt_at = ? WHERE id IN ("+placeholders+")", append([]interface{}{until}, args...)...); err != nil {
+		logger.Log.Error("failed to claim webhook deliveries", zap.Error(err))
+		return nil, common.ErrInternalServerError
+	}
+	return deliveries, nil
+}
+
+// SaveAttempt implements domain.WebhookRepository.
+func (repository *WebhookRepositoryMySQL) SaveAttempt(ctx context.Context, delivery *domain.WebhookDelivery) error {
+	_, err := repository.db.ExecContext(ctx, "UPDATE webhook_deliveries SET status = ?, attempts = ?, response_status = ?, last_error = ?, next_attempt_at = ?, delivered_at = ? WHERE id = ?", delivery.Status, delivery.Attempts, delivery.ResponseStatus, delivery.Error, delivery.NextAttemptAt, delivery.DeliveredAt, delivery.ID)
+	if err != nil {
+		logger.Log.Error("failed to save webhook delivery attempt", zap.Error(err))
+		return common.ErrInternalServerError
+	}
+	return nil
+}
+
+// ResetFailures implements domain.WebhookRepository.
+func (repository *WebhookRepositoryMySQL) ResetFailures(ctx context.Context, webhookID int64) error {
+	if _, err := repository.db.ExecContext(ctx, "UPDATE webhooks SET failure_count = 0 WHERE id = ? AND failure_count > 0", webhookID); err != nil {
+		logger.Log.Error("failed to reset webhook failures", zap.Error(err))
+		return common.ErrInternalServerError
+	}
+	return nil
+}
+
+// RecordFailure implements domain.WebhookRepository. MySQL assigns from left
+// to right, so the enabled flag is computed from the count before it is
+// incremented.
+func (repository *WebhookRepositoryMySQL) RecordFailure(ctx context.Context, webhookID int64, disableAfter int) (bool, error) {
+	_, err := repository.db.ExecContext(ctx, "UPDATE webhooks SET disabled_at = IF(enabled AND failure_count + 1 >= ?, ?, disabled_at), enabled = enabled AND failure_count + 1 < ?, failure_count = failure_count + 1 WHERE id = ?", disableAfter, time.Now(), disableAfter, webhookID)
+	if err != nil {
+		logger.Log.Error("failed to record webhook failure", zap.Error(err))
+		return false, common.ErrInternalServerError
+	}
+	var enabled bool
+	if err := repository.db.QueryRowContext(ctx, "SELECT enabled FROM webhooks WHERE id = ?", webhookID).Scan(&enabled); err != nil && err != sql.ErrNoRows {
+		logger.Log.Error("failed to select webhook", zap.Error(err))
+		return false, common.ErrInternalServerError
+	}
+	return !enabled, nil
+}
+
+func scanWebhook(row rowScanner, webhook *domain.Webhook) error {
+	var events string
+	if err := row.Scan(&webhook.ID, &webhook.URL, &webhook.Secret, &events, &webhook.Enabled, &webhook.FailureCount, &webhook.DisabledAt, &webhook.CreatedAt, &webhook.UpdatedAt); err != nil {
+		return err
+	}
+	webhook.Events = strings.Split(events, ",")
+	return nil
+}
+
+func scanDelivery(row rowScanner, delivery *domain.WebhookDelivery) error {
+	var payload string
+	if err := row.Scan(&delivery.ID, &delivery.WebhookID, &delivery.Event, &payload, &delivery.Status, &delivery.Attempts, &delivery.ResponseStatus, &delivery.Error, &delivery.NextAttemptAt, &delivery.DeliveredAt, &delivery.CreatedAt); err != nil {
+		return err
+	}
+	delivery.Payload = []byte(payload)
+	return nil
+}
+
+func scanDeliveries(rows *sql.Rows) ([]domain.WebhookDelivery, error) {
+	deliveries := []domain.WebhookDelivery{}
+	for rows.Next() {
+		var delivery domain.WebhookDelivery
+		if err := scanDelivery(rows, &delivery); err != nil {
+			logger.Log.Error("failed to scan webhook delivery", zap.Error(err))
+			return nil, common.ErrInternalServerError
+		}
+		deliveries = append(deliveries, delivery)
+	}
+	return deliveries, nil
+}
@@ -6,6 +6,7 @@ import (
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -14,6 +15,7 @@ import (
 func registerUser(t *testing.T, name, email string, password string) int {
 	registerRequest := &domain.RegisterRequestDTO{
 		Name:     name,
+		Username: strings.Split(email, "@")[0],
 		Email:    email,
 		Password: password,
 	}
@@ -0,0 +1,131 @@
+package test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBookmarks(t *testing.T) {
+	registerUser(t, "reader", "reader@email.com", "password")
+	cookie := loginUser(t, "reader@email.com", "password")
+	first := createPost(t, cookie, "Read later one", "content")
+	second := createPost(t, cookie, "Read later two", "content")
+	third := createPost(t, cookie, "Read later three", "content")
+
+	save := func(postID int64, body interface{}) {
+		req := authorizedRequest(t, "PUT", fmt.Sprintf("/me/bookmarks/%d", postID), cookie, body)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusOK, w.Code)
+	}
+	save(first, nil)
+	save(second, map[string]string{"collection": "recipes"})
+	save(third, map[string]string{"collection": "recipes"})
+	save(third, map[string]string{"collection": "recipes"})
+
+	type bookmarkPage struct {
+		NextCursor *string `json:"next_cursor"`
+		Data       []struct {
+			PostID     int64   `json:"post_id"`
+			Collection *string `json:"collection"`
+			Post       struct {
+				Title string `json:"title"`
+			} `json:"post"`
+		} `json:"data"`
+	}
+	list := func(query string) bookmarkPage {
+		req := authorizedRequest(t, "GET", "/me/bookmarks?"+query, cookie, nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusOK, w.Code)
+		var page bookmarkPage
+		assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &page))
+		return page
+	}
+
+	t.Run("cursor pagination", func(t *testing.T) {
+		page := list("limit=2")
+		assert.Len(t, page.Data, 2)
+		assert.NotNil(t, page.NextCursor)
+		next := list("limit=2&after=" + url.QueryEscape(*page.NextCursor))
+		assert.Len(t, next.Data, 1)
+		assert.Nil(t, next.NextCursor)
+		var ids []int64
+		for _, bookmark := range append(page.Data, next.Data...) {
+			ids = append(ids, bookmark.PostID)
+		}
+		assert.ElementsMatch(t, []int64{first, second, third}, ids)
+	})
+
+	t.Run("collections", func(t *testing.T) {
+		page := list("collection=recipes")
+		assert.Len(t, page.Data, 2)
+		for _, bookmark := range page.Data {
+			assert.Equal(t, "recipes", *bookmark.Collection)
+			assert.NotEmpty(t, bookmark.Post.Title)
+		}
+
+		req := authorizedRequest(t, "GET", "/me/collections", cookie, nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusOK, w.Code)
+		var response struct {
+			Data []struct {
+				Name          string `json:"name"`
+				BookmarkCount int64  `json:"bookmark_count"`
+			} `json:"data"`
+		}
+		assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &response))
+		assert.Len(t, response.Data, 1)
+		assert.Equal(t, int64(2), response.Data[0].BookmarkCount)
+	})
+
+	t.Run("unpublished posts are left out", func(t *testing.T) {
+		registerUser(t, "retractor", "retractor@email.com", "password")
+		authorCookie := loginUser(t, "retractor@email.com", "password")
+		postID := createPost(t, authorCookie, "Soon retracted", "content")
+		save(postID, nil)
+		assert.Len(t, list("").Data, 4)
+
+		req := authorizedRequest(t, "PATCH", fmt.Sprintf("/posts/%d", postID), authorCookie, map[string]string{"status": "draft"})
+		req.Header.Set("Content-Type", "application/merge-patch+json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusOK, w.Code)
+		page := list("")
+		assert.Len(t, page.Data, 3)
+		for _, bookmark := range page.Data {
+			assert.NotEqual(t, postID, bookmark.PostID)
+		}
+	})
+
+	t.Run("bookmarked flag for the signed in reader", func(t *testing.T) {
+		bookmarked := func(cookie string) *bool {
+			req := authorizedRequest(t, "GET", fmt.Sprintf("/posts/%d", first), cookie, nil)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			assert.Equal(t, http.StatusOK, w.Code)
+			var response struct {
+				Data struct {
+					Bookmarked *bool `json:"bookmarked"`
+				} `json:"data"`
+			}
+			assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &response))
+			return response.Data.Bookmarked
+		}
+		assert.Nil(t, bookmarked(""))
+		assert.True(t, *bookmarked(cookie))
+
+		req := authorizedRequest(t, "DELETE", fmt.Sprintf("/me/bookmarks/%d", first), cookie, nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.False(t, *bookmarked(cookie))
+	})
+}
@@ -0,0 +1,155 @@
+package test
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type streamEvent struct {
+	ID   string
+	Type string
+	Data string
+}
+
+// readEvent reads the next event of a Server-Sent Events stream, skipping
+// heartbeats.
+func readEvent(t *testing.T, reader *bufio.Reader) streamEvent {
+	var event streamEvent
+	for {
+		line, err := reader.ReadString('\n')
+		if !assert.Nil(t, err) {
+			return event
+		}
+		line = strings.TrimRight(line, "\n")
+		switch {
+		case line == "" && event.Type != "":
+			return event
+		case strings.HasPrefix(line, "id:"):
+			event.ID = strings.TrimSpace(strings.TrimPrefix(line, "id:"))
+		case strings.HasPrefix(line, "event:"):
+			event.Type = strings.TrimSpace(strings.TrimPrefix(line, "event:"))
+		case strings.HasPrefix(line, "data:"):
+			event.Data = strings.TrimSpace(strings.TrimPrefix(line, "data:"))
+		}
+	}
+}
+
+func TestCommentEditing(t *testing.T) {
+	registerUser(t, "annotator", "annotator@email.com", "password")
+	cookie := loginUser(t, "annotator@email.com", "password")
+	registerUser(t, "meddler", "meddler@email.com", "password")
+	otherCookie := loginUser(t, "meddler@email.com", "password")
+	postID := createPost(t, cookie, "Annotated", "content")
+
+	request := func(method, url, cookie string, body interface{}) *httptest.ResponseRecorder {
+		req := authorizedRequest(t, method, url, cookie, body)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+	w := request("POST", fmt.Sprintf("/posts/%d/comments", postID), cookie, map[string]string{"content": "Frist"})
+	assert.Equal(t, http.StatusCreated, w.Code)
+	var created struct {
+		Data struct {
+			ID int64 `json:"id"`
+		} `json:"data"`
+	}
+	assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &created))
+	url := fmt.Sprintf("/posts/%d/comments/%d", postID, created.Data.ID)
+
+	t.Run("only the author edits", func(t *testing.T) {
+		assert.Equal(t, http.StatusForbidden, request("PUT", url, otherCookie, map[string]string{"content": "Mine"}).Code)
+		w := request("PUT", url, cookie, map[string]string{"content": "First"})
+		assert.Equal(t, http.StatusOK, w.Code)
+		var response struct {
+			Data struct {
+				Content   string  `json:"content"`
+				UpdatedAt *string `json:"updated_at"`
+			} `json:"data"`
+		}
+		assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &response))
+		assert.Equal(t, "First", response.Data.Content)
+		assert.NotNil(t, response.Data.UpdatedAt)
+	})
+
+	t.Run("only the author deletes", func(t *testing.T) {
+		assert.Equal(t, http.StatusForbidden, request("DELETE", url, otherCookie, nil).Code)
+		assert.Equal(t, http.StatusOK, request("DELETE", url, cookie, nil).Code)
+		assert.Equal(t, http.StatusNotFound, request("DELETE", url, cookie, nil).Code)
+
+		w := request("GET", fmt.Sprintf("/posts/%d/comments", postID), "", nil)
+		var response struct {
+			Total int64 `json:"total"`
+		}
+		assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &response))
+		assert.Equal(t, int64(0), response.Total)
+	})
+}
+
+func TestCommentStream(t *testing.T) {
+	registerUser(t, "streamer", "streamer@email.com", "password")
+	cookie := loginUser(t, "streamer@email.com", "password")
+	postID := createPost(t, cookie, "Live", "content")
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	subscribe := func(lastEventID string) (*bufio.Reader, context.CancelFunc) {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		req, err := http.NewRequestWithContext(ctx, "GET", fmt.Sprintf("%s/posts/%d/comments/stream", server.URL, postID), nil)
+		assert.Nil(t, err)
+		if lastEventID != "" {
+			req.Header.Set("Last-Event-ID", lastEventID)
+		}
+		res, err := http.DefaultClient.Do(req)
+		if !assert.Nil(t, err) {
+			t.FailNow()
+		}
+		assert.Equal(t, http.StatusOK, res.StatusCode)
+		assert.Contains(t, res.Header.Get("Content-Type"), "text/event-stream")
+		return bufio.NewReader(res.Body), func() {
+			cancel()
+			res.Body.Close()
+		}
+	}
+	request := func(method, url string, body interface{}) *httptest.ResponseRecorder {
+		req := authorizedRequest(t, method, url, cookie, body)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	reader, cancel := subscribe("")
+	w := request("POST", fmt.Sprintf("/posts/%d/comments", postID), map[string]string{"content": "Hello live"})
+	assert.Equal(t, http.StatusCreated, w.Code)
+	created := readEvent(t, reader)
+	assert.Equal(t, "comment.created", created.Type)
+	var comment struct {
+		ID      int64  `json:"id"`
+		Content string `json:"content"`
+	}
+	assert.Nil(t, json.Unmarshal([]byte(created.Data), &comment))
+	assert.Equal(t, "Hello live", comment.Content)
+	cancel()
+
+	url := fmt.Sprintf("/posts/%d/comments/%d", postID, comment.ID)
+	assert.Equal(t, http.StatusOK, request("PUT", url, map[string]string{"content": "Hello again"}).Code)
+	assert.Equal(t, http.StatusOK, request("DELETE", url, nil).Code)
+
+	reader, cancel = subscribe(created.ID)
+	defer cancel()
+	updated := readEvent(t, reader)
+	assert.Equal(t, "comment.updated", updated.Type)
+	assert.Contains(t, updated.Data, "Hello again")
+	deleted := readEvent(t, reader)
+	assert.Equal(t, "comment.deleted", deleted.Type)
+	assert.Equal(t, fmt.Sprintf(`{"id":%d,"post_id":%d}`, comment.ID, postID), deleted.Data)
+}
@@ -0,0 +1,99 @@
+package test
+
+import (
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFeed(t *testing.T) {
+	userID := registerUser(t, "syndicator", "syndicator@email.com", "password")
+	cookie := loginUser(t, "syndicator@email.com", "password")
+	req := authorizedRequest(t, "POST", "/posts", cookie, map[string]interface{}{
+		"title":   "Feed reader",
+		"content": "<p>Subscribe to this blog</p>",
+		"tags":    []string{"Syndication"},
+	})
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusCreated, w.Code)
+
+	type atomFeed struct {
+		Title   string `xml:"title"`
+		Entries []struct {
+			ID      string `xml:"id"`
+			Title   string `xml:"title"`
+			Summary string `xml:"summary"`
+			Content string `xml:"content"`
+			Link    struct {
+				Href string `xml:"href,attr"`
+			} `xml:"link"`
+		} `xml:"entry"`
+	}
+	getFeed := func(url string) *httptest.ResponseRecorder {
+		req := authorizedRequest(t, "GET", url, "", nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	t.Run("atom feed per author with excerpts", func(t *testing.T) {
+		w := getFeed(fmt.Sprintf("/users/%d/feed.atom", userID))
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Contains(t, w.Header().Get("Content-Type"), "application/atom+xml")
+		var feed atomFeed
+		assert.Nil(t, xml.Unmarshal(w.Body.Bytes(), &feed))
+		assert.Equal(t, "Posts by syndicator", feed.Title)
+		assert.Len(t, feed.Entries, 1)
+		assert.Equal(t, "Subscribe to this blog", feed.Entries[0].Summary)
+		assert.Empty(t, feed.Entries[0].Content)
+		assert.Equal(t, "http://example.com/posts/by-slug/feed-reader", feed.Entries[0].Link.Href)
+	})
+
+	t.Run("full content per tag", func(t *testing.T) {
+		w := getFeed("/tags/syndication/feed.atom?content=full")
+		assert.Equal(t, http.StatusOK, w.Code)
+		var feed atomFeed
+		assert.Nil(t, xml.Unmarshal(w.Body.Bytes(), &feed))
+		assert.Len(t, feed.Entries, 1)
+		assert.Equal(t, "<p>Subscribe to this blog</p>", feed.Entries[0].Content)
+	})
+
+	t.Run("rss feed", func(t *testing.T) {
+		w := getFeed("/feed.rss")
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Contains(t, w.Header().Get("Content-Type"), "application/rss+xml")
+		var feed struct {
+			Items []struct {
+				Title string `xml:"title"`
+			} `xml:"channel>item"`
+		}
+		assert.Nil(t, xml.Unmarshal(w.Body.Bytes(), &feed))
+		assert.NotEmpty(t, feed.Items)
+	})
+
+	t.Run("conditional requests", func(t *testing.T) {
+		w := getFeed("/feed.atom")
+		assert.Equal(t, http.StatusOK, w.Code)
+		etag := w.Header().Get("ETag")
+		lastModified := w.Header().Get("Last-Modified")
+		assert.NotEmpty(t, etag)
+		assert.NotEmpty(t, lastModified)
+
+		req := authorizedRequest(t, "GET", "/feed.atom", "", nil)
+		req.Header.Set("If-None-Match", etag)
+		w = httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusNotModified, w.Code)
+
+		req = authorizedRequest(t, "GET", "/feed.atom", "", nil)
+		req.Header.Set("If-Modified-Since", lastModified)
+		w = httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusNotModified, w.Code)
+	})
+}
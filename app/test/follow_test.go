@@ -0,0 +1,87 @@
+package test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFollow(t *testing.T) {
+	authorID := registerUser(t, "followed", "followed@email.com", "password")
+	authorCookie := loginUser(t, "followed@email.com", "password")
+	followerID := registerUser(t, "follower", "follower@email.com", "password")
+	cookie := loginUser(t, "follower@email.com", "password")
+	registerUser(t, "ignored", "ignored@email.com", "password")
+	ignoredCookie := loginUser(t, "ignored@email.com", "password")
+
+	request := func(method, url, cookie string) *httptest.ResponseRecorder {
+		req := authorizedRequest(t, method, url, cookie, nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+	assert.Equal(t, http.StatusOK, request("PUT", fmt.Sprintf("/users/%d/follow", authorID), cookie).Code)
+	assert.Equal(t, http.StatusOK, request("PUT", fmt.Sprintf("/users/%d/follow", authorID), cookie).Code)
+	assert.Equal(t, http.StatusBadRequest, request("PUT", fmt.Sprintf("/users/%d/follow", followerID), cookie).Code)
+
+	t.Run("follower and following lists", func(t *testing.T) {
+		var response struct {
+			Total int64 `json:"total"`
+			Data  []struct {
+				ID   int64  `json:"id"`
+				Name string `json:"name"`
+			} `json:"data"`
+		}
+		w := request("GET", fmt.Sprintf("/users/%d/followers", authorID), "")
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &response))
+		assert.Equal(t, int64(1), response.Total)
+		assert.Equal(t, "follower", response.Data[0].Name)
+
+		w = request("GET", fmt.Sprintf("/users/%d/following", followerID), "")
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &response))
+		assert.Equal(t, int64(1), response.Total)
+		assert.Equal(t, int64(authorID), response.Data[0].ID)
+	})
+
+	t.Run("home feed", func(t *testing.T) {
+		first := createPost(t, authorCookie, "Followed one", "content")
+		second := createPost(t, authorCookie, "Followed two", "content")
+		createPost(t, ignoredCookie, "Not followed", "content")
+
+		type feedPage struct {
+			NextCursor *string `json:"next_cursor"`
+			Data       []struct {
+				ID     int64 `json:"id"`
+				Author struct {
+					Name string `json:"name"`
+				} `json:"author"`
+			} `json:"data"`
+		}
+		var page feedPage
+		w := request("GET", "/me/feed?limit=1", cookie)
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &page))
+		assert.Len(t, page.Data, 1)
+		assert.Equal(t, second, page.Data[0].ID)
+		assert.Equal(t, "followed", page.Data[0].Author.Name)
+
+		w = request("GET", "/me/feed?limit=1&after="+url.QueryEscape(*page.NextCursor), cookie)
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &page))
+		assert.Len(t, page.Data, 1)
+		assert.Equal(t, first, page.Data[0].ID)
+		assert.Nil(t, page.NextCursor)
+
+		assert.Equal(t, http.StatusOK, request("DELETE", fmt.Sprintf("/users/%d/follow", authorID), cookie).Code)
+		w = request("GET", "/me/feed", cookie)
+		assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &page))
+		assert.Empty(t, page.Data)
+	})
+}
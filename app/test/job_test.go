@@ -0,0 +1,173 @@
+package test
+
+import (
+	"app/domain"
+	"app/repository"
+	"app/usecase"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestJobs(t *testing.T) {
+	adminID := registerUser(t, "foreman", "foreman@email.com", "password")
+	_, err := db.Exec("UPDATE users SET role = 'admin' WHERE id = ?", adminID)
+	assert.Nil(t, err)
+	adminCookie := loginUser(t, "foreman@email.com", "password")
+	registerUser(t, "laborer", "laborer@email.com", "password")
+	cookie := loginUser(t, "laborer@email.com", "password")
+
+	request := func(method, url, cookie string) *httptest.ResponseRecorder {
+		req := authorizedRequest(t, method, url, cookie, nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+	type jobResponse struct {
+		Data struct {
+			ID       int64   `json:"id"`
+			Type     string  `json:"type"`
+			Status   string  `json:"status"`
+			Attempts int     `json:"attempts"`
+			Error    *string `json:"error"`
+		} `json:"data"`
+	}
+	findJob := func(id int64) jobResponse {
+		w := request("GET", fmt.Sprintf("/admin/jobs/%d", id), adminCookie)
+		assert.Equal(t, http.StatusOK, w.Code)
+		var job jobResponse
+		assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &job))
+		return job
+	}
+
+	// Jobs are run by a worker, which is driven by hand here with handlers
+	// of its own.
+	transactor := repository.NewSQLTransactor(db)
+	worker := usecase.NewJobUseCaseImpl(repository.NewJobRepositoryMySQL(db), transactor)
+	type greeting struct {
+		Name string `json:"name"`
+	}
+	var greeted []string
+	worker.Register("test.greet", usecase.JobFunc(func(ctx context.Context, payload greeting) error {
+		greeted = append(greeted, payload.Name)
+		return nil
+	}))
+	broken := true
+	worker.Register("test.broken", usecase.JobFunc(func(ctx context.Context, payload greeting) error {
+		if broken {
+			panic("boom")
+		}
+		return nil
+	}))
+	enqueue := func(jobType string, commit bool) int64 {
+		tx, err := transactor.Begin()
+		assert.Nil(t, err)
+		defer tx.Rollback()
+		assert.Nil(t, worker.Enqueue(context.Background(), tx, jobType, greeting{Name: "world"}, time.Now()))
+		if commit {
+			assert.Nil(t, tx.Commit())
+		}
+		var id int64
+		err = db.QueryRow("SELECT COALESCE(MAX(id), 0) FROM jobs WHERE type = ?", jobType).Scan(&id)
+		assert.Nil(t, err)
+		return id
+	}
+	work := func() int {
+		worked, err := worker.Work(context.Background())
+		assert.Nil(t, err)
+		return worked
+	}
+	makeDue := func() {
+		_, err := db.Exec("UPDATE jobs SET run_at = ? WHERE status = 'pending'", time.Now())
+		assert.Nil(t, err)
+	}
+
+	t.Run("admins only", func(t *testing.T) {
+		assert.Equal(t, http.StatusUnauthorized, request("GET", "/admin/jobs", "").Code)
+		assert.Equal(t, http.StatusForbidden, request("GET", "/admin/jobs", cookie).Code)
+		assert.Equal(t, http.StatusOK, request("GET", "/admin/jobs", adminCookie).Code)
+		assert.Equal(t, http.StatusBadRequest, request("GET", "/admin/jobs?status=lost", adminCookie).Code)
+		assert.Equal(t, http.StatusNotFound, request("GET", "/admin/jobs/999999", adminCookie).Code)
+	})
+
+	t.Run("run once committed", func(t *testing.T) {
+		enqueue("test.greet", false)
+		assert.Zero(t, work())
+		id := enqueue("test.greet", true)
+		assert.Equal(t, 1, work())
+		assert.Equal(t, []string{"world"}, greeted)
+		job := findJob(id)
+		assert.Equal(t, "succeeded", job.Data.Status)
+		assert.Equal(t, 1, job.Data.Attempts)
+		assert.Zero(t, work())
+	})
+
+	t.Run("dead after retries", func(t *testing.T) {
+		id := enqueue("test.broken", true)
+		assert.Equal(t, 1, work())
+		job := findJob(id)
+		assert.Equal(t, "pending", job.Data.Status)
+		assert.Equal(t, "panic: boom", *job.Data.Error)
+		// The retry is backed off.
+		assert.Zero(t, work())
+		for i := 1; i < 5; i++ {
+			makeDue()
+			assert.Equal(t, 1, work())
+		}
+		job = findJob(id)
+		assert.Equal(t, "dead", job.Data.Status)
+		assert.Equal(t, 5, job.Data.Attempts)
+		makeDue()
+		assert.Zero(t, work())
+
+		w := request("GET", "/admin/jobs?status=dead&type=test.broken", adminCookie)
+		assert.Equal(t, http.StatusOK, w.Code)
+		var page struct {
+			Total int64 `json:"total"`
+		}
+		assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &page))
+		assert.Equal(t, int64(1), page.Total)
+
+		broken = false
+		retryURL := fmt.Sprintf("/admin/jobs/%d/retry", id)
+		assert.Equal(t, http.StatusOK, request("POST", retryURL, adminCookie).Code)
+		assert.Equal(t, http.StatusConflict, request("POST", retryURL, adminCookie).Code)
+		assert.Equal(t, 1, work())
+		job = findJob(id)
+		assert.Equal(t, "succeeded", job.Data.Status)
+		assert.Equal(t, 1, job.Data.Attempts)
+		assert.Nil(t, job.Data.Error)
+	})
+
+	t.Run("scheduled", func(t *testing.T) {
+		assert.NotNil(t, worker.Schedule("test.greeting", "0 0 30 2 *", "test.greet", greeting{Name: "never"}))
+		assert.Nil(t, worker.Schedule("test.greeting", "@every 1h", "test.greet", greeting{Name: "hourly"}))
+		greeted = nil
+		assert.Zero(t, work())
+		_, err := db.Exec("UPDATE job_schedules SET next_run_at = ? WHERE name = 'test.greeting'", time.Now())
+		assert.Nil(t, err)
+		assert.Equal(t, 1, work())
+		assert.Equal(t, []string{"hourly"}, greeted)
+		// It is not due again for an hour.
+		assert.Zero(t, work())
+		var next time.Time
+		err = db.QueryRow("SELECT next_run_at FROM job_schedules WHERE name = 'test.greeting'").Scan(&next)
+		assert.Nil(t, err)
+		assert.WithinDuration(t, time.Now().Add(time.Hour), next, time.Minute)
+	})
+
+	t.Run("cleanup", func(t *testing.T) {
+		_, err := db.Exec("UPDATE jobs SET finished_at = ? WHERE status = 'succeeded'", time.Now().AddDate(0, 0, -8))
+		assert.Nil(t, err)
+		assert.Nil(t, worker.CleanupJobs(context.Background(), domain.CleanupJobPayload{RetentionDays: 7}))
+		var count int
+		assert.Nil(t, db.QueryRow("SELECT COUNT(*) FROM jobs WHERE status = 'succeeded'").Scan(&count))
+		assert.Zero(t, count)
+	})
+}
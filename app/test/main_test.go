@@ -1,23 +1,51 @@
 package test
 
 import (
+	"app/bootstrap"
 	"app/delivery/http"
+	"app/domain"
 	"app/pkg/database"
 	"app/pkg/logger"
+	"app/repository"
+	"app/usecase"
 	"context"
 	"database/sql"
+	nethttp "net/http"
 	"os"
 	"testing"
 
 	"github.com/gin-gonic/gin"
 	"github.com/testcontainers/testcontainers-go"
 	"github.com/testcontainers/testcontainers-go/modules/mysql"
+	"go.uber.org/zap"
 )
 
 var db *sql.DB
-var router *gin.Engine
+var router *relayingRouter
+var blobStore domain.BlobStore
 var mysqlContainer testcontainers.Container
 
+// relayingRouter relays the events published by each request once it is
+// served. The API leaves them to its background relay; relaying them here
+// lets the tests check their effects right away.
+type relayingRouter struct {
+	engine *gin.Engine
+	outbox *usecase.OutboxRelay
+}
+
+func (r *relayingRouter) ServeHTTP(w nethttp.ResponseWriter, req *nethttp.Request) {
+	r.engine.ServeHTTP(w, req)
+	for {
+		relayed, err := r.outbox.Relay(context.Background())
+		if err != nil {
+			logger.Log.Error("failed to relay outbox events", zap.Error(err))
+		}
+		if relayed == 0 {
+			return
+		}
+	}
+}
+
 func TestMain(m *testing.M) {
 	// Setup
 	// Start MySQL container
@@ -59,13 +87,42 @@ func TestMain(m *testing.M) {
 		panic(err)
 	}
 
-	// Setup router
-	router, err = http.SetupRouter(db, string(privateKey), string(publicKey))
+	// Setup router with an in-memory search index
+	searchIndex, err := repository.NewSearchIndexBleve("")
+	if err != nil {
+		panic(err)
+	}
+	// Uploads are kept in a temporary directory
+	uploadPath, err := os.MkdirTemp("", "uploads")
+	if err != nil {
+		panic(err)
+	}
+	defer os.RemoveAll(uploadPath)
+	blobStore, err = repository.NewBlobStoreLocal(uploadPath)
+	if err != nil {
+		panic(err)
+	}
+	outbox := usecase.NewOutboxRelay(repository.NewOutboxRepositoryMySQL(db), repository.NewSQLTransactor(db))
+	useCases, err := bootstrap.NewUseCases(db, bootstrap.Config{
+		JWTPrivateKey: string(privateKey),
+		JWTPublicKey:  string(publicKey),
+		SearchIndex:   searchIndex,
+		BlobStore:     blobStore,
+		SitemapSize:   2,
+		Outbox:        outbox,
+	})
 	if err != nil {
 		panic(err)
 	}
+	engine := http.SetupRouter(useCases, http.Config{
+		JWTPrivateKey: string(privateKey),
+		BaseURL:       "http://example.com",
+	})
+	router = &relayingRouter{engine: engine, outbox: outbox}
 	code := m.Run()
 
-	// Teardown
+	// Teardown; os.Exit skips the deferred calls, which only run on panics.
+	os.RemoveAll(uploadPath)
+	mysqlContainer.Terminate(ctx)
 	os.Exit(code)
 }
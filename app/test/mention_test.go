@@ -0,0 +1,142 @@
+package test
+
+import (
+	"app/domain"
+	"app/pkg/common"
+	"app/repository"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMentions(t *testing.T) {
+	mentionedID := registerUser(t, "mentioned", "mentioned@email.com", "password")
+	mentionedCookie := loginUser(t, "mentioned@email.com", "password")
+	registerUser(t, "mentioner", "mentioner@email.com", "password")
+	cookie := loginUser(t, "mentioner@email.com", "password")
+
+	request := func(method, url, cookie string, body interface{}) *httptest.ResponseRecorder {
+		req := authorizedRequest(t, method, url, cookie, body)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+	type mentionPage struct {
+		Total int64 `json:"total"`
+		Data  []struct {
+			AuthorName string `json:"author_name"`
+			PostTitle  string `json:"post_title"`
+			CommentID  *int64 `json:"comment_id"`
+		} `json:"data"`
+	}
+	mentions := func() mentionPage {
+		w := request("GET", "/me/mentions", mentionedCookie, nil)
+		assert.Equal(t, http.StatusOK, w.Code)
+		var page mentionPage
+		assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &page))
+		return page
+	}
+
+	t.Run("usernames are unique handles", func(t *testing.T) {
+		for username, status := range map[string]int{"mentioned": http.StatusBadRequest, "Not Valid": http.StatusBadRequest} {
+			body, _ := json.Marshal(map[string]string{"name": "name", "username": username, "email": "handle@email.com", "password": "password"})
+			req, _ := http.NewRequest("POST", "/register", bytes.NewBuffer(body))
+			req.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			assert.Equal(t, status, w.Code)
+		}
+
+		// A registration racing past those checks is stopped by the unique
+		// indexes with the same errors.
+		users := repository.NewUserRepositoryMySQL(db)
+		tx, err := repository.NewSQLTransactor(db).Begin()
+		assert.Nil(t, err)
+		defer tx.Rollback()
+		err = users.Create(context.Background(), tx, &domain.User{Name: "name", Username: "mentioned", Email: "racer@email.com"})
+		assert.Equal(t, common.ErrUsernameAlreadyExists, err)
+		err = users.Create(context.Background(), tx, &domain.User{Name: "name", Username: "racer", Email: "mentioned@email.com"})
+		assert.Equal(t, common.ErrEmailAlreadyExists, err)
+	})
+
+	var postID int64
+	t.Run("mentions in posts", func(t *testing.T) {
+		w := request("POST", "/posts", cookie, map[string]interface{}{
+			"title":   "Shout out",
+			"content": "<p>Thanks @Mentioned and @nobody_here, <code>@mentioned</code></p>",
+		})
+		assert.Equal(t, http.StatusCreated, w.Code)
+		var response struct {
+			Data struct {
+				ID      int64  `json:"id"`
+				Content string `json:"content"`
+			} `json:"data"`
+		}
+		assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &response))
+		postID = response.Data.ID
+		assert.Contains(t, response.Data.Content, fmt.Sprintf(`<a href="/users/%d">@Mentioned</a>`, mentionedID))
+		assert.Contains(t, response.Data.Content, "@nobody_here,")
+		assert.Contains(t, response.Data.Content, "<code>@mentioned</code>")
+
+		// The link leads to the public profile of the mentioned user.
+		w = request("GET", fmt.Sprintf("/users/%d", mentionedID), "", nil)
+		assert.Equal(t, http.StatusOK, w.Code)
+		var profile struct {
+			Data struct {
+				ID       int64  `json:"id"`
+				Username string `json:"username"`
+			} `json:"data"`
+		}
+		assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &profile))
+		assert.Equal(t, int64(mentionedID), profile.Data.ID)
+		assert.Equal(t, "mentioned", profile.Data.Username)
+
+		page := mentions()
+		assert.Equal(t, int64(1), page.Total)
+		assert.Equal(t, "Shout out", page.Data[0].PostTitle)
+		assert.Equal(t, "mentioner", page.Data[0].AuthorName)
+		assert.Nil(t, page.Data[0].CommentID)
+
+		w = request("PUT", fmt.Sprintf("/posts/%d", postID), cookie, map[string]interface{}{
+			"title":   "Shout out",
+			"content": response.Data.Content + "<p>Again @mentioned</p>",
+		})
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, int64(1), mentions().Total)
+	})
+
+	t.Run("drafts mention nobody", func(t *testing.T) {
+		w := request("POST", "/posts", cookie, map[string]interface{}{
+			"title":   "Secret shout out",
+			"content": "<p>Thanks @mentioned</p>",
+			"status":  "draft",
+		})
+		assert.Equal(t, http.StatusCreated, w.Code)
+		assert.Equal(t, int64(1), mentions().Total)
+	})
+
+	t.Run("mentions in comments", func(t *testing.T) {
+		w := request("POST", fmt.Sprintf("/posts/%d/comments", postID), cookie, map[string]interface{}{"content": "Right, @mentioned?"})
+		assert.Equal(t, http.StatusCreated, w.Code)
+		page := mentions()
+		assert.Equal(t, int64(2), page.Total)
+		assert.NotNil(t, page.Data[0].CommentID)
+
+		w = request("GET", "/me/notifications", mentionedCookie, nil)
+		var notifications struct {
+			Total int64 `json:"total"`
+			Data  []struct {
+				Type string `json:"type"`
+			} `json:"data"`
+		}
+		assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &notifications))
+		assert.Equal(t, int64(2), notifications.Total)
+		assert.Equal(t, "mention", notifications.Data[0].Type)
+	})
+}
@@ -0,0 +1,146 @@
+package test
+
+import (
+	"app/domain"
+	"app/repository"
+	"app/usecase"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNotifications(t *testing.T) {
+	authorID := registerUser(t, "notified", "notified@email.com", "password")
+	authorCookie := loginUser(t, "notified@email.com", "password")
+	registerUser(t, "commenter", "commenter@email.com", "password")
+	cookie := loginUser(t, "commenter@email.com", "password")
+	postID := createPost(t, authorCookie, "Notify me", "content")
+
+	request := func(method, url, cookie string, body interface{}) *httptest.ResponseRecorder {
+		req := authorizedRequest(t, method, url, cookie, body)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+	comment := func(cookie string, body map[string]interface{}) int64 {
+		w := request("POST", fmt.Sprintf("/posts/%d/comments", postID), cookie, body)
+		assert.Equal(t, http.StatusCreated, w.Code)
+		var response struct {
+			Data struct {
+				ID int64 `json:"id"`
+			} `json:"data"`
+		}
+		assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &response))
+		return response.Data.ID
+	}
+	type notificationPage struct {
+		Total int64 `json:"total"`
+		Data  []struct {
+			ID        int64  `json:"id"`
+			Type      string `json:"type"`
+			ActorName string `json:"actor_name"`
+			CommentID *int64 `json:"comment_id"`
+		} `json:"data"`
+	}
+	list := func(cookie, query string) notificationPage {
+		w := request("GET", "/me/notifications"+query, cookie, nil)
+		assert.Equal(t, http.StatusOK, w.Code)
+		var page notificationPage
+		assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &page))
+		return page
+	}
+	unread := func(cookie string) int64 {
+		w := request("GET", "/me/notifications/unread-count", cookie, nil)
+		assert.Equal(t, http.StatusOK, w.Code)
+		var response struct {
+			Data struct {
+				Unread int64 `json:"unread"`
+			} `json:"data"`
+		}
+		assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &response))
+		return response.Data.Unread
+	}
+
+	t.Run("comments and replies", func(t *testing.T) {
+		commentID := comment(cookie, map[string]interface{}{"content": "First!"})
+		comment(authorCookie, map[string]interface{}{"content": "Thanks", "parent_id": commentID})
+
+		page := list(authorCookie, "")
+		assert.Equal(t, int64(1), page.Total)
+		assert.Equal(t, "comment", page.Data[0].Type)
+		assert.Equal(t, "commenter", page.Data[0].ActorName)
+		assert.Equal(t, commentID, *page.Data[0].CommentID)
+
+		page = list(cookie, "")
+		assert.Equal(t, int64(1), page.Total)
+		assert.Equal(t, "reply", page.Data[0].Type)
+	})
+
+	t.Run("redelivered events notify once", func(t *testing.T) {
+		var event domain.OutboxEvent
+		err := db.QueryRow("SELECT idempotency_key, type, payload FROM outbox WHERE type = ? ORDER BY id DESC LIMIT 1", domain.EventNotification).Scan(&event.Key, &event.Type, &event.Payload)
+		assert.Nil(t, err)
+		notifications := usecase.NewNotificationUseCaseImpl(repository.NewNotificationRepositoryMySQL(db), repository.NewUserRepositoryMySQL(db), repository.NewEventBrokerMemory())
+		assert.Nil(t, notifications.Handle(context.Background(), event))
+		assert.Equal(t, int64(1), list(authorCookie, "").Total)
+		assert.Equal(t, int64(1), list(cookie, "").Total)
+	})
+
+	t.Run("reply to a comment on another post", func(t *testing.T) {
+		otherID := createPost(t, authorCookie, "Another post", "content")
+		w := request("POST", fmt.Sprintf("/posts/%d/comments", otherID), authorCookie, map[string]interface{}{"content": "Elsewhere"})
+		assert.Equal(t, http.StatusCreated, w.Code)
+		var response struct {
+			Data struct {
+				ID int64 `json:"id"`
+			} `json:"data"`
+		}
+		assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &response))
+		w = request("POST", fmt.Sprintf("/posts/%d/comments", postID), cookie, map[string]interface{}{"content": "Lost", "parent_id": response.Data.ID})
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+
+	t.Run("follow", func(t *testing.T) {
+		assert.Equal(t, http.StatusOK, request("PUT", fmt.Sprintf("/users/%d/follow", authorID), cookie, nil).Code)
+		// Following again changes nothing and notifies nobody.
+		assert.Equal(t, http.StatusOK, request("PUT", fmt.Sprintf("/users/%d/follow", authorID), cookie, nil).Code)
+		page := list(authorCookie, "")
+		assert.Equal(t, int64(2), page.Total)
+		assert.Equal(t, "follow", page.Data[0].Type)
+	})
+
+	t.Run("mark read", func(t *testing.T) {
+		assert.Equal(t, int64(2), unread(authorCookie))
+		page := list(authorCookie, "")
+		assert.Equal(t, http.StatusOK, request("POST", fmt.Sprintf("/me/notifications/%d/read", page.Data[0].ID), authorCookie, nil).Code)
+		assert.Equal(t, int64(1), unread(authorCookie))
+		assert.Equal(t, int64(1), list(authorCookie, "?unread=true").Total)
+
+		assert.Equal(t, http.StatusNotFound, request("POST", fmt.Sprintf("/me/notifications/%d/read", page.Data[0].ID), cookie, nil).Code)
+
+		assert.Equal(t, http.StatusOK, request("POST", "/me/notifications/read-all", authorCookie, nil).Code)
+		assert.Equal(t, int64(0), unread(authorCookie))
+	})
+
+	t.Run("preferences", func(t *testing.T) {
+		w := request("PUT", "/me/notifications/preferences", authorCookie, map[string]bool{"comment": false})
+		assert.Equal(t, http.StatusOK, w.Code)
+		var response struct {
+			Data map[string]bool `json:"data"`
+		}
+		assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &response))
+		assert.False(t, response.Data["comment"])
+		assert.True(t, response.Data["reply"])
+
+		comment(cookie, map[string]interface{}{"content": "Muted"})
+		assert.Equal(t, int64(0), unread(authorCookie))
+
+		w = request("PUT", "/me/notifications/preferences", authorCookie, map[string]bool{"digest": true})
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+}
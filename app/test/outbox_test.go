@@ -0,0 +1,112 @@
+package test
+
+import (
+	"app/domain"
+	"app/repository"
+	"app/usecase"
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// recordingHandler counts the events of its type and fails the first
+// failures of them.
+type recordingHandler struct {
+	eventType string
+	failures  int
+	keys      []string
+}
+
+func (h *recordingHandler) Handle(ctx context.Context, event domain.OutboxEvent) error {
+	if event.Type != h.eventType {
+		return nil
+	}
+	h.keys = append(h.keys, event.Key)
+	if h.failures > 0 {
+		h.failures--
+		return errors.New("handler unavailable")
+	}
+	return nil
+}
+
+func TestOutbox(t *testing.T) {
+	registerUser(t, "relayer", "relayer@email.com", "password")
+	cookie := loginUser(t, "relayer@email.com", "password")
+
+	t.Run("changes are handled once committed", func(t *testing.T) {
+		postID := createPost(t, cookie, "Outboxed", "content")
+		var eventID int64
+		err := db.QueryRow("SELECT id FROM outbox WHERE type = 'post.created' AND payload LIKE ? AND processed_at IS NOT NULL", fmt.Sprintf(`{"id":%d,%%`, postID)).Scan(&eventID)
+		assert.Nil(t, err)
+		var handlers []string
+		rows, err := db.Query("SELECT handler FROM outbox_handled WHERE event_id = ? ORDER BY handler", eventID)
+		assert.Nil(t, err)
+		defer rows.Close()
+		for rows.Next() {
+			var handler string
+			assert.Nil(t, rows.Scan(&handler))
+			handlers = append(handlers, handler)
+		}
+		assert.Equal(t, []string{"notifications", "search", "webhooks"}, handlers)
+	})
+
+	// The relay is driven by hand here, with handlers of its own.
+	transactor := repository.NewSQLTransactor(db)
+	relay := usecase.NewOutboxRelay(repository.NewOutboxRepositoryMySQL(db), transactor)
+	steady := &recordingHandler{eventType: "test.flaky"}
+	flaky := &recordingHandler{eventType: "test.flaky", failures: 1}
+	relay.Register("steady", steady)
+	relay.Register("flaky", flaky)
+	publish := func(eventType string, commit bool) {
+		tx, err := transactor.Begin()
+		assert.Nil(t, err)
+		defer tx.Rollback()
+		assert.Nil(t, relay.Publish(context.Background(), tx, eventType, map[string]string{"hello": "world"}))
+		if commit {
+			assert.Nil(t, tx.Commit())
+		}
+	}
+	relayEvents := func() int {
+		relayed, err := relay.Relay(context.Background())
+		assert.Nil(t, err)
+		return relayed
+	}
+
+	t.Run("failed handlers are retried alone", func(t *testing.T) {
+		publish("test.flaky", true)
+		assert.Equal(t, 1, relayEvents())
+		assert.Len(t, steady.keys, 1)
+		assert.Len(t, flaky.keys, 1)
+		var attempts int
+		var lastError string
+		err := db.QueryRow("SELECT attempts, last_error FROM outbox WHERE type = 'test.flaky'").Scan(&attempts, &lastError)
+		assert.Nil(t, err)
+		assert.Equal(t, 1, attempts)
+		assert.Equal(t, "handler unavailable", lastError)
+		// The retry is backed off.
+		assert.Zero(t, relayEvents())
+
+		_, err = db.Exec("UPDATE outbox SET available_at = ? WHERE processed_at IS NULL", time.Now())
+		assert.Nil(t, err)
+		assert.Equal(t, 1, relayEvents())
+		assert.Len(t, steady.keys, 1)
+		assert.Equal(t, []string{steady.keys[0], steady.keys[0]}, flaky.keys)
+		var processed bool
+		err = db.QueryRow("SELECT processed_at IS NOT NULL FROM outbox WHERE type = 'test.flaky'").Scan(&processed)
+		assert.Nil(t, err)
+		assert.True(t, processed)
+		assert.Zero(t, relayEvents())
+	})
+
+	t.Run("rolled back changes are never relayed", func(t *testing.T) {
+		publish("test.flaky", false)
+		assert.Zero(t, relayEvents())
+		var count int
+		assert.Nil(t, db.QueryRow("SELECT COUNT(*) FROM outbox WHERE type = 'test.flaky'").Scan(&count))
+		assert.Equal(t, 1, count)
+	})
+}
@@ -0,0 +1,544 @@
+package test
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func authorizedRequest(t *testing.T, method, url, cookie string, body interface{}) *http.Request {
+	var buf bytes.Buffer
+	if body != nil {
+		err := json.NewEncoder(&buf).Encode(body)
+		assert.Nil(t, err)
+	}
+	req, err := http.NewRequest(method, url, &buf)
+	assert.Nil(t, err)
+	req.Header.Set("Content-Type", "application/json")
+	if cookie != "" {
+		req.AddCookie(&http.Cookie{Name: "AUTHORIZATION", Value: cookie})
+	}
+	return req
+}
+
+func createPost(t *testing.T, cookie, title, content string) int64 {
+	req := authorizedRequest(t, "POST", "/posts", cookie, map[string]string{
+		"title":   title,
+		"content": content,
+	})
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusCreated, w.Code)
+	var response struct {
+		Data map[string]interface{} `json:"data"`
+	}
+	err := json.Unmarshal(w.Body.Bytes(), &response)
+	assert.Nil(t, err)
+	return int64(response.Data["id"].(float64))
+}
+
+func TestPostConcurrency(t *testing.T) {
+	registerUser(t, "editor", "editor@email.com", "password")
+	cookie := loginUser(t, "editor@email.com", "password")
+	postID := createPost(t, cookie, "title", "content")
+	url := fmt.Sprintf("/posts/%d", postID)
+
+	t.Run("get returns etag", func(t *testing.T) {
+		req := authorizedRequest(t, "GET", url, "", nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusOK, w.Code)
+		etag := w.Header().Get("ETag")
+		assert.True(t, strings.HasPrefix(etag, `"1-`))
+
+		req = authorizedRequest(t, "GET", url, "", nil)
+		req.Header.Set("If-None-Match", etag)
+		w = httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusNotModified, w.Code)
+	})
+
+	t.Run("if-match accepts the get etag", func(t *testing.T) {
+		req := authorizedRequest(t, "GET", url, "", nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		etag := w.Header().Get("ETag")
+
+		req = authorizedRequest(t, "PATCH", url, cookie, map[string]string{"title": "patched title"})
+		req.Header.Set("Content-Type", "application/merge-patch+json")
+		req.Header.Set("If-Match", etag)
+		w = httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, `"2"`, w.Header().Get("ETag"))
+	})
+
+	t.Run("update with stale if-match", func(t *testing.T) {
+		body := map[string]string{"title": "new title", "content": "new content"}
+		req := authorizedRequest(t, "PUT", url, cookie, body)
+		req.Header.Set("If-Match", `"2"`)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, `"3"`, w.Header().Get("ETag"))
+
+		req = authorizedRequest(t, "PUT", url, cookie, body)
+		req.Header.Set("If-Match", `"2"`)
+		w = httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusPreconditionFailed, w.Code)
+	})
+
+	t.Run("delete with stale if-match", func(t *testing.T) {
+		req := authorizedRequest(t, "DELETE", url, cookie, nil)
+		req.Header.Set("If-Match", `"2"`)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusPreconditionFailed, w.Code)
+
+		req = authorizedRequest(t, "DELETE", url, cookie, nil)
+		req.Header.Set("If-Match", `"3"`)
+		w = httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusOK, w.Code)
+	})
+}
+
+func TestPostPatch(t *testing.T) {
+	registerUser(t, "patcher", "patcher@email.com", "password")
+	cookie := loginUser(t, "patcher@email.com", "password")
+	postID := createPost(t, cookie, "title", "content")
+	url := fmt.Sprintf("/posts/%d", postID)
+
+	t.Run("patch a single field", func(t *testing.T) {
+		req := authorizedRequest(t, "PATCH", url, cookie, map[string]string{"title": "patched"})
+		req.Header.Set("Content-Type", "application/merge-patch+json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusOK, w.Code)
+		var response struct {
+			Data map[string]interface{} `json:"data"`
+		}
+		err := json.Unmarshal(w.Body.Bytes(), &response)
+		assert.Nil(t, err)
+		assert.Equal(t, "patched", response.Data["title"])
+		assert.Equal(t, "content", response.Data["content"])
+		assert.Equal(t, "content", response.Data["content_html"])
+	})
+
+	t.Run("patch removing a field", func(t *testing.T) {
+		req := authorizedRequest(t, "PATCH", url, cookie, map[string]interface{}{"content": nil})
+		req.Header.Set("Content-Type", "application/merge-patch+json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
+	t.Run("patch a read-only field", func(t *testing.T) {
+		req := authorizedRequest(t, "PATCH", url, cookie, map[string]interface{}{"author_id": 1})
+		req.Header.Set("Content-Type", "application/merge-patch+json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+}
+
+func TestPostSlug(t *testing.T) {
+	registerUser(t, "slugger", "slugger@email.com", "password")
+	cookie := loginUser(t, "slugger@email.com", "password")
+	firstID := createPost(t, cookie, "Crème Brûlée", "content")
+	secondID := createPost(t, cookie, "Creme Brulee", "content")
+
+	getBySlug := func(slug string) *httptest.ResponseRecorder {
+		req := authorizedRequest(t, "GET", "/posts/by-slug/"+slug, "", nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	t.Run("slugs are unique", func(t *testing.T) {
+		var response struct {
+			Data map[string]interface{} `json:"data"`
+		}
+		w := getBySlug("creme-brulee")
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &response))
+		assert.Equal(t, float64(firstID), response.Data["id"])
+
+		w = getBySlug("creme-brulee-2")
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &response))
+		assert.Equal(t, float64(secondID), response.Data["id"])
+	})
+
+	t.Run("renamed post redirects from old slug", func(t *testing.T) {
+		body := map[string]string{"title": "Tiramisu", "content": "content"}
+		req := authorizedRequest(t, "PUT", fmt.Sprintf("/posts/%d", firstID), cookie, body)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		w = getBySlug("creme-brulee")
+		assert.Equal(t, http.StatusMovedPermanently, w.Code)
+		assert.Equal(t, "/posts/by-slug/tiramisu", w.Header().Get("Location"))
+	})
+}
+
+func TestPostMarkdown(t *testing.T) {
+	registerUser(t, "writer", "writer@email.com", "password")
+	cookie := loginUser(t, "writer@email.com", "password")
+	req := authorizedRequest(t, "POST", "/posts", cookie, map[string]string{
+		"title":            "markdown",
+		"content_markdown": "# Intro\n\n- [x] done\n\n<script>alert(1)</script>",
+	})
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusCreated, w.Code)
+	var response struct {
+		Data struct {
+			Content         string `json:"content"`
+			ContentHTML     string `json:"content_html"`
+			ContentMarkdown string `json:"content_markdown"`
+			TOC             []struct {
+				ID    string `json:"id"`
+				Title string `json:"title"`
+			} `json:"toc"`
+		} `json:"data"`
+	}
+	assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Contains(t, response.Data.ContentHTML, `<h1 id="intro">Intro</h1>`)
+	assert.Contains(t, response.Data.ContentHTML, `type="checkbox"`)
+	assert.NotContains(t, response.Data.ContentHTML, "<script>")
+	assert.Equal(t, response.Data.ContentHTML, response.Data.Content)
+	assert.Equal(t, "# Intro\n\n- [x] done\n\n<script>alert(1)</script>", response.Data.ContentMarkdown)
+	assert.Len(t, response.Data.TOC, 1)
+	assert.Equal(t, "intro", response.Data.TOC[0].ID)
+
+	t.Run("content and markdown are exclusive", func(t *testing.T) {
+		req := authorizedRequest(t, "POST", "/posts", cookie, map[string]string{
+			"title":            "both",
+			"content":          "<p>html</p>",
+			"content_markdown": "markdown",
+		})
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+}
+
+func TestPostSearch(t *testing.T) {
+	registerUser(t, "searcher", "searcher@email.com", "password")
+	cookie := loginUser(t, "searcher@email.com", "password")
+	bestID := createPost(t, cookie, "Sourdough sourdough", "<p>Feeding a sourdough starter every day</p>")
+	otherID := createPost(t, cookie, "Bread", "<p>A quick note on sourdough</p>")
+	createPost(t, cookie, "Pasta", "<p>Fresh egg pasta</p>")
+
+	search := func(query string) []map[string]interface{} {
+		req := authorizedRequest(t, "GET", "/posts?"+query, "", nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusOK, w.Code)
+		var response struct {
+			Data []map[string]interface{} `json:"data"`
+		}
+		assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &response))
+		return response.Data
+	}
+
+	t.Run("natural language ranks by relevance", func(t *testing.T) {
+		posts := search("search=sourdough")
+		assert.Len(t, posts, 2)
+		assert.Equal(t, float64(bestID), posts[0]["id"])
+		assert.Equal(t, float64(otherID), posts[1]["id"])
+		assert.NotNil(t, posts[0]["score"])
+		assert.Contains(t, posts[0]["snippet"], "<mark>sourdough</mark>")
+	})
+
+	t.Run("boolean mode", func(t *testing.T) {
+		posts := search("search=%2Bsourdough+-starter&mode=boolean")
+		assert.Len(t, posts, 1)
+		assert.Equal(t, float64(otherID), posts[0]["id"])
+	})
+
+	t.Run("markup is not searched", func(t *testing.T) {
+		createPost(t, cookie, "Crust", `<p>A <strong class="loud">crisp</strong> crust</p>`)
+		assert.Len(t, search("search=strong"), 0)
+		assert.Len(t, search("search=loud"), 0)
+		assert.Len(t, search("search=crisp"), 1)
+	})
+
+	t.Run("sort by oldest", func(t *testing.T) {
+		posts := search("search=sourdough&sort=oldest")
+		assert.Len(t, posts, 2)
+		assert.Equal(t, float64(bestID), posts[0]["id"])
+	})
+
+	t.Run("invalid sort", func(t *testing.T) {
+		req := authorizedRequest(t, "GET", "/posts?sort=random", "", nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+}
+
+func TestPostCursor(t *testing.T) {
+	registerUser(t, "pager", "pager@email.com", "password")
+	cookie := loginUser(t, "pager@email.com", "password")
+	for _, title := range []string{"first", "second", "third"} {
+		req := authorizedRequest(t, "POST", "/posts", cookie, map[string]interface{}{
+			"title":   title,
+			"content": "content",
+			"tags":    []string{"paging"},
+		})
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusCreated, w.Code)
+	}
+
+	type cursorResponse struct {
+		Total      *int64  `json:"total"`
+		NextCursor *string `json:"next_cursor"`
+		PrevCursor *string `json:"prev_cursor"`
+		Data       []struct {
+			Title string `json:"title"`
+		} `json:"data"`
+	}
+	list := func(query string) cursorResponse {
+		req := authorizedRequest(t, "GET", "/posts?tag=paging&limit=2&"+query, "", nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusOK, w.Code)
+		var response cursorResponse
+		assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &response))
+		return response
+	}
+
+	first := list("pagination=cursor&with_total=true")
+	assert.Equal(t, int64(3), *first.Total)
+	assert.Len(t, first.Data, 2)
+	assert.Equal(t, "third", first.Data[0].Title)
+	assert.Nil(t, first.PrevCursor)
+	assert.NotNil(t, first.NextCursor)
+
+	second := list("after=" + *first.NextCursor)
+	assert.Nil(t, second.Total)
+	assert.Len(t, second.Data, 1)
+	assert.Equal(t, "first", second.Data[0].Title)
+	assert.Nil(t, second.NextCursor)
+	assert.NotNil(t, second.PrevCursor)
+
+	back := list("before=" + *second.PrevCursor)
+	assert.Len(t, back.Data, 2)
+	assert.Equal(t, "third", back.Data[0].Title)
+	assert.Nil(t, back.PrevCursor)
+
+	t.Run("tampered cursor", func(t *testing.T) {
+		req := authorizedRequest(t, "GET", "/posts?after=x"+*first.NextCursor, "", nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+}
+
+func TestPostFilters(t *testing.T) {
+	authorID := registerUser(t, "filterer", "filterer@email.com", "password")
+	cookie := loginUser(t, "filterer@email.com", "password")
+	req := authorizedRequest(t, "POST", "/posts", cookie, map[string]string{
+		"title":   "hidden",
+		"content": "content",
+		"status":  "draft",
+	})
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusCreated, w.Code)
+	createPost(t, cookie, "alpha", "content")
+	betaID := createPost(t, cookie, "beta", "content")
+	req = authorizedRequest(t, "POST", fmt.Sprintf("/posts/%d/comments", betaID), cookie, map[string]string{"content": "comment"})
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusCreated, w.Code)
+
+	titles := func(query, cookie string) []string {
+		req := authorizedRequest(t, "GET", fmt.Sprintf("/posts?author_id=%d&%s", authorID, query), cookie, nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusOK, w.Code)
+		var response struct {
+			Data []struct {
+				Title string `json:"title"`
+			} `json:"data"`
+		}
+		assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &response))
+		var titles []string
+		for _, post := range response.Data {
+			titles = append(titles, post.Title)
+		}
+		return titles
+	}
+
+	t.Run("drafts are only listed for their author", func(t *testing.T) {
+		assert.Equal(t, []string{"beta", "alpha"}, titles("", ""))
+		assert.Empty(t, titles("status=draft", ""))
+		assert.Equal(t, []string{"hidden"}, titles("status=draft", cookie))
+		assert.Len(t, titles("status=all", cookie), 3)
+	})
+
+	t.Run("sort with direction", func(t *testing.T) {
+		assert.Equal(t, []string{"alpha", "beta"}, titles("sort=title", ""))
+		assert.Equal(t, []string{"beta", "alpha"}, titles("sort=title&order=desc", ""))
+		assert.Equal(t, []string{"beta", "alpha"}, titles("sort=comment_count", ""))
+		assert.Len(t, titles("sort=popularity", ""), 2)
+	})
+
+	t.Run("date range", func(t *testing.T) {
+		assert.Empty(t, titles("created_from=2999-01-01", ""))
+		assert.Len(t, titles("created_to=2999-01-01", ""), 2)
+	})
+
+	t.Run("unknown sort", func(t *testing.T) {
+		req := authorizedRequest(t, "GET", "/posts?sort=id;DROP", "", nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+}
+
+func TestPostSparseFields(t *testing.T) {
+	authorID := registerUser(t, "sparse", "sparse@email.com", "password")
+	cookie := loginUser(t, "sparse@email.com", "password")
+	req := authorizedRequest(t, "POST", "/posts", cookie, map[string]interface{}{
+		"title":   "sparse",
+		"content": "<p>Some <b>bold</b> content</p>",
+		"tags":    []string{"Lean"},
+	})
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusCreated, w.Code)
+
+	list := func(query string) *httptest.ResponseRecorder {
+		req := authorizedRequest(t, "GET", fmt.Sprintf("/posts?author_id=%d&%s", authorID, query), "", nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	t.Run("fields and includes", func(t *testing.T) {
+		w := list("fields=id,title,excerpt&include=author,tags,comment_count")
+		assert.Equal(t, http.StatusOK, w.Code)
+		var response struct {
+			Data []map[string]interface{} `json:"data"`
+		}
+		assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &response))
+		assert.Len(t, response.Data, 1)
+		post := response.Data[0]
+		assert.ElementsMatch(t, []string{"id", "title", "excerpt", "author", "tags", "comment_count"}, keys(post))
+		assert.Equal(t, "Some bold content", post["excerpt"])
+		assert.Equal(t, "sparse", post["author"].(map[string]interface{})["name"])
+		assert.Equal(t, "lean", post["tags"].([]interface{})[0].(map[string]interface{})["slug"])
+	})
+
+	t.Run("excerpt follows edits", func(t *testing.T) {
+		var created struct {
+			Data struct {
+				ID int64 `json:"id"`
+			} `json:"data"`
+		}
+		assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &created))
+		req := authorizedRequest(t, "PATCH", fmt.Sprintf("/posts/%d", created.Data.ID), cookie, map[string]string{"content": "<p>Edited</p>"})
+		req.Header.Set("Content-Type", "application/merge-patch+json")
+		patched := httptest.NewRecorder()
+		router.ServeHTTP(patched, req)
+		assert.Equal(t, http.StatusOK, patched.Code)
+		var response struct {
+			Data []map[string]interface{} `json:"data"`
+		}
+		assert.Nil(t, json.Unmarshal(list("fields=excerpt").Body.Bytes(), &response))
+		assert.Len(t, response.Data, 1)
+		assert.Equal(t, "Edited", response.Data[0]["excerpt"])
+	})
+
+	t.Run("unknown field", func(t *testing.T) {
+		assert.Equal(t, http.StatusBadRequest, list("fields=password_hash").Code)
+		assert.Equal(t, http.StatusBadRequest, list("include=comments").Code)
+	})
+}
+
+func keys(members map[string]interface{}) []string {
+	var names []string
+	for name := range members {
+		names = append(names, name)
+	}
+	return names
+}
+
+func TestPostVisibility(t *testing.T) {
+	registerUser(t, "drafter", "drafter@email.com", "password")
+	cookie := loginUser(t, "drafter@email.com", "password")
+	registerUser(t, "snooper", "snooper@email.com", "password")
+	otherCookie := loginUser(t, "snooper@email.com", "password")
+	req := authorizedRequest(t, "POST", "/posts", cookie, map[string]string{
+		"title":   "Unfinished thoughts",
+		"content": "content",
+		"status":  "draft",
+	})
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusCreated, w.Code)
+	var created struct {
+		Data struct {
+			ID int64 `json:"id"`
+		} `json:"data"`
+	}
+	assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &created))
+	draftID := created.Data.ID
+
+	get := func(url, cookie string) *httptest.ResponseRecorder {
+		req := authorizedRequest(t, "GET", url, cookie, nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	t.Run("drafts are only shown to their author", func(t *testing.T) {
+		assert.Equal(t, http.StatusNotFound, get(fmt.Sprintf("/posts/%d", draftID), "").Code)
+		assert.Equal(t, http.StatusNotFound, get(fmt.Sprintf("/posts/%d", draftID), otherCookie).Code)
+		assert.Equal(t, http.StatusNotFound, get("/posts/by-slug/unfinished-thoughts", otherCookie).Code)
+		assert.Equal(t, http.StatusNotFound, get(fmt.Sprintf("/posts/%d/comments", draftID), otherCookie).Code)
+		assert.Equal(t, http.StatusOK, get(fmt.Sprintf("/posts/%d", draftID), cookie).Code)
+		assert.Equal(t, http.StatusOK, get(fmt.Sprintf("/posts/%d/comments", draftID), cookie).Code)
+	})
+
+	t.Run("own reactions for the signed in viewer", func(t *testing.T) {
+		postID := createPost(t, cookie, "Published thoughts", "content")
+		req := authorizedRequest(t, "PUT", fmt.Sprintf("/posts/%d/reactions/wow", postID), otherCookie, nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		var response struct {
+			Data map[string]interface{} `json:"data"`
+		}
+		w = get(fmt.Sprintf("/posts/%d", postID), otherCookie)
+		assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &response))
+		assert.Equal(t, []interface{}{"wow"}, response.Data["my_reactions"])
+		w = get(fmt.Sprintf("/posts/%d", postID), "")
+		assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &response))
+		assert.NotContains(t, response.Data, "my_reactions")
+	})
+
+	t.Run("protected routes reject missing and invalid tokens", func(t *testing.T) {
+		body := map[string]string{"title": "Nope", "content": "content"}
+		for _, token := range []string{"", "garbage"} {
+			req := authorizedRequest(t, "POST", "/posts", token, body)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			assert.Equal(t, http.StatusUnauthorized, w.Code)
+		}
+	})
+}
@@ -0,0 +1,100 @@
+package test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReactions(t *testing.T) {
+	registerUser(t, "reactor", "reactor@email.com", "password")
+	cookie := loginUser(t, "reactor@email.com", "password")
+	registerUser(t, "bystander", "bystander@email.com", "password")
+	otherCookie := loginUser(t, "bystander@email.com", "password")
+	postID := createPost(t, cookie, "Reactive post", "content")
+
+	type countsResponse struct {
+		Data struct {
+			Reactions map[string]int64 `json:"reactions"`
+		} `json:"data"`
+	}
+	react := func(method, cookie, reactionType string) countsResponse {
+		req := authorizedRequest(t, method, fmt.Sprintf("/posts/%d/reactions/%s", postID, reactionType), cookie, nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusOK, w.Code)
+		var response countsResponse
+		assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &response))
+		return response
+	}
+
+	t.Run("reactions are idempotent per user", func(t *testing.T) {
+		react("PUT", cookie, "like")
+		response := react("PUT", cookie, "like")
+		assert.Equal(t, map[string]int64{"like": 1}, response.Data.Reactions)
+		react("PUT", cookie, "love")
+		response = react("PUT", otherCookie, "like")
+		assert.Equal(t, map[string]int64{"like": 2, "love": 1}, response.Data.Reactions)
+
+		react("DELETE", otherCookie, "like")
+		response = react("DELETE", otherCookie, "like")
+		assert.Equal(t, map[string]int64{"like": 1, "love": 1}, response.Data.Reactions)
+	})
+
+	t.Run("counts are part of the post", func(t *testing.T) {
+		req := authorizedRequest(t, "GET", fmt.Sprintf("/posts/%d", postID), "", nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusOK, w.Code)
+		var response countsResponse
+		assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &response))
+		assert.Equal(t, map[string]int64{"like": 1, "love": 1}, response.Data.Reactions)
+	})
+
+	t.Run("reacting changes the etag", func(t *testing.T) {
+		url := fmt.Sprintf("/posts/%d", postID)
+		req := authorizedRequest(t, "GET", url, "", nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusOK, w.Code)
+		etag := w.Header().Get("ETag")
+
+		react("PUT", otherCookie, "wow")
+		req = authorizedRequest(t, "GET", url, "", nil)
+		req.Header.Set("If-None-Match", etag)
+		w = httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusOK, w.Code)
+		react("DELETE", otherCookie, "wow")
+	})
+
+	t.Run("list who reacted", func(t *testing.T) {
+		req := authorizedRequest(t, "GET", fmt.Sprintf("/posts/%d/reactions", postID), "", nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusOK, w.Code)
+		var response struct {
+			Total int64 `json:"total"`
+			Data  []struct {
+				UserName string `json:"user_name"`
+				Type     string `json:"type"`
+			} `json:"data"`
+		}
+		assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &response))
+		assert.Equal(t, int64(2), response.Total)
+		for _, reaction := range response.Data {
+			assert.Equal(t, "reactor", reaction.UserName)
+		}
+	})
+
+	t.Run("unknown reaction type", func(t *testing.T) {
+		req := authorizedRequest(t, "PUT", fmt.Sprintf("/posts/%d/reactions/meh", postID), cookie, nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+}
@@ -0,0 +1,130 @@
+package test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSearch(t *testing.T) {
+	registerUser(t, "indexer", "indexer@email.com", "password")
+	cookie := loginUser(t, "indexer@email.com", "password")
+	req := authorizedRequest(t, "POST", "/posts", cookie, map[string]interface{}{
+		"title":   "Kombucha brewing",
+		"content": "<p>Second fermentation in bottles</p>",
+		"tags":    []string{"Drinks"},
+	})
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusCreated, w.Code)
+	postID := createPost(t, cookie, "Vinegar", "<p>Kombucha left too long</p>")
+	req = authorizedRequest(t, "POST", fmt.Sprintf("/posts/%d/comments", postID), cookie, map[string]string{"content": "my kombucha too"})
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusCreated, w.Code)
+
+	type searchResponse struct {
+		Data struct {
+			Total int64 `json:"total"`
+			Hits  []struct {
+				Type   string  `json:"type"`
+				ID     int64   `json:"id"`
+				PostID int64   `json:"post_id"`
+				Score  float64 `json:"score"`
+			} `json:"hits"`
+			Facets map[string][]struct {
+				Term  string `json:"term"`
+				Count int    `json:"count"`
+			} `json:"facets"`
+		} `json:"data"`
+	}
+	search := func(query string) searchResponse {
+		req := authorizedRequest(t, "GET", "/search?"+query, "", nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusOK, w.Code)
+		var response searchResponse
+		assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &response))
+		return response
+	}
+
+	t.Run("posts and comments with facets", func(t *testing.T) {
+		response := search("q=kombucha")
+		assert.Equal(t, int64(3), response.Data.Total)
+		assert.Equal(t, "post", response.Data.Hits[0].Type)
+		assert.Contains(t, response.Data.Facets["tags"], struct {
+			Term  string `json:"term"`
+			Count int    `json:"count"`
+		}{Term: "drinks", Count: 1})
+	})
+
+	t.Run("fuzzy match and filters", func(t *testing.T) {
+		response := search("q=kombuca&type=comment")
+		assert.Equal(t, int64(1), response.Data.Total)
+		assert.Equal(t, postID, response.Data.Hits[0].PostID)
+
+		response = search("q=kombucha&tag=drinks")
+		assert.Equal(t, int64(1), response.Data.Total)
+	})
+
+	t.Run("phrase query", func(t *testing.T) {
+		response := search("q=%22second+fermentation%22")
+		assert.Equal(t, int64(1), response.Data.Total)
+	})
+
+	t.Run("comments follow the publication of their post", func(t *testing.T) {
+		req := authorizedRequest(t, "POST", "/posts", cookie, map[string]interface{}{
+			"title":   "Draft",
+			"content": "<p>Not ready</p>",
+			"status":  "draft",
+		})
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusCreated, w.Code)
+		var created struct {
+			Data struct {
+				ID int64 `json:"id"`
+			} `json:"data"`
+		}
+		assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &created))
+		draftURL := fmt.Sprintf("/posts/%d", created.Data.ID)
+		req = authorizedRequest(t, "POST", draftURL+"/comments", cookie, map[string]string{"content": "gazpacho notes"})
+		w = httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusCreated, w.Code)
+		assert.Equal(t, int64(0), search("q=gazpacho").Data.Total)
+
+		setStatus := func(status string) {
+			req := authorizedRequest(t, "PATCH", draftURL, cookie, map[string]string{"status": status})
+			req.Header.Set("Content-Type", "application/merge-patch+json")
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			assert.Equal(t, http.StatusOK, w.Code)
+		}
+		setStatus("published")
+		assert.Equal(t, int64(1), search("q=gazpacho").Data.Total)
+		setStatus("draft")
+		assert.Equal(t, int64(0), search("q=gazpacho").Data.Total)
+	})
+
+	t.Run("deleted post leaves the index", func(t *testing.T) {
+		req := authorizedRequest(t, "DELETE", fmt.Sprintf("/posts/%d", postID), cookie, nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		response := search("q=kombucha")
+		assert.Equal(t, int64(1), response.Data.Total)
+	})
+
+	t.Run("missing query", func(t *testing.T) {
+		req := authorizedRequest(t, "GET", "/search", "", nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+}
@@ -0,0 +1,63 @@
+package test
+
+import (
+	"encoding/xml"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSitemap(t *testing.T) {
+	registerUser(t, "cartographer", "cartographer@email.com", "password")
+	cookie := loginUser(t, "cartographer@email.com", "password")
+	createPost(t, cookie, "Map one", "content")
+	createPost(t, cookie, "Map two", "content")
+	createPost(t, cookie, "Map three", "content")
+
+	get := func(url string) *httptest.ResponseRecorder {
+		req := authorizedRequest(t, "GET", strings.TrimPrefix(url, "http://example.com"), "", nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	w := get("/sitemap.xml")
+	assert.Equal(t, http.StatusOK, w.Code)
+	var index struct {
+		XMLName  xml.Name
+		Sitemaps []struct {
+			Loc string `xml:"loc"`
+		} `xml:"sitemap"`
+	}
+	assert.Nil(t, xml.Unmarshal(w.Body.Bytes(), &index))
+	assert.Equal(t, "sitemapindex", index.XMLName.Local)
+	assert.GreaterOrEqual(t, len(index.Sitemaps), 2)
+
+	var locations []string
+	for _, sitemap := range index.Sitemaps {
+		w := get(sitemap.Loc)
+		assert.Equal(t, http.StatusOK, w.Code)
+		var urlSet struct {
+			URLs []struct {
+				Loc     string `xml:"loc"`
+				LastMod string `xml:"lastmod"`
+			} `xml:"url"`
+		}
+		assert.Nil(t, xml.Unmarshal(w.Body.Bytes(), &urlSet))
+		assert.LessOrEqual(t, len(urlSet.URLs), 2)
+		for _, url := range urlSet.URLs {
+			assert.NotEmpty(t, url.LastMod)
+			locations = append(locations, url.Loc)
+		}
+	}
+	assert.Contains(t, locations, "http://example.com/posts/by-slug/map-one")
+	assert.Contains(t, locations, "http://example.com/posts/by-slug/map-three")
+
+	w = get("/sitemaps/posts-999999.xml")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	w = get("/sitemaps/other.xml")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
@@ -0,0 +1,114 @@
+package test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTaxonomy(t *testing.T) {
+	taggerID := registerUser(t, "tagger", "tagger@email.com", "password")
+	_, err := db.Exec("UPDATE users SET role = 'admin' WHERE id = ?", taggerID)
+	assert.Nil(t, err)
+	cookie := loginUser(t, "tagger@email.com", "password")
+
+	createCategory := func(name string, parentID interface{}) float64 {
+		req := authorizedRequest(t, "POST", "/categories", cookie, map[string]interface{}{"name": name, "parent_id": parentID})
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusCreated, w.Code)
+		var response struct {
+			Data map[string]interface{} `json:"data"`
+		}
+		assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &response))
+		return response.Data["id"].(float64)
+	}
+	programming := createCategory("Programming", nil)
+	golang := createCategory("Golang", programming)
+
+	req := authorizedRequest(t, "POST", "/posts", cookie, map[string]interface{}{
+		"title":       "Generics",
+		"content":     "content",
+		"tags":        []string{"Go", "Types", "go"},
+		"category_id": golang,
+	})
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusCreated, w.Code)
+
+	t.Run("only admins create categories", func(t *testing.T) {
+		registerUser(t, "labeler", "labeler@email.com", "password")
+		otherCookie := loginUser(t, "labeler@email.com", "password")
+		req := authorizedRequest(t, "POST", "/categories", otherCookie, map[string]interface{}{"name": "Cooking"})
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusForbidden, w.Code)
+	})
+
+	t.Run("create a category twice", func(t *testing.T) {
+		req := authorizedRequest(t, "POST", "/categories", cookie, map[string]interface{}{"name": "programming"})
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusConflict, w.Code)
+	})
+
+	t.Run("list tags with counts", func(t *testing.T) {
+		req := authorizedRequest(t, "POST", "/posts", cookie, map[string]interface{}{
+			"title":   "Upcoming",
+			"content": "content",
+			"tags":    []string{"Unannounced"},
+			"status":  "draft",
+		})
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusCreated, w.Code)
+
+		req = authorizedRequest(t, "GET", "/tags", "", nil)
+		w = httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusOK, w.Code)
+		var response struct {
+			Data []map[string]interface{} `json:"data"`
+		}
+		assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &response))
+		counts := map[string]float64{}
+		for _, tag := range response.Data {
+			counts[tag["slug"].(string)] = tag["post_count"].(float64)
+		}
+		assert.Equal(t, float64(1), counts["go"])
+		assert.Equal(t, float64(1), counts["types"])
+		assert.NotContains(t, counts, "unannounced")
+	})
+
+	t.Run("filter posts by tag and parent category", func(t *testing.T) {
+		for _, url := range []string{"/tags/go/posts", "/posts?tag=types", "/posts?category=programming"} {
+			req := authorizedRequest(t, "GET", url, "", nil)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			assert.Equal(t, http.StatusOK, w.Code)
+			var response struct {
+				Total int64 `json:"total"`
+			}
+			assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &response))
+			assert.Equal(t, int64(1), response.Total, url)
+		}
+	})
+
+	t.Run("tag listings embed like the post listing", func(t *testing.T) {
+		req := authorizedRequest(t, "GET", "/tags/go/posts?include=author,tags", cookie, nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusOK, w.Code)
+		var response struct {
+			Data []map[string]interface{} `json:"data"`
+		}
+		assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &response))
+		assert.Len(t, response.Data, 1)
+		assert.Equal(t, "tagger", response.Data[0]["author"].(map[string]interface{})["name"])
+		assert.Len(t, response.Data[0]["tags"], 2)
+		assert.Equal(t, false, response.Data[0]["bookmarked"])
+	})
+}
@@ -0,0 +1,470 @@
+package test
+
+import (
+	"app/domain"
+	"app/pkg/common"
+	"app/repository"
+	"app/usecase"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/testcontainers/testcontainers-go"
+	"github.com/testcontainers/testcontainers-go/wait"
+)
+
+func pngImage(t *testing.T) []byte {
+	var buf bytes.Buffer
+	assert.Nil(t, png.Encode(&buf, image.NewRGBA(image.Rect(0, 0, 2, 2))))
+	return buf.Bytes()
+}
+
+// attachmentWorker runs the attachment jobs by hand, on the files stored by
+// the router.
+func attachmentWorker() *usecase.AttachmentUseCaseImpl {
+	transactor := repository.NewSQLTransactor(db)
+	jobs := usecase.NewJobUseCaseImpl(repository.NewJobRepositoryMySQL(db), transactor)
+	return usecase.NewAttachmentUseCaseImpl(repository.NewAttachmentRepositoryMySQL(db), repository.NewPostRepositoryMySQL(db), blobStore, jobs, transactor, domain.DefaultImageVariants)
+}
+
+func uploadFile(t *testing.T, cookie, filename string, content []byte) *httptest.ResponseRecorder {
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	part, err := writer.CreateFormFile("file", filename)
+	assert.Nil(t, err)
+	_, err = part.Write(content)
+	assert.Nil(t, err)
+	assert.Nil(t, writer.Close())
+	req, err := http.NewRequest("POST", "/uploads", &body)
+	assert.Nil(t, err)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	if cookie != "" {
+		req.AddCookie(&http.Cookie{Name: "AUTHORIZATION", Value: cookie})
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestUploads(t *testing.T) {
+	ownerID := registerUser(t, "photographer", "photographer@email.com", "password")
+	cookie := loginUser(t, "photographer@email.com", "password")
+	registerUser(t, "onlooker", "onlooker@email.com", "password")
+	otherCookie := loginUser(t, "onlooker@email.com", "password")
+	content := pngImage(t)
+
+	type attachmentResponse struct {
+		ID          int64  `json:"id"`
+		OwnerID     int64  `json:"owner_id"`
+		PostID      *int64 `json:"post_id"`
+		Filename    string `json:"filename"`
+		ContentType string `json:"content_type"`
+		Size        int64  `json:"size"`
+		URL         string `json:"url"`
+	}
+	upload := func(t *testing.T) attachmentResponse {
+		w := uploadFile(t, cookie, "photo.png", content)
+		assert.Equal(t, http.StatusCreated, w.Code)
+		var response struct {
+			Data attachmentResponse `json:"data"`
+		}
+		assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &response))
+		return response.Data
+	}
+	download := func(url, cookie string) *httptest.ResponseRecorder {
+		req := authorizedRequest(t, "GET", url, cookie, nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+	postWithAttachments := func(cookie string, ids ...int64) *httptest.ResponseRecorder {
+		req := authorizedRequest(t, "POST", "/posts", cookie, map[string]interface{}{
+			"title":          "gallery",
+			"content":        "pictures",
+			"attachment_ids": ids,
+		})
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	t.Run("upload an image", func(t *testing.T) {
+		attachment := upload(t)
+		assert.Equal(t, int64(ownerID), attachment.OwnerID)
+		assert.Nil(t, attachment.PostID)
+		assert.Equal(t, "photo.png", attachment.Filename)
+		assert.Equal(t, "image/png", attachment.ContentType)
+		assert.Equal(t, int64(len(content)), attachment.Size)
+		assert.Equal(t, fmt.Sprintf("/uploads/%d", attachment.ID), attachment.URL)
+	})
+
+	t.Run("upload anonymously", func(t *testing.T) {
+		w := uploadFile(t, "", "photo.png", content)
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+	})
+
+	t.Run("upload without a file", func(t *testing.T) {
+		req := authorizedRequest(t, "POST", "/uploads", cookie, nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
+	t.Run("upload a file that is not an image", func(t *testing.T) {
+		w := uploadFile(t, cookie, "photo.png", []byte("<html><body>not an image</body></html>"))
+		assert.Equal(t, http.StatusUnsupportedMediaType, w.Code)
+	})
+
+	t.Run("upload a file that is too large", func(t *testing.T) {
+		large := append(pngImage(t), make([]byte, usecase.MaxUploadSize)...)
+		w := uploadFile(t, cookie, "large.png", large)
+		assert.Equal(t, http.StatusRequestEntityTooLarge, w.Code)
+	})
+
+	t.Run("download an unlinked upload", func(t *testing.T) {
+		attachment := upload(t)
+		w := download(attachment.URL, cookie)
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, content, w.Body.Bytes())
+		assert.Equal(t, "image/png", w.Header().Get("Content-Type"))
+		assert.Equal(t, "nosniff", w.Header().Get("X-Content-Type-Options"))
+		assert.Equal(t, http.StatusNotFound, download(attachment.URL, otherCookie).Code)
+		assert.Equal(t, http.StatusNotFound, download(attachment.URL, "").Code)
+		assert.Equal(t, http.StatusNotFound, download("/uploads/999999", cookie).Code)
+	})
+
+	t.Run("attach uploads to a post", func(t *testing.T) {
+		first, second := upload(t), upload(t)
+		w := postWithAttachments(cookie, first.ID, second.ID)
+		assert.Equal(t, http.StatusCreated, w.Code)
+		var created struct {
+			Data struct {
+				ID          int64                `json:"id"`
+				Attachments []attachmentResponse `json:"attachments"`
+			} `json:"data"`
+		}
+		assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &created))
+		assert.Len(t, created.Data.Attachments, 2)
+
+		w = download(fmt.Sprintf("/posts/%d", created.Data.ID), "")
+		assert.Equal(t, http.StatusOK, w.Code)
+		var post struct {
+			Data struct {
+				Attachments []attachmentResponse `json:"attachments"`
+			} `json:"data"`
+		}
+		assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &post))
+		assert.Len(t, post.Data.Attachments, 2)
+		assert.Equal(t, first.URL, post.Data.Attachments[0].URL)
+		assert.Equal(t, created.Data.ID, *post.Data.Attachments[0].PostID)
+
+		// Attachments of a published post are as public as the post, once
+		// processed.
+		assert.Equal(t, http.StatusNotFound, download(first.URL, otherCookie).Code)
+		assert.Equal(t, http.StatusOK, download(first.URL, cookie).Code)
+		worker := attachmentWorker()
+		for _, id := range []int64{first.ID, second.ID} {
+			assert.Nil(t, worker.Process(context.Background(), domain.ProcessAttachmentPayload{AttachmentID: id}))
+		}
+		assert.Equal(t, http.StatusOK, download(first.URL, otherCookie).Code)
+		assert.Equal(t, http.StatusOK, download(first.URL, "").Code)
+
+		// An upload belongs to one post at a time.
+		assert.Equal(t, http.StatusBadRequest, postWithAttachments(cookie, first.ID).Code)
+
+		req := authorizedRequest(t, "PATCH", fmt.Sprintf("/posts/%d", created.Data.ID), cookie, map[string]interface{}{"attachment_ids": []int64{second.ID}})
+		req.Header.Set("Content-Type", "application/merge-patch+json")
+		w = httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &post))
+		assert.Len(t, post.Data.Attachments, 1)
+		assert.Equal(t, second.ID, post.Data.Attachments[0].ID)
+		assert.Equal(t, http.StatusNotFound, download(first.URL, "").Code)
+	})
+
+	t.Run("attach uploads of another user", func(t *testing.T) {
+		attachment := upload(t)
+		assert.Equal(t, http.StatusBadRequest, postWithAttachments(otherCookie, attachment.ID).Code)
+		assert.Equal(t, http.StatusBadRequest, postWithAttachments(cookie, 999999).Code)
+	})
+
+	t.Run("clean up orphaned uploads", func(t *testing.T) {
+		orphan, kept := upload(t), upload(t)
+		postID := createPost(t, cookie, "temporary", "content")
+		attached := upload(t)
+		_, err := db.Exec("UPDATE attachments SET post_id = ? WHERE id = ?", postID, attached.ID)
+		assert.Nil(t, err)
+		_, err = db.Exec("UPDATE attachments SET created_at = ? WHERE id IN (?, ?)", time.Now().AddDate(0, 0, -2), orphan.ID, attached.ID)
+		assert.Nil(t, err)
+		_, err = db.Exec("UPDATE posts SET deleted_at = ? WHERE id = ?", time.Now().AddDate(0, 0, -2), postID)
+		assert.Nil(t, err)
+
+		var keys []string
+		rows, err := db.Query("SELECT blob_key FROM attachments WHERE id IN (?, ?)", orphan.ID, attached.ID)
+		assert.Nil(t, err)
+		for rows.Next() {
+			var key string
+			assert.Nil(t, rows.Scan(&key))
+			keys = append(keys, key)
+		}
+		rows.Close()
+		assert.Len(t, keys, 2)
+
+		assert.Nil(t, attachmentWorker().CleanupAttachments(context.Background(), domain.CleanupJobPayload{RetentionDays: 1}))
+
+		var count int
+		assert.Nil(t, db.QueryRow("SELECT COUNT(*) FROM attachments WHERE id IN (?, ?)", orphan.ID, attached.ID).Scan(&count))
+		assert.Zero(t, count)
+		for _, key := range keys {
+			_, err := blobStore.Get(context.Background(), key)
+			assert.Equal(t, common.ErrBlobNotFound, err)
+		}
+		assert.Equal(t, http.StatusOK, download(kept.URL, cookie).Code)
+	})
+}
+
+func TestBlobStoreS3(t *testing.T) {
+	ctx := context.Background()
+	minio, err := testcontainers.GenericContainer(ctx, testcontainers.GenericContainerRequest{
+		ContainerRequest: testcontainers.ContainerRequest{
+			Image:        "minio/minio",
+			Cmd:          []string{"server", "/data"},
+			Env:          map[string]string{"MINIO_ROOT_USER": "minioadmin", "MINIO_ROOT_PASSWORD": "minioadmin"},
+			ExposedPorts: []string{"9000/tcp"},
+			WaitingFor:   wait.ForHTTP("/minio/health/live").WithPort("9000/tcp"),
+		},
+		Started: true,
+	})
+	assert.Nil(t, err)
+	defer minio.Terminate(ctx)
+	endpoint, err := minio.PortEndpoint(ctx, "9000/tcp", "http")
+	assert.Nil(t, err)
+
+	store, err := repository.NewBlobStoreS3(repository.S3Config{
+		Endpoint:        endpoint,
+		Bucket:          "uploads",
+		AccessKeyID:     "minioadmin",
+		SecretAccessKey: "minioadmin",
+	})
+	assert.Nil(t, err)
+	assert.Nil(t, store.EnsureBucket(ctx))
+	assert.Nil(t, store.EnsureBucket(ctx))
+
+	key := "photos/a photo+1.png"
+	content := pngImage(t)
+	assert.Nil(t, store.Put(ctx, key, bytes.NewReader(content), int64(len(content)), "image/png"))
+	body, err := store.Get(ctx, key)
+	assert.Nil(t, err)
+	stored, err := io.ReadAll(body)
+	body.Close()
+	assert.Nil(t, err)
+	assert.Equal(t, content, stored)
+
+	assert.Nil(t, store.Delete(ctx, key))
+	assert.Nil(t, store.Delete(ctx, key))
+	_, err = store.Get(ctx, key)
+	assert.Equal(t, common.ErrBlobNotFound, err)
+
+	wrong, err := repository.NewBlobStoreS3(repository.S3Config{
+		Endpoint:        endpoint,
+		Bucket:          "uploads",
+		AccessKeyID:     "minioadmin",
+		SecretAccessKey: "wrong",
+	})
+	assert.Nil(t, err)
+	err = wrong.Put(ctx, key, strings.NewReader("x"), 1, "text/plain")
+	assert.Equal(t, common.ErrInternalServerError, err)
+}
+
+// jpegWithExif encodes a photo carrying EXIF data with the orientation and a
+// location.
+func jpegWithExif(t *testing.T, width, height, orientation int) []byte {
+	var buf bytes.Buffer
+	assert.Nil(t, jpeg.Encode(&buf, image.NewRGBA(image.Rect(0, 0, width, height)), nil))
+	photo := buf.Bytes()
+	// A big endian TIFF structure holding a single IFD with the orientation,
+	// followed by a stand-in for the GPS data.
+	tiff := []byte("MM\x00\x2a\x00\x00\x00\x08\x00\x01\x01\x12\x00\x03\x00\x00\x00\x01\x00\x00\x00\x00\x00\x00\x00\x00GPSLatitude 48.8584")
+	tiff[19] = byte(orientation)
+	payload := append([]byte("Exif\x00\x00"), tiff...)
+	segment := []byte{0xFF, 0xE1, 0, 0}
+	binary.BigEndian.PutUint16(segment[2:], uint16(len(payload)+2))
+	segment = append(segment, payload...)
+	return append(append(append([]byte{}, photo[:2]...), segment...), photo[2:]...)
+}
+
+func TestImageProcessing(t *testing.T) {
+	registerUser(t, "painter", "painter@email.com", "password")
+	cookie := loginUser(t, "painter@email.com", "password")
+	worker := attachmentWorker()
+
+	type attachmentResponse struct {
+		ID          int64      `json:"id"`
+		Size        int64      `json:"size"`
+		Width       *int       `json:"width"`
+		Height      *int       `json:"height"`
+		Blurhash    *string    `json:"blurhash"`
+		URL         string     `json:"url"`
+		ProcessedAt *time.Time `json:"processed_at"`
+		Variants    []struct {
+			Name        string `json:"name"`
+			ContentType string `json:"content_type"`
+			Width       int    `json:"width"`
+			Height      int    `json:"height"`
+			URL         string `json:"url"`
+		} `json:"variants"`
+	}
+	upload := func(t *testing.T, filename string, content []byte) attachmentResponse {
+		w := uploadFile(t, cookie, filename, content)
+		assert.Equal(t, http.StatusCreated, w.Code)
+		var response struct {
+			Data attachmentResponse `json:"data"`
+		}
+		assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &response))
+		return response.Data
+	}
+	download := func(url, cookie string) *httptest.ResponseRecorder {
+		req := authorizedRequest(t, "GET", url, cookie, nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+	findAttachment := func(postID int64) attachmentResponse {
+		w := download(fmt.Sprintf("/posts/%d", postID), "")
+		assert.Equal(t, http.StatusOK, w.Code)
+		var post struct {
+			Data struct {
+				Attachments []attachmentResponse `json:"attachments"`
+			} `json:"data"`
+		}
+		assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &post))
+		assert.Len(t, post.Data.Attachments, 1)
+		return post.Data.Attachments[0]
+	}
+
+	// A 400x200 photo taken with the camera turned, which EXIF says to show
+	// rotated by 90 degrees.
+	photo := jpegWithExif(t, 400, 200, 6)
+	attachment := upload(t, "photo.jpg", photo)
+	req := authorizedRequest(t, "POST", "/posts", cookie, map[string]interface{}{
+		"title":          "landscape",
+		"content":        "a photo",
+		"attachment_ids": []int64{attachment.ID},
+	})
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusCreated, w.Code)
+	var post struct {
+		Data struct {
+			ID int64 `json:"id"`
+		} `json:"data"`
+	}
+	assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &post))
+
+	t.Run("upload queues the processing", func(t *testing.T) {
+		var count int
+		err := db.QueryRow("SELECT COUNT(*) FROM jobs WHERE type = ? AND payload = ?", domain.JobProcessAttachment, fmt.Sprintf(`{"attachment_id":%d}`, attachment.ID)).Scan(&count)
+		assert.Nil(t, err)
+		assert.Equal(t, 1, count)
+	})
+
+	t.Run("unprocessed images are only shown to their owner", func(t *testing.T) {
+		found := findAttachment(post.Data.ID)
+		assert.Nil(t, found.ProcessedAt)
+		assert.Nil(t, found.Width)
+		assert.Empty(t, found.Variants)
+		assert.Equal(t, http.StatusNotFound, download(attachment.URL, "").Code)
+		w := download(attachment.URL, cookie)
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "private, no-cache", w.Header().Get("Cache-Control"))
+		assert.Empty(t, w.Header().Get("ETag"))
+	})
+
+	assert.Nil(t, worker.Process(context.Background(), domain.ProcessAttachmentPayload{AttachmentID: attachment.ID}))
+	// Processing again changes nothing.
+	assert.Nil(t, worker.Process(context.Background(), domain.ProcessAttachmentPayload{AttachmentID: attachment.ID}))
+
+	t.Run("metadata is stripped and the image turned upright", func(t *testing.T) {
+		w := download(attachment.URL, "")
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.NotContains(t, w.Body.String(), "Exif")
+		assert.NotContains(t, w.Body.String(), "GPSLatitude")
+		config, err := jpeg.DecodeConfig(bytes.NewReader(w.Body.Bytes()))
+		assert.Nil(t, err)
+		assert.Equal(t, 200, config.Width)
+		assert.Equal(t, 400, config.Height)
+		assert.Equal(t, "public, max-age=31536000, immutable", w.Header().Get("Cache-Control"))
+
+		etag := w.Header().Get("ETag")
+		assert.NotEmpty(t, etag)
+		for _, header := range []string{etag, `"other", ` + etag, "W/" + etag, "*"} {
+			req := authorizedRequest(t, "GET", attachment.URL, "", nil)
+			req.Header.Set("If-None-Match", header)
+			w = httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			assert.Equal(t, http.StatusNotModified, w.Code, header)
+		}
+	})
+
+	t.Run("dimensions, placeholder and variants are recorded", func(t *testing.T) {
+		found := findAttachment(post.Data.ID)
+		assert.NotNil(t, found.ProcessedAt)
+		assert.Equal(t, 200, *found.Width)
+		assert.Equal(t, 400, *found.Height)
+		assert.Len(t, *found.Blurhash, 28)
+		// The photo is smaller than the other variants.
+		assert.Len(t, found.Variants, 1)
+		assert.Equal(t, "thumb", found.Variants[0].Name)
+		assert.Equal(t, "image/jpeg", found.Variants[0].ContentType)
+		assert.Equal(t, 80, found.Variants[0].Width)
+		assert.Equal(t, 160, found.Variants[0].Height)
+		assert.Equal(t, attachment.URL+"?variant=thumb", found.Variants[0].URL)
+	})
+
+	t.Run("serve a variant", func(t *testing.T) {
+		w := download(attachment.URL+"?variant=thumb", "")
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "image/jpeg", w.Header().Get("Content-Type"))
+		config, err := jpeg.DecodeConfig(bytes.NewReader(w.Body.Bytes()))
+		assert.Nil(t, err)
+		assert.Equal(t, 80, config.Width)
+		assert.Equal(t, 160, config.Height)
+
+		// Images too small for a variant are served as they are.
+		w = download(attachment.URL+"?variant=medium", "")
+		assert.Equal(t, http.StatusOK, w.Code)
+		config, err = jpeg.DecodeConfig(bytes.NewReader(w.Body.Bytes()))
+		assert.Nil(t, err)
+		assert.Equal(t, 400, config.Height)
+
+		assert.Equal(t, http.StatusBadRequest, download(attachment.URL+"?variant=huge", "").Code)
+	})
+
+	t.Run("PNG text chunks are stripped", func(t *testing.T) {
+		drawing := pngImage(t)
+		// A tEXt chunk right after the IHDR one, with a CRC the decoder
+		// does not get to check.
+		chunk := append([]byte{0, 0, 0, 13}, "tEXtAuthor\x00secret\x00\x00\x00\x00"...)
+		content := append(append(append([]byte{}, drawing[:33]...), chunk...), drawing[33:]...)
+		attachment := upload(t, "drawing.png", content)
+		assert.Nil(t, worker.Process(context.Background(), domain.ProcessAttachmentPayload{AttachmentID: attachment.ID}))
+		w := download(attachment.URL, cookie)
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, drawing, w.Body.Bytes())
+		assert.Equal(t, "private, max-age=31536000, immutable", w.Header().Get("Cache-Control"))
+	})
+}
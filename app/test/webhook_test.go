@@ -0,0 +1,297 @@
+package test
+
+import (
+	"app/repository"
+	"app/usecase"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWebhooks(t *testing.T) {
+	adminID := registerUser(t, "webmaster", "webmaster@email.com", "password")
+	_, err := db.Exec("UPDATE users SET role = 'admin' WHERE id = ?", adminID)
+	assert.Nil(t, err)
+	adminCookie := loginUser(t, "webmaster@email.com", "password")
+	registerUser(t, "publisher", "publisher@email.com", "password")
+	cookie := loginUser(t, "publisher@email.com", "password")
+
+	type received struct {
+		header http.Header
+		body   []byte
+	}
+	deliveries := make(chan received, 10)
+	var failing atomic.Bool
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		deliveries <- received{header: r.Header, body: body}
+		if failing.Load() {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer receiver.Close()
+
+	request := func(method, url, cookie string, body interface{}) *httptest.ResponseRecorder {
+		req := authorizedRequest(t, method, url, cookie, body)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+	// Deliveries are attempted by a worker, which is driven by hand here.
+	worker := usecase.NewWebhookUseCaseImpl(repository.NewWebhookRepositoryMySQL(db), repository.NewPostRepositoryMySQL(db), repository.NewSQLTransactor(db))
+	deliver := func() int {
+		delivered, err := worker.Deliver(context.Background())
+		assert.Nil(t, err)
+		return delivered
+	}
+	makeDue := func() {
+		_, err := db.Exec("UPDATE webhook_deliveries SET next_attempt_at = ? WHERE status = 'pending'", time.Now())
+		assert.Nil(t, err)
+	}
+	type webhookResponse struct {
+		Data struct {
+			ID           int64    `json:"id"`
+			Secret       string   `json:"secret"`
+			Events       []string `json:"events"`
+			Enabled      bool     `json:"enabled"`
+			FailureCount int      `json:"failure_count"`
+		} `json:"data"`
+	}
+	type deliveryPage struct {
+		Total int64 `json:"total"`
+		Data  []struct {
+			ID             int64   `json:"id"`
+			Event          string  `json:"event"`
+			Status         string  `json:"status"`
+			Attempts       int     `json:"attempts"`
+			ResponseStatus *int    `json:"response_status"`
+			NextAttemptAt  *string `json:"next_attempt_at"`
+		} `json:"data"`
+	}
+
+	t.Run("admins only", func(t *testing.T) {
+		assert.Equal(t, http.StatusUnauthorized, request("GET", "/admin/webhooks", "", nil).Code)
+		assert.Equal(t, http.StatusForbidden, request("GET", "/admin/webhooks", cookie, nil).Code)
+		assert.Equal(t, http.StatusOK, request("GET", "/admin/webhooks", adminCookie, nil).Code)
+	})
+
+	t.Run("validation", func(t *testing.T) {
+		w := request("POST", "/admin/webhooks", adminCookie, map[string]interface{}{"url": receiver.URL, "events": []string{"post.published"}})
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		w = request("POST", "/admin/webhooks", adminCookie, map[string]interface{}{"url": "ftp://example.com", "events": []string{"post.created"}})
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
+	w := request("POST", "/admin/webhooks", adminCookie, map[string]interface{}{
+		"url":    receiver.URL,
+		"events": []string{"post.created", "comment.created"},
+	})
+	assert.Equal(t, http.StatusCreated, w.Code)
+	var webhook webhookResponse
+	assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &webhook))
+	assert.NotEmpty(t, webhook.Data.Secret)
+	assert.True(t, webhook.Data.Enabled)
+	webhookURL := fmt.Sprintf("/admin/webhooks/%d", webhook.Data.ID)
+	listDeliveries := func() deliveryPage {
+		w := request("GET", webhookURL+"/deliveries", adminCookie, nil)
+		assert.Equal(t, http.StatusOK, w.Code)
+		var page deliveryPage
+		assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &page))
+		return page
+	}
+
+	var postID int64
+	t.Run("signed delivery", func(t *testing.T) {
+		w := request("GET", webhookURL, adminCookie, nil)
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.NotContains(t, w.Body.String(), webhook.Data.Secret)
+
+		postID = createPost(t, cookie, "Hooked", "content")
+		assert.Equal(t, 1, deliver())
+		got := <-deliveries
+		assert.Equal(t, "post.created", got.header.Get("X-Webhook-Event"))
+		mac := hmac.New(sha256.New, []byte(webhook.Data.Secret))
+		mac.Write([]byte(got.header.Get("X-Webhook-Timestamp") + "."))
+		mac.Write(got.body)
+		assert.Equal(t, "sha256="+hex.EncodeToString(mac.Sum(nil)), got.header.Get("X-Webhook-Signature"))
+		var payload struct {
+			Event string `json:"event"`
+			Data  struct {
+				ID    int64  `json:"id"`
+				Title string `json:"title"`
+			} `json:"data"`
+		}
+		assert.Nil(t, json.Unmarshal(got.body, &payload))
+		assert.Equal(t, "post.created", payload.Event)
+		assert.Equal(t, postID, payload.Data.ID)
+		assert.Equal(t, "Hooked", payload.Data.Title)
+
+		page := listDeliveries()
+		assert.Equal(t, int64(1), page.Total)
+		assert.Equal(t, "succeeded", page.Data[0].Status)
+		assert.Equal(t, 1, page.Data[0].Attempts)
+		assert.Zero(t, deliver())
+	})
+
+	t.Run("drafts are not delivered", func(t *testing.T) {
+		w := request("POST", "/posts", cookie, map[string]interface{}{
+			"title":   "Embargoed",
+			"content": "secret plans",
+			"status":  "draft",
+		})
+		assert.Equal(t, http.StatusCreated, w.Code)
+		var draft struct {
+			Data struct {
+				ID int64 `json:"id"`
+			} `json:"data"`
+		}
+		assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &draft))
+		w = request("POST", fmt.Sprintf("/posts/%d/comments", draft.Data.ID), cookie, map[string]interface{}{"content": "note to self"})
+		assert.Equal(t, http.StatusCreated, w.Code)
+		assert.Zero(t, deliver())
+		assert.Equal(t, int64(1), listDeliveries().Total)
+	})
+
+	t.Run("retries", func(t *testing.T) {
+		failing.Store(true)
+		w := request("POST", fmt.Sprintf("/posts/%d/comments", postID), cookie, map[string]interface{}{"content": "Hello hooks"})
+		assert.Equal(t, http.StatusCreated, w.Code)
+		assert.Equal(t, 1, deliver())
+		<-deliveries
+		page := listDeliveries()
+		assert.Equal(t, "comment.created", page.Data[0].Event)
+		assert.Equal(t, "pending", page.Data[0].Status)
+		assert.Equal(t, 1, page.Data[0].Attempts)
+		assert.Equal(t, http.StatusInternalServerError, *page.Data[0].ResponseStatus)
+		assert.NotNil(t, page.Data[0].NextAttemptAt)
+		// The retry is backed off.
+		assert.Zero(t, deliver())
+
+		failing.Store(false)
+		makeDue()
+		assert.Equal(t, 1, deliver())
+		first := <-deliveries
+		page = listDeliveries()
+		assert.Equal(t, "succeeded", page.Data[0].Status)
+		assert.Equal(t, 2, page.Data[0].Attempts)
+
+		w = request("POST", fmt.Sprintf("%s/deliveries/%d/replay", webhookURL, page.Data[0].ID), adminCookie, nil)
+		assert.Equal(t, http.StatusCreated, w.Code)
+		assert.Equal(t, 1, deliver())
+		replayed := <-deliveries
+		assert.Equal(t, first.body, replayed.body)
+		assert.NotEqual(t, first.header.Get("X-Webhook-ID"), replayed.header.Get("X-Webhook-ID"))
+		assert.Equal(t, int64(3), listDeliveries().Total)
+	})
+
+	t.Run("disabled after repeated failures", func(t *testing.T) {
+		failing.Store(true)
+		for i := 0; i < 3; i++ {
+			createPost(t, cookie, fmt.Sprintf("Unreachable %d", i), "content")
+		}
+		for i := 0; i < 5; i++ {
+			makeDue()
+			assert.Equal(t, 3, deliver())
+			for j := 0; j < 3; j++ {
+				<-deliveries
+			}
+		}
+		w := request("GET", webhookURL, adminCookie, nil)
+		var disabled webhookResponse
+		assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &disabled))
+		assert.False(t, disabled.Data.Enabled)
+		assert.Equal(t, 15, disabled.Data.FailureCount)
+		makeDue()
+		assert.Zero(t, deliver())
+
+		// Enabling it again resumes the pending deliveries.
+		failing.Store(false)
+		w = request("PUT", webhookURL, adminCookie, map[string]interface{}{
+			"url":     receiver.URL,
+			"events":  []string{"post.created", "comment.created"},
+			"enabled": true,
+		})
+		assert.Equal(t, http.StatusOK, w.Code)
+		var enabled webhookResponse
+		assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &enabled))
+		assert.True(t, enabled.Data.Enabled)
+		assert.Zero(t, enabled.Data.FailureCount)
+		makeDue()
+		assert.Equal(t, 3, deliver())
+	})
+
+	t.Run("unpublishing is delivered", func(t *testing.T) {
+		w := request("POST", "/admin/webhooks", adminCookie, map[string]interface{}{
+			"url":    receiver.URL,
+			"events": []string{"post.unpublished", "post.deleted", "comment.deleted"},
+		})
+		assert.Equal(t, http.StatusCreated, w.Code)
+		var removals webhookResponse
+		assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &removals))
+		removalsURL := fmt.Sprintf("/admin/webhooks/%d", removals.Data.ID)
+		events := func() []string {
+			w := request("GET", removalsURL+"/deliveries", adminCookie, nil)
+			assert.Equal(t, http.StatusOK, w.Code)
+			var page deliveryPage
+			assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &page))
+			var events []string
+			for _, delivery := range page.Data {
+				events = append(events, delivery.Event)
+			}
+			return events
+		}
+
+		retracted := createPost(t, cookie, "Retracted", "content")
+		w = request("POST", fmt.Sprintf("/posts/%d/comments", retracted), cookie, map[string]interface{}{"content": "soon hidden"})
+		assert.Equal(t, http.StatusCreated, w.Code)
+		var comment struct {
+			Data struct {
+				ID int64 `json:"id"`
+			} `json:"data"`
+		}
+		assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &comment))
+		req := authorizedRequest(t, "PATCH", fmt.Sprintf("/posts/%d", retracted), cookie, map[string]string{"status": "draft"})
+		req.Header.Set("Content-Type", "application/merge-patch+json")
+		w = httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, []string{"post.unpublished"}, events())
+
+		// Receivers were told the post is gone, so removals from the draft
+		// and deleting drafts that never were published are not sent.
+		w = request("DELETE", fmt.Sprintf("/posts/%d/comments/%d", retracted, comment.Data.ID), cookie, nil)
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, http.StatusOK, request("DELETE", fmt.Sprintf("/posts/%d", retracted), cookie, nil).Code)
+		w = request("POST", "/posts", cookie, map[string]interface{}{"title": "Never out", "content": "content", "status": "draft"})
+		assert.Equal(t, http.StatusCreated, w.Code)
+		var draft struct {
+			Data struct {
+				ID int64 `json:"id"`
+			} `json:"data"`
+		}
+		assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &draft))
+		assert.Equal(t, http.StatusOK, request("DELETE", fmt.Sprintf("/posts/%d", draft.Data.ID), cookie, nil).Code)
+		assert.Equal(t, []string{"post.unpublished"}, events())
+
+		deleted := createPost(t, cookie, "Deleted", "content")
+		assert.Equal(t, http.StatusOK, request("DELETE", fmt.Sprintf("/posts/%d", deleted), cookie, nil).Code)
+		assert.Equal(t, []string{"post.deleted", "post.unpublished"}, events())
+
+		assert.Equal(t, http.StatusOK, request("DELETE", removalsURL, adminCookie, nil).Code)
+	})
+
+	w = request("DELETE", webhookURL, adminCookie, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, http.StatusNotFound, request("GET", webhookURL, adminCookie, nil).Code)
+}
@@ -0,0 +1,122 @@
+package test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/net/websocket"
+)
+
+type wsMessage struct {
+	Type    string          `json:"type"`
+	PostID  int64           `json:"post_id"`
+	Readers int             `json:"readers"`
+	Data    json.RawMessage `json:"data"`
+	Message string          `json:"message"`
+}
+
+func TestWebSocket(t *testing.T) {
+	authorID := registerUser(t, "broadcaster", "broadcaster@email.com", "password")
+	authorCookie := loginUser(t, "broadcaster@email.com", "password")
+	registerUser(t, "watcher", "watcher@email.com", "password")
+	cookie := loginUser(t, "watcher@email.com", "password")
+	postID := createPost(t, authorCookie, "Watched", "content")
+	draftReq := authorizedRequest(t, "POST", "/posts", authorCookie, map[string]string{"title": "Hidden", "content": "content", "status": "draft"})
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, draftReq)
+	var draft struct {
+		Data struct {
+			ID int64 `json:"id"`
+		} `json:"data"`
+	}
+	assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &draft))
+
+	server := httptest.NewServer(router)
+	defer server.Close()
+	wsURL := "ws" + strings.TrimPrefix(server.URL, "http") + "/ws"
+	dial := func(cookie, origin string) (*websocket.Conn, error) {
+		config, err := websocket.NewConfig(wsURL, origin)
+		assert.Nil(t, err)
+		config.Header.Set("Cookie", "AUTHORIZATION="+cookie)
+		return websocket.DialConfig(config)
+	}
+	receive := func(ws *websocket.Conn, messageType string) wsMessage {
+		ws.SetReadDeadline(time.Now().Add(10 * time.Second))
+		for {
+			var message wsMessage
+			if !assert.Nil(t, websocket.JSON.Receive(ws, &message)) || message.Type == messageType {
+				return message
+			}
+		}
+	}
+
+	t.Run("authentication and origin", func(t *testing.T) {
+		_, err := dial("", server.URL)
+		assert.NotNil(t, err)
+		_, err = dial(cookie, "http://evil.example.org")
+		assert.NotNil(t, err)
+	})
+
+	watcher, err := dial(cookie, server.URL)
+	if !assert.Nil(t, err) {
+		return
+	}
+	defer watcher.Close()
+	author, err := dial(authorCookie, server.URL)
+	if !assert.Nil(t, err) {
+		return
+	}
+	defer author.Close()
+
+	t.Run("subscriptions and presence", func(t *testing.T) {
+		assert.Nil(t, websocket.JSON.Send(watcher, map[string]interface{}{"type": "subscribe", "post_id": draft.Data.ID}))
+		assert.Equal(t, "Post not found", receive(watcher, "error").Message)
+
+		assert.Nil(t, websocket.JSON.Send(watcher, map[string]interface{}{"type": "subscribe", "post_id": postID}))
+		assert.Equal(t, postID, receive(watcher, "subscribed").PostID)
+		assert.Equal(t, 1, receive(watcher, "presence").Readers)
+
+		assert.Nil(t, websocket.JSON.Send(author, map[string]interface{}{"type": "subscribe", "post_id": postID}))
+		assert.Equal(t, 2, receive(author, "presence").Readers)
+		assert.Equal(t, 2, receive(watcher, "presence").Readers)
+	})
+
+	t.Run("comments on subscribed posts", func(t *testing.T) {
+		req := authorizedRequest(t, "POST", fmt.Sprintf("/posts/%d/comments", postID), authorCookie, map[string]string{"content": "Welcome"})
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusCreated, w.Code)
+		message := receive(watcher, "comment.created")
+		assert.Equal(t, postID, message.PostID)
+		assert.Contains(t, string(message.Data), "Welcome")
+	})
+
+	t.Run("notifications", func(t *testing.T) {
+		req := authorizedRequest(t, "PUT", fmt.Sprintf("/users/%d/follow", authorID), cookie, nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusOK, w.Code)
+		message := receive(author, "notification")
+		var notification struct {
+			ID        int64  `json:"id"`
+			Type      string `json:"type"`
+			ActorName string `json:"actor_name"`
+		}
+		assert.Nil(t, json.Unmarshal(message.Data, &notification))
+		assert.NotZero(t, notification.ID)
+		assert.Equal(t, "follow", notification.Type)
+		assert.Equal(t, "watcher", notification.ActorName)
+	})
+
+	t.Run("leaving updates presence", func(t *testing.T) {
+		assert.Nil(t, websocket.JSON.Send(watcher, map[string]interface{}{"type": "unsubscribe", "post_id": postID}))
+		assert.Equal(t, postID, receive(watcher, "unsubscribed").PostID)
+		assert.Equal(t, 1, receive(author, "presence").Readers)
+	})
+}
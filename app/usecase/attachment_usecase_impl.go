@@ -0,0 +1,367 @@
+package usecase
+
+import (
+	"app/domain"
+	"app/pkg/common"
+	"app/pkg/imaging"
+	"app/pkg/logger"
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"image"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"path"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/gabriel-vasile/mimetype"
+	"go.uber.org/zap"
+)
+
+const (
+	// MaxUploadSize is the largest file accepted for upload, in bytes.
+	MaxUploadSize = 10 << 20
+	// sniffLength is how much of a file is read to detect its type.
+	sniffLength          = 3072
+	attachmentBatchSize  = 100
+	attachmentKeyLength  = 16
+	attachmentURLPattern = "/uploads/%d"
+	// maxImagePixels bounds the images decoded for processing, as a small
+	// file may unpack into a huge image.
+	maxImagePixels = 50_000_000
+	// blurhashSize is the longer side of the copy a placeholder is computed
+	// from; more detail would be lost in the blur anyway.
+	blurhashSize = 32
+	imageQuality = 85
+)
+
+// decoders are the image types that can be resized; WebP cannot be decoded
+// with the standard library and is only stripped of its metadata.
+var decoders = map[string]func(io.Reader) (image.Image, error){
+	"image/jpeg": jpeg.Decode,
+	"image/png":  png.Decode,
+	"image/gif":  gif.Decode,
+}
+
+type AttachmentUseCaseImpl struct {
+	attachmentRepository domain.AttachmentRepository
+	postRepository       domain.PostRepository
+	blobStore            domain.BlobStore
+	jobs                 domain.JobQueue
+	transactor           domain.Transactor
+	variants             []domain.ImageVariant
+}
+
+// NewAttachmentUseCaseImpl returns the use case storing uploads, which also
+// runs the jobs processing them and cleaning up orphaned ones. Images get a
+// version of each of the variants that is smaller than the original.
+func NewAttachmentUseCaseImpl(attachmentRepository domain.AttachmentRepository, postRepository domain.PostRepository, blobStore domain.BlobStore, jobs domain.JobQueue, transactor domain.Transactor, variants []domain.ImageVariant) *AttachmentUseCaseImpl {
+	return &AttachmentUseCaseImpl{
+		attachmentRepository: attachmentRepository,
+		postRepository:       postRepository,
+		blobStore:            blobStore,
+		jobs:                 jobs,
+		transactor:           transactor,
+		variants:             variants,
+	}
+}
+
+// Upload implements domain.AttachmentUseCase. The type is sniffed from the
+// first bytes of the file, whatever its name or the client claims. The file
+// is processed in the background.
+func (uc *AttachmentUseCaseImpl) Upload(ctx context.Context, request domain.UploadRequestDTO) (*domain.Attachment, error) {
+	if request.Size > MaxUploadSize {
+		return nil, common.ErrFileTooLarge
+	}
+	head := make([]byte, sniffLength)
+	n, err := io.ReadFull(request.File, head)
+	if err != nil && !errors.Is(err, io.EOF) && !errors.Is(err, io.ErrUnexpectedEOF) {
+		logger.Log.Error("failed to read upload", zap.Error(err))
+		return nil, common.ErrInternalServerError
+	}
+	head = head[:n]
+	detected := mimetype.Detect(head)
+	index := slices.IndexFunc(domain.AttachmentTypes, detected.Is)
+	if index < 0 {
+		return nil, common.ErrUnsupportedMedia
+	}
+	key := make([]byte, attachmentKeyLength)
+	if _, err := rand.Read(key); err != nil {
+		logger.Log.Error("failed to generate attachment key", zap.Error(err))
+		return nil, common.ErrInternalServerError
+	}
+	attachment := &domain.Attachment{
+		OwnerID:     request.OwnerID,
+		Key:         hex.EncodeToString(key) + detected.Extension(),
+		Filename:    common.Excerpt(request.Filename, 255),
+		ContentType: domain.AttachmentTypes[index],
+		Size:        request.Size,
+		CreatedAt:   time.Now(),
+	}
+	err = uc.blobStore.Put(ctx, attachment.Key, io.MultiReader(bytes.NewReader(head), request.File), request.Size, attachment.ContentType)
+	if err != nil {
+		return nil, err
+	}
+	if err := uc.create(ctx, attachment); err != nil {
+		uc.blobStore.Delete(ctx, attachment.Key)
+		return nil, err
+	}
+	setAttachmentURLs(attachment)
+	return attachment, nil
+}
+
+// create records the attachment along with the job processing it.
+func (uc *AttachmentUseCaseImpl) create(ctx context.Context, attachment *domain.Attachment) error {
+	tx, err := uc.transactor.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	err = uc.attachmentRepository.Create(ctx, tx, attachment)
+	if err != nil {
+		return err
+	}
+	err = uc.jobs.Enqueue(ctx, tx, domain.JobProcessAttachment, domain.ProcessAttachmentPayload{AttachmentID: attachment.ID}, time.Now())
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// Open implements domain.AttachmentUseCase.
+func (uc *AttachmentUseCaseImpl) Open(ctx context.Context, id, viewerID int64, variant string) (*domain.AttachmentFile, error) {
+	if variant != "" && !slices.ContainsFunc(uc.variants, func(v domain.ImageVariant) bool { return v.Name == variant }) {
+		return nil, common.ErrInvalidVariant
+	}
+	attachment, err := uc.attachmentRepository.FindByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	public := false
+	if attachment.PostID != nil {
+		post, err := uc.postRepository.GetByID(ctx, *attachment.PostID)
+		if err != nil && !errors.Is(err, common.ErrPostNotFound) {
+			return nil, err
+		}
+		if post != nil && post.VisibleTo(viewerID) && attachment.ProcessedAt != nil {
+			public = post.Status == domain.PostStatusPublished
+		} else if attachment.OwnerID != viewerID {
+			return nil, common.ErrAttachmentNotFound
+		}
+	} else if attachment.OwnerID != viewerID {
+		return nil, common.ErrAttachmentNotFound
+	}
+	file := &domain.AttachmentFile{
+		Key:         attachment.Key,
+		Filename:    attachment.Filename,
+		ContentType: attachment.ContentType,
+		Size:        attachment.Size,
+		Processed:   attachment.ProcessedAt != nil,
+		Public:      public,
+	}
+	for _, v := range attachment.Variants {
+		if v.Name == variant {
+			file.Key, file.ContentType, file.Size = v.Key, v.ContentType, v.Size
+		}
+	}
+	file.Content, err = uc.blobStore.Get(ctx, file.Key)
+	if err != nil {
+		return nil, err
+	}
+	return file, nil
+}
+
+// Process strips the metadata of an uploaded image, turning it upright when
+// its EXIF data said how, and records its dimensions, placeholder and
+// variants. Files whose metadata cannot be stripped are left unprocessed,
+// and so only ever shown to their owner.
+func (uc *AttachmentUseCaseImpl) Process(ctx context.Context, payload domain.ProcessAttachmentPayload) error {
+	attachment, err := uc.attachmentRepository.FindByID(ctx, payload.AttachmentID)
+	if errors.Is(err, common.ErrAttachmentNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if attachment.ProcessedAt != nil {
+		return nil
+	}
+	content, err := uc.blobStore.Get(ctx, attachment.Key)
+	if err != nil {
+		return err
+	}
+	original, err := io.ReadAll(io.LimitReader(content, MaxUploadSize+1))
+	content.Close()
+	if err != nil {
+		logger.Log.Error("failed to read attachment", zap.Int64("id", attachment.ID), zap.Error(err))
+		return common.ErrInternalServerError
+	}
+	stripped, err := imaging.StripMetadata(attachment.ContentType, original)
+	if err != nil {
+		logger.Log.Warn("failed to strip attachment metadata", zap.Int64("id", attachment.ID), zap.Error(err))
+		return nil
+	}
+
+	img, err := uc.decode(attachment.ContentType, stripped)
+	if err != nil {
+		logger.Log.Warn("failed to decode attachment", zap.Int64("id", attachment.ID), zap.Error(err))
+	}
+	attachment.Variants = []domain.AttachmentVariant{}
+	if img != nil {
+		if orientation := imaging.Orientation(attachment.ContentType, original); orientation != 1 {
+			img = imaging.Orient(img, orientation)
+			if stripped, err = encodeImage(attachment.ContentType, img); err != nil {
+				return err
+			}
+		}
+		width, height := img.Bounds().Dx(), img.Bounds().Dy()
+		blurWidth, blurHeight := imaging.Fit(width, height, blurhashSize)
+		blurhash := imaging.Blurhash(imaging.Resize(img, blurWidth, blurHeight), 4, 3)
+		attachment.Width, attachment.Height, attachment.Blurhash = &width, &height, &blurhash
+		for _, v := range uc.variants {
+			if max(width, height) <= v.Size {
+				continue
+			}
+			variant, err := uc.saveVariant(ctx, attachment, v, img)
+			if err != nil {
+				return err
+			}
+			attachment.Variants = append(attachment.Variants, *variant)
+		}
+	}
+	if !bytes.Equal(stripped, original) {
+		err = uc.blobStore.Put(ctx, attachment.Key, bytes.NewReader(stripped), int64(len(stripped)), attachment.ContentType)
+		if err != nil {
+			return err
+		}
+		attachment.Size = int64(len(stripped))
+	}
+
+	now := time.Now()
+	attachment.ProcessedAt = &now
+	tx, err := uc.transactor.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	err = uc.attachmentRepository.SaveProcessed(ctx, tx, attachment)
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// decode returns the pixels of the image, or nil when its type cannot be
+// decoded or it is too large to be.
+func (uc *AttachmentUseCaseImpl) decode(contentType string, data []byte) (*image.RGBA, error) {
+	decoder, ok := decoders[contentType]
+	if !ok {
+		return nil, nil
+	}
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	if config.Width*config.Height > maxImagePixels {
+		return nil, fmt.Errorf("image of %dx%d pixels is too large", config.Width, config.Height)
+	}
+	img, err := decoder(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	return imaging.RGBA(img), nil
+}
+
+// saveVariant scales the image down to the variant and stores it next to
+// the original. JPEG photos stay JPEG, other images become PNG so as to keep
+// their transparency.
+func (uc *AttachmentUseCaseImpl) saveVariant(ctx context.Context, attachment *domain.Attachment, v domain.ImageVariant, img *image.RGBA) (*domain.AttachmentVariant, error) {
+	contentType, extension := "image/png", ".png"
+	if attachment.ContentType == "image/jpeg" {
+		contentType, extension = "image/jpeg", ".jpg"
+	}
+	width, height := imaging.Fit(img.Bounds().Dx(), img.Bounds().Dy(), v.Size)
+	data, err := encodeImage(contentType, imaging.Resize(img, width, height))
+	if err != nil {
+		return nil, err
+	}
+	variant := &domain.AttachmentVariant{
+		Name:        v.Name,
+		Key:         strings.TrimSuffix(attachment.Key, path.Ext(attachment.Key)) + "_" + v.Name + extension,
+		ContentType: contentType,
+		Width:       width,
+		Height:      height,
+		Size:        int64(len(data)),
+	}
+	err = uc.blobStore.Put(ctx, variant.Key, bytes.NewReader(data), variant.Size, contentType)
+	if err != nil {
+		return nil, err
+	}
+	return variant, nil
+}
+
+func encodeImage(contentType string, img image.Image) ([]byte, error) {
+	var buf bytes.Buffer
+	var err error
+	switch contentType {
+	case "image/jpeg":
+		err = jpeg.Encode(&buf, img, &jpeg.Options{Quality: imageQuality})
+	default:
+		err = png.Encode(&buf, img)
+	}
+	if err != nil {
+		logger.Log.Error("failed to encode image", zap.Error(err))
+		return nil, common.ErrInternalServerError
+	}
+	return buf.Bytes(), nil
+}
+
+// CleanupAttachments deletes the uploads never linked to a post within the
+// retention, and those of posts deleted longer ago than that. The files go
+// first, so a failure leaves the row to be retried rather than files nobody
+// knows about.
+func (uc *AttachmentUseCaseImpl) CleanupAttachments(ctx context.Context, payload domain.CleanupJobPayload) error {
+	before := time.Now().AddDate(0, 0, -payload.RetentionDays)
+	deleted := 0
+	for {
+		attachments, err := uc.attachmentRepository.FindOrphans(ctx, before, attachmentBatchSize)
+		if err != nil {
+			return err
+		}
+		for _, attachment := range attachments {
+			for _, variant := range attachment.Variants {
+				if err := uc.blobStore.Delete(ctx, variant.Key); err != nil {
+					return err
+				}
+			}
+			if err := uc.blobStore.Delete(ctx, attachment.Key); err != nil {
+				return err
+			}
+			if err := uc.attachmentRepository.Delete(ctx, attachment.ID); err != nil {
+				return err
+			}
+			deleted++
+		}
+		if len(attachments) < attachmentBatchSize {
+			break
+		}
+	}
+	logger.Log.Info("deleted orphaned attachments", zap.Int("attachments", deleted))
+	return nil
+}
+
+// setAttachmentURLs points the attachment and its variants at their
+// download endpoint.
+func setAttachmentURLs(attachment *domain.Attachment) {
+	attachment.URL = fmt.Sprintf(attachmentURLPattern, attachment.ID)
+	for i := range attachment.Variants {
+		attachment.Variants[i].URL = attachment.URL + "?variant=" + attachment.Variants[i].Name
+	}
+}
@@ -74,12 +74,23 @@ func (uc *AuthUseCaseImpl) Register(ctx context.Context, request *domain.Registe
 	if user != nil {
 		return nil, common.ErrEmailAlreadyExists
 	}
+	if !common.ValidUsername(request.Username) {
+		return nil, common.ErrInvalidUsername
+	}
+	taken, err := uc.userRepository.FindByUsernames(ctx, []string{request.Username})
+	if err != nil {
+		return nil, err
+	}
+	if len(taken) > 0 {
+		return nil, common.ErrUsernameAlreadyExists
+	}
 	passwordHash, err := bcrypt.GenerateFromPassword([]byte(request.Password), 14)
 	if err != nil {
 		return nil, err
 	}
 	user = &domain.User{
 		Name:         request.Name,
+		Username:     request.Username,
 		Email:        request.Email,
 		PasswordHash: string(passwordHash),
 		CreatedAt:    now,
@@ -106,6 +117,31 @@ func (uc *AuthUseCaseImpl) VerifyToken(ctx context.Context, token string) (*doma
 	return uc.tokenRepository.Verify(ctx, token)
 }
 
+// RequireRole implements domain.AuthUseCase. The role is read from the
+// database on every request, so revoking it takes effect immediately.
+func (uc *AuthUseCaseImpl) RequireRole(ctx context.Context, userID int64, role string) error {
+	user, err := uc.userRepository.FindByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if user.Role != role {
+		return common.ErrForbidden
+	}
+	return nil
+}
+
+// SetRole implements domain.AuthUseCase.
+func (uc *AuthUseCaseImpl) SetRole(ctx context.Context, email string, role string) error {
+	if role != domain.RoleUser && role != domain.RoleAdmin {
+		return common.ErrInvalidParam
+	}
+	user, err := uc.userRepository.FindByEmail(ctx, email)
+	if err != nil {
+		return err
+	}
+	return uc.userRepository.SetRole(ctx, user.ID, role)
+}
+
 // RefreshToken implements domain.AuthUseCase.
 func (uc *AuthUseCaseImpl) RefreshToken(ctx context.Context, token string) (*domain.RefreshTokenResponse, error) {
 	verifiedToken, err := uc.tokenRepository.Verify(ctx, token)
@@ -0,0 +1,92 @@
+package usecase
+
+import (
+	"app/domain"
+	"app/pkg/common"
+	"context"
+	"strings"
+)
+
+type BookmarkUseCaseImpl struct {
+	bookmarkRepository domain.BookmarkRepository
+	postRepository     domain.PostRepository
+	transactor         domain.Transactor
+}
+
+func NewBookmarkUseCaseImpl(bookmarkRepository domain.BookmarkRepository, postRepository domain.PostRepository, transactor domain.Transactor) domain.BookmarkUseCase {
+	return &BookmarkUseCaseImpl{
+		bookmarkRepository: bookmarkRepository,
+		postRepository:     postRepository,
+		transactor:         transactor,
+	}
+}
+
+// Save implements domain.BookmarkUseCase. The collection is created on first
+// use, and an empty name files the bookmark under no collection.
+func (uc *BookmarkUseCaseImpl) Save(ctx context.Context, req domain.SaveBookmarkRequestDTO) error {
+	post, err := uc.postRepository.GetByID(ctx, req.PostID)
+	if err != nil {
+		return err
+	}
+	if !post.VisibleTo(req.UserID) {
+		return common.ErrPostNotFound
+	}
+	tx, err := uc.transactor.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	var collectionID *int64
+	if name := strings.TrimSpace(req.Collection); name != "" {
+		id, err := uc.bookmarkRepository.UpsertCollection(ctx, tx, req.UserID, name)
+		if err != nil {
+			return err
+		}
+		collectionID = &id
+	}
+	if err := uc.bookmarkRepository.Save(ctx, tx, req.UserID, req.PostID, collectionID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// Delete implements domain.BookmarkUseCase. Removing a post that is not
+// bookmarked succeeds.
+func (uc *BookmarkUseCaseImpl) Delete(ctx context.Context, userID, postID int64) error {
+	return uc.bookmarkRepository.Delete(ctx, userID, postID)
+}
+
+// FindByUser implements domain.BookmarkUseCase. Each bookmark embeds the
+// saved post.
+func (uc *BookmarkUseCaseImpl) FindByUser(ctx context.Context, userID int64, param domain.BookmarkParam) ([]domain.Bookmark, *domain.PageInfo, error) {
+	bookmarks, page, err := uc.bookmarkRepository.FindByUserCursor(ctx, userID, param)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(bookmarks) == 0 {
+		return bookmarks, page, nil
+	}
+	ids := make([]int64, len(bookmarks))
+	for i, bookmark := range bookmarks {
+		ids[i] = bookmark.PostID
+	}
+	posts, err := uc.postRepository.FindByIDs(ctx, ids)
+	if err != nil {
+		return nil, nil, err
+	}
+	byID := make(map[int64]*domain.Post, len(posts))
+	bookmarked := true
+	for i := range posts {
+		posts[i].Bookmarked = &bookmarked
+		byID[posts[i].ID] = &posts[i]
+	}
+	for i := range bookmarks {
+		bookmarks[i].Post = byID[bookmarks[i].PostID]
+	}
+	return bookmarks, page, nil
+}
+
+// GetCollections implements domain.BookmarkUseCase.
+func (uc *BookmarkUseCaseImpl) GetCollections(ctx context.Context, userID int64) ([]domain.BookmarkCollection, error) {
+	return uc.bookmarkRepository.GetCollections(ctx, userID)
+}
@@ -0,0 +1,83 @@
+package usecase
+
+import (
+	"app/domain"
+	"app/pkg/common"
+	"context"
+	"time"
+)
+
+type CategoryUseCaseImpl struct {
+	categoryRepository domain.CategoryRepository
+	transactor         domain.Transactor
+}
+
+func NewCategoryUseCaseImpl(categoryRepository domain.CategoryRepository, transactor domain.Transactor) domain.CategoryUseCase {
+	return &CategoryUseCaseImpl{
+		categoryRepository: categoryRepository,
+		transactor:         transactor,
+	}
+}
+
+// Create implements domain.CategoryUseCase.
+func (uc *CategoryUseCaseImpl) Create(ctx context.Context, request *domain.CreateCategoryRequestDTO) (*domain.Category, error) {
+	slug := common.Slugify(request.Name)
+	if slug == "" {
+		return nil, common.ErrInvalidParam
+	}
+	existing, err := uc.categoryRepository.GetBySlug(ctx, slug)
+	if err != nil && err != common.ErrCategoryNotFound {
+		return nil, err
+	}
+	if existing != nil {
+		return nil, common.ErrCategoryExists
+	}
+	if request.ParentID != nil {
+		if _, err := uc.categoryRepository.GetByID(ctx, *request.ParentID); err != nil {
+			return nil, err
+		}
+	}
+	tx, err := uc.transactor.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+	category := &domain.Category{
+		Name:      request.Name,
+		Slug:      slug,
+		ParentID:  request.ParentID,
+		CreatedAt: time.Now(),
+	}
+	err = uc.categoryRepository.Create(ctx, tx, category)
+	if err != nil {
+		return nil, err
+	}
+	err = tx.Commit()
+	if err != nil {
+		return nil, err
+	}
+	return category, nil
+}
+
+// GetTree implements domain.CategoryUseCase.
+func (uc *CategoryUseCaseImpl) GetTree(ctx context.Context) ([]*domain.Category, error) {
+	categories, err := uc.categoryRepository.GetAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+	byID := make(map[int64]*domain.Category, len(categories))
+	for _, category := range categories {
+		byID[category.ID] = category
+	}
+	roots := []*domain.Category{}
+	for _, category := range categories {
+		if category.ParentID == nil {
+			roots = append(roots, category)
+			continue
+		}
+		if parent, ok := byID[*category.ParentID]; ok {
+			parent.Children = append(parent.Children, category)
+		}
+	}
+	return roots, nil
+}
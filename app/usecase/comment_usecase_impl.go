@@ -3,26 +3,39 @@ package usecase
 import (
 	"app/domain"
 	"app/pkg/common"
+	"app/pkg/logger"
 	"context"
+	"fmt"
+	"time"
+
+	"go.uber.org/zap"
 )
 
 type CommentUseCaseImpl struct {
 	commentRepository domain.CommentRepository
 	userRepository    domain.UserRepository
 	postRepository    domain.PostRepository
+	mentionRepository domain.MentionRepository
+	events            domain.EventPublisher
+	broker            domain.EventBroker
 	transactor        domain.Transactor
 }
 
-func NewCommentUseCaseImpl(commentRepository domain.CommentRepository, userRepository domain.UserRepository, postRepository domain.PostRepository, transactor domain.Transactor) domain.CommentUsecase {
+func NewCommentUseCaseImpl(commentRepository domain.CommentRepository, userRepository domain.UserRepository, postRepository domain.PostRepository, mentionRepository domain.MentionRepository, events domain.EventPublisher, broker domain.EventBroker, transactor domain.Transactor) domain.CommentUsecase {
 	return &CommentUseCaseImpl{
 		commentRepository: commentRepository,
 		userRepository:    userRepository,
 		postRepository:    postRepository,
+		mentionRepository: mentionRepository,
+		events:            events,
+		broker:            broker,
 		transactor:        transactor,
 	}
 }
 
-// CreateComment implements domain.CommentUsecase.
+// CreateComment implements domain.CommentUsecase. The post author is told
+// about the comment, the author of the comment replied to about the reply and
+// the users mentioned about their mention.
 func (uc *CommentUseCaseImpl) CreateComment(ctx context.Context, postID int64, req domain.CreateCommentRequestDTO) (*domain.CreateCommentResponseDTO, error) {
 	tx, err := uc.transactor.Begin()
 	if err != nil {
@@ -41,26 +54,67 @@ func (uc *CommentUseCaseImpl) CreateComment(ctx context.Context, postID int64, r
 	if err != nil {
 		return nil, err
 	}
-	if post == nil {
+	if post == nil || !post.VisibleTo(req.AuthorID) {
 		return nil, common.ErrPostNotFound
 	}
+	var parent *domain.Comment
+	if req.ParentID != nil {
+		parent, err = uc.commentRepository.GetByID(ctx, *req.ParentID)
+		if err != nil {
+			return nil, err
+		}
+		if parent.PostID != postID {
+			return nil, common.ErrCommentNotFound
+		}
+	}
+	content, mentioned, err := linkMentions(ctx, uc.userRepository, req.Content, req.AuthorID)
+	if err != nil {
+		return nil, err
+	}
+	if post.Status != domain.PostStatusPublished {
+		mentioned = nil
+	}
 	comment := &domain.Comment{
-		Content:    req.Content,
+		Content:    content,
 		PostID:     postID,
+		AuthorID:   &req.AuthorID,
+		ParentID:   req.ParentID,
 		AuthorName: user.Name,
+		CreatedAt:  time.Now(),
 	}
 	err = uc.commentRepository.Create(ctx, tx, comment)
 	if err != nil {
 		return nil, err
 	}
+	err = uc.postRepository.IncrementCommentCount(ctx, tx, postID, 1)
+	if err != nil {
+		return nil, err
+	}
+	_, err = uc.mentionRepository.Replace(ctx, tx, req.AuthorID, postID, &comment.ID, mentioned)
+	if err != nil {
+		return nil, err
+	}
+	err = uc.events.Publish(ctx, tx, domain.EventCommentCreated, comment)
+	if err != nil {
+		return nil, err
+	}
+	notifications := append(commentNotifications(post, parent, comment), mentionNotifications(req.AuthorID, postID, &comment.ID, mentioned)...)
+	err = publishNotifications(ctx, uc.events, tx, notifications...)
+	if err != nil {
+		return nil, err
+	}
 	err = tx.Commit()
 	if err != nil {
 		return nil, err
 	}
+	uc.events.Flush(ctx)
+	uc.publish(ctx, postID, domain.EventCommentCreated, comment)
 	response := &domain.CreateCommentResponseDTO{
 		ID:         comment.ID,
 		Content:    comment.Content,
 		PostID:     comment.PostID,
+		AuthorID:   comment.AuthorID,
+		ParentID:   comment.ParentID,
 		AuthorName: comment.AuthorName,
 		CreatedAt:  comment.CreatedAt,
 	}
@@ -73,7 +127,7 @@ func (uc *CommentUseCaseImpl) FindCommentsByPostID(ctx context.Context, postID i
 	if err != nil {
 		return nil, 0, err
 	}
-	if post == nil {
+	if post == nil || !post.VisibleTo(param.ViewerID) {
 		return nil, 0, common.ErrPostNotFound
 	}
 	comments, total, err := uc.commentRepository.FindByPostID(ctx, postID, param)
@@ -82,3 +136,164 @@ func (uc *CommentUseCaseImpl) FindCommentsByPostID(ctx context.Context, postID i
 	}
 	return comments, total, nil
 }
+
+// FindCommentsByPostIDCursor implements domain.CommentUsecase.
+func (uc *CommentUseCaseImpl) FindCommentsByPostIDCursor(ctx context.Context, postID int64, param domain.SearchParam) ([]*domain.Comment, *domain.PageInfo, error) {
+	post, err := uc.postRepository.GetByID(ctx, postID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if post == nil || !post.VisibleTo(param.ViewerID) {
+		return nil, nil, common.ErrPostNotFound
+	}
+	return uc.commentRepository.FindByPostIDCursor(ctx, postID, param)
+}
+
+// commentNotifications tells the post author about a new comment, unless it
+// replies to one of their own comments, in which case they are only told
+// about the reply.
+func commentNotifications(post *domain.Post, parent, comment *domain.Comment) []domain.Notification {
+	var notifications []domain.Notification
+	if parent != nil && parent.AuthorID != nil {
+		notifications = append(notifications, domain.Notification{UserID: *parent.AuthorID, ActorID: *comment.AuthorID, Type: domain.NotificationReply, PostID: &post.ID, CommentID: &comment.ID})
+	}
+	if parent == nil || parent.AuthorID == nil || *parent.AuthorID != post.AuthorID {
+		notifications = append(notifications, domain.Notification{UserID: post.AuthorID, ActorID: *comment.AuthorID, Type: domain.NotificationComment, PostID: &post.ID, CommentID: &comment.ID})
+	}
+	return notifications
+}
+
+// UpdateComment implements domain.CommentUsecase. Only the author of a
+// comment can edit it.
+func (uc *CommentUseCaseImpl) UpdateComment(ctx context.Context, postID, commentID int64, req domain.UpdateCommentRequestDTO) (*domain.Comment, error) {
+	tx, err := uc.transactor.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+	post, comment, err := uc.ownComment(ctx, postID, commentID, req.AuthorID)
+	if err != nil {
+		return nil, err
+	}
+	content, mentioned, err := linkMentions(ctx, uc.userRepository, common.Sanitize(req.Content), req.AuthorID)
+	if err != nil {
+		return nil, err
+	}
+	if post.Status != domain.PostStatusPublished {
+		mentioned = nil
+	}
+	now := time.Now()
+	comment.Content = content
+	comment.UpdatedAt = &now
+	err = uc.commentRepository.Update(ctx, tx, comment)
+	if err != nil {
+		return nil, err
+	}
+	mentioned, err = uc.mentionRepository.Replace(ctx, tx, req.AuthorID, postID, &comment.ID, mentioned)
+	if err != nil {
+		return nil, err
+	}
+	err = uc.events.Publish(ctx, tx, domain.EventCommentUpdated, comment)
+	if err != nil {
+		return nil, err
+	}
+	err = publishNotifications(ctx, uc.events, tx, mentionNotifications(req.AuthorID, postID, &comment.ID, mentioned)...)
+	if err != nil {
+		return nil, err
+	}
+	err = tx.Commit()
+	if err != nil {
+		return nil, err
+	}
+	uc.events.Flush(ctx)
+	uc.publish(ctx, postID, domain.EventCommentUpdated, comment)
+	return comment, nil
+}
+
+// DeleteComment implements domain.CommentUsecase. Only the author of a
+// comment can delete it.
+func (uc *CommentUseCaseImpl) DeleteComment(ctx context.Context, postID, commentID, authorID int64) error {
+	tx, err := uc.transactor.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if _, _, err := uc.ownComment(ctx, postID, commentID, authorID); err != nil {
+		return err
+	}
+	deleted, err := uc.commentRepository.Delete(ctx, tx, commentID)
+	if err != nil {
+		return err
+	}
+	if !deleted {
+		return common.ErrCommentNotFound
+	}
+	err = uc.postRepository.IncrementCommentCount(ctx, tx, postID, -1)
+	if err != nil {
+		return err
+	}
+	_, err = uc.mentionRepository.Replace(ctx, tx, authorID, postID, &commentID, nil)
+	if err != nil {
+		return err
+	}
+	deletedEvent := domain.CommentDeletedEvent{ID: commentID, PostID: postID}
+	err = uc.events.Publish(ctx, tx, domain.EventCommentDeleted, deletedEvent)
+	if err != nil {
+		return err
+	}
+	err = tx.Commit()
+	if err != nil {
+		return err
+	}
+	uc.events.Flush(ctx)
+	uc.publish(ctx, postID, domain.EventCommentDeleted, deletedEvent)
+	return nil
+}
+
+// StreamComments implements domain.CommentUsecase.
+func (uc *CommentUseCaseImpl) StreamComments(ctx context.Context, postID, viewerID, lastEventID int64) (<-chan domain.Event, error) {
+	post, err := uc.postRepository.GetByID(ctx, postID)
+	if err != nil {
+		return nil, err
+	}
+	if post == nil || !post.VisibleTo(viewerID) {
+		return nil, common.ErrPostNotFound
+	}
+	return uc.broker.Subscribe(ctx, commentTopic(postID), lastEventID)
+}
+
+// ownComment loads a comment of a post the author can still see, making
+// sure they wrote it.
+func (uc *CommentUseCaseImpl) ownComment(ctx context.Context, postID, commentID, authorID int64) (*domain.Post, *domain.Comment, error) {
+	post, err := uc.postRepository.GetByID(ctx, postID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if post == nil || !post.VisibleTo(authorID) {
+		return nil, nil, common.ErrPostNotFound
+	}
+	comment, err := uc.commentRepository.GetByID(ctx, commentID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if comment.PostID != postID {
+		return nil, nil, common.ErrCommentNotFound
+	}
+	if comment.AuthorID == nil || *comment.AuthorID != authorID {
+		return nil, nil, common.ErrCommentOwnerMismatch
+	}
+	return post, comment, nil
+}
+
+// publish tells the readers streaming the comments of the post about a
+// change. Readers can always reload the comments, so failures are only
+// logged.
+func (uc *CommentUseCaseImpl) publish(ctx context.Context, postID int64, eventType string, data interface{}) {
+	if err := uc.broker.Publish(ctx, commentTopic(postID), domain.Event{Type: eventType, Data: data}); err != nil {
+		logger.Log.Error("failed to publish comment event", zap.Int64("postID", postID), zap.Error(err))
+	}
+}
+
+func commentTopic(postID int64) string {
+	return fmt.Sprintf("posts/%d/comments", postID)
+}
@@ -0,0 +1,28 @@
+package usecase
+
+import (
+	"app/domain"
+	"context"
+)
+
+// FanOutOnReadFeed builds home feeds when they are requested, reading the
+// latest posts of the followed authors through the posts(author_id,
+// created_at) index.
+type FanOutOnReadFeed struct {
+	postRepository domain.PostRepository
+}
+
+func NewFanOutOnReadFeed(postRepository domain.PostRepository) domain.FeedStrategy {
+	return &FanOutOnReadFeed{postRepository: postRepository}
+}
+
+// Feed implements domain.FeedStrategy. Only published posts are listed,
+// newest first and with every field.
+func (f *FanOutOnReadFeed) Feed(ctx context.Context, userID int64, param domain.SearchParam) ([]domain.Post, *domain.PageInfo, error) {
+	param.FollowerID = userID
+	param.Status = domain.PostStatusPublished
+	param.Sort = domain.SortNewest
+	param.Order = ""
+	param.Fields = ""
+	return f.postRepository.GetAllByCursor(ctx, param)
+}
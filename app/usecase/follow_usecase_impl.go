@@ -0,0 +1,125 @@
+package usecase
+
+import (
+	"app/domain"
+	"app/pkg/common"
+	"context"
+	"slices"
+)
+
+type FollowUseCaseImpl struct {
+	followRepository domain.FollowRepository
+	userRepository   domain.UserRepository
+	feedStrategy     domain.FeedStrategy
+	events           domain.EventPublisher
+	transactor       domain.Transactor
+}
+
+func NewFollowUseCaseImpl(followRepository domain.FollowRepository, userRepository domain.UserRepository, feedStrategy domain.FeedStrategy, events domain.EventPublisher, transactor domain.Transactor) domain.FollowUseCase {
+	return &FollowUseCaseImpl{
+		followRepository: followRepository,
+		userRepository:   userRepository,
+		feedStrategy:     feedStrategy,
+		events:           events,
+		transactor:       transactor,
+	}
+}
+
+// GetProfile implements domain.FollowUseCase.
+func (uc *FollowUseCaseImpl) GetProfile(ctx context.Context, userID int64) (*domain.UserProfile, error) {
+	user, err := uc.userRepository.FindByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	followers, following, err := uc.followRepository.Count(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	return &domain.UserProfile{
+		ID:        user.ID,
+		Name:      user.Name,
+		Username:  user.Username,
+		Followers: followers,
+		Following: following,
+	}, nil
+}
+
+// Follow implements domain.FollowUseCase. The followed user is notified the
+// first time only.
+func (uc *FollowUseCaseImpl) Follow(ctx context.Context, followerID, followeeID int64) error {
+	if followerID == followeeID {
+		return common.ErrFollowSelf
+	}
+	if _, err := uc.userRepository.FindByID(ctx, followeeID); err != nil {
+		return err
+	}
+	tx, err := uc.transactor.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	followed, err := uc.followRepository.Follow(ctx, tx, followerID, followeeID)
+	if err != nil {
+		return err
+	}
+	if !followed {
+		return nil
+	}
+	err = publishNotifications(ctx, uc.events, tx, domain.Notification{UserID: followeeID, ActorID: followerID, Type: domain.NotificationFollow})
+	if err != nil {
+		return err
+	}
+	err = tx.Commit()
+	if err != nil {
+		return err
+	}
+	uc.events.Flush(ctx)
+	return nil
+}
+
+// Unfollow implements domain.FollowUseCase.
+func (uc *FollowUseCaseImpl) Unfollow(ctx context.Context, followerID, followeeID int64) error {
+	return uc.followRepository.Unfollow(ctx, followerID, followeeID)
+}
+
+// FindFollowers implements domain.FollowUseCase.
+func (uc *FollowUseCaseImpl) FindFollowers(ctx context.Context, userID int64, param domain.SearchParam) ([]domain.FollowUser, int64, error) {
+	if _, err := uc.userRepository.FindByID(ctx, userID); err != nil {
+		return nil, 0, err
+	}
+	return uc.followRepository.FindFollowers(ctx, userID, param)
+}
+
+// FindFollowing implements domain.FollowUseCase.
+func (uc *FollowUseCaseImpl) FindFollowing(ctx context.Context, userID int64, param domain.SearchParam) ([]domain.FollowUser, int64, error) {
+	if _, err := uc.userRepository.FindByID(ctx, userID); err != nil {
+		return nil, 0, err
+	}
+	return uc.followRepository.FindFollowing(ctx, userID, param)
+}
+
+// Feed implements domain.FollowUseCase. Posts come with their author.
+func (uc *FollowUseCaseImpl) Feed(ctx context.Context, userID int64, param domain.SearchParam) ([]domain.Post, *domain.PageInfo, error) {
+	posts, page, err := uc.feedStrategy.Feed(ctx, userID, param)
+	if err != nil {
+		return nil, nil, err
+	}
+	var ids []int64
+	for _, post := range posts {
+		if !slices.Contains(ids, post.AuthorID) {
+			ids = append(ids, post.AuthorID)
+		}
+	}
+	users, err := uc.userRepository.FindByIDs(ctx, ids)
+	if err != nil {
+		return nil, nil, err
+	}
+	authors := make(map[int64]*domain.Author, len(users))
+	for _, user := range users {
+		authors[user.ID] = &domain.Author{ID: user.ID, Name: user.Name}
+	}
+	for i := range posts {
+		posts[i].Author = authors[posts[i].AuthorID]
+	}
+	return posts, page, nil
+}
@@ -0,0 +1,287 @@
+package usecase
+
+import (
+	"app/domain"
+	"app/pkg/common"
+	"app/pkg/cron"
+	"app/pkg/logger"
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+const (
+	jobBatchSize    = 10
+	jobTimeout      = 5 * time.Minute
+	jobMaxAttempts  = 5
+	jobRetryBackoff = 30 * time.Second
+	jobMaxBackoff   = 6 * time.Hour
+	jobErrorLength  = 250
+)
+
+// jobSchedule enqueues a job of jobType with payload whenever it is due.
+type jobSchedule struct {
+	schedule *cron.Schedule
+	jobType  string
+	payload  interface{}
+}
+
+type JobUseCaseImpl struct {
+	jobRepository domain.JobRepository
+	transactor    domain.Transactor
+	handlers      map[string]domain.JobHandler
+	schedules     map[string]jobSchedule
+}
+
+// NewJobUseCaseImpl returns the use case letting admins inspect jobs, which
+// is also the domain.JobQueue other use cases defer work with and the
+// worker running it.
+func NewJobUseCaseImpl(jobRepository domain.JobRepository, transactor domain.Transactor) *JobUseCaseImpl {
+	return &JobUseCaseImpl{
+		jobRepository: jobRepository,
+		transactor:    transactor,
+		handlers:      map[string]domain.JobHandler{},
+		schedules:     map[string]jobSchedule{},
+	}
+}
+
+// JobFunc adapts a function taking the decoded payload of a job into a
+// domain.JobHandler.
+func JobFunc[T any](fn func(ctx context.Context, payload T) error) domain.JobHandler {
+	return jobFunc[T](fn)
+}
+
+type jobFunc[T any] func(ctx context.Context, payload T) error
+
+// Handle implements domain.JobHandler.
+func (fn jobFunc[T]) Handle(ctx context.Context, job domain.Job) error {
+	var payload T
+	if err := json.Unmarshal(job.Payload, &payload); err != nil {
+		return fmt.Errorf("invalid payload: %w", err)
+	}
+	return fn(ctx, payload)
+}
+
+// Register sets the handler of a job type, at startup before any job runs.
+func (uc *JobUseCaseImpl) Register(jobType string, handler domain.JobHandler) {
+	uc.handlers[jobType] = handler
+}
+
+// Schedule enqueues a job of the type whenever the cron spec is due, at
+// startup before any job runs. The name keeps track of when it is due next,
+// so it must stay the same across releases. Every worker can schedule the
+// same job; it is only enqueued once each time, and times missed while no
+// worker was running are caught up with a single job.
+func (uc *JobUseCaseImpl) Schedule(name, spec, jobType string, payload interface{}) error {
+	schedule, err := cron.Parse(spec)
+	if err != nil {
+		return err
+	}
+	if schedule.Next(time.Now()).IsZero() {
+		return fmt.Errorf("cron: %q is never due", spec)
+	}
+	uc.schedules[name] = jobSchedule{schedule: schedule, jobType: jobType, payload: payload}
+	return nil
+}
+
+// Enqueue implements domain.JobQueue.
+func (uc *JobUseCaseImpl) Enqueue(ctx context.Context, tx domain.Transaction, jobType string, payload interface{}, runAt time.Time) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		logger.Log.Error("failed to render job payload", zap.String("type", jobType), zap.Error(err))
+		return common.ErrInternalServerError
+	}
+	job := &domain.Job{
+		Type:        jobType,
+		Payload:     data,
+		Status:      domain.JobPending,
+		MaxAttempts: jobMaxAttempts,
+		RunAt:       runAt,
+		CreatedAt:   time.Now(),
+	}
+	return uc.jobRepository.Create(ctx, tx, job)
+}
+
+// FindAll implements domain.JobUseCase.
+func (uc *JobUseCaseImpl) FindAll(ctx context.Context, filter domain.JobFilter) ([]domain.Job, int64, error) {
+	return uc.jobRepository.FindAll(ctx, filter)
+}
+
+// FindByID implements domain.JobUseCase.
+func (uc *JobUseCaseImpl) FindByID(ctx context.Context, id int64) (*domain.Job, error) {
+	return uc.jobRepository.FindByID(ctx, id)
+}
+
+// Retry implements domain.JobUseCase.
+func (uc *JobUseCaseImpl) Retry(ctx context.Context, id int64) (*domain.Job, error) {
+	retried, err := uc.jobRepository.Retry(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	job, err := uc.jobRepository.FindByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if !retried {
+		return nil, common.ErrJobNotDead
+	}
+	return job, nil
+}
+
+// Work implements domain.JobUseCase. Scheduled jobs that are due are
+// enqueued first. The batch is claimed in a short transaction, so jobs run
+// without holding locks on the queue.
+func (uc *JobUseCaseImpl) Work(ctx context.Context) (int, error) {
+	if err := uc.enqueueScheduled(ctx); err != nil {
+		return 0, err
+	}
+	tx, err := uc.transactor.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+	jobs, err := uc.jobRepository.Claim(ctx, tx, jobBatchSize, time.Now().Add(jobBatchSize*jobTimeout+time.Minute))
+	if err != nil {
+		return 0, err
+	}
+	err = tx.Commit()
+	if err != nil {
+		return 0, err
+	}
+	for i := range jobs {
+		if err := uc.run(ctx, &jobs[i]); err != nil {
+			return 0, err
+		}
+	}
+	return len(jobs), nil
+}
+
+// Run works through due jobs every interval until ctx is done, draining the
+// whole backlog on each tick.
+func (uc *JobUseCaseImpl) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		for ctx.Err() == nil {
+			worked, err := uc.Work(ctx)
+			if err != nil {
+				if ctx.Err() == nil {
+					logger.Log.Error("failed to run jobs", zap.Error(err))
+				}
+				break
+			}
+			if worked < jobBatchSize {
+				break
+			}
+		}
+	}
+}
+
+// enqueueScheduled enqueues the scheduled jobs that are due and moves their
+// schedules on, in one transaction so that a job is enqueued exactly once
+// each time.
+func (uc *JobUseCaseImpl) enqueueScheduled(ctx context.Context) error {
+	if len(uc.schedules) == 0 {
+		return nil
+	}
+	now := time.Now()
+	names := make([]string, 0, len(uc.schedules))
+	for name, schedule := range uc.schedules {
+		if err := uc.jobRepository.EnsureSchedule(ctx, name, schedule.schedule.Next(now)); err != nil {
+			return err
+		}
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	tx, err := uc.transactor.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	due, err := uc.jobRepository.ClaimSchedules(ctx, tx, names)
+	if err != nil {
+		return err
+	}
+	if len(due) == 0 {
+		return nil
+	}
+	for _, name := range due {
+		schedule := uc.schedules[name]
+		if err := uc.Enqueue(ctx, tx, schedule.jobType, schedule.payload, now); err != nil {
+			return err
+		}
+		if err := uc.jobRepository.SetScheduleNext(ctx, tx, name, schedule.schedule.Next(now)); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// run handles the job once and records the outcome. Failed jobs are retried
+// with exponential backoff until they run out of attempts and are left dead.
+// A run cut short by ctx is not counted; the job is picked up again once its
+// claim ends.
+func (uc *JobUseCaseImpl) run(ctx context.Context, job *domain.Job) error {
+	err := uc.handle(ctx, job)
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	now := time.Now()
+	job.Attempts++
+	job.UpdatedAt = &now
+	job.RunAt = now
+	if err == nil {
+		job.Status = domain.JobSucceeded
+		job.Error = nil
+		job.FinishedAt = &now
+		return uc.jobRepository.SaveAttempt(ctx, job)
+	}
+	logger.Log.Error("failed to run job", zap.Int64("jobID", job.ID), zap.String("type", job.Type), zap.Error(err))
+	message := common.Excerpt(err.Error(), jobErrorLength)
+	job.Error = &message
+	if job.Attempts < job.MaxAttempts {
+		job.Status = domain.JobPending
+		job.RunAt = now.Add(min(jobRetryBackoff<<(job.Attempts-1), jobMaxBackoff))
+	} else {
+		job.Status = domain.JobDead
+		job.FinishedAt = &now
+	}
+	return uc.jobRepository.SaveAttempt(ctx, job)
+}
+
+// handle runs the handler of the job with a timeout, turning a panic into an
+// error so that one bad job cannot take the worker down.
+func (uc *JobUseCaseImpl) handle(ctx context.Context, job *domain.Job) (err error) {
+	handler, ok := uc.handlers[job.Type]
+	if !ok {
+		return fmt.Errorf("no handler for job type %q", job.Type)
+	}
+	ctx, cancel := context.WithTimeout(ctx, jobTimeout)
+	defer cancel()
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			err = fmt.Errorf("panic: %v", recovered)
+		}
+	}()
+	return handler.Handle(ctx, *job)
+}
+
+// CleanupJobs deletes the jobs that succeeded more than the retention ago.
+// Dead jobs are kept for admins to look into.
+func (uc *JobUseCaseImpl) CleanupJobs(ctx context.Context, payload domain.CleanupJobPayload) error {
+	deleted, err := uc.jobRepository.DeleteFinished(ctx, time.Now().AddDate(0, 0, -payload.RetentionDays))
+	if err != nil {
+		return err
+	}
+	logger.Log.Info("deleted finished jobs", zap.Int64("jobs", deleted))
+	return nil
+}
@@ -0,0 +1,57 @@
+package usecase
+
+import (
+	"app/domain"
+	"app/pkg/common"
+	"context"
+	"fmt"
+)
+
+type MentionUseCaseImpl struct {
+	mentionRepository domain.MentionRepository
+}
+
+func NewMentionUseCaseImpl(mentionRepository domain.MentionRepository) domain.MentionUseCase {
+	return &MentionUseCaseImpl{mentionRepository: mentionRepository}
+}
+
+// FindByUser implements domain.MentionUseCase.
+func (uc *MentionUseCaseImpl) FindByUser(ctx context.Context, userID int64, param domain.SearchParam) ([]domain.Mention, int64, error) {
+	return uc.mentionRepository.FindByUser(ctx, userID, param)
+}
+
+// linkMentions links the @username mentions in sanitized content to the
+// profiles of the users they name. It returns the linked content and the ids
+// of the users mentioned, leaving out the author and unknown usernames.
+func linkMentions(ctx context.Context, userRepository domain.UserRepository, content string, authorID int64) (string, []int64, error) {
+	usernames := common.Mentions(content)
+	if len(usernames) == 0 {
+		return content, nil, nil
+	}
+	users, err := userRepository.FindByUsernames(ctx, usernames)
+	if err != nil {
+		return "", nil, err
+	}
+	ids := make(map[string]int64, len(users))
+	var mentioned []int64
+	for _, user := range users {
+		ids[user.Username] = user.ID
+		if user.ID != authorID {
+			mentioned = append(mentioned, user.ID)
+		}
+	}
+	content = common.LinkMentions(content, func(username string) (string, bool) {
+		id, ok := ids[username]
+		return fmt.Sprintf("/users/%d", id), ok
+	})
+	return content, mentioned, nil
+}
+
+// mentionNotifications tells the users newly mentioned in a post or comment.
+func mentionNotifications(authorID, postID int64, commentID *int64, userIDs []int64) []domain.Notification {
+	notifications := make([]domain.Notification, 0, len(userIDs))
+	for _, userID := range userIDs {
+		notifications = append(notifications, domain.Notification{UserID: userID, ActorID: authorID, Type: domain.NotificationMention, PostID: &postID, CommentID: commentID})
+	}
+	return notifications
+}
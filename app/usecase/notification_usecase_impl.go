@@ -0,0 +1,213 @@
+package usecase
+
+import (
+	"app/domain"
+	"app/pkg/common"
+	"app/pkg/logger"
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+type NotificationUseCaseImpl struct {
+	notificationRepository domain.NotificationRepository
+	userRepository         domain.UserRepository
+	broker                 domain.EventBroker
+}
+
+// NewNotificationUseCaseImpl returns the use case serving notifications to
+// their recipients, which is also the outbox handler sending the ones other
+// use cases publish.
+func NewNotificationUseCaseImpl(notificationRepository domain.NotificationRepository, userRepository domain.UserRepository, broker domain.EventBroker) *NotificationUseCaseImpl {
+	return &NotificationUseCaseImpl{
+		notificationRepository: notificationRepository,
+		userRepository:         userRepository,
+		broker:                 broker,
+	}
+}
+
+// notificationEvent is a notification recorded in the outbox, which unlike
+// the API keeps its recipient.
+type notificationEvent struct {
+	UserID    int64  `json:"user_id"`
+	ActorID   int64  `json:"actor_id"`
+	Type      string `json:"type"`
+	PostID    *int64 `json:"post_id"`
+	CommentID *int64 `json:"comment_id"`
+}
+
+// publishNotifications records notifications to send once tx is committed.
+func publishNotifications(ctx context.Context, events domain.EventPublisher, tx domain.Transaction, notifications ...domain.Notification) error {
+	if len(notifications) == 0 {
+		return nil
+	}
+	recorded := make([]notificationEvent, len(notifications))
+	for i, notification := range notifications {
+		recorded[i] = notificationEvent{
+			UserID:    notification.UserID,
+			ActorID:   notification.ActorID,
+			Type:      notification.Type,
+			PostID:    notification.PostID,
+			CommentID: notification.CommentID,
+		}
+	}
+	return events.Publish(ctx, tx, domain.EventNotification, recorded)
+}
+
+// Handle implements domain.OutboxHandler, sending the notifications recorded
+// by other use cases. They are stored with the key of the event, so a
+// redelivered event does not send them again.
+func (uc *NotificationUseCaseImpl) Handle(ctx context.Context, event domain.OutboxEvent) error {
+	if event.Type != domain.EventNotification {
+		return nil
+	}
+	var recorded []notificationEvent
+	if err := json.Unmarshal(event.Payload, &recorded); err != nil {
+		return err
+	}
+	notifications := make([]domain.Notification, len(recorded))
+	for i, notification := range recorded {
+		notifications[i] = domain.Notification{
+			UserID:    notification.UserID,
+			ActorID:   notification.ActorID,
+			Type:      notification.Type,
+			PostID:    notification.PostID,
+			CommentID: notification.CommentID,
+			EventKey:  &event.Key,
+		}
+	}
+	return uc.notify(ctx, notifications)
+}
+
+// notify stores the notifications and pushes them to their recipients. Every
+// type is enabled until the recipient switches it off. Once they are stored,
+// failing to push them is only logged, and the ones stored before are not
+// pushed again.
+func (uc *NotificationUseCaseImpl) notify(ctx context.Context, notifications []domain.Notification) error {
+	var userIDs []int64
+	for _, notification := range notifications {
+		if !slices.Contains(userIDs, notification.UserID) {
+			userIDs = append(userIDs, notification.UserID)
+		}
+	}
+	preferences, err := uc.notificationRepository.GetPreferences(ctx, userIDs)
+	if err != nil {
+		return err
+	}
+	var kept []domain.Notification
+	for _, notification := range notifications {
+		if notification.UserID == notification.ActorID {
+			continue
+		}
+		if enabled, ok := preferences[notification.UserID][notification.Type]; ok && !enabled {
+			continue
+		}
+		kept = append(kept, notification)
+	}
+	if len(kept) == 0 {
+		return nil
+	}
+	now := time.Now()
+	var actorIDs []int64
+	for i := range kept {
+		kept[i].CreatedAt = now
+		if !slices.Contains(actorIDs, kept[i].ActorID) {
+			actorIDs = append(actorIDs, kept[i].ActorID)
+		}
+	}
+	if err := uc.notificationRepository.Create(ctx, kept); err != nil {
+		return err
+	}
+	actors, err := uc.userRepository.FindByIDs(ctx, actorIDs)
+	if err != nil {
+		logger.Log.Error("failed to push notifications", zap.Error(err))
+		return nil
+	}
+	names := make(map[int64]string, len(actors))
+	for _, actor := range actors {
+		names[actor.ID] = actor.Name
+	}
+	for _, notification := range kept {
+		if notification.ID == 0 {
+			continue
+		}
+		notification.ActorName = names[notification.ActorID]
+		if err := uc.broker.Publish(ctx, notificationTopic(notification.UserID), domain.Event{Type: domain.EventNotification, Data: notification}); err != nil {
+			logger.Log.Error("failed to push notification", zap.Error(err))
+		}
+	}
+	return nil
+}
+
+// Stream implements domain.NotificationUseCase.
+func (uc *NotificationUseCaseImpl) Stream(ctx context.Context, userID int64) (<-chan domain.Event, error) {
+	return uc.broker.Subscribe(ctx, notificationTopic(userID), 0)
+}
+
+// FindByUser implements domain.NotificationUseCase.
+func (uc *NotificationUseCaseImpl) FindByUser(ctx context.Context, userID int64, param domain.NotificationParam) ([]domain.Notification, int64, error) {
+	return uc.notificationRepository.FindByUser(ctx, userID, param)
+}
+
+// CountUnread implements domain.NotificationUseCase.
+func (uc *NotificationUseCaseImpl) CountUnread(ctx context.Context, userID int64) (*domain.UnreadCountResponseDTO, error) {
+	unread, err := uc.notificationRepository.CountUnread(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	return &domain.UnreadCountResponseDTO{Unread: unread}, nil
+}
+
+// MarkRead implements domain.NotificationUseCase.
+func (uc *NotificationUseCaseImpl) MarkRead(ctx context.Context, userID, id int64) error {
+	found, err := uc.notificationRepository.MarkRead(ctx, userID, id)
+	if err != nil {
+		return err
+	}
+	if !found {
+		return common.ErrNotificationNotFound
+	}
+	return nil
+}
+
+// MarkAllRead implements domain.NotificationUseCase.
+func (uc *NotificationUseCaseImpl) MarkAllRead(ctx context.Context, userID int64) error {
+	return uc.notificationRepository.MarkAllRead(ctx, userID)
+}
+
+// GetPreferences implements domain.NotificationUseCase. Every type is
+// listed, with the ones the user never set enabled.
+func (uc *NotificationUseCaseImpl) GetPreferences(ctx context.Context, userID int64) (map[string]bool, error) {
+	preferences, err := uc.notificationRepository.GetPreferences(ctx, []int64{userID})
+	if err != nil {
+		return nil, err
+	}
+	result := make(map[string]bool, len(domain.NotificationTypes))
+	for _, kind := range domain.NotificationTypes {
+		enabled, ok := preferences[userID][kind]
+		result[kind] = !ok || enabled
+	}
+	return result, nil
+}
+
+// SetPreferences implements domain.NotificationUseCase. Types left out keep
+// their current setting.
+func (uc *NotificationUseCaseImpl) SetPreferences(ctx context.Context, userID int64, preferences map[string]bool) (map[string]bool, error) {
+	for kind := range preferences {
+		if !slices.Contains(domain.NotificationTypes, kind) {
+			return nil, common.ErrNotificationType
+		}
+	}
+	if err := uc.notificationRepository.SetPreferences(ctx, userID, preferences); err != nil {
+		return nil, err
+	}
+	return uc.GetPreferences(ctx, userID)
+}
+
+func notificationTopic(userID int64) string {
+	return fmt.Sprintf("users/%d/notifications", userID)
+}
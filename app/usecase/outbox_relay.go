@@ -0,0 +1,200 @@
+package usecase
+
+import (
+	"app/domain"
+	"app/pkg/common"
+	"app/pkg/logger"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"slices"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+const (
+	outboxBatchSize    = 50
+	outboxClaim        = time.Minute
+	outboxMaxAttempts  = 10
+	outboxRetryBackoff = 10 * time.Second
+	outboxMaxBackoff   = time.Hour
+	outboxErrorLength  = 250
+)
+
+// OutboxRelay records domain events in the outbox and relays them to the
+// registered handlers, in the order they were recorded. Delivery is at least
+// once: an event is done when every handler has handled it, and a failed
+// handler is retried with backoff without holding up later events or
+// running the handlers that already succeeded again.
+type OutboxRelay struct {
+	outboxRepository domain.OutboxRepository
+	transactor       domain.Transactor
+	handlers         map[string]domain.OutboxHandler
+	// wake tells Run that events were committed; one pending signal is
+	// enough as it works through the whole backlog.
+	wake chan struct{}
+}
+
+func NewOutboxRelay(outboxRepository domain.OutboxRepository, transactor domain.Transactor) *OutboxRelay {
+	return &OutboxRelay{
+		outboxRepository: outboxRepository,
+		transactor:       transactor,
+		handlers:         map[string]domain.OutboxHandler{},
+		wake:             make(chan struct{}, 1),
+	}
+}
+
+// Register adds a handler, at startup before anything is relayed. The name
+// records which handlers are done with an event, so it must stay the same
+// across releases.
+func (r *OutboxRelay) Register(name string, handler domain.OutboxHandler) {
+	r.handlers[name] = handler
+}
+
+// Publish implements domain.EventPublisher.
+func (r *OutboxRelay) Publish(ctx context.Context, tx domain.Transaction, eventType string, data interface{}) error {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		logger.Log.Error("failed to render outbox event", zap.String("type", eventType), zap.Error(err))
+		return common.ErrInternalServerError
+	}
+	key := make([]byte, 16)
+	if _, err := rand.Read(key); err != nil {
+		logger.Log.Error("failed to generate idempotency key", zap.Error(err))
+		return common.ErrInternalServerError
+	}
+	event := &domain.OutboxEvent{
+		Key:       hex.EncodeToString(key),
+		Type:      eventType,
+		Payload:   payload,
+		CreatedAt: time.Now(),
+	}
+	return r.outboxRepository.Create(ctx, tx, event)
+}
+
+// Flush implements domain.EventPublisher. It only wakes Run up, so the
+// request never waits for the handlers.
+func (r *OutboxRelay) Flush(ctx context.Context) {
+	select {
+	case r.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Relay hands a batch of due events to the handlers and returns how many it
+// picked up. The batch is claimed in a short transaction, so handlers run
+// without holding locks on the outbox.
+func (r *OutboxRelay) Relay(ctx context.Context) (int, error) {
+	tx, err := r.transactor.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+	events, err := r.outboxRepository.Claim(ctx, tx, outboxBatchSize, time.Now().Add(outboxClaim))
+	if err != nil {
+		return 0, err
+	}
+	err = tx.Commit()
+	if err != nil {
+		return 0, err
+	}
+	if len(events) == 0 {
+		return 0, nil
+	}
+	ids := make([]int64, len(events))
+	for i := range events {
+		ids[i] = events[i].ID
+	}
+	handled, err := r.outboxRepository.FindHandled(ctx, ids)
+	if err != nil {
+		return 0, err
+	}
+	for _, event := range events {
+		if err := r.handle(ctx, event, handled[event.ID]); err != nil {
+			return 0, err
+		}
+	}
+	return len(events), nil
+}
+
+// Run relays due events every interval, and whenever Flush is called, until
+// ctx is done, working through the whole backlog each time.
+func (r *OutboxRelay) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-r.wake:
+		}
+		for ctx.Err() == nil {
+			relayed, err := r.Relay(ctx)
+			if err != nil {
+				if ctx.Err() == nil {
+					logger.Log.Error("failed to relay outbox events", zap.Error(err))
+				}
+				break
+			}
+			if relayed < outboxBatchSize {
+				break
+			}
+		}
+	}
+}
+
+// Cleanup deletes the events processed more than the retention ago, along
+// with the record of their handlers.
+func (r *OutboxRelay) Cleanup(ctx context.Context, payload domain.CleanupJobPayload) error {
+	deleted, err := r.outboxRepository.DeleteProcessed(ctx, time.Now().AddDate(0, 0, -payload.RetentionDays))
+	if err != nil {
+		return err
+	}
+	logger.Log.Info("deleted processed outbox events", zap.Int64("events", deleted))
+	return nil
+}
+
+// handle runs the handlers not done with the event yet, in name order. An
+// error is only returned when the outcome could not be recorded.
+func (r *OutboxRelay) handle(ctx context.Context, event domain.OutboxEvent, done []string) error {
+	names := make([]string, 0, len(r.handlers))
+	for name := range r.handlers {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	var failure error
+	for _, name := range names {
+		if slices.Contains(done, name) {
+			continue
+		}
+		if err := r.handlers[name].Handle(ctx, event); err != nil {
+			logger.Log.Error("failed to handle outbox event", zap.Int64("eventID", event.ID), zap.String("handler", name), zap.Error(err))
+			failure = err
+			continue
+		}
+		if err := r.outboxRepository.MarkHandled(ctx, event.ID, name); err != nil {
+			return err
+		}
+	}
+	if failure == nil {
+		return r.outboxRepository.MarkProcessed(ctx, event.ID)
+	}
+	// Cancelled attempts are not counted; the event is retried once its
+	// claim runs out.
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	attempts := event.Attempts + 1
+	var retryAt *time.Time
+	if attempts < outboxMaxAttempts {
+		backoff := min(outboxRetryBackoff<<(attempts-1), outboxMaxBackoff)
+		next := time.Now().Add(backoff)
+		retryAt = &next
+	} else {
+		logger.Log.Error("gave up on outbox event", zap.Int64("eventID", event.ID), zap.String("type", event.Type))
+	}
+	return r.outboxRepository.RecordFailure(ctx, event.ID, attempts, common.Excerpt(failure.Error(), outboxErrorLength), retryAt)
+}
@@ -3,27 +3,56 @@ package usecase
 import (
 	"app/domain"
 	"app/pkg/common"
+	"app/pkg/markdown"
 	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
 	"time"
 )
 
+const (
+	maxTagLength  = 64
+	snippetLength = 160
+	excerptLength = 280
+	// maxSlugRetries bounds the slugs tried when concurrent writers keep
+	// taking them.
+	maxSlugRetries = 5
+)
+
 type PostUsecaseImpl struct {
-	postRepository domain.PostRepository
-	userRepository domain.UserRepository
-	transactor     domain.Transactor
+	postRepository       domain.PostRepository
+	userRepository       domain.UserRepository
+	tagRepository        domain.TagRepository
+	categoryRepository   domain.CategoryRepository
+	reactionRepository   domain.ReactionRepository
+	bookmarkRepository   domain.BookmarkRepository
+	mentionRepository    domain.MentionRepository
+	attachmentRepository domain.AttachmentRepository
+	searchIndex          domain.SearchIndex
+	events               domain.EventPublisher
+	transactor           domain.Transactor
 }
 
-func NewPostUsecaseImpl(postRepository domain.PostRepository, userRepository domain.UserRepository, transactor domain.Transactor) domain.PostUseCase {
+func NewPostUsecaseImpl(postRepository domain.PostRepository, userRepository domain.UserRepository, tagRepository domain.TagRepository, categoryRepository domain.CategoryRepository, reactionRepository domain.ReactionRepository, bookmarkRepository domain.BookmarkRepository, mentionRepository domain.MentionRepository, attachmentRepository domain.AttachmentRepository, searchIndex domain.SearchIndex, events domain.EventPublisher, transactor domain.Transactor) domain.PostUseCase {
 	return &PostUsecaseImpl{
-		postRepository: postRepository,
-		userRepository: userRepository,
-		transactor:     transactor,
+		postRepository:       postRepository,
+		userRepository:       userRepository,
+		tagRepository:        tagRepository,
+		categoryRepository:   categoryRepository,
+		reactionRepository:   reactionRepository,
+		bookmarkRepository:   bookmarkRepository,
+		mentionRepository:    mentionRepository,
+		attachmentRepository: attachmentRepository,
+		searchIndex:          searchIndex,
+		events:               events,
+		transactor:           transactor,
 	}
 }
 
 // Create implements domain.PostUseCase.
 func (uc *PostUsecaseImpl) Create(ctx context.Context, post *domain.CreatePostRequestDTO) (*domain.CreatePostResponseDTO, error) {
-	post.Content = common.Sanitize(post.Content)
 	tx, err := uc.transactor.Begin()
 	if err != nil {
 		return nil, err
@@ -36,26 +65,78 @@ func (uc *PostUsecaseImpl) Create(ctx context.Context, post *domain.CreatePostRe
 	if user == nil {
 		return nil, common.ErrUserNotFound
 	}
+	if err := uc.checkCategory(ctx, post.CategoryID); err != nil {
+		return nil, err
+	}
+	slug, err := uc.uniqueSlug(ctx, tx, post.Title, 0)
+	if err != nil {
+		return nil, err
+	}
 	postModel := &domain.Post{
-		Title:    post.Title,
-		Content:  post.Content,
-		AuthorID: post.AuthorID,
+		Title:      post.Title,
+		Slug:       slug,
+		AuthorID:   post.AuthorID,
+		CategoryID: post.CategoryID,
+		Status:     post.Status,
 	}
-	err = uc.postRepository.Create(ctx, tx, postModel)
+	if postModel.Status == "" {
+		postModel.Status = domain.PostStatusPublished
+	}
+	if err := setContent(postModel, post.Content, post.ContentMarkdown); err != nil {
+		return nil, err
+	}
+	var mentioned []int64
+	postModel.Content, mentioned, err = linkMentions(ctx, uc.userRepository, postModel.Content, postModel.AuthorID)
 	if err != nil {
 		return nil, err
 	}
-	err = tx.Commit()
+	err = uc.saveWithSlug(ctx, tx, postModel, func() error {
+		return uc.postRepository.Create(ctx, tx, postModel)
+	})
+	if err != nil {
+		return nil, err
+	}
+	postModel.Tags, err = uc.saveTags(ctx, tx, postModel.ID, post.Tags)
+	if err != nil {
+		return nil, err
+	}
+	postModel.Attachments, err = uc.saveAttachments(ctx, tx, postModel, post.AttachmentIDs)
+	if err != nil {
+		return nil, err
+	}
+	mentioned, err = uc.saveMentions(ctx, tx, postModel, mentioned)
 	if err != nil {
 		return nil, err
 	}
 	res := &domain.CreatePostResponseDTO{
-		ID:        postModel.ID,
-		Title:     postModel.Title,
-		Content:   postModel.Content,
-		AuthorID:  postModel.AuthorID,
-		CreatedAt: postModel.CreatedAt,
+		ID:              postModel.ID,
+		Title:           postModel.Title,
+		Slug:            postModel.Slug,
+		Excerpt:         postModel.Excerpt,
+		Content:         postModel.Content,
+		ContentMarkdown: postModel.ContentMarkdown,
+		TOC:             postModel.TOC,
+		AuthorID:        postModel.AuthorID,
+		CategoryID:      postModel.CategoryID,
+		Tags:            postModel.Tags,
+		Attachments:     postModel.Attachments,
+		Status:          postModel.Status,
+		Version:         postModel.Version,
+		CreatedAt:       postModel.CreatedAt,
+	}
+	err = uc.events.Publish(ctx, tx, domain.EventPostCreated, res)
+	if err != nil {
+		return nil, err
+	}
+	err = publishNotifications(ctx, uc.events, tx, mentionNotifications(postModel.AuthorID, postModel.ID, nil, mentioned)...)
+	if err != nil {
+		return nil, err
 	}
+	err = tx.Commit()
+	if err != nil {
+		return nil, err
+	}
+	uc.events.Flush(ctx)
 	return res, nil
 }
 
@@ -76,43 +157,285 @@ func (uc *PostUsecaseImpl) Delete(ctx context.Context, id int64, post *domain.De
 	if postModel.AuthorID != post.AuthorID {
 		return common.ErrPostOwnerMismatch
 	}
+	if !matchesVersion(post.IfMatch, postModel.Version) {
+		return common.ErrPostVersionMismatch
+	}
 	now := time.Now()
 	postModel.DeletedAt = &now
 	err = uc.postRepository.Update(ctx, tx, id, postModel)
 	if err != nil {
 		return err
 	}
+	err = uc.events.Publish(ctx, tx, domain.EventPostDeleted, domain.PostDeletedEvent{ID: id, Status: postModel.Status})
+	if err != nil {
+		return err
+	}
 	err = tx.Commit()
 	if err != nil {
 		return err
 	}
+	uc.events.Flush(ctx)
 	return nil
 }
 
-// GetAll implements domain.PostUseCase.
+// GetAll implements domain.PostUseCase. Searches go to the search index when
+// there is one and it can answer the filters and sort; everything else is
+// served by MySQL.
 func (uc *PostUsecaseImpl) GetAll(ctx context.Context, search domain.SearchParam) ([]domain.Post, int64, error) {
-	posts, total, err := uc.postRepository.GetAll(ctx, search)
+	var posts []domain.Post
+	var total int64
+	var err error
+	if search.Search != "" && uc.searchIndex != nil && indexable(search) {
+		posts, total, err = uc.searchPosts(ctx, search)
+	} else {
+		posts, total, err = uc.postRepository.GetAll(ctx, withIncludedFields(search))
+		setSnippets(posts, search.Search)
+	}
 	if err != nil {
 		return nil, 0, err
 	}
+	if err := uc.embed(ctx, posts, search); err != nil {
+		return nil, 0, err
+	}
 	return posts, total, nil
 }
 
+// GetAllByCursor implements domain.PostUseCase. Keyset pagination walks the
+// (created_at, id) order, so searches are served by MySQL and only creation
+// time sorts are allowed.
+func (uc *PostUsecaseImpl) GetAllByCursor(ctx context.Context, search domain.SearchParam) ([]domain.Post, *domain.PageInfo, error) {
+	switch search.Sort {
+	case "", domain.SortNewest, domain.SortOldest, domain.SortCreatedAt:
+	default:
+		return nil, nil, common.ErrCursorSort
+	}
+	posts, page, err := uc.postRepository.GetAllByCursor(ctx, withIncludedFields(search))
+	if err != nil {
+		return nil, nil, err
+	}
+	setSnippets(posts, search.Search)
+	if err := uc.embed(ctx, posts, search); err != nil {
+		return nil, nil, err
+	}
+	return posts, page, nil
+}
+
+// withIncludedFields adds the comment count to a sparse fieldset that asked
+// to include it; it is a column of its own, not a relation.
+func withIncludedFields(search domain.SearchParam) domain.SearchParam {
+	if search.Fields != "" && search.Includes("comment_count") {
+		search.Fields += ",comment_count"
+	}
+	return search
+}
+
+// embed loads the relations listed in the include parameter for the whole
+// page at once, with a single query per relation.
+func (uc *PostUsecaseImpl) embed(ctx context.Context, posts []domain.Post, search domain.SearchParam) error {
+	if len(posts) == 0 {
+		return nil
+	}
+	if search.Includes("author") {
+		var ids []int64
+		for _, post := range posts {
+			if !slices.Contains(ids, post.AuthorID) {
+				ids = append(ids, post.AuthorID)
+			}
+		}
+		users, err := uc.userRepository.FindByIDs(ctx, ids)
+		if err != nil {
+			return err
+		}
+		authors := make(map[int64]*domain.Author, len(users))
+		for _, user := range users {
+			authors[user.ID] = &domain.Author{ID: user.ID, Name: user.Name}
+		}
+		for i := range posts {
+			posts[i].Author = authors[posts[i].AuthorID]
+		}
+	}
+	if search.Includes("tags") {
+		ids := make([]int64, len(posts))
+		for i, post := range posts {
+			ids[i] = post.ID
+		}
+		tags, err := uc.tagRepository.FindByPostIDs(ctx, ids)
+		if err != nil {
+			return err
+		}
+		for i := range posts {
+			posts[i].Tags = tags[posts[i].ID]
+		}
+	}
+	refs := make([]*domain.Post, len(posts))
+	for i := range posts {
+		refs[i] = &posts[i]
+	}
+	fields := search.FieldList()
+	if len(fields) == 0 || slices.Contains(fields, "reactions") {
+		if err := uc.loadReactions(ctx, refs...); err != nil {
+			return err
+		}
+	}
+	if search.ViewerID != 0 && (len(fields) == 0 || slices.Contains(fields, "my_reactions")) {
+		if err := uc.loadMyReactions(ctx, search.ViewerID, refs...); err != nil {
+			return err
+		}
+	}
+	if search.ViewerID != 0 && (len(fields) == 0 || slices.Contains(fields, "bookmarked")) {
+		if err := uc.loadBookmarked(ctx, search.ViewerID, refs...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// indexable reports whether the search index holds everything the search
+// filters and sorts on. Categories, authors, dates and drafts are only known
+// to MySQL.
+func indexable(search domain.SearchParam) bool {
+	switch search.Sort {
+	case "", domain.SortRelevance, domain.SortNewest, domain.SortOldest:
+	default:
+		return false
+	}
+	return search.Order == "" && search.Category == "" && search.AuthorID == 0 &&
+		(search.Status == "" || search.Status == domain.PostStatusPublished) &&
+		search.CreatedFrom == nil && search.CreatedTo == nil && search.UpdatedFrom == nil && search.UpdatedTo == nil
+}
+
+func setSnippets(posts []domain.Post, search string) {
+	if search == "" {
+		return
+	}
+	terms := common.SearchTerms(search)
+	for i := range posts {
+		posts[i].Snippet = common.Highlight(posts[i].ContentText, terms, snippetLength)
+	}
+}
+
 // GetByID implements domain.PostUseCase.
-func (uc *PostUsecaseImpl) GetByID(ctx context.Context, id int64) (*domain.Post, error) {
+func (uc *PostUsecaseImpl) GetByID(ctx context.Context, id int64, viewerID int64) (*domain.Post, error) {
 	post, err := uc.postRepository.GetByID(ctx, id)
 	if err != nil {
 		return nil, err
 	}
-	if post == nil {
+	if post == nil || !post.VisibleTo(viewerID) {
+		return nil, common.ErrPostNotFound
+	}
+	if err := uc.complete(ctx, post, viewerID); err != nil {
+		return nil, err
+	}
+	return post, nil
+}
+
+// GetBySlug implements domain.PostUseCase. The returned post carries its
+// current slug, which differs from the requested one when an old slug was used.
+func (uc *PostUsecaseImpl) GetBySlug(ctx context.Context, slug string, viewerID int64) (*domain.Post, error) {
+	post, err := uc.postRepository.GetBySlug(ctx, slug)
+	if err != nil {
+		return nil, err
+	}
+	if post == nil || !post.VisibleTo(viewerID) {
 		return nil, common.ErrPostNotFound
 	}
+	if err := uc.complete(ctx, post, viewerID); err != nil {
+		return nil, err
+	}
 	return post, nil
 }
 
+// complete loads what a single post response carries besides the row
+// itself. The viewer's own reactions and bookmark are only known when the
+// viewer is signed in.
+func (uc *PostUsecaseImpl) complete(ctx context.Context, post *domain.Post, viewerID int64) error {
+	if err := uc.loadTags(ctx, post); err != nil {
+		return err
+	}
+	if err := uc.loadAttachments(ctx, post); err != nil {
+		return err
+	}
+	if err := uc.loadReactions(ctx, post); err != nil {
+		return err
+	}
+	if viewerID == 0 {
+		return nil
+	}
+	if err := uc.loadMyReactions(ctx, viewerID, post); err != nil {
+		return err
+	}
+	return uc.loadBookmarked(ctx, viewerID, post)
+}
+
 // Update implements domain.PostUseCase.
 func (uc *PostUsecaseImpl) Update(ctx context.Context, id int64, post *domain.UpdatePostRequestDTO) (*domain.UpdatePostResponseDTO, error) {
-	post.Content = common.Sanitize(post.Content)
+	return uc.update(ctx, id, post.AuthorID, post.IfMatch, func(tx domain.Transaction, postModel *domain.Post) error {
+		if err := uc.checkCategory(ctx, post.CategoryID); err != nil {
+			return err
+		}
+		if err := setContent(postModel, post.Content, post.ContentMarkdown); err != nil {
+			return err
+		}
+		postModel.Title = post.Title
+		postModel.CategoryID = post.CategoryID
+		if post.Status != "" {
+			postModel.Status = post.Status
+		}
+		tags, err := uc.saveTags(ctx, tx, id, post.Tags)
+		if err != nil {
+			return err
+		}
+		postModel.Tags = tags
+		postModel.Attachments, err = uc.saveAttachments(ctx, tx, postModel, post.AttachmentIDs)
+		return err
+	})
+}
+
+// Patch implements domain.PostUseCase.
+func (uc *PostUsecaseImpl) Patch(ctx context.Context, id int64, post *domain.PatchPostRequestDTO) (*domain.UpdatePostResponseDTO, error) {
+	return uc.update(ctx, id, post.AuthorID, post.IfMatch, func(tx domain.Transaction, postModel *domain.Post) error {
+		if post.Title != nil {
+			postModel.Title = *post.Title
+		}
+		if post.ContentMarkdown != nil {
+			if err := setContent(postModel, "", *post.ContentMarkdown); err != nil {
+				return err
+			}
+		} else if post.Content != nil {
+			if err := setContent(postModel, *post.Content, ""); err != nil {
+				return err
+			}
+		}
+		if post.Status != nil {
+			postModel.Status = *post.Status
+		}
+		if post.CategoryID.Set {
+			if err := uc.checkCategory(ctx, post.CategoryID.Value); err != nil {
+				return err
+			}
+			postModel.CategoryID = post.CategoryID.Value
+		}
+		if post.AttachmentIDs != nil {
+			attachments, err := uc.saveAttachments(ctx, tx, postModel, *post.AttachmentIDs)
+			if err != nil {
+				return err
+			}
+			postModel.Attachments = attachments
+		} else if err := uc.loadAttachments(ctx, postModel); err != nil {
+			return err
+		}
+		if post.Tags != nil {
+			tags, err := uc.saveTags(ctx, tx, id, *post.Tags)
+			postModel.Tags = tags
+			return err
+		}
+		return uc.loadTags(ctx, postModel)
+	})
+}
+
+// update locks the post, checks ownership and the If-Match precondition, then
+// persists the changes made by apply.
+func (uc *PostUsecaseImpl) update(ctx context.Context, id int64, authorID int64, ifMatch []int64, apply func(tx domain.Transaction, postModel *domain.Post) error) (*domain.UpdatePostResponseDTO, error) {
 	tx, err := uc.transactor.Begin()
 	if err != nil {
 		return nil, err
@@ -125,28 +448,355 @@ func (uc *PostUsecaseImpl) Update(ctx context.Context, id int64, post *domain.Up
 	if postModel == nil {
 		return nil, common.ErrPostNotFound
 	}
-	if postModel.AuthorID != post.AuthorID {
+	if postModel.AuthorID != authorID {
 		return nil, common.ErrPostOwnerMismatch
 	}
+	if !matchesVersion(ifMatch, postModel.Version) {
+		return nil, common.ErrPostVersionMismatch
+	}
 	now := time.Now()
-	postModel.Title = post.Title
-	postModel.Content = post.Content
+	oldTitle, oldSlug, oldStatus := postModel.Title, postModel.Slug, postModel.Status
+	if err := apply(tx, postModel); err != nil {
+		return nil, err
+	}
+	var mentioned []int64
+	postModel.Content, mentioned, err = linkMentions(ctx, uc.userRepository, postModel.Content, authorID)
+	if err != nil {
+		return nil, err
+	}
 	postModel.UpdatedAt = &now
-	err = uc.postRepository.Update(ctx, tx, id, postModel)
+	if common.Slugify(postModel.Title) != common.Slugify(oldTitle) {
+		postModel.Slug, err = uc.uniqueSlug(ctx, tx, postModel.Title, id)
+		if err != nil {
+			return nil, err
+		}
+	}
+	err = uc.saveWithSlug(ctx, tx, postModel, func() error {
+		return uc.postRepository.Update(ctx, tx, id, postModel)
+	})
 	if err != nil {
 		return nil, err
 	}
-	err = tx.Commit()
+	if postModel.Slug != oldSlug {
+		err = uc.postRepository.ArchiveSlug(ctx, tx, id, oldSlug, postModel.Slug)
+		if err != nil {
+			return nil, err
+		}
+	}
+	mentioned, err = uc.saveMentions(ctx, tx, postModel, mentioned)
 	if err != nil {
 		return nil, err
 	}
 	res := &domain.UpdatePostResponseDTO{
-		ID:        postModel.ID,
-		Title:     postModel.Title,
-		Content:   postModel.Content,
-		AuthorID:  postModel.AuthorID,
-		CreatedAt: postModel.CreatedAt,
-		UpdatedAt: postModel.UpdatedAt,
+		ID:              postModel.ID,
+		Title:           postModel.Title,
+		Slug:            postModel.Slug,
+		Excerpt:         postModel.Excerpt,
+		Content:         postModel.Content,
+		ContentMarkdown: postModel.ContentMarkdown,
+		TOC:             postModel.TOC,
+		AuthorID:        postModel.AuthorID,
+		CategoryID:      postModel.CategoryID,
+		Tags:            postModel.Tags,
+		Attachments:     postModel.Attachments,
+		Status:          postModel.Status,
+		Version:         postModel.Version,
+		CreatedAt:       postModel.CreatedAt,
+		UpdatedAt:       postModel.UpdatedAt,
 	}
+	err = uc.events.Publish(ctx, tx, domain.EventPostUpdated, res)
+	if err != nil {
+		return nil, err
+	}
+	if oldStatus == domain.PostStatusPublished && postModel.Status != domain.PostStatusPublished {
+		err = uc.events.Publish(ctx, tx, domain.EventPostUnpublished, domain.PostDeletedEvent{ID: id, Status: oldStatus})
+		if err != nil {
+			return nil, err
+		}
+	}
+	err = publishNotifications(ctx, uc.events, tx, mentionNotifications(authorID, id, nil, mentioned)...)
+	if err != nil {
+		return nil, err
+	}
+	err = tx.Commit()
+	if err != nil {
+		return nil, err
+	}
+	uc.events.Flush(ctx)
 	return res, nil
 }
+
+// searchPosts runs the search against the index and loads the matching posts
+// from MySQL in the order of their relevance.
+func (uc *PostUsecaseImpl) searchPosts(ctx context.Context, search domain.SearchParam) ([]domain.Post, int64, error) {
+	result, err := uc.searchIndex.Search(ctx, domain.SearchQuery{
+		Query: search.Search,
+		Mode:  search.Mode,
+		Sort:  search.Sort,
+		Type:  domain.SearchTypePost,
+		Tag:   search.Tag,
+		Page:  search.Page,
+		Limit: search.Limit,
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+	ids := make([]int64, len(result.Hits))
+	for i, hit := range result.Hits {
+		ids[i] = hit.ID
+	}
+	found, err := uc.postRepository.FindByIDs(ctx, ids)
+	if err != nil {
+		return nil, 0, err
+	}
+	byID := make(map[int64]domain.Post, len(found))
+	for _, post := range found {
+		byID[post.ID] = post
+	}
+	posts := make([]domain.Post, 0, len(result.Hits))
+	terms := common.SearchTerms(search.Search)
+	for _, hit := range result.Hits {
+		post, ok := byID[hit.ID]
+		if !ok {
+			continue
+		}
+		score := hit.Score
+		post.Score = &score
+		if fragments := hit.Highlights["content"]; len(fragments) > 0 {
+			post.Snippet = fragments[0]
+		} else {
+			post.Snippet = common.Highlight(post.ContentText, terms, snippetLength)
+		}
+		posts = append(posts, post)
+	}
+	return posts, result.Total, nil
+}
+
+// setContent stores the post body. Markdown takes precedence: its source is
+// kept and rendered to sanitized HTML with a table of contents. Raw HTML is
+// only sanitized and drops any previous Markdown source.
+func setContent(postModel *domain.Post, html string, source string) error {
+	if source == "" {
+		postModel.Content = common.Sanitize(html)
+		setText(postModel)
+		postModel.ContentMarkdown = nil
+		postModel.TOC = nil
+		return nil
+	}
+	rendered, headings, err := markdown.Render(source)
+	if err != nil {
+		return err
+	}
+	toc := make(domain.TableOfContents, 0, len(headings))
+	for _, heading := range headings {
+		toc = append(toc, domain.TOCEntry{Level: heading.Level, ID: heading.ID, Title: heading.Title})
+	}
+	postModel.Content = common.SanitizeMarkdown(rendered)
+	setText(postModel)
+	postModel.ContentMarkdown = &source
+	postModel.TOC = toc
+	return nil
+}
+
+// setText derives the plain text of the content, which is searched, and the
+// excerpt cut from it.
+func setText(postModel *domain.Post) {
+	postModel.ContentText = common.StripTags(postModel.Content)
+	postModel.Excerpt = common.Excerpt(postModel.ContentText, excerptLength)
+}
+
+// saveMentions records the users mentioned by the post and returns those
+// mentioned for the first time. Drafts mention nobody until they are published.
+func (uc *PostUsecaseImpl) saveMentions(ctx context.Context, tx domain.Transaction, postModel *domain.Post, userIDs []int64) ([]int64, error) {
+	if postModel.Status != domain.PostStatusPublished {
+		userIDs = nil
+	}
+	return uc.mentionRepository.Replace(ctx, tx, postModel.AuthorID, postModel.ID, nil, userIDs)
+}
+
+// checkCategory makes sure the optional category exists.
+func (uc *PostUsecaseImpl) checkCategory(ctx context.Context, categoryID *int64) error {
+	if categoryID == nil {
+		return nil
+	}
+	_, err := uc.categoryRepository.GetByID(ctx, *categoryID)
+	return err
+}
+
+// saveTags normalizes the tag names, creates the missing tags and replaces
+// the tags attached to the post.
+func (uc *PostUsecaseImpl) saveTags(ctx context.Context, tx domain.Transaction, postID int64, names []string) ([]domain.Tag, error) {
+	tags := make([]domain.Tag, 0, len(names))
+	seen := make(map[string]bool, len(names))
+	for _, name := range names {
+		name = strings.TrimSpace(name)
+		slug := common.Slugify(name)
+		if len(slug) > maxTagLength {
+			slug = strings.TrimRight(slug[:maxTagLength], "-")
+		}
+		if slug == "" {
+			return nil, common.ErrInvalidTag
+		}
+		if seen[slug] {
+			continue
+		}
+		seen[slug] = true
+		tags = append(tags, domain.Tag{Name: name, Slug: slug})
+	}
+	var err error
+	if len(tags) > 0 {
+		tags, err = uc.tagRepository.Upsert(ctx, tx, tags)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if err := uc.tagRepository.SetPostTags(ctx, tx, postID, tags); err != nil {
+		return nil, err
+	}
+	return tags, nil
+}
+
+// loadReactions sets the reaction counts of the posts with a single query.
+func (uc *PostUsecaseImpl) loadReactions(ctx context.Context, posts ...*domain.Post) error {
+	ids := make([]int64, len(posts))
+	for i, post := range posts {
+		ids[i] = post.ID
+	}
+	counts, err := uc.reactionRepository.CountByPostIDs(ctx, ids)
+	if err != nil {
+		return err
+	}
+	for _, post := range posts {
+		post.Reactions = counts[post.ID]
+		if post.Reactions == nil {
+			post.Reactions = map[string]int64{}
+		}
+	}
+	return nil
+}
+
+func (uc *PostUsecaseImpl) loadMyReactions(ctx context.Context, viewerID int64, posts ...*domain.Post) error {
+	ids := make([]int64, len(posts))
+	for i, post := range posts {
+		ids[i] = post.ID
+	}
+	reactions, err := uc.reactionRepository.FindByUser(ctx, viewerID, ids)
+	if err != nil {
+		return err
+	}
+	for _, post := range posts {
+		post.MyReactions = reactions[post.ID]
+	}
+	return nil
+}
+
+func (uc *PostUsecaseImpl) loadBookmarked(ctx context.Context, viewerID int64, posts ...*domain.Post) error {
+	ids := make([]int64, len(posts))
+	for i, post := range posts {
+		ids[i] = post.ID
+	}
+	bookmarked, err := uc.bookmarkRepository.FindBookmarked(ctx, viewerID, ids)
+	if err != nil {
+		return err
+	}
+	for _, post := range posts {
+		saved := bookmarked[post.ID]
+		post.Bookmarked = &saved
+	}
+	return nil
+}
+
+func (uc *PostUsecaseImpl) loadTags(ctx context.Context, post *domain.Post) error {
+	tags, err := uc.tagRepository.FindByPostIDs(ctx, []int64{post.ID})
+	if err != nil {
+		return err
+	}
+	post.Tags = tags[post.ID]
+	if post.Tags == nil {
+		post.Tags = []domain.Tag{}
+	}
+	return nil
+}
+
+// saveAttachments links the uploads to the post in place of the ones it had.
+func (uc *PostUsecaseImpl) saveAttachments(ctx context.Context, tx domain.Transaction, post *domain.Post, ids []int64) ([]domain.Attachment, error) {
+	attachments, err := uc.attachmentRepository.Attach(ctx, tx, post.ID, post.AuthorID, ids)
+	if err != nil {
+		return nil, err
+	}
+	for i := range attachments {
+		setAttachmentURLs(&attachments[i])
+	}
+	return attachments, nil
+}
+
+func (uc *PostUsecaseImpl) loadAttachments(ctx context.Context, post *domain.Post) error {
+	attachments, err := uc.attachmentRepository.FindByPostIDs(ctx, []int64{post.ID})
+	if err != nil {
+		return err
+	}
+	post.Attachments = attachments[post.ID]
+	if post.Attachments == nil {
+		post.Attachments = []domain.Attachment{}
+	}
+	for i := range post.Attachments {
+		setAttachmentURLs(&post.Attachments[i])
+	}
+	return nil
+}
+
+// saveWithSlug writes the post with save, moving on to the next free slug
+// when a concurrent writer took the chosen one first. That writer's row is
+// not part of the snapshot FindSlugs reads, so the slugs found taken this way
+// are remembered.
+func (uc *PostUsecaseImpl) saveWithSlug(ctx context.Context, tx domain.Transaction, post *domain.Post, save func() error) error {
+	var taken []string
+	for {
+		err := save()
+		if !errors.Is(err, common.ErrSlugTaken) || len(taken) == maxSlugRetries {
+			return err
+		}
+		taken = append(taken, post.Slug)
+		post.Slug, err = uc.uniqueSlug(ctx, tx, post.Title, post.ID, taken...)
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// uniqueSlug derives a slug from the title and appends the lowest free
+// numeric suffix when it is already used by another post, currently or in the
+// slug history, or is one of the taken slugs.
+func (uc *PostUsecaseImpl) uniqueSlug(ctx context.Context, tx domain.Transaction, title string, postID int64, taken ...string) (string, error) {
+	base := common.Slugify(title)
+	if base == "" {
+		base = "post"
+	}
+	slugs, err := uc.postRepository.FindSlugs(ctx, tx, base, postID)
+	if err != nil {
+		return "", err
+	}
+	used := make(map[string]bool, len(slugs)+len(taken))
+	for _, slug := range append(slugs, taken...) {
+		used[slug] = true
+	}
+	slug := base
+	for i := 2; used[slug]; i++ {
+		slug = fmt.Sprintf("%s-%d", base, i)
+	}
+	return slug, nil
+}
+
+// matchesVersion reports whether the current post version satisfies the
+// versions sent by the client. An empty list means the request is unconditional.
+func matchesVersion(versions []int64, current int64) bool {
+	if len(versions) == 0 {
+		return true
+	}
+	for _, version := range versions {
+		if version == current {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,90 @@
+package usecase
+
+import (
+	"app/domain"
+	"app/pkg/common"
+	"context"
+)
+
+type ReactionUseCaseImpl struct {
+	reactionRepository domain.ReactionRepository
+	postRepository     domain.PostRepository
+	transactor         domain.Transactor
+}
+
+func NewReactionUseCaseImpl(reactionRepository domain.ReactionRepository, postRepository domain.PostRepository, transactor domain.Transactor) domain.ReactionUseCase {
+	return &ReactionUseCaseImpl{
+		reactionRepository: reactionRepository,
+		postRepository:     postRepository,
+		transactor:         transactor,
+	}
+}
+
+// React implements domain.ReactionUseCase. Reacting twice with the same type
+// leaves the counters untouched.
+func (uc *ReactionUseCaseImpl) React(ctx context.Context, req domain.ReactionRequestDTO) (*domain.ReactionCountsResponseDTO, error) {
+	return uc.change(ctx, req, uc.reactionRepository.Add, 1)
+}
+
+// Unreact implements domain.ReactionUseCase. Removing a reaction that does
+// not exist succeeds without changing the counters.
+func (uc *ReactionUseCaseImpl) Unreact(ctx context.Context, req domain.ReactionRequestDTO) (*domain.ReactionCountsResponseDTO, error) {
+	return uc.change(ctx, req, uc.reactionRepository.Remove, -1)
+}
+
+// change applies a reaction change and moves the counter in the same
+// transaction, so the counter always matches the reactions it summarizes.
+func (uc *ReactionUseCaseImpl) change(ctx context.Context, req domain.ReactionRequestDTO, apply func(context.Context, domain.Transaction, int64, int64, string) (bool, error), delta int) (*domain.ReactionCountsResponseDTO, error) {
+	if err := uc.checkPost(ctx, req.PostID, req.UserID); err != nil {
+		return nil, err
+	}
+	tx, err := uc.transactor.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+	changed, err := apply(ctx, tx, req.PostID, req.UserID, req.Type)
+	if err != nil {
+		return nil, err
+	}
+	if changed {
+		if err := uc.reactionRepository.IncrementCount(ctx, tx, req.PostID, req.Type, delta); err != nil {
+			return nil, err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return uc.counts(ctx, req.PostID)
+}
+
+func (uc *ReactionUseCaseImpl) counts(ctx context.Context, postID int64) (*domain.ReactionCountsResponseDTO, error) {
+	counts, err := uc.reactionRepository.CountByPostIDs(ctx, []int64{postID})
+	if err != nil {
+		return nil, err
+	}
+	response := &domain.ReactionCountsResponseDTO{PostID: postID, Reactions: counts[postID]}
+	if response.Reactions == nil {
+		response.Reactions = map[string]int64{}
+	}
+	return response, nil
+}
+
+// FindByPostID implements domain.ReactionUseCase.
+func (uc *ReactionUseCaseImpl) FindByPostID(ctx context.Context, postID int64, param domain.SearchParam) ([]domain.Reaction, int64, error) {
+	if err := uc.checkPost(ctx, postID, param.ViewerID); err != nil {
+		return nil, 0, err
+	}
+	return uc.reactionRepository.FindByPostID(ctx, postID, param)
+}
+
+func (uc *ReactionUseCaseImpl) checkPost(ctx context.Context, postID, viewerID int64) error {
+	post, err := uc.postRepository.GetByID(ctx, postID)
+	if err != nil {
+		return err
+	}
+	if !post.VisibleTo(viewerID) {
+		return common.ErrPostNotFound
+	}
+	return nil
+}
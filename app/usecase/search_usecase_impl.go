@@ -0,0 +1,228 @@
+package usecase
+
+import (
+	"app/domain"
+	"app/pkg/common"
+	"app/pkg/logger"
+	"context"
+	"encoding/json"
+	"errors"
+
+	"go.uber.org/zap"
+)
+
+const reindexBatchSize = 200
+
+type SearchUseCaseImpl struct {
+	searchIndex       domain.SearchIndex
+	postRepository    domain.PostRepository
+	commentRepository domain.CommentRepository
+	userRepository    domain.UserRepository
+	tagRepository     domain.TagRepository
+}
+
+// NewSearchUseCaseImpl returns the use case searching the index, which is
+// also the outbox handler keeping it up to date. Without an index it serves
+// as a handler that does nothing.
+func NewSearchUseCaseImpl(searchIndex domain.SearchIndex, postRepository domain.PostRepository, commentRepository domain.CommentRepository, userRepository domain.UserRepository, tagRepository domain.TagRepository) *SearchUseCaseImpl {
+	return &SearchUseCaseImpl{
+		searchIndex:       searchIndex,
+		postRepository:    postRepository,
+		commentRepository: commentRepository,
+		userRepository:    userRepository,
+		tagRepository:     tagRepository,
+	}
+}
+
+// Search implements domain.SearchUseCase.
+func (uc *SearchUseCaseImpl) Search(ctx context.Context, query domain.SearchQuery) (*domain.SearchResult, error) {
+	return uc.searchIndex.Search(ctx, query)
+}
+
+// Reindex implements domain.SearchUseCase. The index is emptied and rebuilt
+// from every post and comment stored in MySQL.
+func (uc *SearchUseCaseImpl) Reindex(ctx context.Context) error {
+	if err := uc.searchIndex.Reset(ctx); err != nil {
+		return err
+	}
+	authors := make(map[int64]string)
+	var total int
+	for page := 1; ; page++ {
+		posts, _, err := uc.postRepository.GetAll(ctx, domain.SearchParam{Page: page, Limit: reindexBatchSize, Sort: domain.SortOldest})
+		if err != nil {
+			return err
+		}
+		if len(posts) == 0 {
+			break
+		}
+		ids := make([]int64, len(posts))
+		for i := range posts {
+			ids[i] = posts[i].ID
+		}
+		tags, err := uc.tagRepository.FindByPostIDs(ctx, ids)
+		if err != nil {
+			return err
+		}
+		var documents []domain.SearchDocument
+		for i := range posts {
+			post := &posts[i]
+			post.Tags = tags[post.ID]
+			author, ok := authors[post.AuthorID]
+			if !ok {
+				user, err := uc.userRepository.FindByID(ctx, post.AuthorID)
+				if err != nil {
+					return err
+				}
+				if user != nil {
+					author = user.Name
+				}
+				authors[post.AuthorID] = author
+			}
+			documents = append(documents, postDocument(post, author))
+			comments, err := uc.allComments(ctx, post.ID)
+			if err != nil {
+				return err
+			}
+			for _, comment := range comments {
+				documents = append(documents, commentDocument(comment))
+			}
+		}
+		if err := uc.searchIndex.Index(ctx, documents...); err != nil {
+			return err
+		}
+		total += len(documents)
+	}
+	logger.Log.Info("rebuilt search index", zap.Int("documents", total))
+	return nil
+}
+
+// Handle implements domain.OutboxHandler. Documents are reloaded from MySQL
+// rather than taken from the event, so a late or repeated event never puts
+// stale content back into the index.
+func (uc *SearchUseCaseImpl) Handle(ctx context.Context, event domain.OutboxEvent) error {
+	if uc.searchIndex == nil {
+		return nil
+	}
+	var data struct {
+		ID int64 `json:"id"`
+	}
+	switch event.Type {
+	case domain.EventPostCreated, domain.EventPostUpdated, domain.EventPostDeleted,
+		domain.EventCommentCreated, domain.EventCommentUpdated, domain.EventCommentDeleted:
+		if err := json.Unmarshal(event.Payload, &data); err != nil {
+			return err
+		}
+	default:
+		return nil
+	}
+	switch event.Type {
+	case domain.EventPostDeleted:
+		return uc.searchIndex.DeletePost(ctx, data.ID)
+	case domain.EventCommentDeleted:
+		return uc.searchIndex.DeleteComment(ctx, data.ID)
+	case domain.EventCommentCreated, domain.EventCommentUpdated:
+		return uc.indexComment(ctx, data.ID)
+	}
+	return uc.indexPost(ctx, data.ID)
+}
+
+// indexComment copies the comment into the search index, or removes it
+// unless its post is published.
+func (uc *SearchUseCaseImpl) indexComment(ctx context.Context, id int64) error {
+	comment, err := uc.commentRepository.GetByID(ctx, id)
+	if errors.Is(err, common.ErrCommentNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	post, err := uc.postRepository.GetByID(ctx, comment.PostID)
+	if err != nil && !errors.Is(err, common.ErrPostNotFound) {
+		return err
+	}
+	if post == nil || post.Status != domain.PostStatusPublished {
+		return uc.searchIndex.DeleteComment(ctx, id)
+	}
+	return uc.searchIndex.Index(ctx, commentDocument(comment))
+}
+
+// indexPost copies the post and its comments into the search index, or
+// removes them while it is a draft or once it is gone. The comments are
+// indexed again as well since they were dropped when the post was last
+// unpublished.
+func (uc *SearchUseCaseImpl) indexPost(ctx context.Context, id int64) error {
+	post, err := uc.postRepository.GetByID(ctx, id)
+	if errors.Is(err, common.ErrPostNotFound) {
+		return uc.searchIndex.DeletePost(ctx, id)
+	}
+	if err != nil {
+		return err
+	}
+	if post == nil || post.Status != domain.PostStatusPublished {
+		return uc.searchIndex.DeletePost(ctx, id)
+	}
+	tags, err := uc.tagRepository.FindByPostIDs(ctx, []int64{id})
+	if err != nil {
+		return err
+	}
+	post.Tags = tags[id]
+	user, err := uc.userRepository.FindByID(ctx, post.AuthorID)
+	if err != nil {
+		return err
+	}
+	var author string
+	if user != nil {
+		author = user.Name
+	}
+	documents := []domain.SearchDocument{postDocument(post, author)}
+	comments, err := uc.allComments(ctx, id)
+	if err != nil {
+		return err
+	}
+	for _, comment := range comments {
+		documents = append(documents, commentDocument(comment))
+	}
+	return uc.searchIndex.Index(ctx, documents...)
+}
+
+func (uc *SearchUseCaseImpl) allComments(ctx context.Context, postID int64) ([]*domain.Comment, error) {
+	var comments []*domain.Comment
+	for page := 1; ; page++ {
+		batch, _, err := uc.commentRepository.FindByPostID(ctx, postID, domain.SearchParam{Page: page, Limit: reindexBatchSize})
+		if err != nil {
+			return nil, err
+		}
+		comments = append(comments, batch...)
+		if len(batch) < reindexBatchSize {
+			return comments, nil
+		}
+	}
+}
+
+func postDocument(post *domain.Post, author string) domain.SearchDocument {
+	tags := make([]string, len(post.Tags))
+	for i, tag := range post.Tags {
+		tags[i] = tag.Slug
+	}
+	return domain.SearchDocument{
+		Type:      domain.SearchTypePost,
+		ID:        post.ID,
+		PostID:    post.ID,
+		Title:     post.Title,
+		Content:   post.ContentText,
+		Author:    author,
+		Tags:      tags,
+		CreatedAt: post.CreatedAt,
+	}
+}
+
+func commentDocument(comment *domain.Comment) domain.SearchDocument {
+	return domain.SearchDocument{
+		Type:      domain.SearchTypeComment,
+		ID:        comment.ID,
+		PostID:    comment.PostID,
+		Content:   common.StripTags(comment.Content),
+		Author:    comment.AuthorName,
+		CreatedAt: comment.CreatedAt,
+	}
+}
@@ -0,0 +1,45 @@
+package usecase
+
+import (
+	"app/domain"
+	"app/pkg/common"
+	"context"
+)
+
+type SitemapUseCaseImpl struct {
+	postRepository domain.PostRepository
+	pageSize       int
+}
+
+// NewSitemapUseCaseImpl lists published posts in sitemaps of pageSize URLs,
+// falling back to the protocol limit when pageSize is not positive.
+func NewSitemapUseCaseImpl(postRepository domain.PostRepository, pageSize int) domain.SitemapUseCase {
+	if pageSize <= 0 || pageSize > domain.SitemapMaxURLs {
+		pageSize = domain.SitemapMaxURLs
+	}
+	return &SitemapUseCaseImpl{
+		postRepository: postRepository,
+		pageSize:       pageSize,
+	}
+}
+
+// Pages implements domain.SitemapUseCase. A site without posts still has one
+// empty sitemap.
+func (uc *SitemapUseCaseImpl) Pages(ctx context.Context) (int, error) {
+	total, err := uc.postRepository.CountPublished(ctx)
+	if err != nil {
+		return 0, err
+	}
+	if total == 0 {
+		return 1, nil
+	}
+	return int((total + int64(uc.pageSize) - 1) / int64(uc.pageSize)), nil
+}
+
+// Stream implements domain.SitemapUseCase.
+func (uc *SitemapUseCaseImpl) Stream(ctx context.Context, page int, fn func(domain.SitemapEntry) error) error {
+	if page < 1 {
+		return common.ErrSitemapNotFound
+	}
+	return uc.postRepository.StreamPublished(ctx, (page-1)*uc.pageSize, uc.pageSize, fn)
+}
@@ -0,0 +1,34 @@
+package usecase
+
+import (
+	"app/domain"
+	"context"
+)
+
+type TagUseCaseImpl struct {
+	tagRepository domain.TagRepository
+	postUseCase   domain.PostUseCase
+}
+
+func NewTagUseCaseImpl(tagRepository domain.TagRepository, postUseCase domain.PostUseCase) domain.TagUseCase {
+	return &TagUseCaseImpl{
+		tagRepository: tagRepository,
+		postUseCase:   postUseCase,
+	}
+}
+
+// GetAll implements domain.TagUseCase.
+func (uc *TagUseCaseImpl) GetAll(ctx context.Context) ([]domain.TagCount, error) {
+	return uc.tagRepository.GetAllWithCount(ctx)
+}
+
+// GetPosts implements domain.TagUseCase. The posts are listed like those of
+// the post listing, filtered by the tag.
+func (uc *TagUseCaseImpl) GetPosts(ctx context.Context, slug string, search domain.SearchParam) ([]domain.Post, int64, error) {
+	tag, err := uc.tagRepository.GetBySlug(ctx, slug)
+	if err != nil {
+		return nil, 0, err
+	}
+	search.Tag = tag.Slug
+	return uc.postUseCase.GetAll(ctx, search)
+}
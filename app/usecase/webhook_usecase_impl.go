@@ -0,0 +1,402 @@
+package usecase
+
+import (
+	"app/domain"
+	"app/pkg/common"
+	"app/pkg/logger"
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+const (
+	webhookBatchSize    = 10
+	webhookTimeout      = 10 * time.Second
+	webhookMaxAttempts  = 6
+	webhookRetryBackoff = time.Minute
+	webhookDisableAfter = 15
+	webhookErrorLength  = 250
+)
+
+// webhookPayload is the body posted to webhooks.
+type webhookPayload struct {
+	ID        string          `json:"id"`
+	Event     string          `json:"event"`
+	CreatedAt time.Time       `json:"created_at"`
+	Data      json.RawMessage `json:"data"`
+}
+
+type WebhookUseCaseImpl struct {
+	webhookRepository domain.WebhookRepository
+	postRepository    domain.PostRepository
+	transactor        domain.Transactor
+	client            *http.Client
+}
+
+// NewWebhookUseCaseImpl returns the use case managing webhooks and
+// delivering to them, which is also the outbox handler queueing the
+// deliveries of content events.
+func NewWebhookUseCaseImpl(webhookRepository domain.WebhookRepository, postRepository domain.PostRepository, transactor domain.Transactor) *WebhookUseCaseImpl {
+	return &WebhookUseCaseImpl{
+		webhookRepository: webhookRepository,
+		postRepository:    postRepository,
+		transactor:        transactor,
+		client:            &http.Client{Timeout: webhookTimeout},
+	}
+}
+
+// Handle implements domain.OutboxHandler, queueing content events for the
+// webhooks subscribed to them. The payload is rendered once and stored, so
+// retries and replays send exactly the same body. Its id is the key of the
+// event, which lets receivers drop the duplicates at-least-once delivery
+// can cause. Drafts are private, so neither they nor the comments on them
+// are sent anywhere; a post taken back to draft is announced by
+// post.unpublished instead.
+func (uc *WebhookUseCaseImpl) Handle(ctx context.Context, event domain.OutboxEvent) error {
+	if !slices.Contains(domain.WebhookEvents, event.Type) {
+		return nil
+	}
+	published, err := uc.published(ctx, event)
+	if err != nil || !published {
+		return err
+	}
+	webhooks, err := uc.webhookRepository.FindAll(ctx)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	var deliveries []domain.WebhookDelivery
+	var payload []byte
+	for _, webhook := range webhooks {
+		if !webhook.Enabled || !slices.Contains(webhook.Events, event.Type) {
+			continue
+		}
+		if payload == nil {
+			payload, err = json.Marshal(webhookPayload{ID: event.Key, Event: event.Type, CreatedAt: event.CreatedAt, Data: event.Payload})
+			if err != nil {
+				return err
+			}
+		}
+		deliveries = append(deliveries, domain.WebhookDelivery{
+			WebhookID:     webhook.ID,
+			Event:         event.Type,
+			Payload:       payload,
+			Status:        domain.WebhookDeliveryPending,
+			NextAttemptAt: &now,
+			CreatedAt:     now,
+		})
+	}
+	if len(deliveries) == 0 {
+		return nil
+	}
+	return uc.webhookRepository.CreateDeliveries(ctx, deliveries)
+}
+
+// published reports whether the event is about a published post or a
+// comment on one. Posts are judged by the status they had when the event was
+// published, which for removals is the status before them, and comments by
+// the current status of their post.
+func (uc *WebhookUseCaseImpl) published(ctx context.Context, event domain.OutboxEvent) (bool, error) {
+	var data struct {
+		PostID int64  `json:"post_id"`
+		Status string `json:"status"`
+	}
+	if err := json.Unmarshal(event.Payload, &data); err != nil {
+		return false, err
+	}
+	switch event.Type {
+	case domain.EventPostCreated, domain.EventPostUpdated, domain.EventPostUnpublished, domain.EventPostDeleted:
+		return data.Status == domain.PostStatusPublished, nil
+	}
+	post, err := uc.postRepository.GetByID(ctx, data.PostID)
+	if errors.Is(err, common.ErrPostNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return post.Status == domain.PostStatusPublished, nil
+}
+
+// Create implements domain.WebhookUseCase.
+func (uc *WebhookUseCaseImpl) Create(ctx context.Context, request domain.WebhookRequestDTO) (*domain.CreateWebhookResponseDTO, error) {
+	if err := validateWebhook(request); err != nil {
+		return nil, err
+	}
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		logger.Log.Error("failed to generate webhook secret", zap.Error(err))
+		return nil, common.ErrInternalServerError
+	}
+	webhook := &domain.Webhook{
+		URL:       request.URL,
+		Secret:    hex.EncodeToString(secret),
+		Events:    request.Events,
+		Enabled:   request.Enabled == nil || *request.Enabled,
+		CreatedAt: time.Now(),
+	}
+	if !webhook.Enabled {
+		webhook.DisabledAt = &webhook.CreatedAt
+	}
+	if err := uc.webhookRepository.Create(ctx, webhook); err != nil {
+		return nil, err
+	}
+	return &domain.CreateWebhookResponseDTO{Webhook: webhook, Secret: webhook.Secret}, nil
+}
+
+// Update implements domain.WebhookUseCase. Enabling a webhook again clears
+// its failures.
+func (uc *WebhookUseCaseImpl) Update(ctx context.Context, id int64, request domain.WebhookRequestDTO) (*domain.Webhook, error) {
+	if err := validateWebhook(request); err != nil {
+		return nil, err
+	}
+	webhook, err := uc.webhookRepository.FindByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	webhook.URL = request.URL
+	webhook.Events = request.Events
+	webhook.UpdatedAt = &now
+	if request.Enabled != nil && *request.Enabled != webhook.Enabled {
+		webhook.Enabled = *request.Enabled
+		webhook.FailureCount = 0
+		webhook.DisabledAt = nil
+		if !webhook.Enabled {
+			webhook.DisabledAt = &now
+		}
+	}
+	if err := uc.webhookRepository.Update(ctx, webhook); err != nil {
+		return nil, err
+	}
+	return webhook, nil
+}
+
+// Delete implements domain.WebhookUseCase.
+func (uc *WebhookUseCaseImpl) Delete(ctx context.Context, id int64) error {
+	found, err := uc.webhookRepository.Delete(ctx, id)
+	if err != nil {
+		return err
+	}
+	if !found {
+		return common.ErrWebhookNotFound
+	}
+	return nil
+}
+
+// FindByID implements domain.WebhookUseCase.
+func (uc *WebhookUseCaseImpl) FindByID(ctx context.Context, id int64) (*domain.Webhook, error) {
+	return uc.webhookRepository.FindByID(ctx, id)
+}
+
+// FindAll implements domain.WebhookUseCase.
+func (uc *WebhookUseCaseImpl) FindAll(ctx context.Context) ([]domain.Webhook, error) {
+	return uc.webhookRepository.FindAll(ctx)
+}
+
+// FindDeliveries implements domain.WebhookUseCase.
+func (uc *WebhookUseCaseImpl) FindDeliveries(ctx context.Context, webhookID int64, param domain.SearchParam) ([]domain.WebhookDelivery, int64, error) {
+	if _, err := uc.webhookRepository.FindByID(ctx, webhookID); err != nil {
+		return nil, 0, err
+	}
+	return uc.webhookRepository.FindDeliveries(ctx, webhookID, param)
+}
+
+// Replay implements domain.WebhookUseCase.
+func (uc *WebhookUseCaseImpl) Replay(ctx context.Context, webhookID, deliveryID int64) (*domain.WebhookDelivery, error) {
+	original, err := uc.webhookRepository.FindDelivery(ctx, webhookID, deliveryID)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	deliveries := []domain.WebhookDelivery{{
+		WebhookID:     webhookID,
+		Event:         original.Event,
+		Payload:       original.Payload,
+		Status:        domain.WebhookDeliveryPending,
+		NextAttemptAt: &now,
+		CreatedAt:     now,
+	}}
+	if err := uc.webhookRepository.CreateDeliveries(ctx, deliveries); err != nil {
+		return nil, err
+	}
+	return &deliveries[0], nil
+}
+
+// Deliver implements domain.WebhookUseCase. The batch is claimed in a short
+// transaction and attempted outside of it, so a slow endpoint holds no locks.
+func (uc *WebhookUseCaseImpl) Deliver(ctx context.Context) (int, error) {
+	tx, err := uc.transactor.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+	until := time.Now().Add(webhookBatchSize*webhookTimeout + time.Minute)
+	deliveries, err := uc.webhookRepository.ClaimDeliveries(ctx, tx, webhookBatchSize, until)
+	if err != nil {
+		return 0, err
+	}
+	err = tx.Commit()
+	if err != nil {
+		return 0, err
+	}
+	webhooks := map[int64]*domain.Webhook{}
+	for i := range deliveries {
+		delivery := &deliveries[i]
+		webhook, ok := webhooks[delivery.WebhookID]
+		if !ok {
+			webhook, err = uc.webhookRepository.FindByID(ctx, delivery.WebhookID)
+			if err != nil && err != common.ErrWebhookNotFound {
+				return 0, err
+			}
+			webhooks[delivery.WebhookID] = webhook
+		}
+		// The webhook may have been deleted since the claim. Deliveries
+		// left once it is disabled stay pending until it is enabled again.
+		if webhook == nil || !webhook.Enabled {
+			continue
+		}
+		if err := uc.attempt(ctx, webhook, delivery); err != nil {
+			return 0, err
+		}
+	}
+	return len(deliveries), nil
+}
+
+// Run delivers due webhooks every interval until ctx is done, working
+// through the whole backlog on each tick.
+func (uc *WebhookUseCaseImpl) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		for ctx.Err() == nil {
+			delivered, err := uc.Deliver(ctx)
+			if err != nil {
+				if ctx.Err() == nil {
+					logger.Log.Error("failed to deliver webhooks", zap.Error(err))
+				}
+				break
+			}
+			if delivered < webhookBatchSize {
+				break
+			}
+		}
+	}
+}
+
+// attempt posts the delivery once and records the outcome. Failed attempts
+// are retried with exponential backoff until they run out. An attempt cut
+// short by ctx is not counted; the delivery is retried once its claim ends.
+func (uc *WebhookUseCaseImpl) attempt(ctx context.Context, webhook *domain.Webhook, delivery *domain.WebhookDelivery) error {
+	status, err := uc.post(ctx, webhook, delivery)
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	now := time.Now()
+	delivery.Attempts++
+	delivery.ResponseStatus = nil
+	if status != 0 {
+		delivery.ResponseStatus = &status
+	}
+	if err == nil {
+		delivery.Status = domain.WebhookDeliverySucceeded
+		delivery.Error = nil
+		delivery.NextAttemptAt = nil
+		delivery.DeliveredAt = &now
+		if err := uc.webhookRepository.SaveAttempt(ctx, delivery); err != nil {
+			return err
+		}
+		webhook.FailureCount = 0
+		return uc.webhookRepository.ResetFailures(ctx, webhook.ID)
+	}
+	message := common.Excerpt(err.Error(), webhookErrorLength)
+	delivery.Error = &message
+	delivery.NextAttemptAt = nil
+	if delivery.Attempts < webhookMaxAttempts {
+		next := now.Add(webhookRetryBackoff << (delivery.Attempts - 1))
+		delivery.NextAttemptAt = &next
+	} else {
+		delivery.Status = domain.WebhookDeliveryFailed
+	}
+	if err := uc.webhookRepository.SaveAttempt(ctx, delivery); err != nil {
+		return err
+	}
+	disabled, err := uc.webhookRepository.RecordFailure(ctx, webhook.ID, webhookDisableAfter)
+	if err != nil {
+		return err
+	}
+	if disabled {
+		logger.Log.Warn("disabled failing webhook", zap.Int64("webhookID", webhook.ID))
+		webhook.Enabled = false
+	}
+	return nil
+}
+
+// post sends the payload of the delivery and returns the response status.
+// The signature covers the timestamp and the body, so receivers can reject
+// both forged and replayed requests.
+func (uc *WebhookUseCaseImpl) post(ctx context.Context, webhook *domain.Webhook, delivery *domain.WebhookDelivery) (int, error) {
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhook.URL, bytes.NewReader(delivery.Payload))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Webhook-ID", strconv.FormatInt(delivery.ID, 10))
+	req.Header.Set("X-Webhook-Event", delivery.Event)
+	req.Header.Set("X-Webhook-Timestamp", timestamp)
+	req.Header.Set("X-Webhook-Signature", "sha256="+signWebhook(webhook.Secret, timestamp, delivery.Payload))
+	resp, err := uc.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("unexpected response status %d", resp.StatusCode)
+	}
+	return resp.StatusCode, nil
+}
+
+// signWebhook returns the hex HMAC-SHA256 of "timestamp.body".
+func signWebhook(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp + "."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func validateWebhook(request domain.WebhookRequestDTO) error {
+	parsed, err := url.Parse(request.URL)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return common.ErrInvalidWebhookURL
+	}
+	if len(request.Events) == 0 {
+		return common.ErrWebhookEvent
+	}
+	for _, event := range request.Events {
+		if !slices.Contains(domain.WebhookEvents, event) {
+			return common.ErrWebhookEvent
+		}
+	}
+	return nil
+}